@@ -0,0 +1,67 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/util/response"
+	"github.com/gorilla/mux"
+)
+
+// ClientRequest is the body of client update requests
+type ClientRequest struct {
+	// SkipConsent is left unchanged when omitted
+	SkipConsent *bool `json:"skip_consent,omitempty"`
+}
+
+// Handles requests to get a client (GET /v1/admin/clients/{client_id})
+func (s *Service) getClientHandler(w http.ResponseWriter, r *http.Request) {
+	client, ok := s.getManagedClient(w, r)
+	if !ok {
+		return
+	}
+	response.WriteJSON(w, NewClientResponse(r, client), http.StatusOK)
+}
+
+// Handles requests to update a client (PUT /v1/admin/clients/{client_id}),
+// marking first-party clients which skip the consent screen is only
+// possible here and not through dynamic client registration
+func (s *Service) updateClientHandler(w http.ResponseWriter, r *http.Request) {
+	client, ok := s.getManagedClient(w, r)
+	if !ok {
+		return
+	}
+
+	clientRequest := new(ClientRequest)
+	if err := json.NewDecoder(r.Body).Decode(clientRequest); err != nil {
+		response.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if clientRequest.SkipConsent != nil {
+		if err := s.oauthService.SetSkipConsent(client, *clientRequest.SkipConsent); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+
+	s.recordChange(r, "client_updated", client.TenantID, map[string]string{"client_id": client.Key})
+	response.WriteJSON(w, NewClientResponse(r, client), http.StatusOK)
+}
+
+// getManagedClient looks up the client of the request path and makes sure
+// the superuser can manage it, writing the error response otherwise
+func (s *Service) getManagedClient(w http.ResponseWriter, r *http.Request) (*models.OauthClient, bool) {
+	client, err := s.oauthService.FindClientByClientID(mux.Vars(r)["client_id"])
+	if err != nil && err != oauth.ErrClientNotFound {
+		response.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	// Clients of other tenants look like they don't exist
+	if err == oauth.ErrClientNotFound || !canManageTenant(getSuperuser(r), client.TenantID) {
+		response.Error(w, oauth.ErrClientNotFound.Error(), http.StatusNotFound)
+		return nil, false
+	}
+	return client, true
+}
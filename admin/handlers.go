@@ -0,0 +1,819 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/audit"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/passwordpolicy"
+	"github.com/RichardKnop/go-oauth2-server/session"
+	"github.com/RichardKnop/go-oauth2-server/util/response"
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultPageLimit = 25
+	maxPageLimit     = 100
+)
+
+// UserRequest is the body of user create and update requests
+type UserRequest struct {
+	TenantID string `json:"tenant_id"`
+	Account  string `json:"account"`
+	Password string `json:"password"`
+	Name     string `json:"name"`
+	Phone    string `json:"phone"`
+	RoleID   string `json:"role_id"`
+	// Email is sent a confirmation link when it changes
+	Email string `json:"email,omitempty"`
+	// MFARequired is left unchanged when omitted
+	MFARequired *bool `json:"mfa_required,omitempty"`
+}
+
+// RoleRequest is the body of role create and update requests
+type RoleRequest struct {
+	TenantID    string   `json:"tenant_id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+	// Scope caps the scope granted to users of the role
+	Scope string `json:"scope"`
+}
+
+// PasswordRequest is the body of set password requests
+type PasswordRequest struct {
+	Password string `json:"password"`
+}
+
+// PasswordResponse returns a password generated by a reset
+type PasswordResponse struct {
+	Password string `json:"password"`
+}
+
+// SigningKeyResponse returns the key ID of a new signing key
+type SigningKeyResponse struct {
+	KeyID string `json:"kid"`
+}
+
+// Handles requests to create a user (POST /v1/admin/users)
+func (s *Service) createUserHandler(w http.ResponseWriter, r *http.Request) {
+	userRequest := new(UserRequest)
+	if err := json.NewDecoder(r.Body).Decode(userRequest); err != nil {
+		response.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !canManageTenant(getSuperuser(r), userRequest.TenantID) {
+		response.Error(w, ErrTenantForbidden.Error(), http.StatusForbidden)
+		return
+	}
+
+	user, err := s.oauthService.CreateUser(
+		userRequest.RoleID,
+		userRequest.Account,
+		userRequest.Password,
+		userRequest.TenantID,
+	)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if userRequest.Name != "" || userRequest.Phone != "" {
+		if err := s.oauthService.UpdateUser(user, userRequest.Name, "", userRequest.Phone); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	if userRequest.MFARequired != nil {
+		if err := s.oauthService.SetMFARequired(user, *userRequest.MFARequired); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	if userRequest.Email != "" {
+		if err := s.setEmail(user, userRequest.Email); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+
+	s.recordChange(r, "user_created", user.TenantID, map[string]string{"user_id": user.ID})
+	response.WriteJSON(w, NewUserResponse(r, user), http.StatusCreated)
+}
+
+// Handles requests to list a tenant's users (GET /v1/admin/users)
+func (s *Service) listUsersHandler(w http.ResponseWriter, r *http.Request) {
+	superuser := getSuperuser(r)
+	query := r.URL.Query()
+	tenantID := superuser.TenantID
+	if _, ok := query["tenant_id"]; ok {
+		tenantID = query.Get("tenant_id")
+	}
+	if !canManageTenant(superuser, tenantID) {
+		response.Error(w, ErrTenantForbidden.Error(), http.StatusForbidden)
+		return
+	}
+
+	page, err := queryInt(query.Get("page"), 1)
+	if err != nil || page < 1 {
+		response.Error(w, "Invalid page", http.StatusBadRequest)
+		return
+	}
+	limit, err := queryInt(query.Get("limit"), defaultPageLimit)
+	if err != nil || limit < 1 || limit > maxPageLimit {
+		response.Error(w, "Invalid limit", http.StatusBadRequest)
+		return
+	}
+
+	users, count, err := s.oauthService.ListUsers(tenantID, (page-1)*limit, limit)
+	if err != nil {
+		response.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	items := make([]*UserResponse, len(users))
+	for i, user := range users {
+		items[i] = NewUserResponse(r, user)
+	}
+
+	lastPage := (count + limit - 1) / limit
+	if lastPage < 1 {
+		lastPage = 1
+	}
+	pageURL := func(p int) string {
+		if p < 1 || p > lastPage {
+			return ""
+		}
+		return fmt.Sprintf("%s?tenant_id=%s&page=%d&limit=%d", r.URL.Path, tenantID, p, limit)
+	}
+
+	response.WriteJSON(w, response.NewListResponse(
+		count,
+		page,
+		pageURL(page),
+		pageURL(1),
+		pageURL(lastPage),
+		pageURL(page-1),
+		pageURL(page+1),
+		"users",
+		items,
+	), http.StatusOK)
+}
+
+// Handles requests to get a user (GET /v1/admin/users/{id})
+func (s *Service) getUserHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.getManagedUser(w, r)
+	if !ok {
+		return
+	}
+
+	response.WriteJSON(w, NewUserResponse(r, user), http.StatusOK)
+}
+
+// Handles requests to update a user (PUT /v1/admin/users/{id})
+func (s *Service) updateUserHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.getManagedUser(w, r)
+	if !ok {
+		return
+	}
+
+	userRequest := new(UserRequest)
+	if err := json.NewDecoder(r.Body).Decode(userRequest); err != nil {
+		response.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err := s.oauthService.UpdateUser(user, userRequest.Name, userRequest.Account, userRequest.Phone)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if userRequest.MFARequired != nil {
+		if err := s.oauthService.SetMFARequired(user, *userRequest.MFARequired); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	if userRequest.Email != "" {
+		if err := s.setEmail(user, userRequest.Email); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+
+	s.recordChange(r, "user_updated", user.TenantID, map[string]string{"user_id": user.ID})
+	response.WriteJSON(w, NewUserResponse(r, user), http.StatusOK)
+}
+
+// Handles requests to delete a user (DELETE /v1/admin/users/{id})
+func (s *Service) deleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.getManagedUser(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.oauthService.DeleteUser(user); err != nil {
+		writeError(w, err)
+		return
+	}
+	s.endUserSessions(user)
+
+	s.recordChange(r, "user_deleted", user.TenantID, map[string]string{"user_id": user.ID})
+	response.NoContent(w)
+}
+
+// Handles requests to set a user's password (PUT /v1/admin/users/{id}/password)
+func (s *Service) setPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.getManagedUser(w, r)
+	if !ok {
+		return
+	}
+
+	passwordRequest := new(PasswordRequest)
+	if err := json.NewDecoder(r.Body).Decode(passwordRequest); err != nil {
+		response.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.oauthService.SetPassword(user, passwordRequest.Password); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	s.recordChange(r, "password_set", user.TenantID, map[string]string{"user_id": user.ID})
+	response.NoContent(w)
+}
+
+// Handles requests to reset a user's password (POST /v1/admin/users/{id}/password/reset)
+func (s *Service) resetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.getManagedUser(w, r)
+	if !ok {
+		return
+	}
+
+	password, err := s.oauthService.ResetPassword(user)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	s.recordChange(r, "password_reset", user.TenantID, map[string]string{"user_id": user.ID})
+	response.WriteJSON(w, &PasswordResponse{Password: password}, http.StatusOK)
+}
+
+// Handles requests to disable a user (POST /v1/admin/users/{id}/disable)
+func (s *Service) disableUserHandler(w http.ResponseWriter, r *http.Request) {
+	s.setUserDisabled(w, r, true)
+}
+
+// Handles requests to enable a user (POST /v1/admin/users/{id}/enable)
+func (s *Service) enableUserHandler(w http.ResponseWriter, r *http.Request) {
+	s.setUserDisabled(w, r, false)
+}
+
+// Handles requests to lift a user's login lockout (POST /v1/admin/users/{id}/unlock)
+func (s *Service) unlockUserHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.getManagedUser(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.oauthService.UnlockUser(user); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	s.recordChange(r, "user_unlocked", user.TenantID, map[string]string{"user_id": user.ID})
+	response.NoContent(w)
+}
+
+// Handles requests to remove a user's authenticator and recovery codes
+// (POST /v1/admin/users/{id}/mfa/reset)
+func (s *Service) resetMFAHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.getManagedUser(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.oauthService.ResetMFA(user); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	s.recordChange(r, "mfa_reset", user.TenantID, map[string]string{"user_id": user.ID})
+	response.WriteJSON(w, NewUserResponse(r, user), http.StatusOK)
+}
+
+// Handles requests to list a user's roles (GET /v1/admin/users/{id}/roles)
+func (s *Service) listUserRolesHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.getManagedUser(w, r)
+	if !ok {
+		return
+	}
+
+	userRoles, err := s.oauthService.GetUserRoles(user)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	s.writeRoles(w, r, r.URL.Path, userRoles)
+}
+
+// Handles requests to assign a role to a user (PUT /v1/admin/users/{id}/roles/{role_id})
+func (s *Service) assignRoleHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.getManagedUser(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.oauthService.AssignRole(user, mux.Vars(r)["role_id"]); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	s.recordChange(r, "role_assigned", user.TenantID, map[string]string{
+		"user_id": user.ID,
+		"role_id": mux.Vars(r)["role_id"],
+	})
+	response.NoContent(w)
+}
+
+// Handles requests to unassign a role from a user (DELETE /v1/admin/users/{id}/roles/{role_id})
+func (s *Service) unassignRoleHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.getManagedUser(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.oauthService.UnassignRole(user, mux.Vars(r)["role_id"]); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	s.recordChange(r, "role_unassigned", user.TenantID, map[string]string{
+		"user_id": user.ID,
+		"role_id": mux.Vars(r)["role_id"],
+	})
+	response.NoContent(w)
+}
+
+// Handles requests to list a user's active sessions (GET /v1/admin/users/{id}/sessions)
+func (s *Service) listUserSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.getManagedUser(w, r)
+	if !ok {
+		return
+	}
+
+	userSessions, err := s.sessionService.ListUserSessions(user.ID)
+	if err != nil {
+		writeSessionError(w, err)
+		return
+	}
+
+	self := r.URL.Path
+	items := make([]*SessionResponse, len(userSessions))
+	for i, info := range userSessions {
+		items[i] = NewSessionResponse(self, info)
+	}
+
+	response.WriteJSON(w, response.NewListResponse(
+		len(items),
+		1,
+		self,
+		self,
+		self,
+		"",
+		"",
+		"sessions",
+		items,
+	), http.StatusOK)
+}
+
+// Handles requests to end all sessions of a user (DELETE /v1/admin/users/{id}/sessions)
+func (s *Service) revokeUserSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.getManagedUser(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.sessionService.RevokeUserSessions(user.ID); err != nil {
+		writeSessionError(w, err)
+		return
+	}
+
+	logger.WithContext(r.Context()).Info("audit: sessions revoked", "user_id", user.ID, "tenant_id", user.TenantID)
+	s.recordChange(r, "sessions_revoked", user.TenantID, map[string]string{"user_id": user.ID})
+	response.NoContent(w)
+}
+
+// Handles requests to end a session of a user (DELETE /v1/admin/users/{id}/sessions/{session_id})
+func (s *Service) revokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.getManagedUser(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.sessionService.RevokeSession(user.ID, mux.Vars(r)["session_id"]); err != nil {
+		writeSessionError(w, err)
+		return
+	}
+
+	logger.WithContext(r.Context()).Info("audit: session revoked", "user_id", user.ID, "tenant_id", user.TenantID)
+	s.recordChange(r, "session_revoked", user.TenantID, map[string]string{
+		"user_id":    user.ID,
+		"session_id": mux.Vars(r)["session_id"],
+	})
+	response.NoContent(w)
+}
+
+// Handles requests to create a custom role (POST /v1/admin/roles)
+func (s *Service) createRoleHandler(w http.ResponseWriter, r *http.Request) {
+	roleRequest := new(RoleRequest)
+	if err := json.NewDecoder(r.Body).Decode(roleRequest); err != nil {
+		response.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !canManageTenant(getSuperuser(r), roleRequest.TenantID) {
+		response.Error(w, ErrTenantForbidden.Error(), http.StatusForbidden)
+		return
+	}
+
+	role, err := s.oauthService.CreateRole(
+		roleRequest.TenantID,
+		roleRequest.Name,
+		roleRequest.Description,
+		roleRequest.Permissions,
+		roleRequest.Scope,
+	)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	s.recordChange(r, "role_created", role.TenantID.String, map[string]string{"role_id": role.ID})
+	response.WriteJSON(w, NewRoleResponse(r, role), http.StatusCreated)
+}
+
+// Handles requests to list the built-in roles and a tenant's custom roles
+// (GET /v1/admin/roles)
+func (s *Service) listRolesHandler(w http.ResponseWriter, r *http.Request) {
+	superuser := getSuperuser(r)
+	query := r.URL.Query()
+	tenantID := superuser.TenantID
+	if _, ok := query["tenant_id"]; ok {
+		tenantID = query.Get("tenant_id")
+	}
+	if !canManageTenant(superuser, tenantID) {
+		response.Error(w, ErrTenantForbidden.Error(), http.StatusForbidden)
+		return
+	}
+
+	tenantRoles, err := s.oauthService.ListRoles(tenantID)
+	if err != nil {
+		response.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.writeRoles(w, r, fmt.Sprintf("%s?tenant_id=%s", r.URL.Path, tenantID), tenantRoles)
+}
+
+// Handles requests to get a role (GET /v1/admin/roles/{id})
+func (s *Service) getRoleHandler(w http.ResponseWriter, r *http.Request) {
+	role, ok := s.getManagedRole(w, r)
+	if !ok {
+		return
+	}
+
+	response.WriteJSON(w, NewRoleResponse(r, role), http.StatusOK)
+}
+
+// Handles requests to update a custom role (PUT /v1/admin/roles/{id})
+func (s *Service) updateRoleHandler(w http.ResponseWriter, r *http.Request) {
+	role, ok := s.getManagedRole(w, r)
+	if !ok {
+		return
+	}
+
+	roleRequest := new(RoleRequest)
+	if err := json.NewDecoder(r.Body).Decode(roleRequest); err != nil {
+		response.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err := s.oauthService.UpdateRole(
+		role,
+		roleRequest.Name,
+		roleRequest.Description,
+		roleRequest.Permissions,
+		roleRequest.Scope,
+	)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	s.recordChange(r, "role_updated", role.TenantID.String, map[string]string{"role_id": role.ID})
+
+	// Return the saved role
+	role, err = s.oauthService.FindRoleByID(role.ID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, NewRoleResponse(r, role), http.StatusOK)
+}
+
+// Handles requests to delete a custom role (DELETE /v1/admin/roles/{id})
+func (s *Service) deleteRoleHandler(w http.ResponseWriter, r *http.Request) {
+	role, ok := s.getManagedRole(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.oauthService.DeleteRole(role); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	s.recordChange(r, "role_deleted", role.TenantID.String, map[string]string{"role_id": role.ID})
+	response.NoContent(w)
+}
+
+// Handles requests to query the audit log (GET /v1/admin/audit-events)
+func (s *Service) listAuditEventsHandler(w http.ResponseWriter, r *http.Request) {
+	superuser := getSuperuser(r)
+	query := r.URL.Query()
+	tenantID := superuser.TenantID
+	if _, ok := query["tenant_id"]; ok {
+		tenantID = query.Get("tenant_id")
+	}
+	if !canManageTenant(superuser, tenantID) {
+		response.Error(w, ErrTenantForbidden.Error(), http.StatusForbidden)
+		return
+	}
+
+	page, limit, ok := queryPage(w, query)
+	if !ok {
+		return
+	}
+	filter := &audit.Filter{
+		Type:     query.Get("type"),
+		TenantID: tenantID,
+		ActorID:  query.Get("actor_id"),
+		ClientID: query.Get("client_id"),
+	}
+	var err error
+	if filter.Since, err = queryTime(query.Get("since")); err != nil {
+		response.Error(w, "Invalid since", http.StatusBadRequest)
+		return
+	}
+	if filter.Until, err = queryTime(query.Get("until")); err != nil {
+		response.Error(w, "Invalid until", http.StatusBadRequest)
+		return
+	}
+
+	events, count, err := s.auditService.FindEvents(filter, (page-1)*limit, limit)
+	if err == audit.ErrQueryNotSupported {
+		response.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	}
+	if err != nil {
+		response.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writePage(w, r, tenantID, count, page, limit, "audit_events", events)
+}
+
+// Handles requests to rotate a tenant's signing key
+// (POST /v1/admin/signing-keys/rotate)
+func (s *Service) rotateSigningKeyHandler(w http.ResponseWriter, r *http.Request) {
+	superuser := getSuperuser(r)
+	query := r.URL.Query()
+	tenantID := superuser.TenantID
+	if _, ok := query["tenant_id"]; ok {
+		tenantID = query.Get("tenant_id")
+	}
+	if !canManageTenant(superuser, tenantID) {
+		response.Error(w, ErrTenantForbidden.Error(), http.StatusForbidden)
+		return
+	}
+
+	keyID, err := s.oauthService.RotateSigningKey(tenantID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	s.recordChange(r, "signing_key_rotated", tenantID, map[string]string{"kid": keyID})
+	response.WriteJSON(w, &SigningKeyResponse{KeyID: keyID}, http.StatusOK)
+}
+
+func (s *Service) setUserDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	user, ok := s.getManagedUser(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.oauthService.SetUserDisabled(user, disabled); err != nil {
+		writeError(w, err)
+		return
+	}
+	user.Disabled = disabled
+	action := "user_enabled"
+	if disabled {
+		action = "user_disabled"
+	}
+	s.recordChange(r, action, user.TenantID, map[string]string{"user_id": user.ID})
+	if disabled {
+		s.endUserSessions(user)
+	}
+
+	response.WriteJSON(w, NewUserResponse(r, user), http.StatusOK)
+}
+
+// getManagedUser looks up the user of the request path and makes sure the
+// superuser can manage it, writing the error response otherwise
+func (s *Service) getManagedUser(w http.ResponseWriter, r *http.Request) (*models.OauthUser, bool) {
+	user, err := s.oauthService.FindUserByID(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, err)
+		return nil, false
+	}
+	// Users of other tenants look like they don't exist
+	if !canManageTenant(getSuperuser(r), user.TenantID) {
+		response.Error(w, oauth.ErrUserNotFound.Error(), http.StatusNotFound)
+		return nil, false
+	}
+	return user, true
+}
+
+// getManagedRole looks up the role of the request path and makes sure the
+// superuser can see it, writing the error response otherwise
+func (s *Service) getManagedRole(w http.ResponseWriter, r *http.Request) (*models.OauthRole, bool) {
+	role, err := s.oauthService.FindRoleByID(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, err)
+		return nil, false
+	}
+	// Custom roles of other tenants look like they don't exist
+	if !role.IsBuiltIn() && !canManageTenant(getSuperuser(r), role.TenantID.String) {
+		response.Error(w, oauth.ErrRoleNotFound.Error(), http.StatusNotFound)
+		return nil, false
+	}
+	return role, true
+}
+
+// writeRoles writes a list of roles, all on a single page
+func (s *Service) writeRoles(w http.ResponseWriter, r *http.Request, self string, roleList []*models.OauthRole) {
+	items := make([]*RoleResponse, len(roleList))
+	for i, role := range roleList {
+		items[i] = NewRoleResponse(r, role)
+	}
+
+	response.WriteJSON(w, response.NewListResponse(
+		len(items),
+		1,
+		self,
+		self,
+		self,
+		"",
+		"",
+		"roles",
+		items,
+	), http.StatusOK)
+}
+
+// writePage writes a page of a tenant's items, the page links keep the
+// query of the request
+func writePage(w http.ResponseWriter, r *http.Request, tenantID string, count, page, limit int, name string, items interface{}) {
+	lastPage := (count + limit - 1) / limit
+	if lastPage < 1 {
+		lastPage = 1
+	}
+	query := r.URL.Query()
+	pageURL := func(p int) string {
+		if p < 1 || p > lastPage {
+			return ""
+		}
+		pageQuery := make(url.Values)
+		for key, values := range query {
+			pageQuery[key] = values
+		}
+		pageQuery.Set("tenant_id", tenantID)
+		pageQuery.Set("page", strconv.Itoa(p))
+		pageQuery.Set("limit", strconv.Itoa(limit))
+		return fmt.Sprintf("%s?%s", r.URL.Path, pageQuery.Encode())
+	}
+
+	response.WriteJSON(w, response.NewListResponse(
+		count,
+		page,
+		pageURL(page),
+		pageURL(1),
+		pageURL(lastPage),
+		pageURL(page-1),
+		pageURL(page+1),
+		name,
+		items,
+	), http.StatusOK)
+}
+
+// queryPage reads the page and limit query parameters, writing the error
+// response when they are invalid
+func queryPage(w http.ResponseWriter, query url.Values) (int, int, bool) {
+	page, err := queryInt(query.Get("page"), 1)
+	if err != nil || page < 1 {
+		response.Error(w, "Invalid page", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	limit, err := queryInt(query.Get("limit"), defaultPageLimit)
+	if err != nil || limit < 1 || limit > maxPageLimit {
+		response.Error(w, "Invalid limit", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	return page, limit, true
+}
+
+func queryInt(value string, defaultValue int) (int, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// queryTime parses an RFC 3339 time, the zero time when the value is empty
+func queryTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// writeError writes an oauth service error, with the violations of password
+// policy errors
+func writeError(w http.ResponseWriter, err error) {
+	if policyErr, ok := err.(*passwordpolicy.Error); ok {
+		response.WriteJSON(w, policyErr, http.StatusBadRequest)
+		return
+	}
+	response.Error(w, err.Error(), oauth.ErrStatusCode(err))
+}
+
+// writeSessionError writes a session service error
+func writeSessionError(w http.ResponseWriter, err error) {
+	switch err {
+	case session.ErrSessionNotFound:
+		response.Error(w, err.Error(), http.StatusNotFound)
+	case session.ErrSessionManagementNotSupported:
+		response.Error(w, err.Error(), http.StatusNotImplemented)
+	default:
+		response.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// endUserSessions ends the sessions of a user who can no longer log in,
+// the user change succeeded already so failures are only logged
+func (s *Service) endUserSessions(user *models.OauthUser) {
+	err := s.sessionService.RevokeUserSessions(user.ID)
+	if err != nil && err != session.ErrSessionManagementNotSupported {
+		logger.Errorf("Revoking sessions of user %s failed: %v", user.ID, err)
+	}
+}
+
+// recordChange records a change a superuser made to a tenant, the details
+// identify what was changed
+func (s *Service) recordChange(r *http.Request, action, tenantID string, details map[string]string) {
+	event := audit.NewEvent(audit.AdminChange, r)
+	event.ActorID = getSuperuser(r).ID
+	event.TenantID = tenantID
+	event.Details["action"] = action
+	for key, value := range details {
+		event.Details[key] = value
+	}
+	s.recordEvent(event)
+}
+
+// recordEvent records a security event if there is an audit service
+func (s *Service) recordEvent(event *audit.Event) {
+	if s.auditService != nil {
+		s.auditService.Record(event)
+	}
+}
+
+// setEmail changes the email address of a user, emailing a confirmation
+// link when the address is new
+func (s *Service) setEmail(user *models.OauthUser, email string) error {
+	previous := user.Email
+	if err := s.oauthService.SetEmail(user, email); err != nil {
+		return err
+	}
+	if user.Email == previous {
+		return nil
+	}
+	return s.oauthService.SendEmailConfirmation(user)
+}
@@ -0,0 +1,79 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
+	"github.com/RichardKnop/go-oauth2-server/util/response"
+)
+
+type contextKey int
+
+const superuserKey contextKey = 0
+
+var (
+	// ErrSuperuserRequired ...
+	ErrSuperuserRequired = errors.New("Superuser role required")
+	// ErrTenantForbidden ...
+	ErrTenantForbidden = errors.New("Superuser cannot manage other tenants")
+)
+
+// superuserMiddleware authenticates the access token of the request and
+// makes sure it belongs to an enabled superuser
+type superuserMiddleware struct {
+	service *Service
+}
+
+func newSuperuserMiddleware(service *Service) *superuserMiddleware {
+	return &superuserMiddleware{service: service}
+}
+
+// ServeHTTP as per the negroni.Handler interface
+func (m *superuserMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	accessToken, err := m.service.oauthService.AuthenticateRequest(r)
+	if err != nil {
+		response.UnauthorizedError(w, err.Error())
+		return
+	}
+	if !accessToken.UserID.Valid {
+		response.Error(w, ErrSuperuserRequired.Error(), http.StatusForbidden)
+		return
+	}
+
+	user, err := m.service.oauthService.FindUserByID(accessToken.UserID.String)
+	if err != nil {
+		response.UnauthorizedError(w, err.Error())
+		return
+	}
+	if user.Disabled {
+		response.Error(w, oauth.ErrUserDisabled.Error(), http.StatusForbidden)
+		return
+	}
+	isSuperuser, err := m.service.oauthService.UserHasRole(user, roles.Superuser)
+	if err != nil {
+		response.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !isSuperuser {
+		response.Error(w, ErrSuperuserRequired.Error(), http.StatusForbidden)
+		return
+	}
+
+	next(w, r.WithContext(context.WithValue(r.Context(), superuserKey, user)))
+}
+
+// getSuperuser returns the superuser authenticated by the middleware
+func getSuperuser(r *http.Request) *models.OauthUser {
+	user, _ := r.Context().Value(superuserKey).(*models.OauthUser)
+	return user
+}
+
+// canManageTenant returns true if the superuser can manage users of the
+// tenant, superusers of the default tenant manage every tenant
+func canManageTenant(superuser *models.OauthUser, tenantID string) bool {
+	return superuser.TenantID == "" || superuser.TenantID == tenantID
+}
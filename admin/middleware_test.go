@@ -0,0 +1,66 @@
+package admin
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/mocks"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSuperuserMiddleware(t *testing.T) {
+	accessToken := &models.OauthAccessToken{
+		UserID: sql.NullString{String: "1", Valid: true},
+	}
+	testCases := []struct {
+		user        *models.OauthUser
+		authErr     error
+		isSuperuser bool
+		expected    int
+	}{
+		{nil, oauth.ErrAccessTokenNotFound, false, http.StatusUnauthorized},
+		{&models.OauthUser{ID: "1"}, nil, false, http.StatusForbidden},
+		{&models.OauthUser{ID: "1", Disabled: true}, nil, true, http.StatusForbidden},
+		{&models.OauthUser{ID: "1"}, nil, true, http.StatusOK},
+	}
+
+	for _, testCase := range testCases {
+		oauthServiceMock := new(mocks.ServiceInterface)
+		if testCase.authErr != nil {
+			oauthServiceMock.On("AuthenticateRequest", mock.Anything).Return(nil, testCase.authErr)
+		} else {
+			oauthServiceMock.On("AuthenticateRequest", mock.Anything).Return(accessToken, nil)
+			oauthServiceMock.On("FindUserByID", "1").Return(testCase.user, nil)
+			oauthServiceMock.On("UserHasRole", testCase.user, roles.Superuser).Return(testCase.isSuperuser, nil)
+		}
+		middleware := newSuperuserMiddleware(NewService(new(config.Config), oauthServiceMock, nil, nil, nil))
+
+		var superuser *models.OauthUser
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/v1/admin/users", nil)
+		middleware.ServeHTTP(w, r, func(w http.ResponseWriter, r *http.Request) {
+			superuser = getSuperuser(r)
+		})
+
+		assert.Equal(t, testCase.expected, w.Code)
+		if testCase.expected == http.StatusOK {
+			assert.Equal(t, testCase.user, superuser)
+		} else {
+			assert.Nil(t, superuser)
+		}
+	}
+}
+
+func TestCanManageTenant(t *testing.T) {
+	assert.True(t, canManageTenant(&models.OauthUser{TenantID: ""}, "tenant_a"))
+	assert.True(t, canManageTenant(&models.OauthUser{TenantID: "tenant_a"}, "tenant_a"))
+	assert.False(t, canManageTenant(&models.OauthUser{TenantID: "tenant_a"}, "tenant_b"))
+	assert.False(t, canManageTenant(&models.OauthUser{TenantID: "tenant_a"}, ""))
+}
@@ -0,0 +1,233 @@
+package admin
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
+	"github.com/RichardKnop/go-oauth2-server/session"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/RichardKnop/jsonhal"
+)
+
+// UserResponse is the admin API representation of a user
+type UserResponse struct {
+	jsonhal.Hal
+	ID        string `json:"id"`
+	TenantID  string `json:"tenant_id"`
+	Name      string `json:"name"`
+	Account   string `json:"account"`
+	Phone     string `json:"phone"`
+	RoleID    string `json:"role_id,omitempty"`
+	Disabled  bool   `json:"disabled"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+	// PasswordChangedAt is empty for users without a password change date
+	PasswordChangedAt string `json:"password_changed_at,omitempty"`
+	// MFAEnabled is true once the user confirmed an authenticator
+	MFAEnabled  bool `json:"mfa_enabled"`
+	MFARequired bool `json:"mfa_required"`
+	// Email is empty for users without an email address
+	Email         string `json:"email,omitempty"`
+	EmailVerified bool   `json:"email_verified"`
+}
+
+// NewUserResponse creates new UserResponse instance
+func NewUserResponse(r *http.Request, user *models.OauthUser) *UserResponse {
+	response := &UserResponse{
+		ID:            user.ID,
+		TenantID:      user.TenantID,
+		Name:          user.Name,
+		Account:       user.Account,
+		Phone:         user.Phone,
+		RoleID:        user.RoleID.String,
+		Disabled:      user.Disabled,
+		MFAEnabled:    user.MFAEnabled,
+		MFARequired:   user.MFARequired,
+		EmailVerified: user.EmailVerified,
+		CreatedAt:     util.FormatTime(&user.CreatedAt),
+		UpdatedAt:     util.FormatTime(&user.UpdatedAt),
+	}
+
+	if user.Email.Valid {
+		response.Email = user.Email.String
+	}
+	if user.PasswordChangedAt != nil {
+		response.PasswordChangedAt = util.FormatTime(user.PasswordChangedAt)
+	}
+
+	// Links are relative to the users collection of the request
+	prefix := r.URL.Path
+	if i := strings.Index(prefix, usersPath); i >= 0 {
+		prefix = prefix[:i+len(usersPath)]
+	}
+	response.SetLink("self", prefix+"/"+user.ID, "")
+
+	return response
+}
+
+// RoleResponse is the admin API representation of a role
+type RoleResponse struct {
+	jsonhal.Hal
+	ID string `json:"id"`
+	// TenantID is empty for built-in roles
+	TenantID    string   `json:"tenant_id"`
+	BuiltIn     bool     `json:"built_in"`
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Permissions []string `json:"permissions"`
+	Scope       string   `json:"scope,omitempty"`
+	CreatedAt   string   `json:"created_at"`
+	UpdatedAt   string   `json:"updated_at"`
+}
+
+// NewRoleResponse creates new RoleResponse instance
+func NewRoleResponse(r *http.Request, role *models.OauthRole) *RoleResponse {
+	response := &RoleResponse{
+		ID:          role.ID,
+		TenantID:    role.TenantID.String,
+		BuiltIn:     role.IsBuiltIn(),
+		Name:        role.Name,
+		Description: role.Description,
+		Permissions: roles.SplitPermissions(role.Permissions),
+		Scope:       role.Scope,
+		CreatedAt:   util.FormatTime(&role.CreatedAt),
+		UpdatedAt:   util.FormatTime(&role.UpdatedAt),
+	}
+
+	// Links are relative to the admin API prefix of the request
+	prefix := r.URL.Path
+	if i := strings.Index(prefix, usersPath); i >= 0 {
+		prefix = prefix[:i]
+	} else if i := strings.Index(prefix, rolesPath); i >= 0 {
+		prefix = prefix[:i]
+	}
+	response.SetLink("self", prefix+rolesPath+"/"+role.ID, "")
+
+	return response
+}
+
+// SessionResponse is the admin API representation of an active session
+type SessionResponse struct {
+	jsonhal.Hal
+	ID         string `json:"id"`
+	ClientID   string `json:"client_id,omitempty"`
+	IP         string `json:"ip"`
+	UserAgent  string `json:"user_agent"`
+	CreatedAt  string `json:"created_at"`
+	LastSeenAt string `json:"last_seen_at"`
+}
+
+// NewSessionResponse creates new SessionResponse instance, links are
+// relative to the sessions collection of the user
+func NewSessionResponse(collection string, info *session.SessionInfo) *SessionResponse {
+	response := &SessionResponse{
+		ID:         info.ID,
+		ClientID:   info.ClientID,
+		IP:         info.IP,
+		UserAgent:  info.UserAgent,
+		CreatedAt:  util.FormatTime(&info.CreatedAt),
+		LastSeenAt: util.FormatTime(&info.LastSeenAt),
+	}
+	response.SetLink("self", collection+"/"+info.ID, "")
+	return response
+}
+
+// ClientResponse is the admin API representation of a client
+type ClientResponse struct {
+	jsonhal.Hal
+	ClientID    string `json:"client_id"`
+	TenantID    string `json:"tenant_id"`
+	RedirectURI string `json:"redirect_uri,omitempty"`
+	// SkipConsent marks first-party clients, which never ask for consent
+	SkipConsent bool   `json:"skip_consent"`
+	CreatedAt   string `json:"created_at"`
+	UpdatedAt   string `json:"updated_at"`
+}
+
+// NewClientResponse creates new ClientResponse instance
+func NewClientResponse(r *http.Request, client *models.OauthClient) *ClientResponse {
+	response := &ClientResponse{
+		ClientID:    client.Key,
+		TenantID:    client.TenantID,
+		RedirectURI: client.RedirectURI.String,
+		SkipConsent: client.SkipConsent,
+		CreatedAt:   util.FormatTime(&client.CreatedAt),
+		UpdatedAt:   util.FormatTime(&client.UpdatedAt),
+	}
+	response.SetLink("self", r.URL.Path, "")
+	return response
+}
+
+// WebhookResponse is the admin API representation of a webhook subscription
+type WebhookResponse struct {
+	jsonhal.Hal
+	ID         string   `json:"id"`
+	TenantID   string   `json:"tenant_id"`
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	// Secret is only returned when the subscription is created
+	Secret    string `json:"secret,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
+
+// NewWebhookResponse creates new WebhookResponse instance
+func NewWebhookResponse(r *http.Request, subscription *models.WebhookSubscription) *WebhookResponse {
+	response := &WebhookResponse{
+		ID:         subscription.ID,
+		TenantID:   subscription.TenantID,
+		URL:        subscription.URL,
+		EventTypes: strings.Fields(subscription.EventTypes),
+		CreatedAt:  util.FormatTime(&subscription.CreatedAt),
+	}
+	response.SetLink("self", webhooksPrefix(r)+webhooksPath+"/"+subscription.ID, "")
+	return response
+}
+
+// DeliveryResponse is the admin API representation of a webhook delivery
+type DeliveryResponse struct {
+	jsonhal.Hal
+	ID        string `json:"id"`
+	WebhookID string `json:"webhook_id"`
+	EventID   string `json:"event_id"`
+	EventType string `json:"event_type"`
+	Status    string `json:"status"`
+	Attempts  int    `json:"attempts"`
+	LastError string `json:"last_error,omitempty"`
+	// NextAttemptAt is empty once the delivery is no longer pending
+	NextAttemptAt string `json:"next_attempt_at,omitempty"`
+	CreatedAt     string `json:"created_at"`
+	UpdatedAt     string `json:"updated_at"`
+}
+
+// NewDeliveryResponse creates new DeliveryResponse instance
+func NewDeliveryResponse(r *http.Request, delivery *models.WebhookDelivery) *DeliveryResponse {
+	response := &DeliveryResponse{
+		ID:        delivery.ID,
+		WebhookID: delivery.SubscriptionID,
+		EventID:   delivery.EventID,
+		EventType: delivery.EventType,
+		Status:    delivery.Status,
+		Attempts:  delivery.Attempts,
+		LastError: delivery.LastError,
+		CreatedAt: util.FormatTime(&delivery.CreatedAt),
+		UpdatedAt: util.FormatTime(&delivery.UpdatedAt),
+	}
+	if delivery.Status == models.WebhookDeliveryPending {
+		response.NextAttemptAt = util.FormatTime(&delivery.NextAttemptAt)
+	}
+	prefix := webhooksPrefix(r)
+	response.SetLink("self", prefix+webhooksPath+"/"+delivery.SubscriptionID, "")
+	response.SetLink("replay", prefix+webhooksPath+"/deliveries/"+delivery.ID+"/replay", "")
+	return response
+}
+
+// webhooksPrefix returns the admin API prefix of a webhooks request
+func webhooksPrefix(r *http.Request) string {
+	prefix := r.URL.Path
+	if i := strings.Index(prefix, webhooksPath); i >= 0 {
+		prefix = prefix[:i]
+	}
+	return prefix
+}
@@ -0,0 +1,269 @@
+package admin
+
+import (
+	"github.com/RichardKnop/go-oauth2-server/util/routes"
+	"github.com/gorilla/mux"
+	"github.com/urfave/negroni"
+)
+
+const (
+	usersPath        = "/users"
+	userPath         = "/users/{id}"
+	userPasswordPath = "/users/{id}/password"
+	userResetPath    = "/users/{id}/password/reset"
+	userDisablePath  = "/users/{id}/disable"
+	userEnablePath   = "/users/{id}/enable"
+	userUnlockPath   = "/users/{id}/unlock"
+	userMFAResetPath = "/users/{id}/mfa/reset"
+	userRolesPath    = "/users/{id}/roles"
+	userRolePath     = "/users/{id}/roles/{role_id}"
+	userSessionsPath = "/users/{id}/sessions"
+	userSessionPath  = "/users/{id}/sessions/{session_id}"
+	rolesPath        = "/roles"
+	rolePath         = "/roles/{id}"
+	auditEventsPath  = "/audit-events"
+	signingKeyPath   = "/signing-keys/rotate"
+	clientPath       = "/clients/{client_id}"
+	webhooksPath     = "/webhooks"
+	webhookPath      = "/webhooks/{id}"
+	deadLettersPath  = "/webhooks/dead-letters"
+	replayPath       = "/webhooks/deliveries/{id}/replay"
+)
+
+// RegisterRoutes registers route handlers for the admin service
+func (s *Service) RegisterRoutes(router *mux.Router, prefix string) {
+	subRouter := router.PathPrefix(prefix).Subrouter()
+	routes.AddRoutes(s.GetRoutes(), subRouter)
+}
+
+// GetRoutes returns []routes.Route slice for the admin service
+func (s *Service) GetRoutes() []routes.Route {
+	superuser := []negroni.Handler{newSuperuserMiddleware(s)}
+	return []routes.Route{
+		{
+			Name:        "admin_users_create",
+			Method:      "POST",
+			Pattern:     usersPath,
+			HandlerFunc: s.createUserHandler,
+			Middlewares: superuser,
+		},
+		{
+			Name:        "admin_users_list",
+			Method:      "GET",
+			Pattern:     usersPath,
+			HandlerFunc: s.listUsersHandler,
+			Middlewares: superuser,
+		},
+		{
+			Name:        "admin_users_get",
+			Method:      "GET",
+			Pattern:     userPath,
+			HandlerFunc: s.getUserHandler,
+			Middlewares: superuser,
+		},
+		{
+			Name:        "admin_users_update",
+			Method:      "PUT",
+			Pattern:     userPath,
+			HandlerFunc: s.updateUserHandler,
+			Middlewares: superuser,
+		},
+		{
+			Name:        "admin_users_delete",
+			Method:      "DELETE",
+			Pattern:     userPath,
+			HandlerFunc: s.deleteUserHandler,
+			Middlewares: superuser,
+		},
+		{
+			Name:        "admin_users_set_password",
+			Method:      "PUT",
+			Pattern:     userPasswordPath,
+			HandlerFunc: s.setPasswordHandler,
+			Middlewares: superuser,
+		},
+		{
+			Name:        "admin_users_reset_password",
+			Method:      "POST",
+			Pattern:     userResetPath,
+			HandlerFunc: s.resetPasswordHandler,
+			Middlewares: superuser,
+		},
+		{
+			Name:        "admin_users_disable",
+			Method:      "POST",
+			Pattern:     userDisablePath,
+			HandlerFunc: s.disableUserHandler,
+			Middlewares: superuser,
+		},
+		{
+			Name:        "admin_users_enable",
+			Method:      "POST",
+			Pattern:     userEnablePath,
+			HandlerFunc: s.enableUserHandler,
+			Middlewares: superuser,
+		},
+		{
+			Name:        "admin_users_unlock",
+			Method:      "POST",
+			Pattern:     userUnlockPath,
+			HandlerFunc: s.unlockUserHandler,
+			Middlewares: superuser,
+		},
+		{
+			Name:        "admin_users_reset_mfa",
+			Method:      "POST",
+			Pattern:     userMFAResetPath,
+			HandlerFunc: s.resetMFAHandler,
+			Middlewares: superuser,
+		},
+		{
+			Name:        "admin_users_roles_list",
+			Method:      "GET",
+			Pattern:     userRolesPath,
+			HandlerFunc: s.listUserRolesHandler,
+			Middlewares: superuser,
+		},
+		{
+			Name:        "admin_users_roles_assign",
+			Method:      "PUT",
+			Pattern:     userRolePath,
+			HandlerFunc: s.assignRoleHandler,
+			Middlewares: superuser,
+		},
+		{
+			Name:        "admin_users_roles_unassign",
+			Method:      "DELETE",
+			Pattern:     userRolePath,
+			HandlerFunc: s.unassignRoleHandler,
+			Middlewares: superuser,
+		},
+		{
+			Name:        "admin_users_sessions_list",
+			Method:      "GET",
+			Pattern:     userSessionsPath,
+			HandlerFunc: s.listUserSessionsHandler,
+			Middlewares: superuser,
+		},
+		{
+			Name:        "admin_users_sessions_revoke_all",
+			Method:      "DELETE",
+			Pattern:     userSessionsPath,
+			HandlerFunc: s.revokeUserSessionsHandler,
+			Middlewares: superuser,
+		},
+		{
+			Name:        "admin_users_sessions_revoke",
+			Method:      "DELETE",
+			Pattern:     userSessionPath,
+			HandlerFunc: s.revokeSessionHandler,
+			Middlewares: superuser,
+		},
+		{
+			Name:        "admin_roles_create",
+			Method:      "POST",
+			Pattern:     rolesPath,
+			HandlerFunc: s.createRoleHandler,
+			Middlewares: superuser,
+		},
+		{
+			Name:        "admin_roles_list",
+			Method:      "GET",
+			Pattern:     rolesPath,
+			HandlerFunc: s.listRolesHandler,
+			Middlewares: superuser,
+		},
+		{
+			Name:        "admin_roles_get",
+			Method:      "GET",
+			Pattern:     rolePath,
+			HandlerFunc: s.getRoleHandler,
+			Middlewares: superuser,
+		},
+		{
+			Name:        "admin_roles_update",
+			Method:      "PUT",
+			Pattern:     rolePath,
+			HandlerFunc: s.updateRoleHandler,
+			Middlewares: superuser,
+		},
+		{
+			Name:        "admin_roles_delete",
+			Method:      "DELETE",
+			Pattern:     rolePath,
+			HandlerFunc: s.deleteRoleHandler,
+			Middlewares: superuser,
+		},
+		{
+			Name:        "admin_audit_events_list",
+			Method:      "GET",
+			Pattern:     auditEventsPath,
+			HandlerFunc: s.listAuditEventsHandler,
+			Middlewares: superuser,
+		},
+		{
+			Name:        "admin_signing_keys_rotate",
+			Method:      "POST",
+			Pattern:     signingKeyPath,
+			HandlerFunc: s.rotateSigningKeyHandler,
+			Middlewares: superuser,
+		},
+		{
+			Name:        "admin_clients_get",
+			Method:      "GET",
+			Pattern:     clientPath,
+			HandlerFunc: s.getClientHandler,
+			Middlewares: superuser,
+		},
+		{
+			Name:        "admin_clients_update",
+			Method:      "PUT",
+			Pattern:     clientPath,
+			HandlerFunc: s.updateClientHandler,
+			Middlewares: superuser,
+		},
+		{
+			Name:        "admin_webhooks_create",
+			Method:      "POST",
+			Pattern:     webhooksPath,
+			HandlerFunc: s.createWebhookHandler,
+			Middlewares: superuser,
+		},
+		{
+			Name:        "admin_webhooks_list",
+			Method:      "GET",
+			Pattern:     webhooksPath,
+			HandlerFunc: s.listWebhooksHandler,
+			Middlewares: superuser,
+		},
+		// Registered before the subscription routes, which would match it
+		{
+			Name:        "admin_webhooks_dead_letters",
+			Method:      "GET",
+			Pattern:     deadLettersPath,
+			HandlerFunc: s.listDeadLettersHandler,
+			Middlewares: superuser,
+		},
+		{
+			Name:        "admin_webhooks_get",
+			Method:      "GET",
+			Pattern:     webhookPath,
+			HandlerFunc: s.getWebhookHandler,
+			Middlewares: superuser,
+		},
+		{
+			Name:        "admin_webhooks_delete",
+			Method:      "DELETE",
+			Pattern:     webhookPath,
+			HandlerFunc: s.deleteWebhookHandler,
+			Middlewares: superuser,
+		},
+		{
+			Name:        "admin_webhooks_replay",
+			Method:      "POST",
+			Pattern:     replayPath,
+			HandlerFunc: s.replayDeliveryHandler,
+			Middlewares: superuser,
+		},
+	}
+}
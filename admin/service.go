@@ -0,0 +1,45 @@
+package admin
+
+import (
+	"github.com/RichardKnop/go-oauth2-server/audit"
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/log"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/session"
+	"github.com/RichardKnop/go-oauth2-server/webhook"
+)
+
+var logger = log.New("admin")
+
+// Service struct keeps objects to avoid passing them around
+type Service struct {
+	cnf            *config.Config
+	oauthService   oauth.ServiceInterface
+	sessionService session.ServiceInterface
+	auditService   audit.ServiceInterface
+	webhookService webhook.ServiceInterface
+}
+
+// NewService returns a new Service instance
+func NewService(cnf *config.Config, oauthService oauth.ServiceInterface, sessionService session.ServiceInterface, auditService audit.ServiceInterface, webhookService webhook.ServiceInterface) *Service {
+	return &Service{
+		cnf:            cnf,
+		oauthService:   oauthService,
+		sessionService: sessionService,
+		auditService:   auditService,
+		webhookService: webhookService,
+	}
+}
+
+// GetConfig returns config.Config instance
+func (s *Service) GetConfig() *config.Config {
+	return s.cnf
+}
+
+// GetOauthService returns oauth.Service instance
+func (s *Service) GetOauthService() oauth.ServiceInterface {
+	return s.oauthService
+}
+
+// Close stops any running services
+func (s *Service) Close() {}
@@ -0,0 +1,205 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util/response"
+	"github.com/RichardKnop/go-oauth2-server/webhook"
+	"github.com/gorilla/mux"
+)
+
+// WebhookRequest is the body of webhook subscription create requests
+type WebhookRequest struct {
+	TenantID   string   `json:"tenant_id"`
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+}
+
+// Handles requests to subscribe to a tenant's events (POST /v1/admin/webhooks)
+func (s *Service) createWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	webhookRequest := new(WebhookRequest)
+	if err := json.NewDecoder(r.Body).Decode(webhookRequest); err != nil {
+		response.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !canManageTenant(getSuperuser(r), webhookRequest.TenantID) {
+		response.Error(w, ErrTenantForbidden.Error(), http.StatusForbidden)
+		return
+	}
+
+	subscription, err := s.webhookService.CreateSubscription(
+		webhookRequest.TenantID,
+		webhookRequest.URL,
+		webhookRequest.EventTypes,
+	)
+	if err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+
+	s.recordChange(r, "webhook_created", subscription.TenantID, map[string]string{"webhook_id": subscription.ID})
+
+	// The secret is only ever returned here
+	webhookResponse := NewWebhookResponse(r, subscription)
+	webhookResponse.Secret = subscription.Secret
+	response.WriteJSON(w, webhookResponse, http.StatusCreated)
+}
+
+// Handles requests to list a tenant's webhook subscriptions
+// (GET /v1/admin/webhooks)
+func (s *Service) listWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	superuser := getSuperuser(r)
+	query := r.URL.Query()
+	tenantID := superuser.TenantID
+	if _, ok := query["tenant_id"]; ok {
+		tenantID = query.Get("tenant_id")
+	}
+	if !canManageTenant(superuser, tenantID) {
+		response.Error(w, ErrTenantForbidden.Error(), http.StatusForbidden)
+		return
+	}
+
+	subscriptions, err := s.webhookService.ListSubscriptions(tenantID)
+	if err != nil {
+		response.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	items := make([]*WebhookResponse, len(subscriptions))
+	for i, subscription := range subscriptions {
+		items[i] = NewWebhookResponse(r, subscription)
+	}
+
+	self := fmt.Sprintf("%s?tenant_id=%s", r.URL.Path, tenantID)
+	response.WriteJSON(w, response.NewListResponse(
+		len(items),
+		1,
+		self,
+		self,
+		self,
+		"",
+		"",
+		"webhooks",
+		items,
+	), http.StatusOK)
+}
+
+// Handles requests to get a webhook subscription (GET /v1/admin/webhooks/{id})
+func (s *Service) getWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	subscription, ok := s.getManagedWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	response.WriteJSON(w, NewWebhookResponse(r, subscription), http.StatusOK)
+}
+
+// Handles requests to delete a webhook subscription
+// (DELETE /v1/admin/webhooks/{id})
+func (s *Service) deleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	subscription, ok := s.getManagedWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.webhookService.DeleteSubscription(subscription); err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+
+	s.recordChange(r, "webhook_deleted", subscription.TenantID, map[string]string{"webhook_id": subscription.ID})
+	response.NoContent(w)
+}
+
+// Handles requests to list a tenant's deliveries which ran out of attempts
+// (GET /v1/admin/webhooks/dead-letters)
+func (s *Service) listDeadLettersHandler(w http.ResponseWriter, r *http.Request) {
+	superuser := getSuperuser(r)
+	query := r.URL.Query()
+	tenantID := superuser.TenantID
+	if _, ok := query["tenant_id"]; ok {
+		tenantID = query.Get("tenant_id")
+	}
+	if !canManageTenant(superuser, tenantID) {
+		response.Error(w, ErrTenantForbidden.Error(), http.StatusForbidden)
+		return
+	}
+
+	page, limit, ok := queryPage(w, query)
+	if !ok {
+		return
+	}
+
+	deliveries, count, err := s.webhookService.ListDeadLetters(tenantID, (page-1)*limit, limit)
+	if err != nil {
+		response.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	items := make([]*DeliveryResponse, len(deliveries))
+	for i, delivery := range deliveries {
+		items[i] = NewDeliveryResponse(r, delivery)
+	}
+
+	writePage(w, r, tenantID, count, page, limit, "deliveries", items)
+}
+
+// Handles requests to send a dead delivery again
+// (POST /v1/admin/webhooks/deliveries/{id}/replay)
+func (s *Service) replayDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	delivery, err := s.webhookService.FindDeliveryByID(mux.Vars(r)["id"])
+	if err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+	// Deliveries of other tenants look like they don't exist
+	if !canManageTenant(getSuperuser(r), delivery.TenantID) {
+		response.Error(w, webhook.ErrDeliveryNotFound.Error(), http.StatusNotFound)
+		return
+	}
+
+	if err := s.webhookService.ReplayDelivery(delivery); err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+
+	s.recordChange(r, "webhook_delivery_replayed", delivery.TenantID, map[string]string{
+		"webhook_id":  delivery.SubscriptionID,
+		"delivery_id": delivery.ID,
+	})
+	response.WriteJSON(w, NewDeliveryResponse(r, delivery), http.StatusOK)
+}
+
+// getManagedWebhook looks up the webhook subscription of the request path
+// and makes sure the superuser can manage it, writing the error response
+// otherwise
+func (s *Service) getManagedWebhook(w http.ResponseWriter, r *http.Request) (*models.WebhookSubscription, bool) {
+	subscription, err := s.webhookService.FindSubscriptionByID(mux.Vars(r)["id"])
+	if err != nil {
+		writeWebhookError(w, err)
+		return nil, false
+	}
+	// Subscriptions of other tenants look like they don't exist
+	if !canManageTenant(getSuperuser(r), subscription.TenantID) {
+		response.Error(w, webhook.ErrSubscriptionNotFound.Error(), http.StatusNotFound)
+		return nil, false
+	}
+	return subscription, true
+}
+
+// writeWebhookError writes a webhook service error
+func writeWebhookError(w http.ResponseWriter, err error) {
+	switch err {
+	case webhook.ErrSubscriptionNotFound, webhook.ErrDeliveryNotFound:
+		response.Error(w, err.Error(), http.StatusNotFound)
+	case webhook.ErrInvalidURL, webhook.ErrInternalURL, webhook.ErrInvalidEventType:
+		response.Error(w, err.Error(), http.StatusBadRequest)
+	case webhook.ErrDeliveryNotDead:
+		response.Error(w, err.Error(), http.StatusConflict)
+	default:
+		response.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,76 @@
+package audit
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/RichardKnop/uuid"
+)
+
+// Types of security events
+const (
+	LoginSuccess     = "login_success"
+	LoginFailure     = "login_failure"
+	TokenIssued      = "token_issued"
+	TokenRefreshed   = "token_refreshed"
+	TokenRevoked     = "token_revoked"
+	ClientAuthFailed = "client_auth_failed"
+	KeyRotated       = "key_rotated"
+	AdminChange      = "admin_change"
+	LoginLockedOut   = "login_locked_out"
+	LoginUnlocked    = "login_unlocked"
+)
+
+// EventTypes lists every type of event
+var EventTypes = []string{
+	LoginSuccess,
+	LoginFailure,
+	TokenIssued,
+	TokenRefreshed,
+	TokenRevoked,
+	ClientAuthFailed,
+	KeyRotated,
+	AdminChange,
+	LoginLockedOut,
+	LoginUnlocked,
+}
+
+// Event is a security event
+type Event struct {
+	ID   string    `json:"id"`
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	// ActorID is the user who acted, empty for clients and the server itself
+	ActorID  string `json:"actor_id,omitempty"`
+	TenantID string `json:"tenant_id"`
+	// ClientID is the client ID the client sent, it may not exist
+	ClientID  string `json:"client_id,omitempty"`
+	IP        string `json:"ip,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	// Details holds event specific fields, such as the grant type of an
+	// issued token or why a login failed
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// NewEvent returns an event of the given type with the IP and user agent of
+// the request, r is nil for events the server raises on its own
+func NewEvent(eventType string, r *http.Request) *Event {
+	event := &Event{Type: eventType, Details: map[string]string{}}
+	if r != nil {
+		event.IP = util.GetClientIP(r)
+		event.UserAgent = r.UserAgent()
+	}
+	return event
+}
+
+// Stamp sets the ID and time of a new event, events passed on to other
+// systems keep the ones they were first given
+func (e *Event) Stamp() {
+	if e.ID == "" {
+		e.ID = uuid.New()
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+}
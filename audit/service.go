@@ -0,0 +1,145 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/log"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	// ErrQueryNotSupported ...
+	ErrQueryNotSupported = errors.New("Audit events can only be queried with the db sink enabled")
+)
+
+// Filter narrows down queried events, empty fields match any event
+type Filter struct {
+	Type     string
+	TenantID string
+	ActorID  string
+	ClientID string
+	Since    time.Time
+	Until    time.Time
+}
+
+var logger = log.New("audit")
+
+// Service records security events to the configured sinks
+type Service struct {
+	db    *gorm.DB
+	sinks []Sink
+	// queryable is true when events are stored in the database
+	queryable bool
+}
+
+// NewService returns a new Service instance with the sinks named in the
+// config, sinks which can not be set up are logged and left out
+func NewService(cnf *config.Config, db *gorm.DB) *Service {
+	s := &Service{db: db}
+	for _, name := range cnf.Audit.Sinks {
+		switch name {
+		case "db":
+			s.sinks = append(s.sinks, NewDBSink(db))
+			s.queryable = true
+		case "file":
+			sink, err := NewFileSink(cnf.Audit.File)
+			if err != nil {
+				logger.Errorf("Audit file sink %s: %s", cnf.Audit.File, err)
+				continue
+			}
+			s.sinks = append(s.sinks, sink)
+		case "stdout":
+			s.sinks = append(s.sinks, NewWriterSink(os.Stdout))
+		default:
+			logger.Warnf("Unknown audit sink %q", name)
+		}
+	}
+	return s
+}
+
+// UseSink adds a sink events are recorded to
+func (s *Service) UseSink(sink Sink) {
+	s.sinks = append(s.sinks, sink)
+}
+
+// Record writes an event to every sink, setting its ID and time unless it
+// has them already. Failed writes are logged, recording an event never
+// fails the action
+func (s *Service) Record(event *Event) {
+	event.Stamp()
+	for _, sink := range s.sinks {
+		if err := sink.Write(event); err != nil {
+			logger.Errorf("Audit event %s (%s): %s", event.ID, event.Type, err)
+		}
+	}
+}
+
+// FindEvents returns a page of the stored events matching the filter, most
+// recent first, and how many match in total
+func (s *Service) FindEvents(filter *Filter, offset, limit int) ([]*Event, int, error) {
+	if !s.queryable {
+		return nil, 0, ErrQueryNotSupported
+	}
+
+	query := s.db.Model(new(models.AuditEvent)).Where("tenant_id = ?", filter.TenantID)
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.ActorID != "" {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.ClientID != "" {
+		query = query.Where("client_id = ?", filter.ClientID)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("time >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("time < ?", filter.Until)
+	}
+
+	var count int
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+	var rows []*models.AuditEvent
+	err := query.Order("time desc").Offset(offset).Limit(limit).Find(&rows).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	events := make([]*Event, len(rows))
+	for i, row := range rows {
+		events[i] = &Event{
+			ID:        row.ID,
+			Type:      row.Type,
+			Time:      row.Time,
+			ActorID:   row.ActorID,
+			TenantID:  row.TenantID,
+			ClientID:  row.ClientID,
+			IP:        row.IP,
+			UserAgent: row.UserAgent,
+		}
+		if row.Details != "" {
+			if err := json.Unmarshal([]byte(row.Details), &events[i].Details); err != nil {
+				return nil, 0, err
+			}
+		}
+	}
+	return events, count, nil
+}
+
+// Close closes the sinks which hold files open
+func (s *Service) Close() {
+	for _, sink := range s.sinks {
+		if closer, ok := sink.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+}
@@ -0,0 +1,10 @@
+package audit
+
+// ServiceInterface defines exported methods
+type ServiceInterface interface {
+	// Exported methods
+	UseSink(sink Sink)
+	Record(event *Event)
+	FindEvents(filter *Filter, offset, limit int) ([]*Event, int, error)
+	Close()
+}
@@ -0,0 +1,101 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingSink fails every write
+type failingSink struct{}
+
+func (failingSink) Write(event *Event) error {
+	return errors.New("sink down")
+}
+
+func TestNewEvent(t *testing.T) {
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("User-Agent", "test-agent")
+
+	event := NewEvent(LoginFailure, r)
+	assert.Equal(t, LoginFailure, event.Type)
+	assert.Equal(t, "10.0.0.1", event.IP)
+	assert.Equal(t, "test-agent", event.UserAgent)
+	assert.NotNil(t, event.Details)
+
+	// Events the server raises on its own have no request
+	event = NewEvent(KeyRotated, nil)
+	assert.Empty(t, event.IP)
+	assert.NotNil(t, event.Details)
+}
+
+func TestRecord(t *testing.T) {
+	out := new(bytes.Buffer)
+	service := NewService(new(config.Config), nil)
+	// A failing sink does not keep events from the others
+	service.UseSink(failingSink{})
+	service.UseSink(NewWriterSink(out))
+
+	event := NewEvent(TokenIssued, nil)
+	event.TenantID = "acme"
+	event.ClientID = "test_client"
+	event.Details["grant_type"] = "client_credentials"
+	service.Record(event)
+	service.Record(NewEvent(TokenRevoked, nil))
+
+	lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+	if !assert.Len(t, lines, 2) {
+		return
+	}
+	written := new(Event)
+	if assert.NoError(t, json.Unmarshal(lines[0], written)) {
+		assert.NotEmpty(t, written.ID)
+		assert.False(t, written.Time.IsZero())
+		assert.Equal(t, TokenIssued, written.Type)
+		assert.Equal(t, "acme", written.TenantID)
+		assert.Equal(t, "test_client", written.ClientID)
+		assert.Equal(t, "client_credentials", written.Details["grant_type"])
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "log", "audit.log")
+
+	cnf := &config.Config{Audit: config.AuditConfig{Sinks: []string{"file"}, File: path}}
+	service := NewService(cnf, nil)
+	service.Record(NewEvent(LoginSuccess, nil))
+	service.Close()
+
+	// Reopening appends to the file
+	service = NewService(cnf, nil)
+	service.Record(NewEvent(LoginFailure, nil))
+	service.Close()
+
+	data, err := ioutil.ReadFile(path)
+	if assert.NoError(t, err) {
+		assert.Len(t, bytes.Split(bytes.TrimSpace(data), []byte("\n")), 2)
+	}
+}
+
+func TestFindEventsRequiresDBSink(t *testing.T) {
+	cnf := &config.Config{Audit: config.AuditConfig{Sinks: []string{"stdout"}}}
+	_, _, err := NewService(cnf, nil).FindEvents(new(Filter), 0, 10)
+	assert.Equal(t, ErrQueryNotSupported, err)
+}
@@ -0,0 +1,94 @@
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/jinzhu/gorm"
+)
+
+// Sink stores or forwards recorded events
+type Sink interface {
+	Write(event *Event) error
+}
+
+// DBSink stores events in the audit_events table, the only sink the admin
+// API can query
+type DBSink struct {
+	db *gorm.DB
+}
+
+// NewDBSink returns a new DBSink instance
+func NewDBSink(db *gorm.DB) *DBSink {
+	return &DBSink{db: db}
+}
+
+// Write inserts an event
+func (s *DBSink) Write(event *Event) error {
+	details, err := json.Marshal(event.Details)
+	if err != nil {
+		return err
+	}
+	return s.db.Create(&models.AuditEvent{
+		ID:        event.ID,
+		Type:      event.Type,
+		Time:      event.Time,
+		ActorID:   event.ActorID,
+		TenantID:  event.TenantID,
+		ClientID:  event.ClientID,
+		IP:        event.IP,
+		UserAgent: event.UserAgent,
+		Details:   string(details),
+	}).Error
+}
+
+// WriterSink writes events as JSON lines, one event per line
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterSink returns a new WriterSink instance
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+// Write writes an event as a line of JSON
+func (s *WriterSink) Write(event *Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(line, '\n'))
+	return err
+}
+
+// FileSink appends events as JSON lines to a file
+type FileSink struct {
+	*WriterSink
+	file *os.File
+}
+
+// NewFileSink opens the file for appending, creating it and its directory
+// if they do not exist
+func NewFileSink(path string) (*FileSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{WriterSink: NewWriterSink(file), file: file}, nil
+}
+
+// Close closes the file
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
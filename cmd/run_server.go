@@ -1,12 +1,19 @@
 package cmd
 
 import (
-	"fmt"
+	"crypto/tls"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/log"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/mtls"
 	"github.com/RichardKnop/go-oauth2-server/services"
+	"github.com/RichardKnop/go-oauth2-server/tracing"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/RichardKnop/go-oauth2-server/util/response"
 	"github.com/gorilla/mux"
 	"github.com/phyber/negroni-gzip/gzip"
 	"github.com/urfave/negroni"
@@ -21,6 +28,11 @@ func RunServer(configBackend string) error {
 	}
 	defer db.Close()
 
+	// Client IPs are read from X-Forwarded-For of trusted proxies only
+	if err := util.SetTrustedProxies(cnf.TrustedProxies); err != nil {
+		return err
+	}
+
 	// start the services
 	if err := services.Init(cnf, db, redisClient); err != nil {
 		return err
@@ -29,28 +41,77 @@ func RunServer(configBackend string) error {
 
 	// Start a classic negroni app
 	app := negroni.New()
-	app.Use(negroni.NewRecovery())
-	app.Use(negroni.NewLogger())
+	app.Use(negroni.HandlerFunc(log.RequestID))
+	recovery := negroni.NewRecovery()
+	recovery.Logger = log.New("http").StdLogger()
+	app.Use(recovery)
+	app.Use(response.NewURLLogger())
+	app.Use(negroni.HandlerFunc(tracing.Middleware))
 	app.Use(gzip.Gzip(gzip.DefaultCompression))
 	app.Use(negroni.NewStatic(http.Dir("public")))
 
 	// Create a router instance
 	router := mux.NewRouter()
 
+	// Serve the metrics on their own listener and observe the requests of
+	// every route
+	if cnf.Metrics.Enabled {
+		services.MetricsService.Instrument(router)
+		if err := services.MetricsService.Start(); err != nil {
+			return err
+		}
+	}
+
 	// Add routes
 	services.HealthService.RegisterRoutes(router, "/v1")
 	services.OauthService.RegisterRoutes(router, "/v1/oauth")
-	// 暂时禁止web上的操作
-	//services.WebService.RegisterRoutes(router, "/web")
+	services.OauthService.RegisterRoutes(router, oauth.TenantRoutePrefix+"/v1/oauth")
+	services.AdminService.RegisterRoutes(router, "/v1/admin")
+	services.OauthService.RegisterWellKnownRoutes(router)
+	services.WebService.RegisterRoutes(router, "/web")
+	services.WebService.RegisterRoutes(router, oauth.TenantRoutePrefix+"/web")
 
 	// Set the router
 	app.UseHandler(router)
 
 	port := strconv.Itoa(cnf.Port)
 	addr := ":" + port
-	fmt.Println(addr)
+	logger.Info("Listening", "addr", addr)
+
+	// Serve HTTPS if a certificate is configured, optionally requesting
+	// client certificates for mutual TLS client authentication
+	if cnf.TLS.CertFile != "" {
+		tlsConfig, err := newTLSConfig(cnf)
+		if err != nil {
+			return err
+		}
+		server := &http.Server{Addr: addr, Handler: app, TLSConfig: tlsConfig}
+		return graceful.ListenAndServeTLS(server, cnf.TLS.CertFile, cnf.TLS.KeyFile, 5*time.Second)
+	}
+
 	// Run the server on port 8080, gracefully stop on SIGTERM signal
 	graceful.Run(addr, 5*time.Second, app)
 
 	return nil
 }
+
+// newTLSConfig returns the server TLS config. Client certificates are only
+// requested here, each client's certificate is verified during client
+// authentication so self-signed certificates can be accepted too
+func newTLSConfig(cnf *config.Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if !cnf.TLS.ClientAuth {
+		return tlsConfig, nil
+	}
+
+	tlsConfig.ClientAuth = tls.RequestClientCert
+	if cnf.TLS.ClientCAFile != "" {
+		// Advertise the trusted CAs so clients pick a matching certificate
+		clientCAs, err := mtls.LoadCertPool(cnf.TLS.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.ClientCAs = clientCAs
+	}
+	return tlsConfig, nil
+}
@@ -3,15 +3,28 @@ package cmd
 import (
 	"github.com/RichardKnop/go-oauth2-server/config"
 	"github.com/RichardKnop/go-oauth2-server/database"
+	"github.com/RichardKnop/go-oauth2-server/log"
 	"github.com/go-redis/redis/v7"
 	"github.com/jinzhu/gorm"
 )
 
+var logger = log.New("cmd")
+
 // initConfigDB loads the configuration and connects to the database
 func initConfigDB(mustLoadOnce, keepReloading bool, configBackend string) (*config.Config, *gorm.DB, *redis.Client, error) {
 	// Config
 	cnf := config.NewConfig(mustLoadOnce, keepReloading, configBackend)
 
+	// Logging
+	err := log.Configure(log.Options{
+		Format: cnf.Log.Format,
+		Level:  cnf.Log.Level,
+		Levels: cnf.Log.Levels,
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
 	// Database
 	db, err := database.NewDatabase(cnf)
 	if err != nil {
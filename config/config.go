@@ -21,6 +21,42 @@ type OauthConfig struct {
 	Issuer               string
 	PasswordSalt         string
 	PasswordSecret       string
+	// MinPasswordLength is the default minimum user password length, values
+	// below passwordpolicy.HardMinLength are raised to it
+	MinPasswordLength int
+	// Password policy defaults, tenants can override them
+	PasswordMinClasses       int
+	PasswordDenylist         bool
+	PasswordDenylistFile     string
+	PasswordDisallowUserInfo bool
+	PasswordHistory          int
+	// PasswordMaxAge in days, 0 never expires passwords
+	PasswordMaxAge int
+	// PasswordHasher is the scheme new user passwords are hashed with:
+	// argon2id, bcrypt or v2, hashes of other schemes are upgraded on login
+	PasswordHasher string
+	// BcryptCost is the cost of bcrypt password and client secret hashes
+	BcryptCost int
+	// Argon2Time, Argon2Memory (in KiB) and Argon2Threads are the argon2id parameters
+	Argon2Time    int
+	Argon2Memory  int
+	Argon2Threads int
+	// TokenEndpoint is the absolute token endpoint URL, used as the expected
+	// audience of client assertions (RFC 7523)
+	TokenEndpoint string
+	// ClientAssertionLifetime is the maximum lifetime of a client assertion in seconds
+	ClientAssertionLifetime int
+	// ClientJWKsCacheLifetime is how long JWKs fetched from a client's jwks_uri are cached in seconds
+	ClientJWKsCacheLifetime int
+	// DPoPProofWindow is the maximum age of a DPoP proof iat in seconds
+	DPoPProofWindow int
+	// PushedAuthRequestLifetime is how long a pushed authorization request is valid in seconds
+	PushedAuthRequestLifetime int
+	// BackchannelLogoutRetries is how many times a failed back-channel
+	// logout request is retried, BackchannelLogoutTimeout is the timeout of
+	// each request in seconds
+	BackchannelLogoutRetries int
+	BackchannelLogoutTimeout int
 }
 
 // SessionConfig stores session configuration for the web app
@@ -35,6 +71,13 @@ type SessionConfig struct {
 	// this particular cookie should only be accessed by the server.
 	// Any attempt to access the cookie from client script is strictly forbidden.
 	HTTPOnly bool
+	// Store is redis (default), keeping sessions server-side with only the
+	// session ID in the cookie, or cookie, keeping the whole session in it
+	Store string
+	// IdleTimeout ends Redis sessions unused for that many seconds,
+	// AbsoluteTimeout ends them that many seconds after they started
+	IdleTimeout     int
+	AbsoluteTimeout int
 }
 
 type RedisConfig struct {
@@ -44,12 +87,194 @@ type RedisConfig struct {
 	Password string
 }
 
+// TLSConfig stores HTTPS and mutual TLS options, the server falls back
+// to plain HTTP when no certificate is configured
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	// ClientAuth requests a client certificate during the TLS handshake,
+	// certificates are verified per client (RFC 8705)
+	ClientAuth bool
+	// ClientCAFile holds PEM encoded CAs trusted for tls_client_auth
+	ClientCAFile string
+}
+
+// RegistrationConfig stores dynamic client registration options (RFC 7591)
+type RegistrationConfig struct {
+	// InitialAccessToken allows registering clients in any tenant
+	InitialAccessToken string
+	// OpenTenants lists tenants accepting registrations without a token
+	OpenTenants []string
+}
+
+// LockoutConfig stores brute-force protection options of the password grant
+type LockoutConfig struct {
+	// AccountMaxFailures is how many failed logins lock out an account,
+	// 0 disables account lockout
+	AccountMaxFailures int
+	// IPMaxFailures is how many failed logins lock out a client IP,
+	// 0 disables IP lockout
+	IPMaxFailures int
+	// FailureWindow is how long failed logins are counted in seconds
+	FailureWindow int
+	// Duration is the first lockout in seconds, doubled by every further
+	// lockout up to MaxDuration
+	Duration    int
+	MaxDuration int
+}
+
+// MFAConfig stores multi-factor authentication options
+type MFAConfig struct {
+	// Required makes every user of the default tenant enroll a second factor,
+	// tenants can override it
+	Required bool
+	// Issuer is the name authenticator apps show for enrolled accounts
+	Issuer string
+	// TokenLifetime is how long an mfa_token is valid in seconds
+	TokenLifetime int
+	// MaxAttempts is how many wrong codes an mfa_token accepts
+	MaxAttempts int
+	// RecoveryCodes is how many recovery codes are generated on enrollment
+	RecoveryCodes int
+}
+
+// SMSConfig stores options of one-time login codes sent by SMS
+type SMSConfig struct {
+	// Sender delivers the messages: none by default, file appends them to
+	// File for tests and log writes them to the log in development only
+	Sender string
+	File   string
+	// CodeLength is the number of digits of a login code
+	CodeLength int
+	// CodeLifetime is how long a login code is valid in seconds
+	CodeLifetime int
+	// MaxAttempts is how many wrong codes a login code accepts
+	MaxAttempts int
+	// SendInterval is the minimum time between codes sent to a phone in seconds
+	SendInterval int
+	// MaxSendsPerHour is how many codes a phone gets per hour
+	MaxSendsPerHour int
+}
+
+// EmailConfig stores options of email confirmation and password reset emails
+type EmailConfig struct {
+	// Mailer delivers the emails: smtp sends them and file drops them into Dir
+	Mailer string
+	From   string
+	Dir    string
+	// SMTP server of the smtp mailer
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	// TemplatesDir holds the email templates, by default the bundled ones
+	TemplatesDir string
+	// ConfirmURL and ResetURL are the pages emailed links open, with the
+	// token appended as the token query parameter
+	ConfirmURL string
+	ResetURL   string
+	// ConfirmationLifetime and ResetLifetime are how long links are valid in seconds
+	ConfirmationLifetime int
+	ResetLifetime        int
+	// SendInterval is the minimum time between emails to an address in seconds
+	SendInterval int
+}
+
+// WebConfig stores options of the login, consent and logout pages
+type WebConfig struct {
+	// TemplatesDir holds the page templates, tenants can override single
+	// pages with templates of the same name in their own directory
+	TemplatesDir string
+	// LocalesDir holds the message catalogs, one <language>.json per language
+	LocalesDir string
+	// DefaultLanguage is used when the browser asks for no known language
+	DefaultLanguage string
+	// Default branding, tenants can override it
+	LogoURL         string
+	PrimaryColor    string
+	BackgroundColor string
+}
+
+// AuditConfig stores options of the security event audit log
+type AuditConfig struct {
+	// Sinks events are recorded to: db, file and stdout. Only events of the
+	// db sink can be queried through the admin API
+	Sinks []string
+	// File the file sink appends JSON lines to
+	File string
+}
+
+// WebhookConfig stores options of webhook deliveries
+type WebhookConfig struct {
+	// Timeout of a delivery request in seconds
+	Timeout int
+	// MaxAttempts is how many times a delivery is tried before it is dead
+	MaxAttempts int
+	// RetryInterval is the delay before the first retry in seconds, doubling
+	// with every attempt up to MaxRetryInterval
+	RetryInterval    int
+	MaxRetryInterval int
+	// PollInterval is how often the outbox is checked in seconds and
+	// BatchSize how many deliveries are sent per check
+	PollInterval int
+	BatchSize    int
+}
+
+// MetricsConfig stores options of the Prometheus metrics endpoint
+type MetricsConfig struct {
+	Enabled bool
+	// Addr is the address of the metrics listener, separate from the API
+	// port so the metrics are not public
+	Addr string
+	// Path the metrics are served at on the metrics listener
+	Path string
+}
+
+// TracingConfig stores options of the OpenTelemetry trace exporter
+type TracingConfig struct {
+	// Exporter is "otlp", "stdout" or empty to disable tracing
+	Exporter string
+	// Endpoint is the host:port of the OTLP/HTTP collector
+	Endpoint string
+	// Insecure sends the spans to the collector over plain HTTP
+	Insecure    bool
+	ServiceName string
+	// SampleRatio of the traces started by the server, requests with a
+	// sampled parent are always traced
+	SampleRatio float64
+}
+
+// LogConfig stores options of the logger
+type LogConfig struct {
+	// Format is "json", or "console" for coloured text
+	Format string
+	// Level is debug, info, warn or error
+	Level string
+	// Levels overrides the level of packages by name
+	Levels map[string]string
+}
+
 // Config stores all configuration options
 type Config struct {
 	Database      DatabaseConfig
 	Redis         RedisConfig
 	Oauth         OauthConfig
 	Session       SessionConfig
+	TLS           TLSConfig
+	Registration  RegistrationConfig
+	Lockout       LockoutConfig
+	MFA           MFAConfig
+	SMS           SMSConfig
+	Email         EmailConfig
+	Web           WebConfig
+	Audit         AuditConfig
+	Webhook       WebhookConfig
+	Metrics       MetricsConfig
+	Tracing       TracingConfig
+	Log           LogConfig
 	IsDevelopment bool
 	Port          int
+	// TrustedProxies are the IPs and CIDR networks of the reverse proxies
+	// whose X-Forwarded-For header is trusted for client IPs
+	TrustedProxies []string
 }
@@ -6,7 +6,6 @@ import (
 	"net/http"
 	"os"
 
-	"github.com/RichardKnop/go-oauth2-server/log"
 	"github.com/hashicorp/consul/api"
 )
 
@@ -74,7 +73,7 @@ func (b *consulBackend) RefreshConfig(newCnf *Config) {
 
 func newConsulClient(theEndpoint, certFile, keyFile, caFile string) (*api.Client, error) {
 	// Log the consul endpoint for debugging purposes
-	log.INFO.Printf("CONSUL Endpoint: %s", theEndpoint)
+	logger.Infof("CONSUL Endpoint: %s", theEndpoint)
 
 	consulConfig := api.DefaultConfig()
 
@@ -6,10 +6,9 @@ import (
 	"os"
 	"strings"
 
-	"github.com/RichardKnop/go-oauth2-server/log"
-	"github.com/coreos/etcd/clientv3"
-	"github.com/coreos/etcd/etcdserver/api/v3rpc/rpctypes"
-	"github.com/coreos/etcd/pkg/transport"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"go.etcd.io/etcd/client/pkg/v3/transport"
+	clientv3 "go.etcd.io/etcd/client/v3"
 	"golang.org/x/net/context"
 )
 
@@ -87,7 +86,7 @@ func (b *etcdBackend) RefreshConfig(newCnf *Config) {
 
 func newEtcdClient(theEndpoints, certFile, keyFile, caFile string) (*clientv3.Client, error) {
 	// Log the etcd endpoint for debugging purposes
-	log.INFO.Printf("ETCD Endpoints: %s", theEndpoints)
+	logger.Infof("ETCD Endpoints: %s", theEndpoints)
 
 	// ETCD config
 	etcdConfig := clientv3.Config{
@@ -1,7 +1,6 @@
 package config
 
 import (
-	"os"
 	"time"
 
 	"github.com/RichardKnop/go-oauth2-server/log"
@@ -12,6 +11,8 @@ var (
 	dialTimeout    = 5 * time.Second
 	contextTimeout = 5 * time.Second
 	reloadDelay    = time.Second * 10
+
+	logger = log.New("config")
 )
 
 // Cnf ...
@@ -28,16 +29,100 @@ var Cnf = &Config{
 		MaxOpenConns: 5,
 	},
 	Oauth: OauthConfig{
-		AccessTokenLifetime:  3600,    // 1 hour
-		RefreshTokenLifetime: 1209600, // 14 days
-		AuthCodeLifetime:     3600,    // 1 hour
-		Jwt:                  true,    // unable jwt
+		AccessTokenLifetime:       3600,    // 1 hour
+		RefreshTokenLifetime:      1209600, // 14 days
+		AuthCodeLifetime:          3600,    // 1 hour
+		Jwt:                       true,    // unable jwt
+		ClientAssertionLifetime:   300,     // 5 minutes
+		ClientJWKsCacheLifetime:   3600,    // 1 hour
+		DPoPProofWindow:           60,      // 1 minute
+		PushedAuthRequestLifetime: 60,      // 1 minute
+		BackchannelLogoutRetries:  3,
+		BackchannelLogoutTimeout:  5, // 5 seconds
+		MinPasswordLength:         8,
+		PasswordDenylist:          true,
+		PasswordDenylistFile:      "",
+		PasswordDisallowUserInfo:  true,
+		PasswordHasher:            "argon2id",
+		BcryptCost:                10,
+		Argon2Time:                1,
+		Argon2Memory:              64 * 1024, // 64 MiB
+		Argon2Threads:             4,
 	},
 	Session: SessionConfig{
-		Secret:   "test_secret",
-		Path:     "/",
-		MaxAge:   86400 * 7, // 7 days
-		HTTPOnly: true,
+		Secret:          "test_secret",
+		Path:            "/",
+		MaxAge:          86400 * 7, // 7 days
+		HTTPOnly:        true,
+		Store:           "redis",
+		IdleTimeout:     1800,  // 30 minutes
+		AbsoluteTimeout: 43200, // 12 hours
+	},
+	Lockout: LockoutConfig{
+		AccountMaxFailures: 5,
+		IPMaxFailures:      50,
+		FailureWindow:      900,  // 15 minutes
+		Duration:           60,   // 1 minute
+		MaxDuration:        3600, // 1 hour
+	},
+	MFA: MFAConfig{
+		Issuer:        "go-oauth2-server",
+		TokenLifetime: 300, // 5 minutes
+		MaxAttempts:   5,
+		RecoveryCodes: 10,
+	},
+	SMS: SMSConfig{
+		CodeLength:      6,
+		CodeLifetime:    300, // 5 minutes
+		MaxAttempts:     5,
+		SendInterval:    60, // 1 minute
+		MaxSendsPerHour: 5,
+	},
+	Email: EmailConfig{
+		Mailer:               "file",
+		From:                 "no-reply@localhost",
+		Dir:                  "/tmp/go-oauth2-server/mail",
+		SMTPPort:             587,
+		TemplatesDir:         "oauth/mail/templates",
+		ConfirmURL:           "http://localhost:8080/web/confirm-email",
+		ResetURL:             "http://localhost:8080/web/reset-password",
+		ConfirmationLifetime: 86400, // 1 day
+		ResetLifetime:        3600,  // 1 hour
+		SendInterval:         60,    // 1 minute
+	},
+	Web: WebConfig{
+		TemplatesDir:    "web/templates",
+		LocalesDir:      "web/locales",
+		DefaultLanguage: "en",
+		PrimaryColor:    "#2a6ebb",
+		BackgroundColor: "#f5f5f5",
+	},
+	Audit: AuditConfig{
+		Sinks: []string{"db"},
+		File:  "/tmp/go-oauth2-server/audit.log",
+	},
+	Webhook: WebhookConfig{
+		Timeout:          10,
+		MaxAttempts:      8,
+		RetryInterval:    30,   // 30 seconds
+		MaxRetryInterval: 3600, // 1 hour
+		PollInterval:     5,
+		BatchSize:        50,
+	},
+	Metrics: MetricsConfig{
+		Enabled: false,
+		Addr:    "127.0.0.1:9090",
+		Path:    "/metrics",
+	},
+	Tracing: TracingConfig{
+		Endpoint:    "localhost:4318",
+		Insecure:    true,
+		ServiceName: "go-oauth2-server",
+		SampleRatio: 1,
+	},
+	Log: LogConfig{
+		Format: "console",
+		Level:  "info",
 	},
 	IsDevelopment: true,
 }
@@ -59,8 +144,7 @@ func NewConfig(mustLoadOnce bool, keepReloading bool, backendType string) *Confi
 	case "file":
 		backend = new(initFileBackend)
 	default:
-		log.FATAL.Printf("%s is not a valid backend", backendType)
-		os.Exit(1)
+		logger.Fatalf("%s is not a valid backend", backendType)
 	}
 
 	backend.InitConfigBackend()
@@ -71,8 +155,7 @@ func NewConfig(mustLoadOnce bool, keepReloading bool, backendType string) *Confi
 		newCnf, err := backend.LoadConfig()
 
 		if err != nil {
-			log.FATAL.Print(err)
-			os.Exit(1)
+			logger.Fatalf("Loading config failed: %s", err)
 		}
 
 		// Refresh the config
@@ -80,7 +163,7 @@ func NewConfig(mustLoadOnce bool, keepReloading bool, backendType string) *Confi
 
 		// Set configLoaded to true
 		configLoaded = true
-		log.INFO.Print("Successfully loaded config for the first time")
+		logger.Info("Successfully loaded config for the first time")
 	}
 
 	if keepReloading {
@@ -93,7 +176,7 @@ func NewConfig(mustLoadOnce bool, keepReloading bool, backendType string) *Confi
 				// Attempt to reload the config
 				newCnf, err := backend.LoadConfig()
 				if err != nil {
-					log.ERROR.Print(err)
+					logger.Errorf("Reloading config failed: %s", err)
 					continue
 				}
 
@@ -102,7 +185,7 @@ func NewConfig(mustLoadOnce bool, keepReloading bool, backendType string) *Confi
 
 				// Set configLoaded to true
 				configLoaded = true
-				log.INFO.Print("Successfully reloaded config")
+				logger.Info("Successfully reloaded config")
 			}
 		}()
 	}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"gopkg.in/ini.v1"
 	"os"
+	"strings"
 )
 
 var (
@@ -39,6 +40,97 @@ func (b *initFileBackend) LoadConfig() (*Config, error) {
 
 	newCnf.IsDevelopment, _ = cfg.Section("option").Key("debug").Bool()
 	newCnf.Port, _ = cfg.Section("option").Key("port").Int()
+	newCnf.TrustedProxies = cfg.Section("option").Key("trusted_proxies").Strings(",")
+
+	newCnf.TLS.CertFile = cfg.Section("tls").Key("cert_file").String()
+	newCnf.TLS.KeyFile = cfg.Section("tls").Key("key_file").String()
+	newCnf.TLS.ClientAuth, _ = cfg.Section("tls").Key("client_auth").Bool()
+	newCnf.TLS.ClientCAFile = cfg.Section("tls").Key("client_ca_file").String()
+
+	newCnf.Registration.InitialAccessToken = cfg.Section("registration").Key("initial_access_token").String()
+	newCnf.Registration.OpenTenants = cfg.Section("registration").Key("open_tenants").Strings(",")
+
+	newCnf.Session.Secret = cfg.Section("session").Key("secret").String()
+	newCnf.Session.Path = cfg.Section("session").Key("path").MustString("/")
+	newCnf.Session.MaxAge = cfg.Section("session").Key("max_age").MustInt(86400 * 7)
+	newCnf.Session.HTTPOnly = cfg.Section("session").Key("http_only").MustBool(true)
+	newCnf.Session.Store = cfg.Section("session").Key("store").MustString("redis")
+	newCnf.Session.IdleTimeout = cfg.Section("session").Key("idle_timeout").MustInt(1800)
+	newCnf.Session.AbsoluteTimeout = cfg.Section("session").Key("absolute_timeout").MustInt(43200)
+
+	newCnf.Lockout.AccountMaxFailures = cfg.Section("lockout").Key("account_max_failures").MustInt(5)
+	newCnf.Lockout.IPMaxFailures = cfg.Section("lockout").Key("ip_max_failures").MustInt(50)
+	newCnf.Lockout.FailureWindow = cfg.Section("lockout").Key("failure_window").MustInt(900)
+	newCnf.Lockout.Duration = cfg.Section("lockout").Key("duration").MustInt(60)
+	newCnf.Lockout.MaxDuration = cfg.Section("lockout").Key("max_duration").MustInt(3600)
+
+	newCnf.MFA.Required = cfg.Section("mfa").Key("required").MustBool(false)
+	newCnf.MFA.Issuer = cfg.Section("mfa").Key("issuer").MustString("go-oauth2-server")
+	newCnf.MFA.TokenLifetime = cfg.Section("mfa").Key("token_lifetime").MustInt(300)
+	newCnf.MFA.MaxAttempts = cfg.Section("mfa").Key("max_attempts").MustInt(5)
+	newCnf.MFA.RecoveryCodes = cfg.Section("mfa").Key("recovery_codes").MustInt(10)
+
+	newCnf.SMS.Sender = cfg.Section("sms").Key("sender").String()
+	newCnf.SMS.File = cfg.Section("sms").Key("file").String()
+	newCnf.SMS.CodeLength = cfg.Section("sms").Key("code_length").MustInt(6)
+	newCnf.SMS.CodeLifetime = cfg.Section("sms").Key("code_lifetime").MustInt(300)
+	newCnf.SMS.MaxAttempts = cfg.Section("sms").Key("max_attempts").MustInt(5)
+	newCnf.SMS.SendInterval = cfg.Section("sms").Key("send_interval").MustInt(60)
+	newCnf.SMS.MaxSendsPerHour = cfg.Section("sms").Key("max_sends_per_hour").MustInt(5)
+
+	newCnf.Email.Mailer = cfg.Section("email").Key("mailer").MustString("file")
+	newCnf.Email.From = cfg.Section("email").Key("from").MustString("no-reply@localhost")
+	newCnf.Email.Dir = cfg.Section("email").Key("dir").MustString("/tmp/go-oauth2-server/mail")
+	newCnf.Email.SMTPHost = cfg.Section("email").Key("smtp_host").String()
+	newCnf.Email.SMTPPort = cfg.Section("email").Key("smtp_port").MustInt(587)
+	newCnf.Email.SMTPUsername = cfg.Section("email").Key("smtp_username").String()
+	newCnf.Email.SMTPPassword = cfg.Section("email").Key("smtp_password").String()
+	newCnf.Email.TemplatesDir = cfg.Section("email").Key("templates_dir").MustString("oauth/mail/templates")
+	newCnf.Email.ConfirmURL = cfg.Section("email").Key("confirm_url").String()
+	newCnf.Email.ResetURL = cfg.Section("email").Key("reset_url").String()
+	newCnf.Email.ConfirmationLifetime = cfg.Section("email").Key("confirmation_lifetime").MustInt(86400)
+	newCnf.Email.ResetLifetime = cfg.Section("email").Key("reset_lifetime").MustInt(3600)
+	newCnf.Email.SendInterval = cfg.Section("email").Key("send_interval").MustInt(60)
+
+	newCnf.Web.TemplatesDir = cfg.Section("web").Key("templates_dir").MustString("web/templates")
+	newCnf.Web.LocalesDir = cfg.Section("web").Key("locales_dir").MustString("web/locales")
+	newCnf.Web.DefaultLanguage = cfg.Section("web").Key("default_language").MustString("en")
+	newCnf.Web.LogoURL = cfg.Section("web").Key("logo_url").String()
+	newCnf.Web.PrimaryColor = cfg.Section("web").Key("primary_color").MustString("#2a6ebb")
+	newCnf.Web.BackgroundColor = cfg.Section("web").Key("background_color").MustString("#f5f5f5")
+
+	newCnf.Audit.Sinks = cfg.Section("audit").Key("sinks").Strings(",")
+	if len(newCnf.Audit.Sinks) == 0 {
+		newCnf.Audit.Sinks = []string{"db"}
+	}
+	newCnf.Audit.File = cfg.Section("audit").Key("file").MustString("/tmp/go-oauth2-server/audit.log")
+
+	newCnf.Webhook.Timeout = cfg.Section("webhook").Key("timeout").MustInt(10)
+	newCnf.Webhook.MaxAttempts = cfg.Section("webhook").Key("max_attempts").MustInt(8)
+	newCnf.Webhook.RetryInterval = cfg.Section("webhook").Key("retry_interval").MustInt(30)
+	newCnf.Webhook.MaxRetryInterval = cfg.Section("webhook").Key("max_retry_interval").MustInt(3600)
+	newCnf.Webhook.PollInterval = cfg.Section("webhook").Key("poll_interval").MustInt(5)
+	newCnf.Webhook.BatchSize = cfg.Section("webhook").Key("batch_size").MustInt(50)
+
+	newCnf.Metrics.Enabled = cfg.Section("metrics").Key("enabled").MustBool(false)
+	newCnf.Metrics.Addr = cfg.Section("metrics").Key("addr").MustString("127.0.0.1:9090")
+	newCnf.Metrics.Path = cfg.Section("metrics").Key("path").MustString("/metrics")
+
+	newCnf.Tracing.Exporter = cfg.Section("tracing").Key("exporter").String()
+	newCnf.Tracing.Endpoint = cfg.Section("tracing").Key("endpoint").MustString("localhost:4318")
+	newCnf.Tracing.Insecure = cfg.Section("tracing").Key("insecure").MustBool(false)
+	newCnf.Tracing.ServiceName = cfg.Section("tracing").Key("service_name").MustString("go-oauth2-server")
+	newCnf.Tracing.SampleRatio = cfg.Section("tracing").Key("sample_ratio").MustFloat64(1)
+
+	newCnf.Log.Format = cfg.Section("log").Key("format").MustString("json")
+	newCnf.Log.Level = cfg.Section("log").Key("level").MustString("info")
+	// Package levels are a list of package:level pairs
+	newCnf.Log.Levels = make(map[string]string)
+	for _, pair := range cfg.Section("log").Key("levels").Strings(",") {
+		if i := strings.Index(pair, ":"); i > 0 {
+			newCnf.Log.Levels[strings.TrimSpace(pair[:i])] = strings.TrimSpace(pair[i+1:])
+		}
+	}
 
 	newCnf.Oauth.Jwt, _ = cfg.Section("oauth").Key("jwt").Bool()
 	newCnf.Oauth.Issuer = cfg.Section("oauth").Key("issuer").String()
@@ -46,6 +138,25 @@ func (b *initFileBackend) LoadConfig() (*Config, error) {
 	newCnf.Oauth.PasswordSecret = cfg.Section("oauth").Key("password_secret").String()
 	newCnf.Oauth.AccessTokenLifetime, _ = cfg.Section("oauth").Key("expires_in").Int()
 	newCnf.Oauth.RefreshTokenLifetime = newCnf.Oauth.AccessTokenLifetime * 2
+	newCnf.Oauth.TokenEndpoint = cfg.Section("oauth").Key("token_endpoint").String()
+	newCnf.Oauth.ClientAssertionLifetime = cfg.Section("oauth").Key("client_assertion_lifetime").MustInt(300)
+	newCnf.Oauth.ClientJWKsCacheLifetime = cfg.Section("oauth").Key("client_jwks_cache_lifetime").MustInt(3600)
+	newCnf.Oauth.DPoPProofWindow = cfg.Section("oauth").Key("dpop_proof_window").MustInt(60)
+	newCnf.Oauth.MinPasswordLength = cfg.Section("oauth").Key("min_password_length").MustInt(8)
+	newCnf.Oauth.PushedAuthRequestLifetime = cfg.Section("oauth").Key("par_lifetime").MustInt(60)
+	newCnf.Oauth.BackchannelLogoutRetries = cfg.Section("oauth").Key("backchannel_logout_retries").MustInt(3)
+	newCnf.Oauth.BackchannelLogoutTimeout = cfg.Section("oauth").Key("backchannel_logout_timeout").MustInt(5)
+	newCnf.Oauth.PasswordMinClasses = cfg.Section("oauth").Key("password_min_classes").MustInt(0)
+	newCnf.Oauth.PasswordDenylist = cfg.Section("oauth").Key("password_denylist").MustBool(true)
+	newCnf.Oauth.PasswordDenylistFile = cfg.Section("oauth").Key("password_denylist_file").String()
+	newCnf.Oauth.PasswordDisallowUserInfo = cfg.Section("oauth").Key("password_disallow_user_info").MustBool(true)
+	newCnf.Oauth.PasswordHistory = cfg.Section("oauth").Key("password_history").MustInt(0)
+	newCnf.Oauth.PasswordMaxAge = cfg.Section("oauth").Key("password_max_age").MustInt(0)
+	newCnf.Oauth.PasswordHasher = cfg.Section("oauth").Key("password_hasher").MustString("argon2id")
+	newCnf.Oauth.BcryptCost = cfg.Section("oauth").Key("bcrypt_cost").MustInt(10)
+	newCnf.Oauth.Argon2Time = cfg.Section("oauth").Key("argon2_time").MustInt(1)
+	newCnf.Oauth.Argon2Memory = cfg.Section("oauth").Key("argon2_memory").MustInt(64 * 1024)
+	newCnf.Oauth.Argon2Threads = cfg.Section("oauth").Key("argon2_threads").MustInt(4)
 	return newCnf, nil
 }
 
@@ -1,26 +1,237 @@
 package log
 
 import (
-	"github.com/RichardKnop/logging"
+	"context"
+	"fmt"
+	"io"
+	stdlog "log"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
+// Options of the logger, see config.LogConfig
+type Options struct {
+	// Format is "json" or "console"
+	Format string
+	// Level is the level of packages without one in Levels
+	Level string
+	// Levels of packages by name
+	Levels map[string]string
+	// Output defaults to stdout
+	Output io.Writer
+}
+
 var (
-	logger = logging.New(nil, nil, new(logging.ColouredFormatter))
-
-	// INFO ...
-	INFO = logger[logging.INFO]
-	// WARNING ...
-	WARNING = logger[logging.WARNING]
-	// ERROR ...
-	ERROR = logger[logging.ERROR]
-	// FATAL ...
-	FATAL = logger[logging.FATAL]
+	// base is the *zap.Logger all loggers write to
+	base atomic.Value
+
+	mu sync.Mutex
+	// levels of the packages with a logger
+	levels = map[string]zap.AtomicLevel{}
+	// defaultLevel and overrides are the levels of the last Configure
+	defaultLevel = zapcore.InfoLevel
+	overrides    = map[string]zapcore.Level{}
 )
 
-// Set sets a custom logger
-func Set(l logging.LoggerInterface) {
-	INFO = l
-	WARNING = l
-	ERROR = l
-	FATAL = l
+func init() {
+	base.Store(newBase("console", os.Stdout))
+}
+
+// Configure sets the format and output of all loggers and the level of
+// each package
+func Configure(opts Options) error {
+	switch opts.Format {
+	case "", "console", "json":
+	default:
+		return fmt.Errorf("unknown log format %q", opts.Format)
+	}
+	level, err := parseLevel(opts.Level)
+	if err != nil {
+		return err
+	}
+	packageLevels := make(map[string]zapcore.Level, len(opts.Levels))
+	for name, text := range opts.Levels {
+		if packageLevels[name], err = parseLevel(text); err != nil {
+			return err
+		}
+	}
+
+	output := opts.Output
+	if output == nil {
+		output = os.Stdout
+	}
+	base.Store(newBase(opts.Format, output))
+
+	mu.Lock()
+	defer mu.Unlock()
+	defaultLevel, overrides = level, packageLevels
+	for name, atomicLevel := range levels {
+		atomicLevel.SetLevel(levelOf(name))
+	}
+	return nil
+}
+
+// levelOf returns the configured level of a package, mu must be held
+func levelOf(name string) zapcore.Level {
+	if level, ok := overrides[name]; ok {
+		return level
+	}
+	return defaultLevel
+}
+
+func parseLevel(text string) (zapcore.Level, error) {
+	if text == "" {
+		return zapcore.InfoLevel, nil
+	}
+	var level zapcore.Level
+	if err := level.UnmarshalText([]byte(strings.ToLower(text))); err != nil {
+		return level, fmt.Errorf("unknown log level %q", text)
+	}
+	return level, nil
+}
+
+// newBase returns a zap logger writing JSON lines, or coloured text for
+// the console. Levels are checked by each Logger
+func newBase(format string, output io.Writer) *zap.Logger {
+	var encoder zapcore.Encoder
+	if format == "json" {
+		encoderConfig := zap.NewProductionEncoderConfig()
+		encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
+	} else {
+		encoderConfig := zap.NewDevelopmentEncoderConfig()
+		encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	}
+	core := zapcore.NewCore(encoder, zapcore.AddSync(output), zapcore.DebugLevel)
+	// Skip Logger.write, Logger.log and the exported method to report the
+	// line logging
+	return zap.New(core, zap.AddCaller(), zap.AddCallerSkip(3))
+}
+
+// Logger is a leveled structured logger of a package
+type Logger struct {
+	level  zap.AtomicLevel
+	fields []zap.Field
+}
+
+// New returns the logger of a package, its level can be set by name
+func New(name string) *Logger {
+	mu.Lock()
+	defer mu.Unlock()
+	level, ok := levels[name]
+	if !ok {
+		level = zap.NewAtomicLevelAt(levelOf(name))
+		levels[name] = level
+	}
+	return &Logger{level: level, fields: []zap.Field{zap.String("package", name)}}
+}
+
+// With returns a logger adding the key value pairs to every line
+func (l *Logger) With(keysAndValues ...interface{}) *Logger {
+	fields := make([]zap.Field, len(l.fields), len(l.fields)+len(keysAndValues)/2)
+	copy(fields, l.fields)
+	return &Logger{level: l.level, fields: append(fields, toFields(keysAndValues)...)}
+}
+
+// WithContext returns a logger adding the request ID of ctx to every line
+func (l *Logger) WithContext(ctx context.Context) *Logger {
+	requestID := RequestIDFromContext(ctx)
+	if requestID == "" {
+		return l
+	}
+	return l.With("request_id", requestID)
+}
+
+// Debug logs a message with key value pairs at the debug level
+func (l *Logger) Debug(msg string, keysAndValues ...interface{}) {
+	l.log(zapcore.DebugLevel, msg, keysAndValues)
+}
+
+// Info logs a message with key value pairs at the info level
+func (l *Logger) Info(msg string, keysAndValues ...interface{}) {
+	l.log(zapcore.InfoLevel, msg, keysAndValues)
+}
+
+// Warn logs a message with key value pairs at the warn level
+func (l *Logger) Warn(msg string, keysAndValues ...interface{}) {
+	l.log(zapcore.WarnLevel, msg, keysAndValues)
+}
+
+// Error logs a message with key value pairs at the error level
+func (l *Logger) Error(msg string, keysAndValues ...interface{}) {
+	l.log(zapcore.ErrorLevel, msg, keysAndValues)
+}
+
+// Fatal logs a message with key value pairs and exits
+func (l *Logger) Fatal(msg string, keysAndValues ...interface{}) {
+	l.log(zapcore.FatalLevel, msg, keysAndValues)
+}
+
+// Debugf logs a formatted message at the debug level
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.logf(zapcore.DebugLevel, format, args)
+}
+
+// Infof logs a formatted message at the info level
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.logf(zapcore.InfoLevel, format, args)
+}
+
+// Warnf logs a formatted message at the warn level
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.logf(zapcore.WarnLevel, format, args)
+}
+
+// Errorf logs a formatted message at the error level
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.logf(zapcore.ErrorLevel, format, args)
+}
+
+// Fatalf logs a formatted message and exits
+func (l *Logger) Fatalf(format string, args ...interface{}) {
+	l.logf(zapcore.FatalLevel, format, args)
+}
+
+func (l *Logger) log(level zapcore.Level, msg string, keysAndValues []interface{}) {
+	l.write(level, msg, toFields(keysAndValues))
+}
+
+func (l *Logger) logf(level zapcore.Level, format string, args []interface{}) {
+	if !l.level.Enabled(level) {
+		return
+	}
+	l.write(level, fmt.Sprintf(format, args...), nil)
+}
+
+func (l *Logger) write(level zapcore.Level, msg string, fields []zap.Field) {
+	// Fatal lines are always written
+	if level < zapcore.FatalLevel && !l.level.Enabled(level) {
+		return
+	}
+	logger := base.Load().(*zap.Logger)
+	if entry := logger.Check(level, Redact(msg)); entry != nil {
+		// The full slice expression makes append copy the logger's fields
+		entry.Write(append(l.fields[:len(l.fields):len(l.fields)], fields...)...)
+	}
+}
+
+// StdLogger returns a standard library logger writing each line at the
+// error level, for libraries logging to a *log.Logger
+func (l *Logger) StdLogger() *stdlog.Logger {
+	return stdlog.New(stdWriter{l}, "", 0)
+}
+
+type stdWriter struct {
+	logger *Logger
+}
+
+func (w stdWriter) Write(p []byte) (int, error) {
+	w.logger.Errorf("%s", strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
 }
@@ -0,0 +1,87 @@
+package log
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// redacted replaces the secrets in log lines
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are the keys whose values are never logged, keys ending in
+// _token or _secret or containing password are sensitive too
+var sensitiveKeys = map[string]bool{
+	"token":            true,
+	"secret":           true,
+	"authorization":    true,
+	"cookie":           true,
+	"code":             true,
+	"code_verifier":    true,
+	"client_assertion": true,
+	"otp":              true,
+	"recovery_code":    true,
+	"private_key":      true,
+}
+
+var (
+	// authSchemes matches the credentials of an Authorization header
+	authSchemes = regexp.MustCompile(`(?i)\b(Bearer|Basic|DPoP)\s+[A-Za-z0-9\-._~+/]+=*`)
+	// jwts matches JSON web tokens
+	jwts = regexp.MustCompile(`\beyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+	// assignments matches sensitive values of query strings, forms and JSON
+	assignments = regexp.MustCompile(`(?i)((?:token|secret|password|code_verifier|client_assertion|otp|recovery_code|\bcode)"?\s*[:=]\s*"?)[^\s"&,;]+`)
+	// codes matches one-time codes in messages, such as the SMS login codes
+	codes = regexp.MustCompile(`(?i)(\bcode\s+is\s+)[A-Za-z0-9-]+`)
+)
+
+// Redact replaces the tokens, secrets and passwords in s
+func Redact(s string) string {
+	s = authSchemes.ReplaceAllString(s, "$1 "+redacted)
+	s = jwts.ReplaceAllString(s, redacted)
+	s = codes.ReplaceAllString(s, "$1"+redacted)
+	return assignments.ReplaceAllString(s, "$1"+redacted)
+}
+
+// isSensitive returns whether the values of key must not be logged
+func isSensitive(key string) bool {
+	key = strings.Replace(strings.ToLower(key), "-", "_", -1)
+	return sensitiveKeys[key] ||
+		strings.HasSuffix(key, "_token") ||
+		strings.HasSuffix(key, "_secret") ||
+		strings.Contains(key, "password")
+}
+
+// toFields converts key value pairs to fields, redacting the values of
+// sensitive keys and the secrets in strings and errors
+func toFields(keysAndValues []interface{}) []zap.Field {
+	fields := make([]zap.Field, 0, len(keysAndValues)/2)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			key = fmt.Sprint(keysAndValues[i])
+		}
+		if i+1 == len(keysAndValues) {
+			fields = append(fields, zap.String(key, "MISSING"))
+			break
+		}
+
+		switch value := keysAndValues[i+1].(type) {
+		case string:
+			if isSensitive(key) {
+				value = redacted
+			}
+			fields = append(fields, zap.String(key, Redact(value)))
+		case error:
+			fields = append(fields, zap.String(key, Redact(value.Error())))
+		default:
+			if isSensitive(key) {
+				value = redacted
+			}
+			fields = append(fields, zap.Any(key, value))
+		}
+	}
+	return fields
+}
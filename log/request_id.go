@@ -0,0 +1,43 @@
+package log
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+
+	"github.com/RichardKnop/uuid"
+)
+
+// RequestIDHeader is the header the request ID is read from and returned in
+const RequestIDHeader = "X-Request-ID"
+
+type contextKey int
+
+const requestIDKey contextKey = 0
+
+// validRequestID limits the request IDs of callers to a safe charset so
+// they can't forge log lines
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID is a negroni middleware passing the request ID of the
+// X-Request-ID header in the request's context, or a new one if the header
+// is missing or invalid. The ID is returned in the same header
+func RequestID(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	requestID := r.Header.Get(RequestIDHeader)
+	if !validRequestID.MatchString(requestID) {
+		requestID = uuid.New()
+	}
+	rw.Header().Set(RequestIDHeader, requestID)
+	next(rw, r.WithContext(WithRequestID(r.Context(), requestID)))
+}
+
+// WithRequestID returns a context carrying the request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestIDFromContext returns the request ID of ctx, empty if it has none
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "oauth2_server"
+
+var (
+	requestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Duration of HTTP requests by route name, method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"route", "method", "code"},
+	)
+
+	tokensIssued = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "tokens_issued_total",
+			Help:      "Access tokens issued by the token endpoint by grant type and tenant.",
+		},
+		[]string{"grant_type", "tenant_id"},
+	)
+
+	authenticationFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "authentication_failures_total",
+			Help:      "Failed client, user and access token authentications by reason.",
+		},
+		[]string{"reason"},
+	)
+
+	introspections = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "introspections_total",
+			Help:      "Token introspections by whether the token was found.",
+		},
+		[]string{"result"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(
+		requestDuration,
+		tokensIssued,
+		authenticationFailures,
+		introspections,
+	)
+}
+
+// TokenIssued counts an access token issued by the token endpoint. Clients
+// are not a label, there can be any number of them
+func TokenIssued(grantType, tenantID string) {
+	tokensIssued.WithLabelValues(grantType, tenantID).Inc()
+}
+
+// AuthenticationFailed counts a failed authentication, the reason should
+// come from a small fixed set so the number of series stays bounded
+func AuthenticationFailed(reason string) {
+	authenticationFailures.WithLabelValues(reason).Inc()
+}
+
+// Introspection counts a token introspection which found the token, or did
+// not
+func Introspection(found bool) {
+	result := "miss"
+	if found {
+		result = "hit"
+	}
+	introspections.WithLabelValues(result).Inc()
+}
@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/go-redis/redis/v7"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// scrape returns the metrics page served by the service's router
+func scrape(t *testing.T, router *mux.Router) string {
+	r, err := http.NewRequest("GET", "http://1.2.3.4/metrics", nil)
+	if !assert.NoError(t, err, "Request setup should not get an error") {
+		return ""
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	return w.Body.String()
+}
+
+func TestMetrics(t *testing.T) {
+	cnf := &config.Config{Metrics: config.MetricsConfig{Enabled: true, Path: "/metrics"}}
+	redisClient := redis.NewClient(&redis.Options{Addr: "127.0.0.1:0"})
+	defer redisClient.Close()
+	service := NewService(cnf, nil, redisClient)
+	defer service.Close()
+
+	router := mux.NewRouter()
+	service.RegisterRoutes(router)
+	service.Instrument(router)
+	router.Methods("GET").Path("/v1/health").Name("health_check").
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	router.Methods("POST").Path("/v1/oauth/tokens").Name("oauth_tokens").
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		})
+
+	for _, req := range []struct{ method, path string }{
+		{"GET", "/v1/health"},
+		{"POST", "/v1/oauth/tokens"},
+	} {
+		r, err := http.NewRequest(req.method, "http://1.2.3.4"+req.path, nil)
+		if !assert.NoError(t, err, "Request setup should not get an error") {
+			return
+		}
+		router.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	TokenIssued("client_credentials", "tenant_a")
+	AuthenticationFailed("invalid_client")
+	Introspection(true)
+	Introspection(false)
+
+	body := scrape(t, router)
+
+	// Requests are observed by route name and status code
+	assert.Contains(t, body, `oauth2_server_http_request_duration_seconds_count{code="200",method="GET",route="health_check"} 1`)
+	assert.Contains(t, body, `oauth2_server_http_request_duration_seconds_count{code="401",method="POST",route="oauth_tokens"} 1`)
+
+	assert.Contains(t, body, `oauth2_server_tokens_issued_total{grant_type="client_credentials",tenant_id="tenant_a"} 1`)
+	assert.Contains(t, body, `oauth2_server_authentication_failures_total{reason="invalid_client"} 1`)
+	assert.Contains(t, body, `oauth2_server_introspections_total{result="hit"} 1`)
+	assert.Contains(t, body, `oauth2_server_introspections_total{result="miss"} 1`)
+
+	// Pool stats are read when scraped, there is no database here
+	assert.Contains(t, body, "oauth2_server_redis_pool_connections 0")
+	assert.NotContains(t, body, "oauth2_server_db_open_connections")
+}
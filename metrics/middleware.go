@@ -0,0 +1,32 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/urfave/negroni"
+)
+
+// InstrumentRoutes is a router middleware observing the duration of
+// requests by the name of the route they matched
+func InstrumentRoutes(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rw := negroni.NewResponseWriter(w)
+		next.ServeHTTP(rw, r)
+
+		name := "unnamed"
+		if route := mux.CurrentRoute(r); route != nil && route.GetName() != "" {
+			name = route.GetName()
+		}
+		// Handlers which write nothing respond with 200
+		status := rw.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		requestDuration.WithLabelValues(name, r.Method, strconv.Itoa(status)).
+			Observe(time.Since(start).Seconds())
+	})
+}
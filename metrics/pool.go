@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"database/sql"
+
+	"github.com/go-redis/redis/v7"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	dbMaxOpenDesc        = newPoolDesc("db_max_open_connections", "Maximum number of open database connections.")
+	dbOpenDesc           = newPoolDesc("db_open_connections", "Open database connections, in use and idle.")
+	dbInUseDesc          = newPoolDesc("db_in_use_connections", "Database connections in use.")
+	dbIdleDesc           = newPoolDesc("db_idle_connections", "Idle database connections.")
+	dbWaitDesc           = newPoolDesc("db_wait_count_total", "Times a database connection was waited for.")
+	dbWaitDurationDesc   = newPoolDesc("db_wait_duration_seconds_total", "Time spent waiting for database connections.")
+	dbIdleClosedDesc     = newPoolDesc("db_max_idle_closed_total", "Database connections closed because the idle pool was full.")
+	dbLifetimeClosedDesc = newPoolDesc("db_max_lifetime_closed_total", "Database connections closed because they reached their maximum lifetime.")
+
+	redisHitsDesc     = newPoolDesc("redis_pool_hits_total", "Times a free Redis connection was found in the pool.")
+	redisMissesDesc   = newPoolDesc("redis_pool_misses_total", "Times no free Redis connection was found in the pool.")
+	redisTimeoutsDesc = newPoolDesc("redis_pool_timeouts_total", "Times waiting for a Redis connection timed out.")
+	redisTotalDesc    = newPoolDesc("redis_pool_connections", "Redis connections in the pool.")
+	redisIdleDesc     = newPoolDesc("redis_pool_idle_connections", "Idle Redis connections in the pool.")
+	redisStaleDesc    = newPoolDesc("redis_pool_stale_connections_total", "Stale Redis connections removed from the pool.")
+)
+
+func newPoolDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "", name), help, nil, nil)
+}
+
+// poolCollector reads the database and Redis connection pool stats when
+// the metrics are scraped
+type poolCollector struct {
+	db    *sql.DB
+	redis *redis.Client
+}
+
+// Describe sends the descriptors of the pool metrics
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	if c.db != nil {
+		for _, desc := range []*prometheus.Desc{
+			dbMaxOpenDesc, dbOpenDesc, dbInUseDesc, dbIdleDesc,
+			dbWaitDesc, dbWaitDurationDesc, dbIdleClosedDesc, dbLifetimeClosedDesc,
+		} {
+			ch <- desc
+		}
+	}
+	if c.redis != nil {
+		for _, desc := range []*prometheus.Desc{
+			redisHitsDesc, redisMissesDesc, redisTimeoutsDesc,
+			redisTotalDesc, redisIdleDesc, redisStaleDesc,
+		} {
+			ch <- desc
+		}
+	}
+}
+
+// Collect sends the current pool stats
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	if c.db != nil {
+		stats := c.db.Stats()
+		ch <- prometheus.MustNewConstMetric(dbMaxOpenDesc, prometheus.GaugeValue, float64(stats.MaxOpenConnections))
+		ch <- prometheus.MustNewConstMetric(dbOpenDesc, prometheus.GaugeValue, float64(stats.OpenConnections))
+		ch <- prometheus.MustNewConstMetric(dbInUseDesc, prometheus.GaugeValue, float64(stats.InUse))
+		ch <- prometheus.MustNewConstMetric(dbIdleDesc, prometheus.GaugeValue, float64(stats.Idle))
+		ch <- prometheus.MustNewConstMetric(dbWaitDesc, prometheus.CounterValue, float64(stats.WaitCount))
+		ch <- prometheus.MustNewConstMetric(dbWaitDurationDesc, prometheus.CounterValue, stats.WaitDuration.Seconds())
+		ch <- prometheus.MustNewConstMetric(dbIdleClosedDesc, prometheus.CounterValue, float64(stats.MaxIdleClosed))
+		ch <- prometheus.MustNewConstMetric(dbLifetimeClosedDesc, prometheus.CounterValue, float64(stats.MaxLifetimeClosed))
+	}
+	if c.redis != nil {
+		stats := c.redis.PoolStats()
+		ch <- prometheus.MustNewConstMetric(redisHitsDesc, prometheus.CounterValue, float64(stats.Hits))
+		ch <- prometheus.MustNewConstMetric(redisMissesDesc, prometheus.CounterValue, float64(stats.Misses))
+		ch <- prometheus.MustNewConstMetric(redisTimeoutsDesc, prometheus.CounterValue, float64(stats.Timeouts))
+		ch <- prometheus.MustNewConstMetric(redisTotalDesc, prometheus.GaugeValue, float64(stats.TotalConns))
+		ch <- prometheus.MustNewConstMetric(redisIdleDesc, prometheus.GaugeValue, float64(stats.IdleConns))
+		ch <- prometheus.MustNewConstMetric(redisStaleDesc, prometheus.CounterValue, float64(stats.StaleConns))
+	}
+}
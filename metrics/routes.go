@@ -0,0 +1,29 @@
+package metrics
+
+import (
+	"github.com/RichardKnop/go-oauth2-server/util/routes"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// RegisterRoutes registers the metrics endpoint at the configured path
+func (s *Service) RegisterRoutes(router *mux.Router) {
+	routes.AddRoutes(s.GetRoutes(), router)
+}
+
+// Instrument observes the requests of all routes of the router
+func (s *Service) Instrument(router *mux.Router) {
+	router.Use(InstrumentRoutes)
+}
+
+// GetRoutes returns []routes.Route slice for the metrics service
+func (s *Service) GetRoutes() []routes.Route {
+	return []routes.Route{
+		{
+			Name:        "metrics",
+			Method:      "GET",
+			Pattern:     s.cnf.Metrics.Path,
+			HandlerFunc: promhttp.Handler().ServeHTTP,
+		},
+	}
+}
@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/log"
+	"github.com/go-redis/redis/v7"
+	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var logger = log.New("metrics")
+
+// Service serves the metrics and reports the connection pool stats
+type Service struct {
+	cnf       *config.Config
+	collector prometheus.Collector
+	server    *http.Server
+}
+
+// NewService returns a new Service instance
+func NewService(cnf *config.Config, db *gorm.DB, redisClient *redis.Client) *Service {
+	collector := &poolCollector{redis: redisClient}
+	if db != nil {
+		collector.db = db.DB()
+	}
+	if err := prometheus.Register(collector); err != nil {
+		logger.Errorf("Registering the connection pool metrics failed: %s", err)
+	}
+	return &Service{cnf: cnf, collector: collector}
+}
+
+// Start serves the metrics on their own listener at the configured address,
+// so they are never exposed on the public API port
+func (s *Service) Start() error {
+	if s.server != nil {
+		return nil
+	}
+	listener, err := net.Listen("tcp", s.cnf.Metrics.Addr)
+	if err != nil {
+		return err
+	}
+
+	router := mux.NewRouter()
+	s.RegisterRoutes(router)
+	s.server = &http.Server{Handler: router}
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logger.Errorf("Serving the metrics failed: %s", err)
+		}
+	}()
+	logger.Info("Serving metrics", "addr", listener.Addr().String())
+	return nil
+}
+
+// Close stops serving the metrics and reporting the connection pool stats
+func (s *Service) Close() {
+	if s.server != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.server.Shutdown(ctx)
+		s.server = nil
+	}
+	prometheus.Unregister(s.collector)
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// AuditEvent is a security event recorded by the db audit sink. Events keep
+// plain IDs rather than foreign keys so they outlive the users and clients
+// they are about
+type AuditEvent struct {
+	ID        string    `gorm:"primary_key" sql:"type:varchar(36)"`
+	Type      string    `sql:"type:varchar(40);index;not null"`
+	Time      time.Time `sql:"index;not null"`
+	ActorID   string    `sql:"type:varchar(36);index"`
+	TenantID  string    `sql:"type:varchar(32);index;not null"`
+	ClientID  string    `sql:"type:varchar(254);index"`
+	IP        string    `sql:"type:varchar(45)"`
+	UserAgent string    `sql:"type:varchar(512)"`
+	// Details is a JSON object of event specific fields
+	Details string `sql:"type:text"`
+}
+
+// TableName specifies table name
+func (e *AuditEvent) TableName() string {
+	return "audit_events"
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// OauthConsent records the scope a user granted a client through the
+// authorization flow, later requests only ask for scopes not granted yet
+type OauthConsent struct {
+	ID       uint   `gorm:"primary_key"`
+	TenantID string `sql:"type:varchar(32);not null"`
+	UserID   string `sql:"type:varchar(32);not null"`
+	ClientID string `sql:"type:varchar(36);index;not null"`
+	Client   *OauthClient
+	// Scope space delimited
+	Scope     string    `sql:"type:varchar(200);not null"`
+	GrantedAt time.Time `sql:"not null"`
+}
+
+// TableName specifies table name
+func (c *OauthConsent) TableName() string {
+	return "oauth_consents"
+}
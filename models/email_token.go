@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"github.com/RichardKnop/uuid"
+)
+
+// OauthEmailConfirmation is a link emailed to confirm a user's email address
+type OauthEmailConfirmation struct {
+	EmailTokenModel
+	UserID string `sql:"type:varchar(32);index;not null"`
+	User   *OauthUser
+	Email  string `sql:"type:varchar(254);not null"`
+}
+
+// TableName specifies table name
+func (c *OauthEmailConfirmation) TableName() string {
+	return "oauth_email_confirmations"
+}
+
+// NewOauthEmailConfirmation creates new OauthEmailConfirmation instance,
+// reference is the hash of the emailed token
+func NewOauthEmailConfirmation(user *OauthUser, email, reference string, expiresIn int) *OauthEmailConfirmation {
+	return &OauthEmailConfirmation{
+		EmailTokenModel: newEmailTokenModel(reference, expiresIn),
+		UserID:          user.ID,
+		Email:           email,
+	}
+}
+
+// OauthPasswordReset is a link emailed to set a new password
+type OauthPasswordReset struct {
+	EmailTokenModel
+	UserID string `sql:"type:varchar(32);index;not null"`
+	User   *OauthUser
+}
+
+// TableName specifies table name
+func (r *OauthPasswordReset) TableName() string {
+	return "oauth_password_resets"
+}
+
+// NewOauthPasswordReset creates new OauthPasswordReset instance, reference
+// is the hash of the emailed token
+func NewOauthPasswordReset(user *OauthUser, reference string, expiresIn int) *OauthPasswordReset {
+	return &OauthPasswordReset{
+		EmailTokenModel: newEmailTokenModel(reference, expiresIn),
+		UserID:          user.ID,
+	}
+}
+
+func newEmailTokenModel(reference string, expiresIn int) EmailTokenModel {
+	return EmailTokenModel{
+		MyGormModel: MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		Reference: reference,
+		ExpiresAt: time.Now().UTC().Add(time.Duration(expiresIn) * time.Second),
+	}
+}
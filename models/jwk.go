@@ -10,6 +10,7 @@ type OauthJwk struct {
 	SID       string    `gorm:"column:sid; type:varchar(255)"`
 	KID       string    `gorm:"column:kid; type:varchar(255)"`
 	KeyData   string    `gorm:"column:key_data; type:text; not null"`
+	TenantID  string    `gorm:"column:tenant_id; type:varchar(32); not null; default:''"`
 	CreatedAt time.Time `gorm:"column:created_at"`
 }
 
@@ -17,6 +17,86 @@ var (
 			Name:     "jwkInitial",
 			Function: jwk0001,
 		},
+		{
+			Name:     "clientJwtAuth",
+			Function: clientJwtAuth0001,
+		},
+		{
+			Name:     "mutualTls",
+			Function: mutualTLS0001,
+		},
+		{
+			Name:     "dpop",
+			Function: dpop0001,
+		},
+		{
+			Name:     "pushedAuthorizationRequests",
+			Function: pushedAuthorizationRequests0001,
+		},
+		{
+			Name:     "dynamicClientRegistration",
+			Function: dynamicClientRegistration0001,
+		},
+		{
+			Name:     "tenants",
+			Function: tenants0001,
+		},
+		{
+			Name:     "tenantSigningKeys",
+			Function: tenantSigningKeys0001,
+		},
+		{
+			Name:     "userManagement",
+			Function: userManagement0001,
+		},
+		{
+			Name:     "passwordPolicy",
+			Function: passwordPolicy0001,
+		},
+		{
+			Name:     "mfa",
+			Function: mfa0001,
+		},
+		{
+			Name:     "email",
+			Function: email0001,
+		},
+		{
+			Name:     "rbac",
+			Function: rbac0001,
+		},
+		{
+			Name:     "consent",
+			Function: consent0001,
+		},
+		{
+			Name:     "sso",
+			Function: sso0001,
+		},
+		{
+			Name:     "logout",
+			Function: logout0001,
+		},
+		{
+			Name:     "branding",
+			Function: branding0001,
+		},
+		{
+			Name:     "audit",
+			Function: audit0001,
+		},
+		{
+			Name:     "webhooks",
+			Function: webhooks0001,
+		},
+		{
+			Name:     "dpopRefreshTokens",
+			Function: dpop0002,
+		},
+		{
+			Name:     "clientAssertionSecret",
+			Function: clientJwtAuth0002,
+		},
 	}
 )
 
@@ -118,3 +198,382 @@ func jwk0001(db *gorm.DB, name string) error {
 	}
 	return nil
 }
+
+func clientJwtAuth0001(db *gorm.DB, name string) error {
+	// Adds token_endpoint_auth_method, jwks and jwks_uri columns
+	if err := db.AutoMigrate(new(OauthClient)).Error; err != nil {
+		return fmt.Errorf("Error adding client authentication columns to oauth_clients table: %s", err)
+	}
+	return nil
+}
+
+func clientJwtAuth0002(db *gorm.DB, name string) error {
+	// Adds assertion_secret to clients
+	if err := db.AutoMigrate(new(OauthClient)).Error; err != nil {
+		return fmt.Errorf("Error adding assertion_secret column to oauth_clients table: %s", err)
+	}
+	return nil
+}
+
+func mutualTLS0001(db *gorm.DB, name string) error {
+	// Adds client certificate metadata to clients
+	if err := db.AutoMigrate(new(OauthClient)).Error; err != nil {
+		return fmt.Errorf("Error adding mutual TLS columns to oauth_clients table: %s", err)
+	}
+	// Adds cert_thumbprint to access tokens
+	if err := db.AutoMigrate(new(OauthAccessToken)).Error; err != nil {
+		return fmt.Errorf("Error adding cert_thumbprint column to oauth_access_tokens table: %s", err)
+	}
+	return nil
+}
+
+func dpop0001(db *gorm.DB, name string) error {
+	// Adds jkt to access tokens
+	if err := db.AutoMigrate(new(OauthAccessToken)).Error; err != nil {
+		return fmt.Errorf("Error adding jkt column to oauth_access_tokens table: %s", err)
+	}
+	return nil
+}
+
+func dpop0002(db *gorm.DB, name string) error {
+	// Adds jkt to refresh tokens
+	if err := db.AutoMigrate(new(OauthRefreshToken)).Error; err != nil {
+		return fmt.Errorf("Error adding jkt column to oauth_refresh_tokens table: %s", err)
+	}
+	return nil
+}
+
+func pushedAuthorizationRequests0001(db *gorm.DB, name string) error {
+	// Adds require_pushed_authorization_requests to clients
+	if err := db.AutoMigrate(new(OauthClient)).Error; err != nil {
+		return fmt.Errorf("Error adding require_pushed_authorization_requests column to oauth_clients table: %s", err)
+	}
+	// Adds PKCE code challenge to authorization codes
+	if err := db.AutoMigrate(new(OauthAuthorizationCode)).Error; err != nil {
+		return fmt.Errorf("Error adding code challenge columns to oauth_authorization_codes table: %s", err)
+	}
+	return nil
+}
+
+func dynamicClientRegistration0001(db *gorm.DB, name string) error {
+	// Adds registration metadata columns to clients
+	if err := db.AutoMigrate(new(OauthClient)).Error; err != nil {
+		return fmt.Errorf("Error adding registration columns to oauth_clients table: %s", err)
+	}
+	return nil
+}
+
+func tenants0001(db *gorm.DB, name string) error {
+	// Create tenants table
+	if err := db.CreateTable(new(Tenant)).Error; err != nil {
+		return fmt.Errorf("Error creating tenants table: %s", err)
+	}
+
+	// Create a tenant for every tenant ID already in use, clients and users
+	// without a tenant ID belong to the default tenant
+	for _, table := range []string{"oauth_clients", "user"} {
+		var tenantIDs []string
+		if err := db.Table(table).Where("tenant_id <> ?", "").Pluck("DISTINCT(tenant_id)", &tenantIDs).Error; err != nil {
+			return fmt.Errorf("Error reading tenant IDs from %s table: %s", table, err)
+		}
+		for _, tenantID := range tenantIDs {
+			tenant := &Tenant{ID: tenantID, Name: tenantID, Status: TenantStatusActive}
+			if err := db.FirstOrCreate(tenant, Tenant{ID: tenantID}).Error; err != nil {
+				return fmt.Errorf("Error creating tenant %s: %s", tenantID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func tenantSigningKeys0001(db *gorm.DB, name string) error {
+	// Adds tenant_id to signing keys, existing keys belong to the default tenant
+	if err := db.AutoMigrate(new(OauthJwk)).Error; err != nil {
+		return fmt.Errorf("Error adding tenant_id column to oauth_jwk table: %s", err)
+	}
+	return nil
+}
+
+func userManagement0001(db *gorm.DB, name string) error {
+	// Adds role_id and disabled columns to users
+	if err := db.AutoMigrate(new(OauthUser)).Error; err != nil {
+		return fmt.Errorf("Error adding role_id and disabled columns to user table: %s", err)
+	}
+	return nil
+}
+
+func passwordPolicy0001(db *gorm.DB, name string) error {
+	// Adds password policy columns to tenants
+	if err := db.AutoMigrate(new(Tenant)).Error; err != nil {
+		return fmt.Errorf("Error adding password policy columns to tenants table: %s", err)
+	}
+
+	// Adds password_changed_at to users, existing passwords count as changed now
+	if err := db.AutoMigrate(new(OauthUser)).Error; err != nil {
+		return fmt.Errorf("Error adding password_changed_at column to user table: %s", err)
+	}
+	err := db.Model(new(OauthUser)).Where("password_changed_at IS NULL").
+		UpdateColumn("password_changed_at", gorm.Expr("NOW()")).Error
+	if err != nil {
+		return fmt.Errorf("Error setting password_changed_at of existing users: %s", err)
+	}
+
+	// Create the password history table
+	if err := db.CreateTable(new(OauthPasswordHistory)).Error; err != nil {
+		return fmt.Errorf("Error creating oauth_password_history table: %s", err)
+	}
+	err = db.Model(new(OauthPasswordHistory)).AddForeignKey(
+		"user_id", "\"user\"(id)",
+		"CASCADE", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_password_history.user_id for user(id): %s", err)
+	}
+	return nil
+}
+
+func mfa0001(db *gorm.DB, name string) error {
+	// Adds require_mfa to tenants
+	if err := db.AutoMigrate(new(Tenant)).Error; err != nil {
+		return fmt.Errorf("Error adding require_mfa column to tenants table: %s", err)
+	}
+
+	// Adds totp_secret, mfa_enabled and mfa_required to users
+	if err := db.AutoMigrate(new(OauthUser)).Error; err != nil {
+		return fmt.Errorf("Error adding mfa columns to user table: %s", err)
+	}
+
+	// Create the recovery codes table
+	if err := db.CreateTable(new(OauthRecoveryCode)).Error; err != nil {
+		return fmt.Errorf("Error creating oauth_recovery_codes table: %s", err)
+	}
+	err := db.Model(new(OauthRecoveryCode)).AddForeignKey(
+		"user_id", "\"user\"(id)",
+		"CASCADE", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_recovery_codes.user_id for user(id): %s", err)
+	}
+	return nil
+}
+
+func email0001(db *gorm.DB, name string) error {
+	// Adds email and email_verified to users
+	if err := db.AutoMigrate(new(OauthUser)).Error; err != nil {
+		return fmt.Errorf("Error adding email columns to user table: %s", err)
+	}
+
+	// Create the email token tables
+	if err := db.CreateTable(new(OauthEmailConfirmation)).Error; err != nil {
+		return fmt.Errorf("Error creating oauth_email_confirmations table: %s", err)
+	}
+	if err := db.CreateTable(new(OauthPasswordReset)).Error; err != nil {
+		return fmt.Errorf("Error creating oauth_password_resets table: %s", err)
+	}
+	err := db.Model(new(OauthEmailConfirmation)).AddForeignKey(
+		"user_id", "\"user\"(id)",
+		"CASCADE", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_email_confirmations.user_id for user(id): %s", err)
+	}
+	err = db.Model(new(OauthPasswordReset)).AddForeignKey(
+		"user_id", "\"user\"(id)",
+		"CASCADE", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_password_resets.user_id for user(id): %s", err)
+	}
+	return nil
+}
+
+func rbac0001(db *gorm.DB, name string) error {
+	// Adds tenant_id, description, permissions and scope to roles, role
+	// names are unique per tenant instead of globally
+	if err := db.AutoMigrate(new(OauthRole)).Error; err != nil {
+		return fmt.Errorf("Error adding rbac columns to oauth_roles table: %s", err)
+	}
+	if err := db.Exec("ALTER TABLE oauth_roles DROP CONSTRAINT IF EXISTS oauth_roles_name_key").Error; err != nil {
+		return fmt.Errorf("Error dropping unique constraint on oauth_roles.name: %s", err)
+	}
+	err := db.Model(new(OauthRole)).AddUniqueIndex(
+		"idx_oauth_roles_tenant_id_name",
+		"tenant_id", "name",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating unique index on oauth_roles(tenant_id, name): %s", err)
+	}
+
+	// Custom role IDs are longer than the built-in ones
+	if err := db.Exec("ALTER TABLE \"user\" ALTER COLUMN role_id TYPE varchar(32)").Error; err != nil {
+		return fmt.Errorf("Error widening user.role_id column: %s", err)
+	}
+
+	// The built-in roles must exist for users to be assigned them
+	for id, roleName := range map[string]string{"superuser": "Superuser", "user": "User"} {
+		role := &OauthRole{ID: id, Name: roleName}
+		if err := db.FirstOrCreate(role, OauthRole{ID: id}).Error; err != nil {
+			return fmt.Errorf("Error creating role %s: %s", id, err)
+		}
+	}
+
+	// Create the role assignments table
+	if err := db.CreateTable(new(OauthUserRole)).Error; err != nil {
+		return fmt.Errorf("Error creating oauth_user_roles table: %s", err)
+	}
+	err = db.Model(new(OauthUserRole)).AddUniqueIndex(
+		"idx_oauth_user_roles_user_id_role_id",
+		"user_id", "role_id",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating unique index on oauth_user_roles(user_id, role_id): %s", err)
+	}
+	err = db.Model(new(OauthUserRole)).AddForeignKey(
+		"user_id", "\"user\"(id)",
+		"CASCADE", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_user_roles.user_id for user(id): %s", err)
+	}
+	err = db.Model(new(OauthUserRole)).AddForeignKey(
+		"role_id", "oauth_roles(id)",
+		"CASCADE", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_user_roles.role_id for oauth_roles(id): %s", err)
+	}
+
+	// Existing users are assigned the role they were created with
+	err = db.Exec(
+		"INSERT INTO oauth_user_roles (tenant_id, user_id, role_id, created_at) " +
+			"SELECT tenant_id, id, role_id, NOW() FROM \"user\" WHERE role_id IS NOT NULL",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error assigning roles of existing users: %s", err)
+	}
+	return nil
+}
+
+func consent0001(db *gorm.DB, name string) error {
+	// Adds skip_consent to clients
+	if err := db.AutoMigrate(new(OauthClient)).Error; err != nil {
+		return fmt.Errorf("Error adding skip_consent column to oauth_clients table: %s", err)
+	}
+
+	// Create the consents table, one row per user and client
+	if err := db.CreateTable(new(OauthConsent)).Error; err != nil {
+		return fmt.Errorf("Error creating oauth_consents table: %s", err)
+	}
+	err := db.Model(new(OauthConsent)).AddUniqueIndex(
+		"idx_oauth_consents_user_id_client_id",
+		"user_id", "client_id",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating unique index on oauth_consents(user_id, client_id): %s", err)
+	}
+	err = db.Model(new(OauthConsent)).AddForeignKey(
+		"user_id", "\"user\"(id)",
+		"CASCADE", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_consents.user_id for user(id): %s", err)
+	}
+	err = db.Model(new(OauthConsent)).AddForeignKey(
+		"client_id", "oauth_clients(id)",
+		"CASCADE", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_consents.client_id for oauth_clients(id): %s", err)
+	}
+	return nil
+}
+
+func sso0001(db *gorm.DB, name string) error {
+	// Adds auth_time and amr to authorization codes
+	if err := db.AutoMigrate(new(OauthAuthorizationCode)).Error; err != nil {
+		return fmt.Errorf("Error adding auth_time and amr columns to oauth_authorization_codes table: %s", err)
+	}
+	return nil
+}
+
+func logout0001(db *gorm.DB, name string) error {
+	// Adds logout metadata to clients and the SSO session to authorization codes
+	if err := db.AutoMigrate(new(OauthClient)).Error; err != nil {
+		return fmt.Errorf("Error adding logout columns to oauth_clients table: %s", err)
+	}
+	if err := db.AutoMigrate(new(OauthAuthorizationCode)).Error; err != nil {
+		return fmt.Errorf("Error adding session_id column to oauth_authorization_codes table: %s", err)
+	}
+
+	// Create the session clients table, one row per SSO session and client
+	if err := db.CreateTable(new(OauthSessionClient)).Error; err != nil {
+		return fmt.Errorf("Error creating oauth_session_clients table: %s", err)
+	}
+	err := db.Model(new(OauthSessionClient)).AddUniqueIndex(
+		"idx_oauth_session_clients_session_id_client_id",
+		"session_id", "client_id",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating unique index on oauth_session_clients(session_id, client_id): %s", err)
+	}
+	err = db.Model(new(OauthSessionClient)).AddForeignKey(
+		"user_id", "\"user\"(id)",
+		"CASCADE", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_session_clients.user_id for user(id): %s", err)
+	}
+	err = db.Model(new(OauthSessionClient)).AddForeignKey(
+		"client_id", "oauth_clients(id)",
+		"CASCADE", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_session_clients.client_id for oauth_clients(id): %s", err)
+	}
+	return nil
+}
+
+func branding0001(db *gorm.DB, name string) error {
+	// Adds the web page branding to tenants
+	if err := db.AutoMigrate(new(Tenant)).Error; err != nil {
+		return fmt.Errorf("Error adding branding columns to tenants table: %s", err)
+	}
+	return nil
+}
+
+func audit0001(db *gorm.DB, name string) error {
+	// Create the audit events table of the db audit sink
+	if err := db.CreateTable(new(AuditEvent)).Error; err != nil {
+		return fmt.Errorf("Error creating audit_events table: %s", err)
+	}
+	return nil
+}
+
+func webhooks0001(db *gorm.DB, name string) error {
+	// Create the webhook subscriptions table and the outbox of deliveries
+	if err := db.CreateTable(new(WebhookSubscription)).Error; err != nil {
+		return fmt.Errorf("Error creating webhook_subscriptions table: %s", err)
+	}
+	if err := db.CreateTable(new(WebhookDelivery)).Error; err != nil {
+		return fmt.Errorf("Error creating webhook_deliveries table: %s", err)
+	}
+	err := db.Model(new(WebhookDelivery)).AddForeignKey(
+		"subscription_id", "webhook_subscriptions(id)",
+		"CASCADE", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"webhook_deliveries.subscription_id for webhook_subscriptions(id): %s", err)
+	}
+	return nil
+}
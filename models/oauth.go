@@ -17,6 +17,32 @@ type OauthClient struct {
 	RedirectURI sql.NullString `sql:"type:varchar(200)"`
 	Name        string         `sql:"type varchar(100);not null"`
 	TenantID    string         `sql:"type varchar(32);not null"`
+	// AssertionSecret is the client secret of client_secret_jwt clients, it
+	// is the HMAC key of their assertions so it cannot be stored hashed
+	AssertionSecret sql.NullString `sql:"type:varchar(60)"`
+	// TokenEndpointAuthMethod is one of authmethods, empty means a legacy secret client
+	TokenEndpointAuthMethod string         `sql:"type:varchar(40)"`
+	JWKS                    sql.NullString `gorm:"column:jwks" sql:"type:text"`
+	JWKSURI                 sql.NullString `gorm:"column:jwks_uri" sql:"type:varchar(200)"`
+	// Mutual TLS client authentication metadata (RFC 8705)
+	TLSClientAuthSubjectDN                sql.NullString `gorm:"column:tls_client_auth_subject_dn" sql:"type:varchar(255)"`
+	TLSClientAuthSANDNS                   sql.NullString `gorm:"column:tls_client_auth_san_dns" sql:"type:varchar(255)"`
+	TLSClientCertificateBoundAccessTokens bool           `gorm:"column:tls_client_certificate_bound_access_tokens" sql:"default:false"`
+	// RequirePushedAuthorizationRequests rejects authorization requests not pushed via PAR (RFC 9126)
+	RequirePushedAuthorizationRequests bool `sql:"default:false"`
+	// SkipConsent marks first-party clients, whose authorization requests
+	// never ask the user for consent
+	SkipConsent bool `sql:"default:false"`
+	// OpenID Connect logout metadata, post logout redirect URIs are space
+	// delimited
+	PostLogoutRedirectURIs string         `sql:"type:varchar(1000)"`
+	FrontchannelLogoutURI  sql.NullString `sql:"type:varchar(200)"`
+	BackchannelLogoutURI   sql.NullString `sql:"type:varchar(200)"`
+	// Dynamic client registration metadata (RFC 7591), empty grant types and
+	// scope leave the client unrestricted
+	GrantTypes              string         `sql:"type:varchar(200)"`
+	Scope                   sql.NullString `sql:"type:varchar(200)"`
+	RegistrationAccessToken sql.NullString `sql:"type:varchar(60)"`
 }
 
 // TableName specifies table name
@@ -37,11 +63,18 @@ func (s *OauthScope) TableName() string {
 	return "oauth_scopes"
 }
 
-// OauthRole is a one of roles user can have (currently superuser or user)
+// OauthRole is a role users are assigned. Built-in roles (superuser and
+// user) have no tenant, custom roles belong to a tenant.
 type OauthRole struct {
 	TimestampModel
-	ID   string `gorm:"primary_key" sql:"type:varchar(32)"`
-	Name string `sql:"type:varchar(50);unique;not null"`
+	ID          string         `gorm:"primary_key" sql:"type:varchar(32)"`
+	TenantID    sql.NullString `sql:"type:varchar(32)"`
+	Name        string         `sql:"type:varchar(50);not null"`
+	Description string         `sql:"type:varchar(255)"`
+	// Permissions space delimited
+	Permissions string `sql:"type:varchar(1000)"`
+	// Scope space delimited, caps the scope granted to the role's users
+	Scope string `sql:"type:varchar(200)"`
 }
 
 // TableName specifies table name
@@ -49,6 +82,11 @@ func (r *OauthRole) TableName() string {
 	return "oauth_roles"
 }
 
+// IsBuiltIn returns true for the roles shared by every tenant
+func (r *OauthRole) IsBuiltIn() bool {
+	return !r.TenantID.Valid
+}
+
 // OauthUser ...
 type OauthUser struct {
 	ID        string         `gorm:"column:id;varchar(32);primary_key"`
@@ -59,6 +97,18 @@ type OauthUser struct {
 	Account   string         `gorm:"type varchar(32)"`
 	CreatedAt time.Time      `gorm:"column:created_at"`
 	UpdatedAt time.Time      `gorm:"column:updated"`
+	RoleID    sql.NullString `gorm:"column:role_id;type:varchar(32)"`
+	Disabled  bool           `gorm:"column:disabled;default:false"`
+	// PasswordChangedAt is when the password was last set, for its maximum age
+	PasswordChangedAt *time.Time `gorm:"column:password_changed_at"`
+	// TOTPSecret is the base32 secret of the user's authenticator app, set
+	// on enrollment and confirmed with the first code
+	TOTPSecret  sql.NullString `gorm:"column:totp_secret;type:varchar(64)"`
+	MFAEnabled  bool           `gorm:"column:mfa_enabled;default:false"`
+	MFARequired bool           `gorm:"column:mfa_required;default:false"`
+	// Email is confirmed by opening the link of a confirmation email
+	Email         sql.NullString `gorm:"column:email;type:varchar(254);index"`
+	EmailVerified bool           `gorm:"column:email_verified;default:false"`
 }
 
 // TableName specifies table name
@@ -77,6 +127,8 @@ type OauthRefreshToken struct {
 	Token     string    `sql:"type:varchar(40);unique;not null"`
 	ExpiresAt time.Time `sql:"not null"`
 	Scope     string    `sql:"type:varchar(200);not null"`
+	// JKT binds the token to a DPoP proof key (JWK SHA-256 thumbprint)
+	JKT sql.NullString `gorm:"column:jkt" sql:"type:varchar(64)"`
 }
 
 // TableName specifies table name
@@ -95,15 +147,21 @@ type OauthAccessToken struct {
 	Token     string    `sql:"type:varchar(10240);unique;not null"`
 	ExpiresAt time.Time `sql:"not null"`
 	Scope     string    `sql:"type:varchar(200);not null"`
+	// CertThumbprint binds the token to a client certificate (x5t#S256)
+	CertThumbprint sql.NullString `sql:"type:varchar(64)"`
+	// JKT binds the token to a DPoP proof key (JWK SHA-256 thumbprint)
+	JKT sql.NullString `gorm:"column:jkt" sql:"type:varchar(64)"`
 }
 
 type OauthAccessTokenRedis struct {
-	TenantID  string
-	ClientID  string
-	UserID    string
-	Token     string
-	ExpiresAt time.Time
-	Scope     string
+	TenantID       string
+	ClientID       string
+	UserID         string
+	Token          string
+	ExpiresAt      time.Time
+	Scope          string
+	CertThumbprint string
+	JKT            string
 }
 
 // TableName specifies table name
@@ -123,6 +181,14 @@ type OauthAuthorizationCode struct {
 	RedirectURI sql.NullString `sql:"type:varchar(200)"`
 	ExpiresAt   time.Time      `sql:"not null"`
 	Scope       string         `sql:"type:varchar(200);not null"`
+	// PKCE code challenge (RFC 7636)
+	CodeChallenge       sql.NullString `sql:"type:varchar(128)"`
+	CodeChallengeMethod sql.NullString `sql:"type:varchar(10)"`
+	// When and how the user authenticated, for the auth_time and amr claims
+	AuthTime *time.Time
+	AMR      string `sql:"type:varchar(100)"`
+	// SessionID is the sid of the SSO session the code was granted through
+	SessionID sql.NullString `sql:"type:varchar(64)"`
 }
 
 // TableName specifies table name
@@ -137,7 +203,7 @@ func NewOauthRefreshToken(client *OauthClient, user *OauthUser, expiresIn int, s
 			ID:        uuid.New(),
 			CreatedAt: time.Now().UTC(),
 		},
-		TenantID:  user.TenantID,
+		TenantID:  client.TenantID,
 		ClientID:  util.StringOrNull(string(client.ID)),
 		Token:     uuid.New(),
 		ExpiresAt: time.Now().UTC().Add(time.Duration(expiresIn) * time.Second),
@@ -156,7 +222,7 @@ func NewOauthAccessToken(client *OauthClient, user *OauthUser, expiresIn int, sc
 			ID:        uuid.New(),
 			CreatedAt: time.Now().UTC(),
 		},
-		TenantID:  user.TenantID,
+		TenantID:  client.TenantID,
 		ClientID:  util.StringOrNull(string(client.ID)),
 		Token:     uuid.New(),
 		ExpiresAt: time.Now().UTC().Add(time.Duration(expiresIn) * time.Second),
@@ -175,7 +241,7 @@ func NewOauthAuthorizationCode(client *OauthClient, user *OauthUser, expiresIn i
 			ID:        uuid.New(),
 			CreatedAt: time.Now().UTC(),
 		},
-		TenantID:    user.TenantID,
+		TenantID:    client.TenantID,
 		ClientID:    util.StringOrNull(string(client.ID)),
 		UserID:      util.StringOrNull(string(user.ID)),
 		Code:        uuid.New(),
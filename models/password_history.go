@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// OauthPasswordHistory keeps previous password hashes of a user so they
+// cannot be reused
+type OauthPasswordHistory struct {
+	ID        uint   `gorm:"primary_key"`
+	UserID    string `sql:"type:varchar(32);index;not null"`
+	Password  string `sql:"type:varchar(255);not null"`
+	CreatedAt time.Time
+}
+
+// TableName specifies table name
+func (h *OauthPasswordHistory) TableName() string {
+	return "oauth_password_history"
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// OauthRecoveryCode is a single use code logging in a user who lost their
+// authenticator, only its SHA256 hash is stored
+type OauthRecoveryCode struct {
+	ID        uint       `gorm:"primary_key"`
+	UserID    string     `sql:"type:varchar(32);index;not null"`
+	CodeHash  string     `sql:"type:varchar(64);not null"`
+	UsedAt    *time.Time `gorm:"column:used_at"`
+	CreatedAt time.Time
+}
+
+// TableName specifies table name
+func (c *OauthRecoveryCode) TableName() string {
+	return "oauth_recovery_codes"
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// OauthSessionClient records a client which got an authorization code through
+// an SSO session, logging out of the session logs the user out of the client
+type OauthSessionClient struct {
+	ID uint `gorm:"primary_key"`
+	// SessionID is the sid of the SSO session
+	SessionID string `sql:"type:varchar(64);index;not null"`
+	TenantID  string `sql:"type:varchar(32);not null"`
+	UserID    string `sql:"type:varchar(32);not null"`
+	ClientID  string `sql:"type:varchar(36);not null"`
+	Client    *OauthClient
+	CreatedAt time.Time `sql:"not null"`
+}
+
+// TableName specifies table name
+func (c *OauthSessionClient) TableName() string {
+	return "oauth_session_clients"
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"database/sql"
+)
+
+const (
+	// TenantStatusActive tenants can use every grant
+	TenantStatusActive = "active"
+	// TenantStatusSuspended tenants cannot get any new tokens
+	TenantStatusSuspended = "suspended"
+)
+
+// Tenant groups clients and users, null overrides fall back to the oauth config
+type Tenant struct {
+	TimestampModel
+	ID     string `gorm:"primary_key" sql:"type:varchar(32)"`
+	Name   string `sql:"type:varchar(100);not null"`
+	Status string `sql:"type:varchar(20);not null;default:'active'"`
+	// Token lifetimes in seconds
+	AccessTokenLifetime  sql.NullInt64
+	RefreshTokenLifetime sql.NullInt64
+	AuthCodeLifetime     sql.NullInt64
+	Issuer               sql.NullString `sql:"type:varchar(200)"`
+	// GrantTypes space delimited, empty allows every grant type
+	GrantTypes string `sql:"type:varchar(200)"`
+	// Password policy
+	MinPasswordLength        sql.NullInt64
+	PasswordMinClasses       sql.NullInt64
+	PasswordDenylist         sql.NullBool
+	PasswordDisallowUserInfo sql.NullBool
+	PasswordHistory          sql.NullInt64
+	// PasswordMaxAge in days
+	PasswordMaxAge sql.NullInt64
+	// RequireMFA makes every user of the tenant enroll a second factor
+	RequireMFA sql.NullBool
+	// OpenRegistration accepts dynamic client registration without an initial access token
+	OpenRegistration bool `sql:"default:false"`
+	// Branding of the web pages
+	LogoURL         sql.NullString `sql:"type:varchar(500)"`
+	PrimaryColor    sql.NullString `sql:"type:varchar(20)"`
+	BackgroundColor sql.NullString `sql:"type:varchar(20)"`
+	// TemplatesDir holds templates replacing the default web pages
+	TemplatesDir sql.NullString `sql:"type:varchar(200)"`
+	// DefaultLanguage of the web pages
+	DefaultLanguage sql.NullString `sql:"type:varchar(10)"`
+}
+
+// TableName specifies table name
+func (t *Tenant) TableName() string {
+	return "tenants"
+}
+
+// IsActive returns true if the tenant is not suspended
+func (t *Tenant) IsActive() bool {
+	return t.Status == TenantStatusActive
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// OauthUserRole assigns a role to a user of a tenant, users can have any
+// number of roles besides the one they were created with (OauthUser.RoleID)
+type OauthUserRole struct {
+	ID        uint   `gorm:"primary_key"`
+	TenantID  string `sql:"type:varchar(32);not null"`
+	UserID    string `sql:"type:varchar(32);index;not null"`
+	RoleID    string `sql:"type:varchar(32);index;not null"`
+	Role      *OauthRole
+	CreatedAt time.Time
+}
+
+// TableName specifies table name
+func (ur *OauthUserRole) TableName() string {
+	return "oauth_user_roles"
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+)
+
+// Statuses of webhook deliveries
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryDead      = "dead"
+)
+
+// WebhookSubscription posts the tenant's events of the listed types to a URL
+type WebhookSubscription struct {
+	MyGormModel
+	TenantID string `sql:"type:varchar(32);index;not null"`
+	URL      string `sql:"type:varchar(2048);not null"`
+	// Secret is the HMAC-SHA256 key deliveries are signed with
+	Secret string `sql:"type:varchar(64);not null"`
+	// EventTypes is a space separated list of audit event types
+	EventTypes string `sql:"type:varchar(500);not null"`
+}
+
+// TableName specifies table name
+func (s *WebhookSubscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+// WebhookDelivery is an event waiting in the outbox to be posted to a
+// subscription, delivered or given up on
+type WebhookDelivery struct {
+	ID             string `gorm:"primary_key" sql:"type:varchar(36)"`
+	SubscriptionID string `sql:"type:varchar(36);index;not null"`
+	Subscription   *WebhookSubscription
+	TenantID       string `sql:"type:varchar(32);index;not null"`
+	EventID        string `sql:"type:varchar(36);not null"`
+	EventType      string `sql:"type:varchar(40);not null"`
+	// Payload is the JSON body posted to the subscription
+	Payload       string    `sql:"type:text;not null"`
+	Status        string    `sql:"type:varchar(20);index;not null"`
+	Attempts      int       `sql:"not null"`
+	NextAttemptAt time.Time `sql:"index;not null"`
+	LastError     string    `sql:"type:varchar(500)"`
+	DeliveredAt   *time.Time
+	CreatedAt     time.Time `sql:"not null"`
+	UpdatedAt     time.Time
+}
+
+// TableName specifies table name
+func (d *WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
@@ -2,10 +2,11 @@ package oauth
 
 import (
 	"crypto/rsa"
+	"github.com/RichardKnop/go-oauth2-server/audit"
 	"github.com/RichardKnop/go-oauth2-server/oauth/jwt"
 	jwtgo "github.com/dgrijalva/jwt-go"
-	"gopkg.in/square/go-jose.v2"
-	"log"
+	"net/http"
+	"strings"
 	"time"
 
 	"errors"
@@ -21,8 +22,31 @@ var (
 	ErrInvalidToken = errors.New("invalid token")
 )
 
-func (s *Service) GrantJWT(user *models.OauthUser, expiresIn int, scope string, accessToken string) (string, error) {
-	if privateJwk, err := s.getJWKPrivateKey(); err != nil {
+// GrantJWT issues a signed JWT describing the access token
+func (s *Service) GrantJWT(user *models.OauthUser, expiresIn int, scope string, accessToken *models.OauthAccessToken) (string, error) {
+	return s.grantJWT(user, expiresIn, scope, accessToken, nil)
+}
+
+// grantJWT is GrantJWT adding the auth_time, amr and sid claims of the
+// authorization code the access token was granted for, if not nil
+func (s *Service) grantJWT(user *models.OauthUser, expiresIn int, scope string, accessToken *models.OauthAccessToken, authorizationCode *models.OauthAuthorizationCode) (string, error) {
+	tenant, err := s.GetTenantConfig(accessToken.TenantID)
+	if err != nil {
+		return "", err
+	}
+
+	// The client is the audience, so the token can be an id_token_hint
+	client := new(models.OauthClient)
+	if err := s.db.Select("key").Where("id = ?", accessToken.ClientID.String).First(client).Error; err != nil {
+		return "", err
+	}
+
+	userRoles, err := s.getUserRoleNames(user.ID)
+	if err != nil {
+		return "", err
+	}
+
+	if privateJwk, err := s.getJWKPrivateKey(accessToken.TenantID); err != nil {
 		return "", err
 	} else {
 		// get jwt private key
@@ -35,73 +59,46 @@ func (s *Service) GrantJWT(user *models.OauthUser, expiresIn int, scope string,
 		var claims = &jwt.Claims{
 			StandardClaims: jwtgo.StandardClaims{
 				ExpiresAt: expiry,
-				Id:        accessToken,
+				Id:        accessToken.Token,
 				IssuedAt:  issueAt,
-				Issuer:    s.cnf.Oauth.Issuer,
+				Issuer:    tenant.Issuer,
 				NotBefore: notBefore,
 				Subject:   user.ID,
+				Audience:  client.Key,
 			},
 			TenantID: user.TenantID,
-			Scope: scope,
+			Scope:    scope,
+			Roles:    userRoles,
+			Cnf:      newConfirmation(accessToken),
+		}
+		if authorizationCode != nil {
+			if authorizationCode.AuthTime != nil {
+				claims.AuthTime = authorizationCode.AuthTime.Unix()
+			}
+			claims.AMR = strings.Fields(authorizationCode.AMR)
+			claims.SessionID = authorizationCode.SessionID.String
 		}
 		token := jwtgo.NewWithClaims(jwtgo.SigningMethodRS256, claims)
 		token.Header["kid"] = publicJwk.KeyID
-		log.Println(publicJwk.KeyID)
+		s.logger.Debug("Signing access token", "kid", publicJwk.KeyID)
 		return token.SignedString(privateKey)
 	}
 }
 
-func (s *Service) getJWKPrivateKey() (*jose.JSONWebKey, error) {
-	var oauthJwks []models.OauthJwk
-	notFound := s.db.Where("sid = ?", "oauth-jwk").Find(&oauthJwks).RecordNotFound()
-	// Not found
-	if notFound {
-		return nil, ErrJwkPrivateKeyNotFound
-	}
-	for _, oauthJwk := range oauthJwks {
-		if oauthJwk.KID[0:7] == "private" {
-			data := []byte(oauthJwk.KeyData)
-			var key = &jose.JSONWebKey{}
-			if err := key.UnmarshalJSON(data); err != nil {
-				return nil, err
-			}
-			return key, nil
-		}
-	}
-	return nil, ErrJwkPrivateKeyNotFound
-}
-
-func (s *Service) JWKs() (*jose.JSONWebKeySet, error) {
-	key, err := s.getJWKPublicKey()
-	if err == nil {
-		return &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{*key}}, nil
-	}
-	return nil, err
+// GrantAccessToken deletes expired tokens and grants a new access token
+func (s *Service) GrantAccessToken(client *models.OauthClient, user *models.OauthUser, expiresIn int, scope string) (*models.OauthAccessToken, error) {
+	return s.grantAccessToken(client, user, expiresIn, scope, nil, audit.TokenIssued)
 }
 
-func (s *Service) getJWKPublicKey() (*jose.JSONWebKey, error) {
-	var oauthJwks []models.OauthJwk
-	notFound := s.db.Where("sid = ?", "oauth-jwk").Find(&oauthJwks).RecordNotFound()
-
-	// Not found
-	if notFound {
-		return nil, ErrJwkPublicKeyNotFound
-	}
-	for _, oauthJwk := range oauthJwks {
-		if oauthJwk.KID[0:6] == "public" {
-			data := []byte(oauthJwk.KeyData)
-			var key = &jose.JSONWebKey{}
-			if err := key.UnmarshalJSON(data); err != nil {
-				return nil, err
-			}
-			return key, nil
-		}
+// grantAccessToken grants a new access token, bound to a key if binding is
+// not nil. The webhook event of the given type is written to the outbox
+// along with the token
+func (s *Service) grantAccessToken(client *models.OauthClient, user *models.OauthUser, expiresIn int, scope string, binding *TokenBinding, eventType string) (*models.OauthAccessToken, error) {
+	// Tokens are never issued across tenants
+	if err := checkUserTenant(client, user); err != nil {
+		return nil, err
 	}
-	return nil, ErrJwkPublicKeyNotFound
-}
 
-// GrantAccessToken deletes expired tokens and grants a new access token
-func (s *Service) GrantAccessToken(client *models.OauthClient, user *models.OauthUser, expiresIn int, scope string) (*models.OauthAccessToken, error) {
 	// Begin a transaction
 	tx := s.db.Begin()
 
@@ -120,12 +117,17 @@ func (s *Service) GrantAccessToken(client *models.OauthClient, user *models.Oaut
 
 	// Create a new access token
 	accessToken := models.NewOauthAccessToken(client, user, expiresIn, scope)
+	binding.apply(accessToken)
 	if err := tx.Create(accessToken).Error; err != nil {
 		tx.Rollback() // rollback the transaction
 		return nil, err
 	}
 	accessToken.Client = client
 	accessToken.User = user
+	if err := s.enqueueEvent(tx, newTokenEvent(eventType, accessToken)); err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
 
 	// Commit the transaction
 	if err := tx.Commit().Error; err != nil {
@@ -147,6 +149,9 @@ func (s *Service) GrantAccessTokenRedis(accessToken *models.OauthAccessToken) (*
 		ExpiresAt: accessToken.ExpiresAt,
 		Scope:     accessToken.Scope,
 		UserID:    accessToken.UserID.String,
+
+		CertThumbprint: accessToken.CertThumbprint.String,
+		JKT:            accessToken.JKT.String,
 	}
 	if err := s.redis.Set(accessTokenRedis.Token, accessTokenRedis, time.Since(accessTokenRedis.ExpiresAt)).Err(); err != nil {
 		return nil, err
@@ -154,22 +159,46 @@ func (s *Service) GrantAccessTokenRedis(accessToken *models.OauthAccessToken) (*
 	return accessTokenRedis, nil
 }
 
-func (s *Service) revokeToken(token string) error {
+func (s *Service) revokeToken(r *http.Request, token string) error {
 	accessToken := &models.OauthAccessToken{}
-	notFound := s.db.Where("id = ?", token).First(accessToken).RecordNotFound()
+	notFound := s.db.Preload("Client").Where("id = ?", token).First(accessToken).RecordNotFound()
 	if notFound {
 		freshToken := &models.OauthRefreshToken{}
-		notFound = s.db.Where("id = ?", token).First(accessToken).RecordNotFound()
+		notFound = s.db.Preload("Client").Where("id = ?", token).First(freshToken).RecordNotFound()
 		if notFound {
 			return ErrInvalidToken
 		}
-		s.db.Where("id = ?", freshToken.ID).Delete(models.OauthRefreshToken{})
-		return nil
+		event := newTokenRevokedEvent(r, "refresh_token", freshToken.TenantID, freshToken.UserID.String, freshToken.Client)
+		return s.deleteRevokedToken(new(models.OauthRefreshToken), freshToken.ID, event)
 	}
 	if err := s.RemoveAccessTokenRedis(token); err != nil {
 		return err
 	}
-	s.db.Where("id = ?", accessToken.ID).Delete(models.OauthAccessToken{})
+	event := newTokenRevokedEvent(r, "access_token", accessToken.TenantID, accessToken.UserID.String, accessToken.Client)
+	return s.deleteRevokedToken(new(models.OauthAccessToken), accessToken.ID, event)
+}
+
+// deleteRevokedToken deletes a revoked token, writing the event of the
+// revocation to the webhook outbox in the same transaction
+func (s *Service) deleteRevokedToken(model interface{}, id string, event *audit.Event) error {
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	if err := tx.Where("id = ?", id).Delete(model).Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+	if err := s.enqueueEvent(tx, event); err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+	s.recordEvent(event)
 	return nil
 }
 
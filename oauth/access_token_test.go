@@ -4,7 +4,9 @@ import (
 	"time"
 
 	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
 	"github.com/RichardKnop/uuid"
+	jwtgo "github.com/dgrijalva/jwt-go"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -185,3 +187,29 @@ func (suite *OauthTestSuite) TestGrantAccessTokenDeletesExpiredTokens() {
 		assert.False(suite.T(), notFound)
 	}
 }
+
+func (suite *OauthTestSuite) TestGrantJWTClientWithUUID() {
+	// Registered clients have UUIDs, unlike the fixture clients
+	client, err := suite.service.CreateClient("test_jwt_client", "test_secret", "https://www.example.com", "")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	user, err := suite.service.CreateUser(roles.User, "test@jwt_client", "correct_horse_battery", "")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	accessToken, _, err := suite.service.Login(client, user, "read")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+
+	idToken, err := suite.service.GrantJWT(user, 3600, "read", accessToken)
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	claims := jwtgo.MapClaims{}
+	_, _, err = new(jwtgo.Parser).ParseUnverified(idToken, claims)
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), "test_jwt_client", claims["aud"])
+	}
+}
@@ -0,0 +1,140 @@
+package oauth
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/audit"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/webhook"
+	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm"
+)
+
+// UseAuditService sets the audit service security events are recorded
+// with, no events are recorded without one
+func (s *Service) UseAuditService(auditService audit.ServiceInterface) {
+	s.auditService = auditService
+}
+
+// UseWebhookService sets the webhook service token and key changes are
+// written to the outbox of, in the transaction making the change
+func (s *Service) UseWebhookService(webhookService webhook.ServiceInterface) {
+	s.webhookService = webhookService
+}
+
+// recordEvent records a security event if there is an audit service
+func (s *Service) recordEvent(event *audit.Event) {
+	if s.auditService != nil {
+		s.auditService.Record(event)
+	}
+}
+
+// enqueueEvent writes an event to the webhook outbox in the transaction of
+// the change it is about, if there is a webhook service
+func (s *Service) enqueueEvent(db *gorm.DB, event *audit.Event) error {
+	if s.webhookService == nil {
+		return nil
+	}
+	return s.webhookService.Enqueue(db, event)
+}
+
+// recordGrant records the outcome of a token request: the tokens issued or
+// refreshed, and for grants logging a user in whether the login succeeded
+func (s *Service) recordGrant(r *http.Request, grantDTO *GrantDTO, client *models.OauthClient, resp *AccessTokenResponse, err error) {
+	newEvent := func(eventType string) *audit.Event {
+		event := audit.NewEvent(eventType, r)
+		event.ActorID = grantDTO.UserID
+		event.TenantID = client.TenantID
+		event.ClientID = client.Key
+		event.Details["grant_type"] = grantDTO.GrantType
+		return event
+	}
+	loginGrant := isLoginGrantType(grantDTO.GrantType)
+
+	if err != nil {
+		// Users asked for a second factor have not failed to log in
+		if _, ok := err.(*MFARequiredError); ok || !loginGrant {
+			return
+		}
+		event := newEvent(audit.LoginFailure)
+		if grantDTO.Username != "" {
+			event.Details["username"] = grantDTO.Username
+		}
+		event.Details["error"] = err.Error()
+		s.recordEvent(event)
+		return
+	}
+
+	if loginGrant {
+		s.recordEvent(newEvent(audit.LoginSuccess))
+	}
+	eventType := audit.TokenIssued
+	if grantDTO.GrantType == "refresh_token" {
+		eventType = audit.TokenRefreshed
+	}
+	event := newEvent(eventType)
+	event.Details["scope"] = resp.Scope
+	s.recordEvent(event)
+}
+
+// newTokenEvent returns the webhook event of a new access token
+func newTokenEvent(eventType string, accessToken *models.OauthAccessToken) *audit.Event {
+	event := audit.NewEvent(eventType, nil)
+	event.ActorID = accessToken.UserID.String
+	event.TenantID = accessToken.TenantID
+	event.ClientID = accessToken.Client.Key
+	event.Details["scope"] = accessToken.Scope
+	event.Details["expires_at"] = accessToken.ExpiresAt.Format(time.RFC3339)
+	return event
+}
+
+// recordClientAuthFailure records a failed client authentication, the
+// tenant is the one of the issuer path the client called
+func (s *Service) recordClientAuthFailure(r *http.Request, clientID, tenantID string, err error) {
+	event := audit.NewEvent(audit.ClientAuthFailed, r)
+	event.TenantID = tenantID
+	event.ClientID = clientID
+	event.Details["endpoint"] = r.URL.Path
+	event.Details["error"] = err.Error()
+	s.recordEvent(event)
+}
+
+// newTokenRevokedEvent returns the event of a token revoked through the
+// revocation endpoint
+func newTokenRevokedEvent(r *http.Request, tokenType, tenantID, userID string, client *models.OauthClient) *audit.Event {
+	event := audit.NewEvent(audit.TokenRevoked, r)
+	event.ActorID = userID
+	event.TenantID = tenantID
+	if client != nil {
+		event.ClientID = client.Key
+	}
+	event.Details["token_type"] = tokenType
+	return event
+}
+
+// newTokensRevokedEvent returns the event of revoking the tokens a user
+// holds, for one client or all of them when client is nil
+func newTokensRevokedEvent(user *models.OauthUser, client *models.OauthClient, reason string, count int) *audit.Event {
+	event := audit.NewEvent(audit.TokenRevoked, nil)
+	event.ActorID = user.ID
+	event.TenantID = user.TenantID
+	if client != nil {
+		event.ClientID = client.Key
+	}
+	event.Details["reason"] = reason
+	event.Details["access_tokens"] = strconv.Itoa(count)
+	return event
+}
+
+// isLoginGrantType returns true for grants authenticating the user
+func isLoginGrantType(grantType string) bool {
+	return grantType == "password" || grantType == PasswordlessOTPGrantType || isMFAGrantType(grantType)
+}
+
+// pathTenantID returns the tenant of the issuer path, the default tenant
+// for requests to the default paths
+func pathTenantID(r *http.Request) string {
+	return mux.Vars(r)["tenant"]
+}
@@ -0,0 +1,104 @@
+package oauth_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/RichardKnop/go-oauth2-server/audit"
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingSink keeps recorded events in memory
+type recordingSink struct {
+	events []*audit.Event
+}
+
+func (s *recordingSink) Write(event *audit.Event) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+// eventsOfType returns the recorded events of a type
+func (s *recordingSink) eventsOfType(eventType string) []*audit.Event {
+	var events []*audit.Event
+	for _, event := range s.events {
+		if event.Type == eventType {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// useRecordingSink records the events of the oauth service until the
+// returned function is called
+func (suite *OauthTestSuite) useRecordingSink() (*recordingSink, func()) {
+	sink := new(recordingSink)
+	auditService := audit.NewService(new(config.Config), suite.db)
+	auditService.UseSink(sink)
+	suite.service.UseAuditService(auditService)
+	return sink, func() { suite.service.UseAuditService(nil) }
+}
+
+func (suite *OauthTestSuite) TestTokensHandlerRecordsClientAuthFailure() {
+	sink, done := suite.useRecordingSink()
+	defer done()
+
+	r, err := http.NewRequest(
+		"POST",
+		"http://1.2.3.4/v1/oauth/token",
+		bytes.NewBufferString(`{"grant_type": "client_credentials", "client_id": "bogus"}`),
+	)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.Header.Set("User-Agent", "audit-test")
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+
+	if assert.Len(suite.T(), sink.events, 1) {
+		event := sink.events[0]
+		assert.Equal(suite.T(), audit.ClientAuthFailed, event.Type)
+		assert.Equal(suite.T(), "bogus", event.ClientID)
+		assert.Equal(suite.T(), "audit-test", event.UserAgent)
+		assert.NotEmpty(suite.T(), event.ID)
+		assert.False(suite.T(), event.Time.IsZero())
+	}
+}
+
+func (suite *OauthTestSuite) TestRotateSigningKey() {
+	err := suite.db.Create(&models.Tenant{
+		ID:     "tenant_a",
+		Name:   "tenant_a",
+		Status: models.TenantStatusActive,
+	}).Error
+	assert.NoError(suite.T(), err)
+	defer suite.db.Unscoped().Where("tenant_id <> ?", "").Delete(new(models.OauthJwk))
+
+	sink, done := suite.useRecordingSink()
+	defer done()
+
+	// The first key of a tenant is recorded as a rotation
+	jwks, err := suite.service.TenantJWKs("tenant_a")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	if assert.Len(suite.T(), sink.events, 1) {
+		assert.Equal(suite.T(), audit.KeyRotated, sink.events[0].Type)
+		assert.Equal(suite.T(), "tenant_a", sink.events[0].TenantID)
+		assert.Equal(suite.T(), jwks.Keys[0].KeyID, sink.events[0].Details["kid"])
+	}
+
+	// The new key signs from now on, the previous one is still published
+	keyID, err := suite.service.RotateSigningKey("tenant_a")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	rotated, err := suite.service.TenantJWKs("tenant_a")
+	if assert.NoError(suite.T(), err) && assert.Len(suite.T(), rotated.Keys, 2) {
+		assert.Equal(suite.T(), keyID, rotated.Keys[0].KeyID)
+		assert.Equal(suite.T(), jwks.Keys[0].KeyID, rotated.Keys[1].KeyID)
+	}
+}
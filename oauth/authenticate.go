@@ -16,8 +16,24 @@ var (
 	ErrAccessTokenExpired = errors.New("Access token expired")
 )
 
-// Authenticate checks the access token is valid
+// Authenticate checks the access token is valid. Tokens bound to a client
+// certificate or DPoP key are refused with ErrTokenBindingMismatch, as the
+// binding can only be checked against the request: resource servers call
+// AuthenticateRequest, or introspect the token and check its cnf claim
 func (s *Service) Authenticate(token string) (*models.OauthAccessToken, error) {
+	accessToken, err := s.authenticate(token)
+	if err != nil {
+		return nil, err
+	}
+	if accessToken.CertThumbprint.Valid || accessToken.JKT.Valid {
+		return nil, ErrTokenBindingMismatch
+	}
+	return accessToken, nil
+}
+
+// authenticate is Authenticate without the binding check, for callers which
+// check it or return it
+func (s *Service) authenticate(token string) (*models.OauthAccessToken, error) {
 	// Fetch the access token from the database
 	accessToken := new(models.OauthAccessToken)
 	notFound := s.db.Where("token = ?", token).First(accessToken).RecordNotFound()
@@ -39,8 +55,12 @@ func (s *Service) Authenticate(token string) (*models.OauthAccessToken, error) {
 	} else {
 		query = query.Where("user_id IS NULL")
 	}
+	refreshTokenLifetime := s.cnf.Oauth.RefreshTokenLifetime
+	if tenant, err := s.GetTenantConfig(accessToken.TenantID); err == nil {
+		refreshTokenLifetime = tenant.RefreshTokenLifetime
+	}
 	increasedExpiresAt := gorm.NowFunc().Add(
-		time.Duration(s.cnf.Oauth.RefreshTokenLifetime) * time.Second,
+		time.Duration(refreshTokenLifetime) * time.Second,
 	)
 	if err := query.UpdateColumn("expires_at", increasedExpiresAt).Error; err != nil {
 		return nil, err
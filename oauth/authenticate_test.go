@@ -6,6 +6,7 @@ import (
 	"github.com/RichardKnop/go-oauth2-server/models"
 	"github.com/RichardKnop/go-oauth2-server/oauth"
 	"github.com/RichardKnop/go-oauth2-server/session"
+	"github.com/RichardKnop/go-oauth2-server/util"
 	"github.com/RichardKnop/uuid"
 	"github.com/jinzhu/gorm"
 	"github.com/stretchr/testify/assert"
@@ -117,6 +118,27 @@ func (suite *OauthTestSuite) TestAuthenticate() {
 	assert.Nil(suite.T(), err)
 }
 
+func (suite *OauthTestSuite) TestAuthenticateBoundToken() {
+	// Insert a certificate-bound access token
+	err := suite.db.Create(&models.OauthAccessToken{
+		MyGormModel: models.MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		Token:          "test_bound_token",
+		ExpiresAt:      time.Now().UTC().Add(+10 * time.Second),
+		Client:         suite.clients[0],
+		User:           suite.users[0],
+		CertThumbprint: util.StringOrNull("A4DtL2JmUMhAsvJj5tKyn64SqzmuXbMrJa0n761y5v0"),
+	}).Error
+	assert.NoError(suite.T(), err, "Inserting test data failed")
+
+	// The binding cannot be checked without the request
+	accessToken, err := suite.service.Authenticate("test_bound_token")
+	assert.Nil(suite.T(), accessToken)
+	assert.Equal(suite.T(), oauth.ErrTokenBindingMismatch, err)
+}
+
 func (suite *OauthTestSuite) TestAuthenticateRollingRefreshToken() {
 	var (
 		testAccessTokens  []*models.OauthAccessToken
@@ -395,7 +417,7 @@ func (suite *OauthTestSuite) TestClearUserTokens() {
 
 	testUserSession = &session.UserSession{
 		ClientID:     suite.clients[0].Key,
-		Username:     suite.users[0].Username,
+		Account:      suite.users[0].Account,
 		AccessToken:  "test_token_1",
 		RefreshToken: "test_token_1",
 	}
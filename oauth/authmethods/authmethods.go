@@ -0,0 +1,46 @@
+package authmethods
+
+const (
+	// ClientSecretBasic authenticates with client_id and secret via HTTP Basic auth
+	ClientSecretBasic = "client_secret_basic"
+	// ClientSecretPost authenticates with client_id and secret in the request body
+	ClientSecretPost = "client_secret_post"
+	// ClientSecretJWT authenticates with a JWT signed by a shared HMAC key (RFC 7523)
+	ClientSecretJWT = "client_secret_jwt"
+	// PrivateKeyJWT authenticates with a JWT signed by the client's private key (RFC 7523)
+	PrivateKeyJWT = "private_key_jwt"
+	// TLSClientAuth authenticates with a CA issued client certificate (RFC 8705)
+	TLSClientAuth = "tls_client_auth"
+	// SelfSignedTLSClientAuth authenticates with a self-signed client certificate
+	// registered in the client's JWKs (RFC 8705)
+	SelfSignedTLSClientAuth = "self_signed_tls_client_auth"
+	// None is used by public clients which cannot keep a secret
+	None = "none"
+)
+
+// All lists the supported token endpoint auth methods
+var All = []string{
+	ClientSecretBasic,
+	ClientSecretPost,
+	ClientSecretJWT,
+	PrivateKeyJWT,
+	TLSClientAuth,
+	SelfSignedTLSClientAuth,
+	None,
+}
+
+// IsJWT returns true if the method authenticates with a client assertion
+func IsJWT(method string) bool {
+	return method == ClientSecretJWT || method == PrivateKeyJWT
+}
+
+// IsTLS returns true if the method authenticates with a client certificate
+func IsTLS(method string) bool {
+	return method == TLSClientAuth || method == SelfSignedTLSClientAuth
+}
+
+// UsesSecret returns true if the method authenticates with the client secret,
+// an empty method is treated as a legacy secret based client
+func UsesSecret(method string) bool {
+	return method == "" || method == ClientSecretBasic || method == ClientSecretPost
+}
@@ -2,9 +2,13 @@ package oauth
 
 import (
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth/pkce"
+	"github.com/RichardKnop/go-oauth2-server/session"
+	"github.com/RichardKnop/go-oauth2-server/util"
 )
 
 var (
@@ -12,10 +16,17 @@ var (
 	ErrAuthorizationCodeNotFound = errors.New("Authorization code not found")
 	// ErrAuthorizationCodeExpired ...
 	ErrAuthorizationCodeExpired = errors.New("Authorization code expired")
+	// ErrInvalidCodeVerifier ...
+	ErrInvalidCodeVerifier = errors.New("Invalid code verifier")
 )
 
 // GrantAuthorizationCode grants a new authorization code
 func (s *Service) GrantAuthorizationCode(client *models.OauthClient, user *models.OauthUser, expiresIn int, redirectURI, scope string) (*models.OauthAuthorizationCode, error) {
+	// Users can only authorize clients of their tenant
+	if err := checkUserTenant(client, user); err != nil {
+		return nil, err
+	}
+
 	// Create a new authorization code
 	authorizationCode := models.NewOauthAuthorizationCode(client, user, expiresIn, redirectURI, scope)
 	if err := s.db.Create(authorizationCode).Error; err != nil {
@@ -27,8 +38,82 @@ func (s *Service) GrantAuthorizationCode(client *models.OauthClient, user *model
 	return authorizationCode, nil
 }
 
+// GrantAuthorizationCodeForRequest grants a new authorization code for a
+// validated authorization request, keeping its PKCE code challenge and when
+// and how the user of the SSO session authenticated
+func (s *Service) GrantAuthorizationCodeForRequest(client *models.OauthClient, user *models.OauthUser, req *AuthorizationRequest, ssoSession *session.SSOSession) (*models.OauthAuthorizationCode, error) {
+	// Users can only authorize clients of their tenant
+	if err := checkUserTenant(client, user); err != nil {
+		return nil, err
+	}
+	tenant, err := s.GetTenantConfig(client.TenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The user must have consented to the whole scope
+	missing, err := s.GetMissingConsent(client, user, req.Scope)
+	if err != nil {
+		return nil, err
+	}
+	if missing != "" {
+		return nil, ErrConsentRequired
+	}
+
+	// Create a new authorization code
+	authorizationCode := models.NewOauthAuthorizationCode(
+		client,
+		user,
+		tenant.AuthCodeLifetime,
+		req.RedirectURI,
+		req.Scope,
+	)
+	authorizationCode.CodeChallenge = util.StringOrNull(req.CodeChallenge)
+	authorizationCode.CodeChallengeMethod = util.StringOrNull(req.CodeChallengeMethod)
+	if ssoSession != nil && ssoSession.UserID == user.ID {
+		authTime := ssoSession.AuthTime.UTC()
+		authorizationCode.AuthTime = &authTime
+		authorizationCode.AMR = strings.Join(ssoSession.AMR, " ")
+		authorizationCode.SessionID = util.StringOrNull(ssoSession.ID)
+	}
+
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	if err := tx.Create(authorizationCode).Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+	// Logging out of the SSO session logs the user out of the client
+	if authorizationCode.SessionID.Valid {
+		sessionClient := &models.OauthSessionClient{
+			SessionID: ssoSession.ID,
+			TenantID:  user.TenantID,
+			UserID:    user.ID,
+			ClientID:  client.ID,
+			CreatedAt: time.Now().UTC(),
+		}
+		err := tx.Where("session_id = ? AND client_id = ?", ssoSession.ID, client.ID).
+			FirstOrCreate(sessionClient).Error
+		if err != nil {
+			tx.Rollback() // rollback the transaction
+			return nil, err
+		}
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+	authorizationCode.Client = client
+	authorizationCode.User = user
+
+	return authorizationCode, nil
+}
+
 // getValidAuthorizationCode returns a valid non expired authorization code
-func (s *Service) getValidAuthorizationCode(code, redirectURI string, client *models.OauthClient) (*models.OauthAuthorizationCode, error) {
+func (s *Service) getValidAuthorizationCode(code, redirectURI, codeVerifier string, client *models.OauthClient) (*models.OauthAuthorizationCode, error) {
 	// Fetch the auth code from the database
 	authorizationCode := new(models.OauthAuthorizationCode)
 	notFound := models.OauthAuthorizationCodePreload(s.db).Where("client_id = ?", client.ID).
@@ -49,5 +134,18 @@ func (s *Service) getValidAuthorizationCode(code, redirectURI string, client *mo
 		return nil, ErrAuthorizationCodeExpired
 	}
 
+	// The code verifier must match the challenge, and can't be sent without one
+	if authorizationCode.CodeChallenge.Valid {
+		if !pkce.Verify(
+			authorizationCode.CodeChallenge.String,
+			authorizationCode.CodeChallengeMethod.String,
+			codeVerifier,
+		) {
+			return nil, ErrInvalidCodeVerifier
+		}
+	} else if codeVerifier != "" {
+		return nil, ErrInvalidCodeVerifier
+	}
+
 	return authorizationCode, nil
 }
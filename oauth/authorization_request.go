@@ -0,0 +1,230 @@
+package oauth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth/authmethods"
+	"github.com/RichardKnop/go-oauth2-server/oauth/pkce"
+	"github.com/RichardKnop/uuid"
+)
+
+const (
+	// RequestURIPrefix prefixes references to pushed authorization requests (RFC 9126)
+	RequestURIPrefix = "urn:ietf:params:oauth:request_uri:"
+
+	pushedAuthorizationRequestPrefix = "par:"
+)
+
+var (
+	// ErrInvalidResponseType ...
+	ErrInvalidResponseType = errors.New("Invalid response type")
+	// ErrRequestURINotFound ...
+	ErrRequestURINotFound = errors.New("Request URI not found or expired")
+	// ErrRequestURINotAllowed ...
+	ErrRequestURINotAllowed = errors.New("Request URI cannot be pushed")
+	// ErrPushedAuthorizationRequestRequired ...
+	ErrPushedAuthorizationRequestRequired = errors.New("Client requires pushed authorization requests")
+	// ErrCodeChallengeRequired ...
+	ErrCodeChallengeRequired = errors.New("Public clients must send a code challenge")
+)
+
+// AuthorizationRequest holds the parameters of an authorization request
+type AuthorizationRequest struct {
+	ClientID            string `json:"client_id"`
+	ResponseType        string `json:"response_type"`
+	RedirectURI         string `json:"redirect_uri"`
+	Scope               string `json:"scope"`
+	State               string `json:"state,omitempty"`
+	CodeChallenge       string `json:"code_challenge,omitempty"`
+	CodeChallengeMethod string `json:"code_challenge_method,omitempty"`
+	// Prompt and MaxAge control re-authentication as in OpenID Connect
+	Prompt string `json:"prompt,omitempty"`
+	MaxAge string `json:"max_age,omitempty"`
+}
+
+// NewAuthorizationRequest reads authorization request parameters
+func NewAuthorizationRequest(values url.Values) *AuthorizationRequest {
+	return &AuthorizationRequest{
+		ClientID:            values.Get("client_id"),
+		ResponseType:        values.Get("response_type"),
+		RedirectURI:         values.Get("redirect_uri"),
+		Scope:               values.Get("scope"),
+		State:               values.Get("state"),
+		CodeChallenge:       values.Get("code_challenge"),
+		CodeChallengeMethod: values.Get("code_challenge_method"),
+		Prompt:              values.Get("prompt"),
+		MaxAge:              values.Get("max_age"),
+	}
+}
+
+// Values returns the parameters of the request, the inverse of
+// NewAuthorizationRequest
+func (req *AuthorizationRequest) Values() url.Values {
+	values := url.Values{}
+	params := map[string]string{
+		"client_id":             req.ClientID,
+		"response_type":         req.ResponseType,
+		"redirect_uri":          req.RedirectURI,
+		"scope":                 req.Scope,
+		"state":                 req.State,
+		"code_challenge":        req.CodeChallenge,
+		"code_challenge_method": req.CodeChallengeMethod,
+		"prompt":                req.Prompt,
+		"max_age":               req.MaxAge,
+	}
+	for name, value := range params {
+		if value != "" {
+			values.Set(name, value)
+		}
+	}
+	return values
+}
+
+// ValidateAuthorizationRequest validates an authorization request of the
+// client, filling in the default scope and registered redirect URI
+func (s *Service) ValidateAuthorizationRequest(client *models.OauthClient, req *AuthorizationRequest) error {
+	if req.ClientID != "" && !strings.EqualFold(req.ClientID, client.Key) {
+		return ErrClientNotFound
+	}
+	req.ClientID = client.Key
+
+	// Only the authorization code flow is supported
+	if req.ResponseType != "code" {
+		return ErrInvalidResponseType
+	}
+
+	// The client's tenant must be active and allow the flow
+	tenant, err := s.GetTenantConfig(client.TenantID)
+	if err != nil {
+		return err
+	}
+	if !tenant.AllowsGrantType("authorization_code") {
+		return ErrGrantTypeNotAllowedForTenant
+	}
+
+	// Redirect URI must match the registered one, which is the default
+	if req.RedirectURI == "" {
+		req.RedirectURI = client.RedirectURI.String
+	}
+	if req.RedirectURI == "" ||
+		(client.RedirectURI.Valid && req.RedirectURI != client.RedirectURI.String) {
+		return ErrInvalidRedirectURI
+	}
+	if _, err := url.ParseRequestURI(req.RedirectURI); err != nil {
+		return ErrInvalidRedirectURI
+	}
+
+	// Get the scope string
+	scope, err := s.getClientScope(client, req.Scope)
+	if err != nil {
+		return err
+	}
+	req.Scope = scope
+
+	// PKCE is optional, but a challenge must be a valid S256 one when
+	// present. Public clients have no secret, so they must use it to redeem
+	// their codes
+	if req.CodeChallenge == "" && client.TokenEndpointAuthMethod == authmethods.None {
+		return ErrCodeChallengeRequired
+	}
+	if req.CodeChallenge != "" || req.CodeChallengeMethod != "" {
+		if err := pkce.ValidateChallenge(req.CodeChallenge, req.CodeChallengeMethod); err != nil {
+			return err
+		}
+	}
+
+	if err := validatePrompt(req.Prompt); err != nil {
+		return err
+	}
+	if req.MaxAge != "" {
+		if maxAge, err := strconv.Atoi(req.MaxAge); err != nil || maxAge < 0 {
+			return ErrInvalidMaxAge
+		}
+	}
+
+	return nil
+}
+
+// PushAuthorizationRequest validates and stores an authorization request for
+// a short time, returning the request_uri to pass to the authorize endpoint
+func (s *Service) PushAuthorizationRequest(client *models.OauthClient, req *AuthorizationRequest) (string, error) {
+	if err := s.ValidateAuthorizationRequest(client, req); err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	requestURI := RequestURIPrefix + uuid.New()
+	lifetime := time.Duration(s.cnf.Oauth.PushedAuthRequestLifetime) * time.Second
+	if err := s.redis.Set(pushedAuthorizationRequestPrefix+requestURI, data, lifetime).Err(); err != nil {
+		return "", err
+	}
+
+	return requestURI, nil
+}
+
+// GetPushedAuthorizationRequest returns the authorization request pushed by
+// the client, a request_uri can only be used once
+func (s *Service) GetPushedAuthorizationRequest(client *models.OauthClient, requestURI string) (*AuthorizationRequest, error) {
+	// Get and delete the request atomically so concurrent redemptions can't
+	// both get it
+	key := pushedAuthorizationRequestPrefix + requestURI
+	pipe := s.redis.TxPipeline()
+	get := pipe.Get(key)
+	pipe.Del(key)
+	if _, err := pipe.Exec(); err != nil {
+		return nil, ErrRequestURINotFound
+	}
+	data, err := get.Bytes()
+	if err != nil {
+		return nil, ErrRequestURINotFound
+	}
+
+	req := new(AuthorizationRequest)
+	if err := json.Unmarshal(data, req); err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(req.ClientID, client.Key) {
+		return nil, ErrRequestURINotFound
+	}
+
+	return req, nil
+}
+
+// ResolveAuthorizationRequest returns the client and validated authorization
+// request sent to the authorize endpoint, either by reference to a pushed
+// request or as query parameters if the client allows it
+func (s *Service) ResolveAuthorizationRequest(values url.Values) (*models.OauthClient, *AuthorizationRequest, error) {
+	// Fetch the client
+	client, err := s.FindClientByClientID(values.Get("client_id"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if requestURI := values.Get("request_uri"); requestURI != "" {
+		req, err := s.GetPushedAuthorizationRequest(client, requestURI)
+		if err != nil {
+			return nil, nil, err
+		}
+		return client, req, nil
+	}
+
+	if client.RequirePushedAuthorizationRequests {
+		return nil, nil, ErrPushedAuthorizationRequestRequired
+	}
+
+	req := NewAuthorizationRequest(values)
+	if err := s.ValidateAuthorizationRequest(client, req); err != nil {
+		return nil, nil, err
+	}
+	return client, req, nil
+}
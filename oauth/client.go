@@ -6,8 +6,8 @@ import (
 	"time"
 
 	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth/authmethods"
 	"github.com/RichardKnop/go-oauth2-server/util"
-	"github.com/RichardKnop/go-oauth2-server/util/password"
 	"github.com/RichardKnop/uuid"
 	"github.com/jinzhu/gorm"
 )
@@ -60,14 +60,41 @@ func (s *Service) AuthClient(clientID, secret string) (*models.OauthClient, erro
 		return nil, ErrClientNotFound
 	}
 
+	// Clients registered for JWT or mutual TLS auth cannot use the secret
+	if !authmethods.UsesSecret(client.TokenEndpointAuthMethod) {
+		return nil, ErrClientAuthMethodNotAllowed
+	}
+
 	// Verify the secret
-	if password.VerifyPassword(client.Secret, secret) != nil {
+	if !s.secretHasher.Verify(client.Secret, secret, "") {
 		return nil, ErrInvalidClientSecret
 	}
 
+	// Upgrade hashes of another bcrypt cost, the client is authenticated
+	// even if that fails
+	if s.secretHasher.NeedsRehash(client.Secret) {
+		if err := s.rehashClientSecret(client, secret); err != nil {
+			s.logger.Errorf("Rehashing secret of client %s failed: %s", client.Key, err)
+		}
+	}
+
 	return client, nil
 }
 
+// rehashClientSecret replaces a verified secret's hash with a hash of the
+// configured bcrypt cost
+func (s *Service) rehashClientSecret(client *models.OauthClient, secret string) error {
+	secretHash, err := s.secretHasher.Hash(secret, "")
+	if err != nil {
+		return err
+	}
+	if err := s.db.Model(client).UpdateColumn("secret", secretHash).Error; err != nil {
+		return err
+	}
+	client.Secret = secretHash
+	return nil
+}
+
 func (s *Service) GetClient(clientID string) (*models.OauthClient, error) {
 	// Fetch the client
 	client, err := s.FindClientByClientID(clientID)
@@ -85,7 +112,7 @@ func (s *Service) createClientCommon(db *gorm.DB, clientID, secret, redirectURI
 	}
 
 	// Hash password
-	secretHash, err := password.HashPassword(secret)
+	secretHash, err := s.secretHasher.Hash(secret, "")
 	if err != nil {
 		return nil, err
 	}
@@ -96,7 +123,7 @@ func (s *Service) createClientCommon(db *gorm.DB, clientID, secret, redirectURI
 			CreatedAt: time.Now().UTC(),
 		},
 		Key:         strings.ToLower(clientID),
-		Secret:      string(secretHash),
+		Secret:      secretHash,
 		RedirectURI: util.StringOrNull(redirectURI),
 		TenantID:    tenantID,
 	}
@@ -0,0 +1,234 @@
+package oauth
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth/authmethods"
+	"github.com/RichardKnop/go-oauth2-server/oauth/jwt"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"gopkg.in/square/go-jose.v2"
+)
+
+const (
+	// ClientAssertionTypeJWTBearer is the only client_assertion_type we support (RFC 7523)
+	ClientAssertionTypeJWTBearer = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+	clientJWKsKeyPrefix       = "client_jwks:"
+	clientJWKsRefreshPrefix   = "client_jwks_refresh:"
+	clientJWKsRefreshInterval = time.Minute
+	clientAssertionJTIPrefix  = "client_assertion_jti:"
+	clientJWKsMaxResponseSize = 1 << 20
+)
+
+var (
+	// ErrInvalidClientAssertionType ...
+	ErrInvalidClientAssertionType = errors.New("Invalid client assertion type")
+	// ErrInvalidClientAssertion ...
+	ErrInvalidClientAssertion = errors.New("Invalid client assertion")
+	// ErrClientAssertionReplayed ...
+	ErrClientAssertionReplayed = errors.New("Client assertion already used")
+	// ErrClientJWKsNotFound ...
+	ErrClientJWKsNotFound = errors.New("Client has no registered JWKs")
+	// ErrTokenEndpointNotConfigured ...
+	ErrTokenEndpointNotConfigured = errors.New("Token endpoint URL is not configured")
+	// ErrClientAuthMethodNotAllowed ...
+	ErrClientAuthMethodNotAllowed = errors.New("Client authentication method not allowed for this client")
+	// ErrInsecureJWKSURI ...
+	ErrInsecureJWKSURI = errors.New("Client JWKs URI must be a public https URL")
+
+	// jwks_uri is registered by clients, so it is only fetched from public
+	// addresses and redirects are not followed
+	jwksHTTPClient = util.NewPublicHTTPClient(5 * time.Second)
+)
+
+// AuthClientAssertion authenticates a client with a private_key_jwt or
+// client_secret_jwt assertion. The clientID is optional, if present it must
+// match the assertion issuer.
+func (s *Service) AuthClientAssertion(clientID, assertionType, assertion string) (*models.OauthClient, error) {
+	if assertionType != ClientAssertionTypeJWTBearer {
+		return nil, ErrInvalidClientAssertionType
+	}
+
+	// The issuer identifies the client, it is verified against the signature below
+	issuer, err := jwt.UnverifiedIssuer(assertion)
+	if err != nil {
+		return nil, ErrInvalidClientAssertion
+	}
+	if clientID != "" && clientID != issuer {
+		return nil, ErrInvalidClientAssertion
+	}
+
+	// Fetch the client
+	client, err := s.FindClientByClientID(issuer)
+	if err != nil {
+		return nil, ErrClientNotFound
+	}
+
+	// Assertions are addressed to the global token endpoint or the one under
+	// the issuer of the client's tenant
+	tenant, err := s.GetTenantConfig(client.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	audiences := []string{s.cnf.Oauth.TokenEndpoint, tokenEndpoint(tenant)}
+	if audiences[0] == "" && audiences[1] == "" {
+		return nil, ErrTokenEndpointNotConfigured
+	}
+
+	expected := &jwt.AssertionExpectations{
+		ClientID:    issuer,
+		Audiences:   audiences,
+		MaxLifetime: time.Duration(s.cnf.Oauth.ClientAssertionLifetime) * time.Second,
+	}
+	var keys *jose.JSONWebKeySet
+	switch client.TokenEndpointAuthMethod {
+	case authmethods.PrivateKeyJWT:
+		expected.Algorithms = jwt.AsymmetricAlgorithms
+		keys, err = s.getClientJWKs(client, false)
+		if err != nil {
+			return nil, err
+		}
+	case authmethods.ClientSecretJWT:
+		// Only the client's own secret verifies its assertions
+		if !client.AssertionSecret.Valid {
+			return nil, ErrInvalidClientAssertion
+		}
+		expected.Algorithms = jwt.SymmetricAlgorithms
+		keys = &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: []byte(client.AssertionSecret.String)}}}
+	default:
+		return nil, ErrClientAuthMethodNotAllowed
+	}
+
+	claims, err := jwt.VerifyClientAssertion(assertion, keys, expected)
+	// Clients publish a new key before signing with it, so an unknown key ID
+	// refetches the cached jwks_uri once
+	if err == jwt.ErrAssertionKeyIDUnknown && client.JWKSURI.Valid {
+		if keys, err = s.getClientJWKs(client, true); err != nil {
+			return nil, err
+		}
+		claims, err = jwt.VerifyClientAssertion(assertion, keys, expected)
+	}
+	if err != nil {
+		return nil, ErrInvalidClientAssertion
+	}
+
+	// Each jti can only be used once until the assertion expires
+	ttl := time.Until(claims.Expiry.Time()) + time.Minute
+	fresh, err := s.redis.SetNX(clientAssertionJTIPrefix+client.ID+":"+claims.ID, 1, ttl).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !fresh {
+		return nil, ErrClientAssertionReplayed
+	}
+
+	return client, nil
+}
+
+// getClientJWKs returns the key set registered inline on the client or
+// fetched from the client's jwks_uri, the latter is cached in redis. Refresh
+// refetches the cached keys, at most once per clientJWKsRefreshInterval.
+func (s *Service) getClientJWKs(client *models.OauthClient, refresh bool) (*jose.JSONWebKeySet, error) {
+	if client.JWKS.Valid {
+		return parseJWKs([]byte(client.JWKS.String))
+	}
+	if !client.JWKSURI.Valid {
+		return nil, ErrClientJWKsNotFound
+	}
+
+	cacheKey := clientJWKsKeyPrefix + client.ID
+	if refresh {
+		// Assertions with made up key IDs must not make us fetch every time
+		allowed, err := s.redis.SetNX(clientJWKsRefreshPrefix+client.ID, 1, clientJWKsRefreshInterval).Result()
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			return nil, ErrInvalidClientAssertion
+		}
+	} else if cached, err := s.redis.Get(cacheKey).Bytes(); err == nil {
+		return parseJWKs(cached)
+	}
+
+	data, err := fetchJWKs(client.JWKSURI.String)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := parseJWKs(data)
+	if err != nil {
+		return nil, err
+	}
+
+	lifetime := time.Duration(s.cnf.Oauth.ClientJWKsCacheLifetime) * time.Second
+	if err := s.redis.Set(cacheKey, data, lifetime).Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func fetchJWKs(uri string) ([]byte, error) {
+	// Clients registered before https was required are refused too
+	if !isHTTPSURL(uri) {
+		return nil, ErrInsecureJWKSURI
+	}
+	resp, err := jwksHTTPClient.Get(uri)
+	if errors.Is(err, util.ErrInternalAddress) {
+		return nil, ErrInsecureJWKSURI
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, ErrClientJWKsNotFound
+	}
+	return ioutil.ReadAll(io.LimitReader(resp.Body, clientJWKsMaxResponseSize))
+}
+
+// isHTTPSURL returns true for absolute https URLs with a host
+func isHTTPSURL(uri string) bool {
+	parsed, err := url.Parse(uri)
+	return err == nil && parsed.Scheme == "https" && parsed.Host != ""
+}
+
+// isPublicHTTPSURL returns true for https URLs whose host only resolves to
+// public addresses
+func isPublicHTTPSURL(uri string) bool {
+	if !isHTTPSURL(uri) {
+		return false
+	}
+	parsed, _ := url.Parse(uri)
+	return util.CheckPublicHost(parsed.Hostname()) == nil
+}
+
+// parseJWKs parses a client's key set, symmetric keys are dropped as they
+// would let anyone who can read the keys sign assertions
+func parseJWKs(data []byte) (*jose.JSONWebKeySet, error) {
+	parsed := new(jose.JSONWebKeySet)
+	if err := json.Unmarshal(data, parsed); err != nil {
+		return nil, err
+	}
+	keys := new(jose.JSONWebKeySet)
+	for _, key := range parsed.Keys {
+		if !isSymmetricJWK(key) {
+			keys.Keys = append(keys.Keys, key)
+		}
+	}
+	if len(keys.Keys) == 0 {
+		return nil, ErrClientJWKsNotFound
+	}
+	return keys, nil
+}
+
+// isSymmetricJWK returns true for oct keys
+func isSymmetricJWK(key jose.JSONWebKey) bool {
+	_, ok := key.Key.([]byte)
+	return ok
+}
@@ -0,0 +1,148 @@
+package oauth_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/authmethods"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/RichardKnop/uuid"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/square/go-jose.v2"
+	josejwt "gopkg.in/square/go-jose.v2/jwt"
+)
+
+const testTokenEndpoint = "https://auth.example.com/v1/oauth/token"
+
+func (suite *OauthTestSuite) TestAuthClientSecretJWT() {
+	suite.cnf.Registration.InitialAccessToken = "test_initial_access_token"
+	suite.cnf.Oauth.TokenEndpoint = testTokenEndpoint
+	defer func() {
+		suite.cnf.Registration.InitialAccessToken = ""
+		suite.cnf.Oauth.TokenEndpoint = ""
+	}()
+
+	// Shared keys cannot be registered
+	_, err := suite.service.RegisterClient("test_initial_access_token", &oauth.ClientMetadata{
+		GrantTypes:              []string{"client_credentials"},
+		TokenEndpointAuthMethod: authmethods.PrivateKeyJWT,
+		JWKS:                    json.RawMessage(`{"keys":[{"kty":"oct","k":"MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY"}]}`),
+	})
+	assert.Equal(suite.T(), oauth.ErrInvalidClientMetadata, err)
+
+	// The client signs its assertions with the issued secret
+	registration, err := suite.service.RegisterClient("test_initial_access_token", &oauth.ClientMetadata{
+		GrantTypes:              []string{"client_credentials"},
+		TokenEndpointAuthMethod: authmethods.ClientSecretJWT,
+	})
+	if !assert.NoError(suite.T(), err) || !assert.NotEmpty(suite.T(), registration.ClientSecret) {
+		return
+	}
+	assertion := suite.signClientAssertion(registration.ClientID, jose.HS256, []byte(registration.ClientSecret), "")
+	client, err := suite.service.AuthClientAssertion(registration.ClientID, oauth.ClientAssertionTypeJWTBearer, assertion)
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), registration.ClientID, client.Key)
+	}
+
+	// The token endpoint under the tenant's issuer is a valid audience too
+	suite.cnf.Oauth.TokenEndpoint = ""
+	suite.cnf.Oauth.Issuer = "https://auth.example.com"
+	defer func() { suite.cnf.Oauth.Issuer = "" }()
+	assertion = suite.signClientAssertion(registration.ClientID, jose.HS256, []byte(registration.ClientSecret), "")
+	_, err = suite.service.AuthClientAssertion(registration.ClientID, oauth.ClientAssertionTypeJWTBearer, assertion)
+	assert.NoError(suite.T(), err)
+
+	// Any other key is refused
+	assertion = suite.signClientAssertion(registration.ClientID, jose.HS256, []byte("0123456789abcdef0123456789abcdef"), "")
+	_, err = suite.service.AuthClientAssertion(registration.ClientID, oauth.ClientAssertionTypeJWTBearer, assertion)
+	assert.Equal(suite.T(), oauth.ErrInvalidClientAssertion, err)
+}
+
+func (suite *OauthTestSuite) TestAuthClientAssertionRefetchesJWKs() {
+	suite.cnf.Oauth.TokenEndpoint = testTokenEndpoint
+	defer func() { suite.cnf.Oauth.TokenEndpoint = "" }()
+
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(suite.T(), err)
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(suite.T(), err)
+
+	// The client publishes a new key next to a shared one, which is ignored
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: newKey.Public(), KeyID: "new_key", Use: "sig"},
+			{Key: []byte("0123456789abcdef0123456789abcdef"), KeyID: "shared_key", Use: "sig"},
+		}})
+	}))
+	defer server.Close()
+	oauth.UseJWKsHTTPClient(server.Client())
+	defer oauth.UseJWKsHTTPClient(nil)
+
+	client, err := suite.service.CreateClient("test_jwks_uri_client", uuid.New(), "", "")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	suite.db.Model(client).UpdateColumns(map[string]interface{}{
+		"token_endpoint_auth_method": authmethods.PrivateKeyJWT,
+		"jwks_uri":                   util.StringOrNull(server.URL),
+	})
+
+	// The old key set is still cached
+	cached, err := json.Marshal(&jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: oldKey.Public(), KeyID: "old_key", Use: "sig"},
+	}})
+	assert.NoError(suite.T(), err)
+	suite.redis.Set("client_jwks:"+client.ID, string(cached))
+
+	assertion := suite.signClientAssertion(client.Key, jose.RS256, oldKey, "old_key")
+	_, err = suite.service.AuthClientAssertion(client.Key, oauth.ClientAssertionTypeJWTBearer, assertion)
+	assert.NoError(suite.T(), err)
+
+	// An unknown key ID refetches the keys
+	assertion = suite.signClientAssertion(client.Key, jose.RS256, newKey, "new_key")
+	_, err = suite.service.AuthClientAssertion(client.Key, oauth.ClientAssertionTypeJWTBearer, assertion)
+	assert.NoError(suite.T(), err)
+
+	// Keys fetched from the jwks_uri are never shared keys
+	assertion = suite.signClientAssertion(client.Key, jose.HS256, []byte("0123456789abcdef0123456789abcdef"), "shared_key")
+	_, err = suite.service.AuthClientAssertion(client.Key, oauth.ClientAssertionTypeJWTBearer, assertion)
+	assert.Equal(suite.T(), oauth.ErrInvalidClientAssertion, err)
+
+	// Made up key IDs do not refetch the keys every time
+	var fetched int
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetched++
+	})
+	assertion = suite.signClientAssertion(client.Key, jose.RS256, newKey, "bogus_key")
+	_, err = suite.service.AuthClientAssertion(client.Key, oauth.ClientAssertionTypeJWTBearer, assertion)
+	assert.Equal(suite.T(), oauth.ErrInvalidClientAssertion, err)
+	assert.Equal(suite.T(), 0, fetched)
+
+	suite.db.Unscoped().Delete(new(models.OauthClient), "id = ?", client.ID)
+}
+
+// signClientAssertion returns a client assertion for the test token endpoint
+func (suite *OauthTestSuite) signClientAssertion(clientID string, alg jose.SignatureAlgorithm, key interface{}, kid string) string {
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: alg, Key: jose.JSONWebKey{Key: key, KeyID: kid}},
+		nil,
+	)
+	assert.NoError(suite.T(), err)
+	now := time.Now()
+	assertion, err := josejwt.Signed(signer).Claims(josejwt.Claims{
+		Issuer:   clientID,
+		Subject:  clientID,
+		Audience: josejwt.Audience{testTokenEndpoint},
+		Expiry:   josejwt.NewNumericDate(now.Add(time.Minute)),
+		IssuedAt: josejwt.NewNumericDate(now),
+		ID:       uuid.New(),
+	}).CompactSerialize()
+	assert.NoError(suite.T(), err)
+	return assertion
+}
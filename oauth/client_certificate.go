@@ -0,0 +1,67 @@
+package oauth
+
+import (
+	"crypto/x509"
+	"net/http"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth/authmethods"
+	"github.com/RichardKnop/go-oauth2-server/oauth/mtls"
+)
+
+// AuthClientCertificate authenticates a client with the certificate chain
+// presented during the TLS handshake (RFC 8705)
+func (s *Service) AuthClientCertificate(clientID string, chain []*x509.Certificate) (*models.OauthClient, error) {
+	// Fetch the client
+	client, err := s.FindClientByClientID(clientID)
+	if err != nil {
+		return nil, ErrClientNotFound
+	}
+
+	switch client.TokenEndpointAuthMethod {
+	case authmethods.TLSClientAuth:
+		err = mtls.VerifyPKI(
+			chain,
+			s.getClientCAs(),
+			client.TLSClientAuthSubjectDN.String,
+			client.TLSClientAuthSANDNS.String,
+		)
+	case authmethods.SelfSignedTLSClientAuth:
+		keys, keysErr := s.getClientJWKs(client, false)
+		if keysErr != nil {
+			return nil, keysErr
+		}
+		err = mtls.VerifySelfSigned(chain, keys)
+	default:
+		return nil, ErrClientAuthMethodNotAllowed
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// getClientCAs lazily loads the CAs trusted for tls_client_auth
+func (s *Service) getClientCAs() *x509.CertPool {
+	s.lazy.clientCAsOnce.Do(func() {
+		if s.cnf.TLS.ClientCAFile == "" {
+			return
+		}
+		pool, err := mtls.LoadCertPool(s.cnf.TLS.ClientCAFile)
+		if err != nil {
+			s.logger.Errorf("Loading client CA file failed: %s", err)
+			return
+		}
+		s.lazy.clientCAs = pool
+	})
+	return s.lazy.clientCAs
+}
+
+// peerCertificates returns the client certificate chain of a TLS request
+func peerCertificates(r *http.Request) []*x509.Certificate {
+	if r.TLS == nil {
+		return nil
+	}
+	return r.TLS.PeerCertificates
+}
@@ -0,0 +1,472 @@
+package oauth
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth/authmethods"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/RichardKnop/uuid"
+	"gopkg.in/square/go-jose.v2"
+)
+
+var (
+	// ErrInvalidClientMetadata ...
+	ErrInvalidClientMetadata = errors.New("Invalid client metadata")
+	// ErrInitialAccessTokenRequired ...
+	ErrInitialAccessTokenRequired = errors.New("Initial access token required")
+	// ErrInvalidRegistrationAccessToken ...
+	ErrInvalidRegistrationAccessToken = errors.New("Invalid registration access token")
+	// ErrUnauthorizedGrantType ...
+	ErrUnauthorizedGrantType = errors.New("Grant type not registered for the client")
+
+	// registrableGrantTypes are the grant types a client can register for
+	registrableGrantTypes = []string{
+		"authorization_code",
+		"password",
+		"client_credentials",
+		"refresh_token",
+		PasswordlessOTPGrantType,
+	}
+
+	// openRegistrationGrantTypes are the grant types a client can register
+	// for without the initial access token
+	openRegistrationGrantTypes = []string{
+		"authorization_code",
+		"refresh_token",
+	}
+)
+
+// ClientMetadata is the client metadata of a registration request (RFC 7591)
+type ClientMetadata struct {
+	RedirectURIs            []string        `json:"redirect_uris,omitempty"`
+	GrantTypes              []string        `json:"grant_types,omitempty"`
+	TokenEndpointAuthMethod string          `json:"token_endpoint_auth_method,omitempty"`
+	JWKS                    json.RawMessage `json:"jwks,omitempty"`
+	JWKSURI                 string          `json:"jwks_uri,omitempty"`
+	ClientName              string          `json:"client_name,omitempty"`
+	Scope                   string          `json:"scope,omitempty"`
+	TenantID                string          `json:"tenant_id,omitempty"`
+	// Mutual TLS client authentication metadata (RFC 8705)
+	TLSClientAuthSubjectDN                string `json:"tls_client_auth_subject_dn,omitempty"`
+	TLSClientAuthSANDNS                   string `json:"tls_client_auth_san_dns,omitempty"`
+	TLSClientCertificateBoundAccessTokens bool   `json:"tls_client_certificate_bound_access_tokens,omitempty"`
+	// Pushed authorization requests (RFC 9126)
+	RequirePushedAuthorizationRequests bool `json:"require_pushed_authorization_requests,omitempty"`
+	// OpenID Connect RP-initiated, front-channel and back-channel logout
+	PostLogoutRedirectURIs []string `json:"post_logout_redirect_uris,omitempty"`
+	FrontchannelLogoutURI  string   `json:"frontchannel_logout_uri,omitempty"`
+	BackchannelLogoutURI   string   `json:"backchannel_logout_uri,omitempty"`
+}
+
+// ClientRegistration is the client information response (RFC 7591 / RFC 7592)
+type ClientRegistration struct {
+	ClientMetadata
+	ClientID                string `json:"client_id"`
+	ClientSecret            string `json:"client_secret,omitempty"`
+	ClientIDIssuedAt        int64  `json:"client_id_issued_at"`
+	ClientSecretExpiresAt   int64  `json:"client_secret_expires_at"`
+	RegistrationAccessToken string `json:"registration_access_token,omitempty"`
+	RegistrationClientURI   string `json:"registration_client_uri,omitempty"`
+}
+
+// NewClientRegistration returns the registration of a client, the secret and
+// registration access token are only known when they are issued
+func NewClientRegistration(client *models.OauthClient) *ClientRegistration {
+	registration := &ClientRegistration{
+		ClientMetadata: ClientMetadata{
+			TokenEndpointAuthMethod:               client.TokenEndpointAuthMethod,
+			JWKSURI:                               client.JWKSURI.String,
+			ClientName:                            client.Name,
+			Scope:                                 client.Scope.String,
+			TenantID:                              client.TenantID,
+			TLSClientAuthSubjectDN:                client.TLSClientAuthSubjectDN.String,
+			TLSClientAuthSANDNS:                   client.TLSClientAuthSANDNS.String,
+			TLSClientCertificateBoundAccessTokens: client.TLSClientCertificateBoundAccessTokens,
+			RequirePushedAuthorizationRequests:    client.RequirePushedAuthorizationRequests,
+			PostLogoutRedirectURIs:                strings.Fields(client.PostLogoutRedirectURIs),
+			FrontchannelLogoutURI:                 client.FrontchannelLogoutURI.String,
+			BackchannelLogoutURI:                  client.BackchannelLogoutURI.String,
+		},
+		ClientID:         client.Key,
+		ClientIDIssuedAt: client.CreatedAt.Unix(),
+	}
+	if client.RedirectURI.Valid {
+		registration.RedirectURIs = []string{client.RedirectURI.String}
+	}
+	if client.GrantTypes != "" {
+		registration.GrantTypes = strings.Split(client.GrantTypes, " ")
+	}
+	if client.JWKS.Valid {
+		registration.JWKS = json.RawMessage(client.JWKS.String)
+	}
+	return registration
+}
+
+// RegisterClient creates a client from registration metadata. Registration
+// needs the initial access token unless the tenant is open for registration,
+// where clients are limited to the authorization code flow and the default
+// scopes.
+func (s *Service) RegisterClient(initialAccessToken string, metadata *ClientMetadata) (*ClientRegistration, error) {
+	tenant, err := s.GetTenantConfig(metadata.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	privileged := s.hasInitialAccessToken(initialAccessToken)
+	if !privileged && !s.registrationAllowed(initialAccessToken, tenant) {
+		return nil, ErrInitialAccessTokenRequired
+	}
+
+	if err := s.validateClientMetadata(metadata); err != nil {
+		return nil, err
+	}
+	if !privileged {
+		if err := s.checkOpenRegistration(metadata, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	// Client secrets are only issued to clients authenticating with them
+	secret := uuid.New()
+	registrationAccessToken := uuid.New()
+	tokenHash, err := s.secretHasher.Hash(registrationAccessToken, "")
+	if err != nil {
+		return nil, err
+	}
+
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	client, err := s.createClientCommon(
+		tx,
+		uuid.New(), // client ID
+		secret,
+		firstRedirectURI(metadata),
+		metadata.TenantID,
+	)
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+	metadata.apply(client)
+	client.RegistrationAccessToken = util.StringOrNull(tokenHash)
+	if client.TokenEndpointAuthMethod == authmethods.ClientSecretJWT {
+		client.AssertionSecret = util.StringOrNull(secret)
+	}
+	if err := tx.Save(client).Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+
+	registration := NewClientRegistration(client)
+	registration.RegistrationAccessToken = registrationAccessToken
+	if authmethods.UsesSecret(client.TokenEndpointAuthMethod) ||
+		client.TokenEndpointAuthMethod == authmethods.ClientSecretJWT {
+		registration.ClientSecret = secret
+	}
+	return registration, nil
+}
+
+// AuthClientRegistration authenticates a request to the client configuration
+// endpoint with the registration access token issued to the client (RFC 7592)
+func (s *Service) AuthClientRegistration(clientID, registrationAccessToken string) (*models.OauthClient, error) {
+	client, err := s.FindClientByClientID(clientID)
+	if err != nil {
+		return nil, ErrInvalidRegistrationAccessToken
+	}
+
+	if !client.RegistrationAccessToken.Valid ||
+		!s.secretHasher.Verify(client.RegistrationAccessToken.String, registrationAccessToken, "") {
+		return nil, ErrInvalidRegistrationAccessToken
+	}
+
+	// Upgrade hashes of another bcrypt cost like client secrets
+	if s.secretHasher.NeedsRehash(client.RegistrationAccessToken.String) {
+		tokenHash, err := s.secretHasher.Hash(registrationAccessToken, "")
+		if err == nil {
+			err = s.db.Model(client).UpdateColumn("registration_access_token", tokenHash).Error
+		}
+		if err != nil {
+			s.logger.Errorf("Rehashing registration access token of client %s failed: %s", client.Key, err)
+		} else {
+			client.RegistrationAccessToken = util.StringOrNull(tokenHash)
+		}
+	}
+
+	return client, nil
+}
+
+// UpdateClientRegistration replaces the metadata of a registered client
+func (s *Service) UpdateClientRegistration(client *models.OauthClient, metadata *ClientMetadata) (*ClientRegistration, error) {
+	// The tenant of a client cannot change
+	if metadata.TenantID != "" && metadata.TenantID != client.TenantID {
+		return nil, ErrInvalidClientMetadata
+	}
+	metadata.TenantID = client.TenantID
+
+	if err := s.validateClientMetadata(metadata); err != nil {
+		return nil, err
+	}
+	// Updates cannot add grant types or scopes needing the initial access
+	// token
+	if err := s.checkOpenRegistration(metadata, client); err != nil {
+		return nil, err
+	}
+	// The secret signing client_secret_jwt assertions is only issued at
+	// registration
+	if metadata.TokenEndpointAuthMethod == authmethods.ClientSecretJWT && !client.AssertionSecret.Valid {
+		return nil, ErrInvalidClientMetadata
+	}
+
+	client.RedirectURI = util.StringOrNull(firstRedirectURI(metadata))
+	metadata.apply(client)
+	if err := s.db.Save(client).Error; err != nil {
+		return nil, err
+	}
+
+	return NewClientRegistration(client), nil
+}
+
+// DeleteClientRegistration deletes a registered client and revokes its tokens
+func (s *Service) DeleteClientRegistration(client *models.OauthClient) error {
+	var tokens []string
+	s.db.Model(new(models.OauthAccessToken)).Where("client_id = ?", client.ID).Pluck("token", &tokens)
+
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	for _, model := range []interface{}{
+		new(models.OauthAuthorizationCode),
+		new(models.OauthRefreshToken),
+		new(models.OauthAccessToken),
+	} {
+		if err := tx.Unscoped().Where("client_id = ?", client.ID).Delete(model).Error; err != nil {
+			tx.Rollback() // rollback the transaction
+			return err
+		}
+	}
+	if err := tx.Delete(client).Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	for _, token := range tokens {
+		s.RemoveAccessTokenRedis(token)
+	}
+
+	return nil
+}
+
+// registrationAllowed checks the initial access token, or that the tenant
+// accepts open registration
+func (s *Service) registrationAllowed(initialAccessToken string, tenant *TenantConfig) bool {
+	if s.cnf.Registration.InitialAccessToken != "" && initialAccessToken != "" {
+		return s.hasInitialAccessToken(initialAccessToken)
+	}
+	if tenant.OpenRegistration {
+		return true
+	}
+	return util.StringInSlice(tenant.TenantID, s.cnf.Registration.OpenTenants)
+}
+
+// hasInitialAccessToken checks a registration carries the configured initial
+// access token
+func (s *Service) hasInitialAccessToken(initialAccessToken string) bool {
+	expected := s.cnf.Registration.InitialAccessToken
+	if expected == "" || initialAccessToken == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(initialAccessToken)) == 1
+}
+
+// checkOpenRegistration limits metadata registered without the initial
+// access token to the open registration grant types and the default scopes.
+// When updating a client, the grant types and scope it has are kept.
+func (s *Service) checkOpenRegistration(metadata *ClientMetadata, client *models.OauthClient) error {
+	for _, grantType := range metadata.GrantTypes {
+		if util.StringInSlice(grantType, openRegistrationGrantTypes) {
+			continue
+		}
+		if client == nil || !util.StringInSlice(grantType, strings.Fields(client.GrantTypes)) {
+			return ErrInvalidClientMetadata
+		}
+	}
+
+	if metadata.Scope == "" || (client != nil && metadata.Scope == client.Scope.String) {
+		return nil
+	}
+	defaultScopes := strings.Fields(s.GetDefaultScope())
+	for _, scope := range strings.Fields(metadata.Scope) {
+		if !util.StringInSlice(scope, defaultScopes) {
+			return ErrInvalidScope
+		}
+	}
+	return nil
+}
+
+// validateClientMetadata validates registration metadata and fills in defaults
+func (s *Service) validateClientMetadata(metadata *ClientMetadata) error {
+	// A client has a single redirect URI
+	if len(metadata.RedirectURIs) > 1 {
+		return ErrInvalidRedirectURI
+	}
+	for _, redirectURI := range metadata.RedirectURIs {
+		if !validRedirectURI(redirectURI) {
+			return ErrInvalidRedirectURI
+		}
+	}
+
+	// Authorization code is the default grant type
+	if len(metadata.GrantTypes) == 0 {
+		metadata.GrantTypes = []string{"authorization_code"}
+	}
+	for _, grantType := range metadata.GrantTypes {
+		if !util.StringInSlice(grantType, registrableGrantTypes) {
+			return ErrInvalidClientMetadata
+		}
+		if grantType == "authorization_code" && len(metadata.RedirectURIs) == 0 {
+			return ErrInvalidRedirectURI
+		}
+	}
+
+	if metadata.Scope != "" && !s.ScopeExists(metadata.Scope) {
+		return ErrInvalidScope
+	}
+
+	// Client secret basic is the default auth method
+	if metadata.TokenEndpointAuthMethod == "" {
+		metadata.TokenEndpointAuthMethod = authmethods.ClientSecretBasic
+	}
+	method := metadata.TokenEndpointAuthMethod
+	if !util.StringInSlice(method, authmethods.All) {
+		return ErrInvalidClientMetadata
+	}
+
+	// Keys are registered either by value or by reference
+	if len(metadata.JWKS) > 0 && metadata.JWKSURI != "" {
+		return ErrInvalidClientMetadata
+	}
+	if len(metadata.JWKS) > 0 {
+		keys := new(jose.JSONWebKeySet)
+		if err := json.Unmarshal(metadata.JWKS, keys); err != nil {
+			return ErrInvalidClientMetadata
+		}
+		// client_secret_jwt clients sign with their client secret, shared
+		// keys are never registered
+		for _, key := range keys.Keys {
+			if isSymmetricJWK(key) {
+				return ErrInvalidClientMetadata
+			}
+		}
+	}
+	// Fetching is refused for internal addresses too, this catches mistakes
+	// early
+	if metadata.JWKSURI != "" && !isPublicHTTPSURL(metadata.JWKSURI) {
+		return ErrInsecureJWKSURI
+	}
+	needsKeys := method == authmethods.PrivateKeyJWT || method == authmethods.SelfSignedTLSClientAuth
+	if needsKeys && len(metadata.JWKS) == 0 && metadata.JWKSURI == "" {
+		return ErrInvalidClientMetadata
+	}
+	if method == authmethods.TLSClientAuth &&
+		metadata.TLSClientAuthSubjectDN == "" && metadata.TLSClientAuthSANDNS == "" {
+		return ErrInvalidClientMetadata
+	}
+
+	// The server posts to the back-channel logout URI and has browsers load
+	// the front-channel one, neither may point at internal addresses
+	for _, logoutURI := range []string{metadata.FrontchannelLogoutURI, metadata.BackchannelLogoutURI} {
+		if logoutURI != "" && !isPublicHTTPSURL(logoutURI) {
+			return ErrInsecureLogoutURI
+		}
+	}
+	for _, logoutURI := range metadata.PostLogoutRedirectURIs {
+		// Post logout redirect URIs are stored space delimited
+		if !validRedirectURI(logoutURI) || strings.ContainsAny(logoutURI, " \t\n") {
+			return ErrInvalidClientMetadata
+		}
+	}
+
+	return nil
+}
+
+// apply copies validated metadata to the client
+func (m *ClientMetadata) apply(client *models.OauthClient) {
+	client.Name = m.ClientName
+	client.GrantTypes = strings.Join(m.GrantTypes, " ")
+	client.Scope = util.StringOrNull(m.Scope)
+	client.TokenEndpointAuthMethod = m.TokenEndpointAuthMethod
+	client.JWKS = util.StringOrNull(string(m.JWKS))
+	client.JWKSURI = util.StringOrNull(m.JWKSURI)
+	client.TLSClientAuthSubjectDN = util.StringOrNull(m.TLSClientAuthSubjectDN)
+	client.TLSClientAuthSANDNS = util.StringOrNull(m.TLSClientAuthSANDNS)
+	client.TLSClientCertificateBoundAccessTokens = m.TLSClientCertificateBoundAccessTokens
+	client.RequirePushedAuthorizationRequests = m.RequirePushedAuthorizationRequests
+	client.PostLogoutRedirectURIs = strings.Join(m.PostLogoutRedirectURIs, " ")
+	client.FrontchannelLogoutURI = util.StringOrNull(m.FrontchannelLogoutURI)
+	client.BackchannelLogoutURI = util.StringOrNull(m.BackchannelLogoutURI)
+}
+
+// validRedirectURI checks a redirect URI uses https, http on a loopback
+// address or the private-use scheme of a native app (RFC 8252), and has no
+// fragment
+func validRedirectURI(redirectURI string) bool {
+	u, err := url.Parse(redirectURI)
+	if err != nil || !u.IsAbs() || strings.Contains(redirectURI, "#") {
+		return false
+	}
+	switch scheme := strings.ToLower(u.Scheme); scheme {
+	case "https":
+		return u.Host != ""
+	case "http":
+		return isLoopbackHost(u.Hostname())
+	case "javascript", "data", "vbscript", "file":
+		return false
+	default:
+		// Private-use schemes are reverse domain names, like com.example.app
+		return strings.Contains(scheme, ".")
+	}
+}
+
+// isLoopbackHost checks a host is localhost or a loopback IP
+func isLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// firstRedirectURI returns the registered redirect URI, empty if none
+func firstRedirectURI(metadata *ClientMetadata) string {
+	if len(metadata.RedirectURIs) == 0 {
+		return ""
+	}
+	return metadata.RedirectURIs[0]
+}
+
+// clientAllowsGrantType checks the grant type was registered for the client,
+// clients without registered grant types can use any
+func clientAllowsGrantType(client *models.OauthClient, grantType string) bool {
+	if client.GrantTypes == "" {
+		return true
+	}
+	return util.StringInSlice(grantType, strings.Split(client.GrantTypes, " "))
+}
@@ -0,0 +1,192 @@
+package oauth_test
+
+import (
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/authmethods"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestRegisterClient() {
+	suite.cnf.Registration.InitialAccessToken = "test_initial_access_token"
+	defer func() { suite.cnf.Registration.InitialAccessToken = "" }()
+
+	// Registration without the initial access token should fail
+	_, err := suite.service.RegisterClient("", &oauth.ClientMetadata{
+		RedirectURIs: []string{"https://www.example.com"},
+	})
+	assert.Equal(suite.T(), oauth.ErrInitialAccessTokenRequired, err)
+
+	// Authorization code clients need a redirect URI
+	_, err = suite.service.RegisterClient("test_initial_access_token", &oauth.ClientMetadata{})
+	assert.Equal(suite.T(), oauth.ErrInvalidRedirectURI, err)
+
+	// Redirect URIs must be https, http on a loopback address or a native
+	// app's private-use scheme, without a fragment
+	for _, redirectURI := range []string{
+		"http://www.example.com/callback",
+		"https://www.example.com/callback#fragment",
+		"javascript:alert(1)",
+		"data:text/html,<script>alert(1)</script>",
+		"myapp:/callback",
+		"/callback",
+	} {
+		_, err = suite.service.RegisterClient("test_initial_access_token", &oauth.ClientMetadata{
+			RedirectURIs: []string{redirectURI},
+		})
+		assert.Equal(suite.T(), oauth.ErrInvalidRedirectURI, err, redirectURI)
+	}
+	for _, redirectURI := range []string{
+		"http://127.0.0.1:8400/callback",
+		"http://[::1]/callback",
+		"http://localhost:8400/callback",
+		"com.example.app:/callback",
+	} {
+		_, err = suite.service.RegisterClient("test_initial_access_token", &oauth.ClientMetadata{
+			RedirectURIs: []string{redirectURI},
+		})
+		assert.NoError(suite.T(), err, redirectURI)
+	}
+
+	// Private key JWT clients need keys
+	_, err = suite.service.RegisterClient("test_initial_access_token", &oauth.ClientMetadata{
+		GrantTypes:              []string{"client_credentials"},
+		TokenEndpointAuthMethod: authmethods.PrivateKeyJWT,
+	})
+	assert.Equal(suite.T(), oauth.ErrInvalidClientMetadata, err)
+
+	// The JWKs URI must be a public https URL
+	for _, jwksURI := range []string{
+		"http://www.example.com/jwks.json",
+		"https://127.0.0.1/jwks.json",
+		"https://169.254.169.254/jwks.json",
+	} {
+		_, err = suite.service.RegisterClient("test_initial_access_token", &oauth.ClientMetadata{
+			GrantTypes:              []string{"client_credentials"},
+			TokenEndpointAuthMethod: authmethods.PrivateKeyJWT,
+			JWKSURI:                 jwksURI,
+		})
+		assert.Equal(suite.T(), oauth.ErrInsecureJWKSURI, err, jwksURI)
+	}
+
+	// Logout URIs must be public https URLs too
+	for _, logoutURI := range []string{
+		"http://www.example.com/logout",
+		"https://127.0.0.1/logout",
+		"https://10.0.0.1/logout",
+	} {
+		_, err = suite.service.RegisterClient("test_initial_access_token", &oauth.ClientMetadata{
+			RedirectURIs:         []string{"https://www.example.com"},
+			BackchannelLogoutURI: logoutURI,
+		})
+		assert.Equal(suite.T(), oauth.ErrInsecureLogoutURI, err, logoutURI)
+		_, err = suite.service.RegisterClient("test_initial_access_token", &oauth.ClientMetadata{
+			RedirectURIs:          []string{"https://www.example.com"},
+			FrontchannelLogoutURI: logoutURI,
+		})
+		assert.Equal(suite.T(), oauth.ErrInsecureLogoutURI, err, logoutURI)
+	}
+
+	// Valid registration issues a secret and registration access token
+	registration, err := suite.service.RegisterClient("test_initial_access_token", &oauth.ClientMetadata{
+		RedirectURIs: []string{"https://www.example.com"},
+		ClientName:   "Test Client",
+		Scope:        "read",
+	})
+	if assert.NoError(suite.T(), err) {
+		assert.NotEmpty(suite.T(), registration.ClientSecret)
+		assert.NotEmpty(suite.T(), registration.RegistrationAccessToken)
+		assert.Equal(suite.T(), []string{"authorization_code"}, registration.GrantTypes)
+		assert.Equal(suite.T(), authmethods.ClientSecretBasic, registration.TokenEndpointAuthMethod)
+	}
+
+	// The client can authenticate with the issued secret
+	client, err := suite.service.AuthClient(registration.ClientID, registration.ClientSecret)
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), "Test Client", client.Name)
+		assert.Equal(suite.T(), "read", client.Scope.String)
+	}
+}
+
+func (suite *OauthTestSuite) TestClientRegistrationManagement() {
+	suite.cnf.Registration.OpenTenants = []string{"open_tenant"}
+	defer func() { suite.cnf.Registration.OpenTenants = nil }()
+	err := suite.db.Create(&models.Tenant{
+		ID:     "open_tenant",
+		Name:   "open_tenant",
+		Status: models.TenantStatusActive,
+	}).Error
+	assert.NoError(suite.T(), err)
+
+	// Open registration is limited to the authorization code flow and the
+	// default scopes
+	_, err = suite.service.RegisterClient("", &oauth.ClientMetadata{
+		GrantTypes: []string{"client_credentials"},
+		TenantID:   "open_tenant",
+	})
+	assert.Equal(suite.T(), oauth.ErrInvalidClientMetadata, err)
+	_, err = suite.service.RegisterClient("", &oauth.ClientMetadata{
+		RedirectURIs: []string{"https://www.example.com"},
+		GrantTypes:   []string{"authorization_code", "password"},
+		TenantID:     "open_tenant",
+	})
+	assert.Equal(suite.T(), oauth.ErrInvalidClientMetadata, err)
+	_, err = suite.service.RegisterClient("", &oauth.ClientMetadata{
+		RedirectURIs: []string{"https://www.example.com"},
+		Scope:        "read_write",
+		TenantID:     "open_tenant",
+	})
+	assert.Equal(suite.T(), oauth.ErrInvalidScope, err)
+
+	// Open tenants don't need an initial access token
+	registration, err := suite.service.RegisterClient("", &oauth.ClientMetadata{
+		RedirectURIs: []string{"https://www.example.com"},
+		Scope:        "read",
+		TenantID:     "open_tenant",
+	})
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+
+	// A bogus registration access token should be rejected
+	_, err = suite.service.AuthClientRegistration(registration.ClientID, "bogus")
+	assert.Equal(suite.T(), oauth.ErrInvalidRegistrationAccessToken, err)
+
+	client, err := suite.service.AuthClientRegistration(registration.ClientID, registration.RegistrationAccessToken)
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+
+	// The tenant of a client cannot change
+	_, err = suite.service.UpdateClientRegistration(client, &oauth.ClientMetadata{
+		RedirectURIs: []string{"https://www.example.com"},
+		TenantID:     "other_tenant",
+	})
+	assert.Equal(suite.T(), oauth.ErrInvalidClientMetadata, err)
+
+	// Nor can updates add privileged grant types or scopes
+	_, err = suite.service.UpdateClientRegistration(client, &oauth.ClientMetadata{
+		RedirectURIs: []string{"https://www.example.com"},
+		GrantTypes:   []string{"authorization_code", "client_credentials"},
+	})
+	assert.Equal(suite.T(), oauth.ErrInvalidClientMetadata, err)
+	_, err = suite.service.UpdateClientRegistration(client, &oauth.ClientMetadata{
+		RedirectURIs: []string{"https://www.example.com"},
+		Scope:        "read_write",
+	})
+	assert.Equal(suite.T(), oauth.ErrInvalidScope, err)
+
+	updated, err := suite.service.UpdateClientRegistration(client, &oauth.ClientMetadata{
+		RedirectURIs: []string{"https://www.example.com"},
+		GrantTypes:   []string{"authorization_code", "refresh_token"},
+		ClientName:   "Renamed Client",
+	})
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), "Renamed Client", updated.ClientName)
+		assert.Equal(suite.T(), "open_tenant", updated.TenantID)
+	}
+
+	// Deleted clients are gone
+	assert.NoError(suite.T(), suite.service.DeleteClientRegistration(client))
+	assert.False(suite.T(), suite.service.ClientExists(registration.ClientID))
+}
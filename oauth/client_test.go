@@ -4,6 +4,7 @@ import (
 	"github.com/RichardKnop/go-oauth2-server/models"
 	"github.com/RichardKnop/go-oauth2-server/oauth"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func (suite *OauthTestSuite) TestFindClientByClientID() {
@@ -113,3 +114,30 @@ func (suite *OauthTestSuite) TestAuthClient() {
 		assert.Equal(suite.T(), "test_client_1", client.Key)
 	}
 }
+
+func (suite *OauthTestSuite) TestAuthClientRehashesSecret() {
+	client, err := suite.service.CreateClient("test_rehash_client", "test_secret", "", "")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	defer suite.db.Unscoped().Delete(client)
+
+	// A secret hashed at another cost is rehashed at the configured one
+	oldHash, err := bcrypt.GenerateFromPassword([]byte("test_secret"), bcrypt.MinCost)
+	assert.NoError(suite.T(), err)
+	suite.db.Model(client).UpdateColumn("secret", string(oldHash))
+
+	_, err = suite.service.AuthClient("test_rehash_client", "test_secret")
+	assert.NoError(suite.T(), err)
+
+	client, err = suite.service.FindClientByClientID("test_rehash_client")
+	if assert.NoError(suite.T(), err) {
+		cost, err := bcrypt.Cost([]byte(client.Secret))
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), suite.cnf.Oauth.BcryptCost, cost)
+	}
+
+	// The rehashed secret keeps working
+	_, err = suite.service.AuthClient("test_rehash_client", "test_secret")
+	assert.NoError(suite.T(), err)
+}
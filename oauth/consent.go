@@ -0,0 +1,201 @@
+package oauth
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/audit"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	// ErrConsentRequired ...
+	ErrConsentRequired = errors.New("User consent required")
+	// ErrConsentNotFound ...
+	ErrConsentNotFound = errors.New("Consent not found")
+)
+
+// GetMissingConsent returns the part of a scope the user has not granted the
+// client yet, which the consent screen asks for. It is empty once the user
+// consented to the whole scope and always for first-party clients.
+func (s *Service) GetMissingConsent(client *models.OauthClient, user *models.OauthUser, scope string) (string, error) {
+	if client.SkipConsent {
+		return "", nil
+	}
+
+	consent, err := s.findConsent(s.db, client, user)
+	if err != nil && err != ErrConsentNotFound {
+		return "", err
+	}
+	granted := make(map[string]bool)
+	if consent != nil {
+		for _, grantedScope := range strings.Fields(consent.Scope) {
+			granted[grantedScope] = true
+		}
+	}
+
+	var missing []string
+	for _, requested := range strings.Fields(scope) {
+		if !granted[requested] {
+			missing = append(missing, requested)
+		}
+	}
+	return strings.Join(missing, " "), nil
+}
+
+// GrantConsent records the user granting the client a scope, in addition to
+// the scope granted before
+func (s *Service) GrantConsent(client *models.OauthClient, user *models.OauthUser, scope string) error {
+	// Users can only consent to clients of their tenant
+	if err := checkUserTenant(client, user); err != nil {
+		return err
+	}
+	if scope == "" || !s.ScopeExists(scope) {
+		return ErrInvalidScope
+	}
+
+	consent, err := s.findConsent(s.db, client, user)
+	if err == ErrConsentNotFound {
+		consent = &models.OauthConsent{
+			TenantID: user.TenantID,
+			UserID:   user.ID,
+			ClientID: client.ID,
+		}
+	} else if err != nil {
+		return err
+	}
+	consent.Scope = mergeScopes(consent.Scope, scope)
+	consent.GrantedAt = time.Now().UTC()
+	if err := s.db.Save(consent).Error; err != nil {
+		return err
+	}
+
+	s.logger.Info("audit: consent granted", "user_id", user.ID, "client_id", client.Key, "scope", scope, "tenant_id", user.TenantID)
+	return nil
+}
+
+// SetSkipConsent marks the client as first-party, whose authorization
+// requests never ask for consent, or stops doing so. Only the admin API sets
+// it, clients cannot register themselves as first-party.
+func (s *Service) SetSkipConsent(client *models.OauthClient, skip bool) error {
+	if err := s.db.Model(client).UpdateColumn("skip_consent", skip).Error; err != nil {
+		return err
+	}
+	client.SkipConsent = skip
+	return nil
+}
+
+// ListConsents returns the consents of a user along with their clients
+func (s *Service) ListConsents(user *models.OauthUser) ([]*models.OauthConsent, error) {
+	var consents []*models.OauthConsent
+	err := s.db.Preload("Client").Where("user_id = ?", user.ID).
+		Order("granted_at desc").Find(&consents).Error
+	if err != nil {
+		return nil, err
+	}
+	return consents, nil
+}
+
+// RevokeConsent deletes the consent a user gave a client along with the
+// tokens and authorization codes the client holds for the user
+func (s *Service) RevokeConsent(user *models.OauthUser, clientID string) error {
+	client, err := s.FindClientByClientID(clientID)
+	if err != nil || client.TenantID != user.TenantID {
+		return ErrConsentNotFound
+	}
+
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	consent, err := s.findConsent(tx, client, user)
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+	if err := tx.Delete(consent).Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+	tokens, err := s.revokeUserClientTokens(tx, user, client)
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+	var event *audit.Event
+	if len(tokens) > 0 {
+		event = newTokensRevokedEvent(user, client, "consent_revoked", len(tokens))
+		if err := s.enqueueEvent(tx, event); err != nil {
+			tx.Rollback() // rollback the transaction
+			return err
+		}
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	s.removeAccessTokensRedis(tokens)
+	if event != nil {
+		s.recordEvent(event)
+	}
+	s.logger.Info("audit: consent revoked", "user_id", user.ID, "client_id", client.Key, "tenant_id", user.TenantID)
+	return nil
+}
+
+// findConsent looks up the consent of a user for a client
+func (s *Service) findConsent(db *gorm.DB, client *models.OauthClient, user *models.OauthUser) (*models.OauthConsent, error) {
+	consent := new(models.OauthConsent)
+	notFound := db.Where("user_id = ? AND client_id = ?", user.ID, client.ID).
+		First(consent).RecordNotFound()
+
+	// Not found
+	if notFound {
+		return nil, ErrConsentNotFound
+	}
+
+	return consent, nil
+}
+
+// revokeUserClientTokens deletes the authorization codes, refresh tokens and
+// access tokens a client holds for a user, returning the revoked access
+// tokens so they can be removed from Redis
+func (s *Service) revokeUserClientTokens(db *gorm.DB, user *models.OauthUser, client *models.OauthClient) ([]string, error) {
+	var tokens []string
+	err := db.Model(new(models.OauthAccessToken)).
+		Where("user_id = ? AND client_id = ?", user.ID, client.ID).
+		Pluck("token", &tokens).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, model := range []interface{}{
+		new(models.OauthAuthorizationCode),
+		new(models.OauthRefreshToken),
+		new(models.OauthAccessToken),
+	} {
+		err := db.Unscoped().Where("user_id = ? AND client_id = ?", user.ID, client.ID).
+			Delete(model).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tokens, nil
+}
+
+// mergeScopes returns the union of two space delimited scopes, sorted
+func mergeScopes(first, second string) string {
+	seen := make(map[string]bool)
+	var merged []string
+	for _, scope := range append(strings.Fields(first), strings.Fields(second)...) {
+		if !seen[scope] {
+			seen[scope] = true
+			merged = append(merged, scope)
+		}
+	}
+	sort.Strings(merged)
+	return strings.Join(merged, " ")
+}
@@ -0,0 +1,89 @@
+package oauth_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestConsent() {
+	client := suite.clients[0]
+	user, err := suite.service.CreateUser(roles.User, "test@consent", "correct_horse_battery", "")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	req := &oauth.AuthorizationRequest{
+		ClientID:     client.Key,
+		ResponseType: "code",
+		RedirectURI:  "https://www.example.com",
+		Scope:        "read read_write",
+	}
+
+	// Codes are only granted for consented scopes
+	missing, err := suite.service.GetMissingConsent(client, user, req.Scope)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "read read_write", missing)
+	_, err = suite.service.GrantAuthorizationCodeForRequest(client, user, req, nil)
+	assert.Equal(suite.T(), oauth.ErrConsentRequired, err)
+
+	// Only the scopes not granted before are asked for
+	assert.NoError(suite.T(), suite.service.GrantConsent(client, user, "read"))
+	missing, err = suite.service.GetMissingConsent(client, user, req.Scope)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "read_write", missing)
+	assert.NoError(suite.T(), suite.service.GrantConsent(client, user, "read_write"))
+	_, err = suite.service.GrantAuthorizationCodeForRequest(client, user, req, nil)
+	assert.NoError(suite.T(), err)
+
+	// First-party clients never ask
+	assert.NoError(suite.T(), suite.service.SetSkipConsent(suite.clients[1], true))
+	firstParty, err := suite.service.FindClientByClientID(suite.clients[1].Key)
+	if assert.NoError(suite.T(), err) {
+		assert.True(suite.T(), firstParty.SkipConsent)
+		missing, err = suite.service.GetMissingConsent(firstParty, user, req.Scope)
+		assert.NoError(suite.T(), err)
+		assert.Empty(suite.T(), missing)
+	}
+	assert.NoError(suite.T(), suite.service.SetSkipConsent(suite.clients[1], false))
+
+	accessToken, _, err := suite.service.Login(client, user, "read")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+
+	w := suite.consentRequest("GET", "/v1/oauth/consents", accessToken.Token)
+	if assert.Equal(suite.T(), http.StatusOK, w.Code) {
+		consents := new(oauth.ConsentsResponse)
+		assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), consents))
+		if assert.Len(suite.T(), consents.Consents, 1) {
+			assert.Equal(suite.T(), client.Key, consents.Consents[0].ClientID)
+			assert.Equal(suite.T(), "read read_write", consents.Consents[0].Scope)
+		}
+	}
+
+	// Revoking the consent revokes the client's tokens of the user
+	w = suite.consentRequest("DELETE", "/v1/oauth/consents/"+client.Key, accessToken.Token)
+	assert.Equal(suite.T(), http.StatusNoContent, w.Code)
+	_, err = suite.service.Authenticate(accessToken.Token)
+	assert.Error(suite.T(), err)
+	var count int
+	suite.db.Model(new(models.OauthAuthorizationCode)).Where("user_id = ?", user.ID).Count(&count)
+	assert.Equal(suite.T(), 0, count)
+	missing, err = suite.service.GetMissingConsent(client, user, req.Scope)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "read read_write", missing)
+}
+
+func (suite *OauthTestSuite) consentRequest(method, path, accessToken string) *httptest.ResponseRecorder {
+	r, err := http.NewRequest(method, "http://1.2.3.4"+path, nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.Header.Set("Authorization", "Bearer "+accessToken)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	return w
+}
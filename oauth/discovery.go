@@ -0,0 +1,102 @@
+package oauth
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth/authmethods"
+	"github.com/RichardKnop/go-oauth2-server/oauth/dpop"
+	"github.com/RichardKnop/go-oauth2-server/oauth/pkce"
+)
+
+// oauthPathPrefix is where the oauth routes are registered under an issuer
+const oauthPathPrefix = "/v1/oauth"
+
+var (
+	// ErrIssuerNotConfigured ...
+	ErrIssuerNotConfigured = errors.New("Issuer URL is not configured")
+)
+
+// DiscoveryDocument is the authorization server metadata (RFC 8414)
+type DiscoveryDocument struct {
+	Issuer                                string   `json:"issuer"`
+	JWKSURI                               string   `json:"jwks_uri"`
+	TokenEndpoint                         string   `json:"token_endpoint"`
+	IntrospectionEndpoint                 string   `json:"introspection_endpoint"`
+	RevocationEndpoint                    string   `json:"revocation_endpoint"`
+	PushedAuthorizationRequestEndpoint    string   `json:"pushed_authorization_request_endpoint"`
+	RegistrationEndpoint                  string   `json:"registration_endpoint"`
+	EndSessionEndpoint                    string   `json:"end_session_endpoint"`
+	ResponseTypesSupported                []string `json:"response_types_supported"`
+	GrantTypesSupported                   []string `json:"grant_types_supported"`
+	TokenEndpointAuthMethodsSupported     []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported         []string `json:"code_challenge_methods_supported"`
+	DPoPSigningAlgValuesSupported         []string `json:"dpop_signing_alg_values_supported"`
+	TLSClientCertificateBoundAccessTokens bool     `json:"tls_client_certificate_bound_access_tokens"`
+	PromptValuesSupported                 []string `json:"prompt_values_supported"`
+	FrontchannelLogoutSupported           bool     `json:"frontchannel_logout_supported"`
+	FrontchannelLogoutSessionSupported    bool     `json:"frontchannel_logout_session_supported"`
+	BackchannelLogoutSupported            bool     `json:"backchannel_logout_supported"`
+	BackchannelLogoutSessionSupported     bool     `json:"backchannel_logout_session_supported"`
+}
+
+// NewDiscoveryDocument returns the metadata of a tenant's issuer, the
+// endpoints are under the configured issuer URL
+func (s *Service) NewDiscoveryDocument(tenantID string) (*DiscoveryDocument, error) {
+	tenant, err := s.GetTenantConfig(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if !isIssuerURL(tenant.Issuer) {
+		return nil, ErrIssuerNotConfigured
+	}
+	issuer := strings.TrimRight(tenant.Issuer, "/")
+	endpoint := issuer + oauthPathPrefix
+
+	grantTypes := tenant.GrantTypes
+	if len(grantTypes) == 0 {
+		grantTypes = registrableGrantTypes
+	}
+	dpopAlgorithms := make([]string, 0, len(dpop.Algorithms))
+	for _, alg := range dpop.Algorithms {
+		dpopAlgorithms = append(dpopAlgorithms, string(alg))
+	}
+
+	return &DiscoveryDocument{
+		Issuer:                                tenant.Issuer,
+		JWKSURI:                               issuer + jwksPath,
+		TokenEndpoint:                         endpoint + tokensPath,
+		IntrospectionEndpoint:                 endpoint + introspectPath,
+		RevocationEndpoint:                    endpoint + revokePath,
+		PushedAuthorizationRequestEndpoint:    endpoint + parPath,
+		RegistrationEndpoint:                  endpoint + registerPath,
+		EndSessionEndpoint:                    endpoint + endSessionPath,
+		ResponseTypesSupported:                []string{"code"},
+		GrantTypesSupported:                   grantTypes,
+		TokenEndpointAuthMethodsSupported:     authmethods.All,
+		CodeChallengeMethodsSupported:         []string{pkce.MethodS256},
+		DPoPSigningAlgValuesSupported:         dpopAlgorithms,
+		TLSClientCertificateBoundAccessTokens: true,
+		PromptValuesSupported:                 []string{PromptNone, PromptLogin, PromptConsent, PromptSelectAccount},
+		FrontchannelLogoutSupported:           true,
+		FrontchannelLogoutSessionSupported:    true,
+		BackchannelLogoutSupported:            true,
+		BackchannelLogoutSessionSupported:     true,
+	}, nil
+}
+
+// tokenEndpoint returns the token endpoint under the tenant's issuer, empty
+// if the issuer is not a URL
+func tokenEndpoint(tenant *TenantConfig) string {
+	if !isIssuerURL(tenant.Issuer) {
+		return ""
+	}
+	return strings.TrimRight(tenant.Issuer, "/") + oauthPathPrefix + tokensPath
+}
+
+// isIssuerURL returns true for absolute issuer URLs
+func isIssuerURL(issuer string) bool {
+	parsed, err := url.Parse(issuer)
+	return err == nil && (parsed.Scheme == "https" || parsed.Scheme == "http") && parsed.Host != ""
+}
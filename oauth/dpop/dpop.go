@@ -0,0 +1,180 @@
+package dpop
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+const (
+	// HeaderName is the HTTP header carrying the proof
+	HeaderName = "DPoP"
+	// ProofType is the required typ header of a proof JWT
+	ProofType = "dpop+jwt"
+)
+
+var (
+	// ErrProofMissing ...
+	ErrProofMissing = errors.New("DPoP proof missing")
+	// ErrProofMalformed ...
+	ErrProofMalformed = errors.New("DPoP proof is not a valid signed JWT")
+	// ErrProofInvalidType ...
+	ErrProofInvalidType = errors.New("DPoP proof must have typ dpop+jwt")
+	// ErrProofAlgorithmNotAllowed ...
+	ErrProofAlgorithmNotAllowed = errors.New("DPoP proof signing algorithm not allowed")
+	// ErrProofInvalidKey ...
+	ErrProofInvalidKey = errors.New("DPoP proof must contain a public jwk header")
+	// ErrProofInvalidSignature ...
+	ErrProofInvalidSignature = errors.New("DPoP proof signature invalid")
+	// ErrProofMissingClaims ...
+	ErrProofMissingClaims = errors.New("DPoP proof must contain jti, htm, htu and iat claims")
+	// ErrProofMethodMismatch ...
+	ErrProofMethodMismatch = errors.New("DPoP proof htm does not match the request method")
+	// ErrProofURIMismatch ...
+	ErrProofURIMismatch = errors.New("DPoP proof htu does not match the request URI")
+	// ErrProofExpired ...
+	ErrProofExpired = errors.New("DPoP proof iat outside of the acceptable window")
+	// ErrProofAccessTokenHashMismatch ...
+	ErrProofAccessTokenHashMismatch = errors.New("DPoP proof ath does not match the access token")
+
+	// Algorithms are the signing algorithms accepted for proofs
+	Algorithms = []jose.SignatureAlgorithm{
+		jose.RS256, jose.RS384, jose.RS512,
+		jose.PS256, jose.PS384, jose.PS512,
+		jose.ES256, jose.ES384, jose.ES512,
+		jose.EdDSA,
+	}
+)
+
+// Claims are the claims of a DPoP proof JWT
+type Claims struct {
+	ID       string           `json:"jti"`
+	Method   string           `json:"htm"`
+	URI      string           `json:"htu"`
+	IssuedAt *jwt.NumericDate `json:"iat"`
+	// AccessTokenHash is required when the proof accompanies an access token
+	AccessTokenHash string `json:"ath,omitempty"`
+}
+
+// Proof is a verified DPoP proof
+type Proof struct {
+	Claims
+	// JKT is the SHA-256 JWK thumbprint of the proof key (RFC 7638)
+	JKT string
+}
+
+// Expectations defines what a proof is validated against
+type Expectations struct {
+	Method string
+	URI    string
+	// AccessToken must be hashed into ath, leave empty on the token endpoint
+	AccessToken string
+	// Window is the maximum allowed difference between iat and Now
+	Window time.Duration
+	// Now defaults to time.Now()
+	Now time.Time
+}
+
+// VerifyProof verifies a DPoP proof JWT against the request it was sent with.
+// Replay protection of the returned jti is left to the caller.
+func VerifyProof(raw string, expected *Expectations) (*Proof, error) {
+	if raw == "" {
+		return nil, ErrProofMissing
+	}
+	token, err := jwt.ParseSigned(raw)
+	if err != nil || len(token.Headers) != 1 {
+		return nil, ErrProofMalformed
+	}
+	header := token.Headers[0]
+
+	if typ, _ := header.ExtraHeaders[jose.HeaderType].(string); typ != ProofType {
+		return nil, ErrProofInvalidType
+	}
+	if !algorithmAllowed(header.Algorithm) {
+		return nil, ErrProofAlgorithmNotAllowed
+	}
+	if header.JSONWebKey == nil || !header.JSONWebKey.Valid() || !header.JSONWebKey.IsPublic() {
+		return nil, ErrProofInvalidKey
+	}
+
+	proof := new(Proof)
+	if err := token.Claims(header.JSONWebKey.Key, &proof.Claims); err != nil {
+		return nil, ErrProofInvalidSignature
+	}
+	if proof.ID == "" || proof.Method == "" || proof.URI == "" || proof.IssuedAt == nil {
+		return nil, ErrProofMissingClaims
+	}
+
+	if proof.Method != expected.Method {
+		return nil, ErrProofMethodMismatch
+	}
+	if !SameURI(proof.URI, expected.URI) {
+		return nil, ErrProofURIMismatch
+	}
+
+	now := expected.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	age := now.Sub(proof.IssuedAt.Time())
+	if age > expected.Window || age < -expected.Window {
+		return nil, ErrProofExpired
+	}
+
+	if expected.AccessToken != "" && proof.AccessTokenHash != AccessTokenHash(expected.AccessToken) {
+		return nil, ErrProofAccessTokenHashMismatch
+	}
+
+	proof.JKT, err = Thumbprint(header.JSONWebKey)
+	if err != nil {
+		return nil, ErrProofInvalidKey
+	}
+	return proof, nil
+}
+
+// Thumbprint returns the base64url encoded SHA-256 JWK thumbprint
+func Thumbprint(key *jose.JSONWebKey) (string, error) {
+	sum, err := key.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(sum), nil
+}
+
+// AccessTokenHash returns the ath value for an access token
+func AccessTokenHash(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// SameURI compares two URIs ignoring query, fragment and the case of
+// scheme and host, as htu comparison requires
+func SameURI(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(ua.Scheme, ub.Scheme) &&
+		strings.EqualFold(ua.Host, ub.Host) &&
+		ua.EscapedPath() == ub.EscapedPath()
+}
+
+func algorithmAllowed(alg string) bool {
+	for _, a := range Algorithms {
+		if string(a) == alg {
+			return true
+		}
+	}
+	return false
+}
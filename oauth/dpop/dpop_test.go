@@ -0,0 +1,103 @@
+package dpop_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth/dpop"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+const testURI = "https://auth.example.com/v1/oauth/token"
+
+func newProof(t *testing.T, key *ecdsa.PrivateKey, typ string, claims *dpop.Claims) string {
+	options := (&jose.SignerOptions{EmbedJWK: true}).WithType(jose.ContentType(typ))
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: key}, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestVerifyProof(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	claims := func() *dpop.Claims {
+		return &dpop.Claims{
+			ID:       "jti-1",
+			Method:   "POST",
+			URI:      testURI,
+			IssuedAt: jwt.NewNumericDate(now),
+		}
+	}
+	expected := &dpop.Expectations{
+		Method: "POST",
+		URI:    "https://AUTH.example.com/v1/oauth/token",
+		Window: time.Minute,
+		Now:    now,
+	}
+
+	// Valid proof, thumbprint of the embedded key is returned
+	proof, err := dpop.VerifyProof(newProof(t, key, dpop.ProofType, claims()), expected)
+	if assert.NoError(t, err) {
+		jwk := &jose.JSONWebKey{Key: &key.PublicKey}
+		jkt, err := dpop.Thumbprint(jwk)
+		assert.NoError(t, err)
+		assert.Equal(t, jkt, proof.JKT)
+		assert.Equal(t, "jti-1", proof.ID)
+	}
+
+	// Wrong typ header
+	_, err = dpop.VerifyProof(newProof(t, key, "JWT", claims()), expected)
+	assert.Equal(t, dpop.ErrProofInvalidType, err)
+
+	// Wrong method
+	c := claims()
+	c.Method = "GET"
+	_, err = dpop.VerifyProof(newProof(t, key, dpop.ProofType, c), expected)
+	assert.Equal(t, dpop.ErrProofMethodMismatch, err)
+
+	// Wrong URI
+	c = claims()
+	c.URI = "https://auth.example.com/v1/oauth/introspect"
+	_, err = dpop.VerifyProof(newProof(t, key, dpop.ProofType, c), expected)
+	assert.Equal(t, dpop.ErrProofURIMismatch, err)
+
+	// Stale proof
+	c = claims()
+	c.IssuedAt = jwt.NewNumericDate(now.Add(-time.Hour))
+	_, err = dpop.VerifyProof(newProof(t, key, dpop.ProofType, c), expected)
+	assert.Equal(t, dpop.ErrProofExpired, err)
+
+	// Resource requests must hash the access token into ath
+	withToken := *expected
+	withToken.AccessToken = "test_token"
+	_, err = dpop.VerifyProof(newProof(t, key, dpop.ProofType, claims()), &withToken)
+	assert.Equal(t, dpop.ErrProofAccessTokenHashMismatch, err)
+	c = claims()
+	c.AccessTokenHash = dpop.AccessTokenHash("test_token")
+	_, err = dpop.VerifyProof(newProof(t, key, dpop.ProofType, c), &withToken)
+	assert.NoError(t, err)
+
+	// Missing proof
+	_, err = dpop.VerifyProof("", expected)
+	assert.Equal(t, dpop.ErrProofMissing, err)
+}
+
+func TestSameURI(t *testing.T) {
+	assert.True(t, dpop.SameURI("https://example.com/token", "HTTPS://Example.com/token?x=1"))
+	assert.False(t, dpop.SameURI("https://example.com/token", "http://example.com/token"))
+	assert.False(t, dpop.SameURI("https://example.com/token", "https://example.com/Token"))
+}
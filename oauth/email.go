@@ -0,0 +1,396 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth/mail"
+	"github.com/RichardKnop/go-oauth2-server/util"
+)
+
+const (
+	// SMTPMailerName names the mailer sending emails through SMTP
+	SMTPMailerName = "smtp"
+	// FileMailerName names the mailer dropping emails into a directory
+	FileMailerName = "file"
+
+	emailCooldownPrefix = "email_cooldown:"
+)
+
+var (
+	// ErrInvalidEmail ...
+	ErrInvalidEmail = errors.New("Invalid email address")
+	// ErrEmailTaken ...
+	ErrEmailTaken = errors.New("Email address taken")
+	// ErrEmailNotSet ...
+	ErrEmailNotSet = errors.New("User has no email address")
+	// ErrEmailAlreadyVerified ...
+	ErrEmailAlreadyVerified = errors.New("Email address already verified")
+	// ErrEmailRateLimited ...
+	ErrEmailRateLimited = errors.New("An email was sent recently, try again later")
+	// ErrInvalidEmailToken ...
+	ErrInvalidEmailToken = errors.New("Invalid or expired token")
+)
+
+// emailData is the data of email templates
+type emailData struct {
+	Email     string
+	Link      string
+	ExpiresIn string
+}
+
+// FindUserByEmailAndTenantID looks up a user by email address in a tenant
+func (s *Service) FindUserByEmailAndTenantID(email string, tenantID string) (*models.OauthUser, error) {
+	// Email addresses are case insensitive
+	user := new(models.OauthUser)
+	notFound := s.db.Where("LOWER(email) = LOWER(?) AND tenant_id = ?", email, tenantID).
+		First(user).RecordNotFound()
+
+	// Not found
+	if notFound {
+		return nil, ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+// SetEmail changes the email address of a user, which needs to be confirmed
+// again
+func (s *Service) SetEmail(user *models.OauthUser, email string) error {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if !util.ValidateEmail(email) {
+		return ErrInvalidEmail
+	}
+	if user.Email.Valid && user.Email.String == email {
+		return nil
+	}
+	if other, err := s.FindUserByEmailAndTenantID(email, user.TenantID); err == nil && other.ID != user.ID {
+		return ErrEmailTaken
+	}
+
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	err := tx.Model(user).UpdateColumns(map[string]interface{}{
+		"email":          email,
+		"email_verified": false,
+		"updated":        time.Now().UTC(),
+	}).Error
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+	// Links sent to the previous address no longer confirm anything
+	err = tx.Unscoped().Where("user_id = ?", user.ID).Delete(new(models.OauthEmailConfirmation)).Error
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+	user.Email = util.StringOrNull(email)
+	user.EmailVerified = false
+
+	return nil
+}
+
+// SendEmailConfirmation emails a link confirming the user's email address
+func (s *Service) SendEmailConfirmation(user *models.OauthUser) error {
+	if !user.Email.Valid {
+		return ErrEmailNotSet
+	}
+	if user.EmailVerified {
+		return ErrEmailAlreadyVerified
+	}
+	if !s.allowEmail(user.TenantID, user.Email.String) {
+		return ErrEmailRateLimited
+	}
+
+	token, reference, err := newEmailToken()
+	if err != nil {
+		return err
+	}
+	lifetime := s.cnf.Email.ConfirmationLifetime
+
+	// Only the latest link confirms the email address
+	err = s.db.Unscoped().Where("user_id = ?", user.ID).Delete(new(models.OauthEmailConfirmation)).Error
+	if err != nil {
+		return err
+	}
+	confirmation := models.NewOauthEmailConfirmation(user, user.Email.String, reference, lifetime)
+	if err := s.db.Create(confirmation).Error; err != nil {
+		return err
+	}
+
+	err = s.sendEmail(user.Email.String, mail.EmailConfirmationTemplate, &emailData{
+		Email:     user.Email.String,
+		Link:      linkWithToken(s.cnf.Email.ConfirmURL, token),
+		ExpiresIn: formatLifetime(lifetime),
+	})
+	if err != nil {
+		return err
+	}
+	return s.markEmailSent(confirmation)
+}
+
+// ConfirmEmail marks the email address of a confirmation link as verified,
+// links of a previous email address are refused
+func (s *Service) ConfirmEmail(token string) (*models.OauthUser, error) {
+	confirmation := new(models.OauthEmailConfirmation)
+	if err := s.findEmailToken(token, confirmation); err != nil {
+		return nil, err
+	}
+	user, err := s.FindUserByID(confirmation.UserID)
+	if err != nil {
+		return nil, ErrInvalidEmailToken
+	}
+	if !user.Email.Valid || user.Email.String != confirmation.Email {
+		return nil, ErrInvalidEmailToken
+	}
+
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	if err := tx.Model(user).UpdateColumn("email_verified", true).Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+	if err := tx.Unscoped().Delete(confirmation).Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+	user.EmailVerified = true
+
+	return user, nil
+}
+
+// RequestPasswordReset emails a password reset link to the verified email
+// address of an active user. Nothing tells whether an email was sent, so
+// registered addresses cannot be told apart.
+func (s *Service) RequestPasswordReset(tenantID, email string) error {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if !util.ValidateEmail(email) {
+		return ErrInvalidEmail
+	}
+	user, err := s.FindUserByEmailAndTenantID(email, tenantID)
+	if err != nil || user.Disabled || !user.EmailVerified {
+		return nil
+	}
+	if !s.allowEmail(tenantID, email) {
+		s.logger.Warn("audit: password reset email rate limited", "user_id", user.ID, "tenant_id", tenantID)
+		return nil
+	}
+
+	token, reference, err := newEmailToken()
+	if err != nil {
+		return err
+	}
+	lifetime := s.cnf.Email.ResetLifetime
+
+	// Only the latest link resets the password
+	err = s.db.Unscoped().Where("user_id = ?", user.ID).Delete(new(models.OauthPasswordReset)).Error
+	if err != nil {
+		return err
+	}
+	reset := models.NewOauthPasswordReset(user, reference, lifetime)
+	if err := s.db.Create(reset).Error; err != nil {
+		return err
+	}
+
+	err = s.sendEmail(email, mail.PasswordResetTemplate, &emailData{
+		Email:     email,
+		Link:      linkWithToken(s.cnf.Email.ResetURL, token),
+		ExpiresIn: formatLifetime(lifetime),
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("audit: password reset requested", "user_id", user.ID, "tenant_id", tenantID)
+	return s.markEmailSent(reset)
+}
+
+// ResetPasswordWithToken sets a new password with the token of a password
+// reset link, the link can be used again if the password breaks the policy
+func (s *Service) ResetPasswordWithToken(token, newPassword string) error {
+	reset := new(models.OauthPasswordReset)
+	if err := s.findEmailToken(token, reset); err != nil {
+		return err
+	}
+	user, err := s.FindUserByID(reset.UserID)
+	if err != nil || user.Disabled {
+		return ErrInvalidEmailToken
+	}
+
+	if err := s.SetPassword(user, newPassword); err != nil {
+		return err
+	}
+	if err := s.db.Unscoped().Where("user_id = ?", user.ID).Delete(new(models.OauthPasswordReset)).Error; err != nil {
+		return err
+	}
+
+	// Owning the inbox proves the user is not the one failing to log in
+	if err := s.UnlockUser(user); err != nil {
+		s.logger.Errorf("Lifting lockout after password reset failed: %s", err)
+	}
+
+	s.logger.Info("audit: password reset", "user_id", user.ID, "tenant_id", user.TenantID)
+	return nil
+}
+
+// UseMailer replaces the configured mailer
+func (s *Service) UseMailer(mailer mail.Mailer) {
+	s.mailer = mailer
+}
+
+// findEmailToken looks up an unexpired email token by the token of its link
+func (s *Service) findEmailToken(token string, emailToken interface{}) error {
+	if token == "" {
+		return ErrInvalidEmailToken
+	}
+	notFound := s.db.Where("reference = ? AND expires_at > ?", emailTokenReference(token), time.Now().UTC()).
+		First(emailToken).RecordNotFound()
+	if notFound {
+		return ErrInvalidEmailToken
+	}
+	return nil
+}
+
+// markEmailSent records when the email of a token was sent
+func (s *Service) markEmailSent(emailToken interface{}) error {
+	return s.db.Model(emailToken).UpdateColumns(map[string]interface{}{
+		"email_sent":    true,
+		"email_sent_at": time.Now().UTC(),
+	}).Error
+}
+
+// allowEmail returns false within the send interval of the previous email
+// to an address
+func (s *Service) allowEmail(tenantID, email string) bool {
+	if s.cnf.Email.SendInterval < 1 {
+		return true
+	}
+	interval := time.Duration(s.cnf.Email.SendInterval) * time.Second
+	fresh, err := s.redis.SetNX(emailCooldownPrefix+tenantID+":"+email, 1, interval).Result()
+	if err != nil {
+		s.logger.Errorf("Checking email rate limit failed: %s", err)
+		return true
+	}
+	return fresh
+}
+
+// sendEmail renders a template and sends it to an address
+func (s *Service) sendEmail(to, template string, data *emailData) error {
+	templates, err := s.getMailTemplates()
+	if err != nil {
+		return err
+	}
+	msg, err := templates.Render(template, data)
+	if err != nil {
+		return err
+	}
+	msg.From = s.cnf.Email.From
+	msg.To = to
+	return s.mailer.Send(msg)
+}
+
+// getMailTemplates lazily loads the email templates
+func (s *Service) getMailTemplates() (*mail.Templates, error) {
+	s.lazy.mailTemplatesOnce.Do(func() {
+		dir := s.cnf.Email.TemplatesDir
+		if dir == "" {
+			dir = mail.DefaultTemplatesDir
+		}
+		s.lazy.mailTemplates, s.lazy.mailTemplatesErr = mail.LoadTemplates(dir)
+		if s.lazy.mailTemplatesErr != nil {
+			s.logger.Errorf("Loading email templates failed: %s", s.lazy.mailTemplatesErr)
+		}
+	})
+	return s.lazy.mailTemplates, s.lazy.mailTemplatesErr
+}
+
+// newMailer returns the configured mailer, unknown mailers drop emails into
+// the configured directory
+func newMailer(cnf *config.Config) mail.Mailer {
+	switch cnf.Email.Mailer {
+	case SMTPMailerName:
+		return mail.NewSMTPMailer(
+			cnf.Email.SMTPHost,
+			cnf.Email.SMTPPort,
+			cnf.Email.SMTPUsername,
+			cnf.Email.SMTPPassword,
+		)
+	case FileMailerName, "":
+	default:
+		logger.Errorf("Unknown mailer %q, using the file mailer", cnf.Email.Mailer)
+	}
+	return mail.NewFileMailer(cnf.Email.Dir)
+}
+
+// newEmailToken returns a random token for an emailed link and its
+// reference stored in the database
+func newEmailToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, emailTokenReference(token), nil
+}
+
+// emailTokenReference returns the SHA256 hash of a token, truncated to the
+// reference column, so stored references cannot be used as links
+func emailTokenReference(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])[:40]
+}
+
+// linkWithToken appends the token query parameter to a link
+func linkWithToken(link, token string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return link + "?token=" + url.QueryEscape(token)
+	}
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// formatLifetime returns a lifetime in seconds in words, e.g. "24 hours"
+func formatLifetime(seconds int) string {
+	switch {
+	case seconds >= 3600 && seconds%3600 == 0:
+		return pluralize(seconds/3600, "hour")
+	case seconds >= 60:
+		return pluralize((seconds+59)/60, "minute")
+	default:
+		return pluralize(seconds, "second")
+	}
+}
+
+func pluralize(n int, unit string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", unit)
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
@@ -0,0 +1,112 @@
+package oauth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/mail"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingMailer keeps the last message sent to each address
+type recordingMailer struct {
+	messages map[string]*mail.Message
+}
+
+func (m *recordingMailer) Send(msg *mail.Message) error {
+	m.messages[msg.To] = msg
+	return nil
+}
+
+var emailLinkPattern = regexp.MustCompile(`https?://\S+`)
+
+// emailToken returns the token of the link in the last email to an address
+func (m *recordingMailer) emailToken(email string) string {
+	msg, ok := m.messages[email]
+	if !ok {
+		return ""
+	}
+	link, err := url.Parse(emailLinkPattern.FindString(msg.Body))
+	if err != nil {
+		return ""
+	}
+	return link.Query().Get("token")
+}
+
+func (suite *OauthTestSuite) TestEmailVerificationAndPasswordReset() {
+	mailer := &recordingMailer{messages: make(map[string]*mail.Message)}
+	suite.service.UseMailer(mailer)
+	suite.cnf.Email.TemplatesDir = mail.DefaultTemplatesDir
+	suite.cnf.Email.SendInterval = 0
+
+	user, err := suite.service.CreateUser(roles.User, "test@email", "correct_horse_battery", "")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+
+	// Unverified addresses get no reset link
+	assert.NoError(suite.T(), suite.service.SetEmail(user, "John@Example.com"))
+	assert.Equal(suite.T(), "john@example.com", user.Email.String)
+	assert.NoError(suite.T(), suite.service.RequestPasswordReset(user.TenantID, "john@example.com"))
+	assert.Empty(suite.T(), mailer.messages)
+
+	// The confirmation link verifies the address once
+	assert.NoError(suite.T(), suite.service.SendEmailConfirmation(user))
+	token := mailer.emailToken("john@example.com")
+	if !assert.NotEmpty(suite.T(), token) {
+		return
+	}
+	w := suite.emailRequest("/v1/oauth/email/verification/confirm", `{"token": "`+token+`"}`)
+	assert.Equal(suite.T(), http.StatusNoContent, w.Code)
+	w = suite.emailRequest("/v1/oauth/email/verification/confirm", `{"token": "`+token+`"}`)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	user, err = suite.service.FindUserByEmailAndTenantID("john@example.com", user.TenantID)
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	assert.True(suite.T(), user.EmailVerified)
+	assert.Equal(suite.T(), oauth.ErrEmailAlreadyVerified, suite.service.SendEmailConfirmation(user))
+
+	// Unknown addresses get the same response but no email
+	delete(mailer.messages, "john@example.com")
+	w = suite.passwordResetRequest(`{"email": "nobody@example.com"}`)
+	assert.Equal(suite.T(), http.StatusNoContent, w.Code)
+	assert.Empty(suite.T(), mailer.messages)
+
+	w = suite.passwordResetRequest(`{"email": "john@example.com"}`)
+	assert.Equal(suite.T(), http.StatusNoContent, w.Code)
+	token = mailer.emailToken("john@example.com")
+	if !assert.NotEmpty(suite.T(), token) {
+		return
+	}
+
+	// The reset link sets a new password once
+	w = suite.emailRequest("/v1/oauth/password/reset/confirm", `{"token": "`+token+`", "new_password": "new_password"}`)
+	assert.Equal(suite.T(), http.StatusNoContent, w.Code)
+	w = suite.emailRequest("/v1/oauth/password/reset/confirm", `{"token": "`+token+`", "new_password": "other_password"}`)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	_, err = suite.service.AuthUser("test@email", "new_password", user.TenantID)
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *OauthTestSuite) passwordResetRequest(body string) *httptest.ResponseRecorder {
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/password/reset", strings.NewReader(body))
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	return w
+}
+
+func (suite *OauthTestSuite) emailRequest(path, body string) *httptest.ResponseRecorder {
+	r, err := http.NewRequest("POST", "http://1.2.3.4"+path, strings.NewReader(body))
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	return w
+}
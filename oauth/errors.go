@@ -2,28 +2,105 @@ package oauth
 
 import (
 	"net/http"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth/passwordpolicy"
+	"github.com/RichardKnop/go-oauth2-server/oauth/pkce"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
 )
 
 var (
 	errStatusCodeMap = map[error]int{
-		ErrAuthorizationCodeNotFound:     http.StatusNotFound,
-		ErrAuthorizationCodeExpired:      http.StatusBadRequest,
-		ErrInvalidRedirectURI:            http.StatusBadRequest,
-		ErrInvalidScope:                  http.StatusBadRequest,
-		ErrInvalidUsernameOrPassword:     http.StatusBadRequest,
-		ErrRefreshTokenNotFound:          http.StatusNotFound,
-		ErrRefreshTokenExpired:           http.StatusBadRequest,
-		ErrRequestedScopeCannotBeGreater: http.StatusBadRequest,
-		ErrTokenMissing:                  http.StatusNotFound,
-		ErrTokenHintInvalid:              http.StatusBadRequest,
-		ErrAccessTokenNotFound:           http.StatusNotFound,
-		ErrRefreshTokenNotFound:          http.StatusNotFound,
-		ErrInvalidUsernameOrPassword:     http.StatusUnauthorized,
-		ErrInvalidToken:                  http.StatusUnauthorized,
+		ErrAuthorizationCodeNotFound:          http.StatusNotFound,
+		ErrAuthorizationCodeExpired:           http.StatusBadRequest,
+		ErrInvalidRedirectURI:                 http.StatusBadRequest,
+		ErrInvalidScope:                       http.StatusBadRequest,
+		ErrInvalidUsernameOrPassword:          http.StatusBadRequest,
+		ErrRefreshTokenNotFound:               http.StatusNotFound,
+		ErrRefreshTokenExpired:                http.StatusBadRequest,
+		ErrRequestedScopeCannotBeGreater:      http.StatusBadRequest,
+		ErrTokenMissing:                       http.StatusNotFound,
+		ErrTokenHintInvalid:                   http.StatusBadRequest,
+		ErrAccessTokenNotFound:                http.StatusNotFound,
+		ErrRefreshTokenNotFound:               http.StatusNotFound,
+		ErrInvalidUsernameOrPassword:          http.StatusUnauthorized,
+		ErrInvalidToken:                       http.StatusUnauthorized,
+		ErrInvalidClientAssertionType:         http.StatusBadRequest,
+		ErrInvalidClientAssertion:             http.StatusUnauthorized,
+		ErrClientAssertionReplayed:            http.StatusUnauthorized,
+		ErrInsecureJWKSURI:                    http.StatusBadRequest,
+		ErrInsecureLogoutURI:                  http.StatusBadRequest,
+		ErrClientAuthMethodNotAllowed:         http.StatusUnauthorized,
+		ErrTokenBindingMismatch:               http.StatusUnauthorized,
+		ErrRefreshTokenBindingMismatch:        http.StatusBadRequest,
+		ErrInvalidDPoPProof:                   http.StatusBadRequest,
+		ErrDPoPProofReplayed:                  http.StatusBadRequest,
+		ErrInvalidResponseType:                http.StatusBadRequest,
+		ErrRequestURINotFound:                 http.StatusBadRequest,
+		ErrRequestURINotAllowed:               http.StatusBadRequest,
+		ErrCodeChallengeRequired:              http.StatusBadRequest,
+		ErrPushedAuthorizationRequestRequired: http.StatusBadRequest,
+		ErrInvalidCodeVerifier:                http.StatusBadRequest,
+		ErrInvalidClientMetadata:              http.StatusBadRequest,
+		ErrInitialAccessTokenRequired:         http.StatusUnauthorized,
+		ErrInvalidRegistrationAccessToken:     http.StatusUnauthorized,
+		ErrUnauthorizedGrantType:              http.StatusBadRequest,
+		ErrTenantNotFound:                     http.StatusBadRequest,
+		ErrTenantSuspended:                    http.StatusForbidden,
+		ErrTenantMismatch:                     http.StatusBadRequest,
+		ErrGrantTypeNotAllowedForTenant:       http.StatusBadRequest,
+		ErrUserNotFound:                       http.StatusNotFound,
+		ErrUserDisabled:                       http.StatusForbidden,
+		ErrUsernameTaken:                      http.StatusConflict,
+		ErrPhoneTaken:                         http.StatusConflict,
+		ErrInvalidRole:                        http.StatusBadRequest,
+		ErrRoleNotFound:                       http.StatusNotFound,
+		ErrInvalidRoleName:                    http.StatusBadRequest,
+		ErrRoleNameTaken:                      http.StatusConflict,
+		ErrBuiltInRole:                        http.StatusForbidden,
+		roles.ErrInvalidPermission:            http.StatusBadRequest,
+		ErrPasswordExpired:                    http.StatusForbidden,
+		ErrCannotSetEmptyUsername:             http.StatusBadRequest,
+		ErrInvalidMFAToken:                    http.StatusBadRequest,
+		ErrInvalidOTP:                         http.StatusForbidden,
+		ErrInvalidRecoveryCode:                http.StatusForbidden,
+		ErrMFANotEnrolled:                     http.StatusBadRequest,
+		ErrMFAAlreadyEnrolled:                 http.StatusConflict,
+		ErrMFAEnrollmentNotStarted:            http.StatusBadRequest,
+		ErrMFAAttemptsExceeded:                http.StatusForbidden,
+		ErrInvalidPhone:                       http.StatusBadRequest,
+		ErrInvalidLoginCode:                   http.StatusBadRequest,
+		ErrInvalidEmail:                       http.StatusBadRequest,
+		ErrEmailTaken:                         http.StatusConflict,
+		ErrEmailNotSet:                        http.StatusBadRequest,
+		ErrEmailAlreadyVerified:               http.StatusConflict,
+		ErrEmailRateLimited:                   http.StatusTooManyRequests,
+		ErrInvalidEmailToken:                  http.StatusBadRequest,
+		ErrConsentRequired:                    http.StatusForbidden,
+		ErrConsentNotFound:                    http.StatusNotFound,
+		ErrLoginRequired:                      http.StatusUnauthorized,
+		ErrInvalidPrompt:                      http.StatusBadRequest,
+		ErrInvalidMaxAge:                      http.StatusBadRequest,
+		ErrInvalidIDTokenHint:                 http.StatusBadRequest,
+		ErrInvalidPostLogoutRedirectURI:       http.StatusBadRequest,
+		pkce.ErrInvalidCodeChallenge:          http.StatusBadRequest,
+		pkce.ErrInvalidCodeChallengeMethod:    http.StatusBadRequest,
 	}
 )
 
+// ErrStatusCode returns the HTTP status code of an oauth service error
+func ErrStatusCode(err error) int {
+	return getErrStatusCode(err)
+}
+
 func getErrStatusCode(err error) int {
+	switch err.(type) {
+	case *LockoutError, *LoginCodeRateLimitError:
+		return http.StatusTooManyRequests
+	case *MFARequiredError:
+		return http.StatusForbidden
+	case *passwordpolicy.Error:
+		return http.StatusBadRequest
+	}
 	code, ok := errStatusCodeMap[err]
 	if ok {
 		return code
@@ -0,0 +1,28 @@
+package oauth
+
+import (
+	"net/http"
+)
+
+// defaultJWKsHTTPClient is restored by UseJWKsHTTPClient(nil)
+var defaultJWKsHTTPClient = jwksHTTPClient
+
+// UseBackchannelLogoutClient replaces the client logout tokens are posted
+// with, the default one cannot reach the local test servers. Nil restores
+// the default client.
+func (s *Service) UseBackchannelLogoutClient(client *http.Client) {
+	if client == nil {
+		client = newBackchannelLogoutClient(s.cnf)
+	}
+	s.backchannelLogoutClient = client
+}
+
+// UseJWKsHTTPClient replaces the client jwks_uri is fetched with, the
+// default one cannot reach the local test servers. Nil restores the default
+// client.
+func UseJWKsHTTPClient(client *http.Client) {
+	if client == nil {
+		client = defaultJWKsHTTPClient
+	}
+	jwksHTTPClient = client
+}
@@ -2,6 +2,9 @@ package oauth
 
 import (
 	"errors"
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/audit"
 	"github.com/RichardKnop/go-oauth2-server/models"
 	"github.com/RichardKnop/go-oauth2-server/oauth/tokentypes"
 )
@@ -16,17 +19,32 @@ func (s *Service) authorizationCodeGrant(grantDTO *GrantDTO, client *models.Oaut
 	authorizationCode, err := s.getValidAuthorizationCode(
 		grantDTO.Code,
 		grantDTO.RedirectURI,
+		grantDTO.CodeVerifier,
 		client,
 	)
 	if err != nil {
 		return nil, err
 	}
+	if authorizationCode.User != nil {
+		grantDTO.UserID = authorizationCode.User.ID
+	}
+
+	// Users with MFA continue with the mfa_token, the code cannot be used
+	// again, unless they used a second factor to log in already
+	if authorizationCode.User != nil && mfaRequired(grantDTO.Tenant, authorizationCode.User) &&
+		!strings.Contains(" "+authorizationCode.AMR+" ", " otp ") {
+		s.db.Unscoped().Delete(&authorizationCode)
+		return nil, s.newMFARequiredError(client, authorizationCode.User, authorizationCode.Scope)
+	}
 
 	// Log in the user
-	accessToken, refreshToken, err := s.Login(
+	accessToken, refreshToken, err := s.login(
+		grantDTO.Tenant,
 		authorizationCode.Client,
 		authorizationCode.User,
 		authorizationCode.Scope,
+		grantDTO.Binding,
+		audit.TokenIssued,
 	)
 	if err != nil {
 		return nil, err
@@ -35,13 +53,27 @@ func (s *Service) authorizationCodeGrant(grantDTO *GrantDTO, client *models.Oaut
 	// Delete the authorization code
 	s.db.Unscoped().Delete(&authorizationCode)
 
+	var jwt string
+	if s.cnf.Oauth.Jwt {
+		jwt, err = s.grantJWT(
+			authorizationCode.User,
+			grantDTO.Tenant.AccessTokenLifetime,
+			accessToken.Scope,
+			accessToken,
+			authorizationCode,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Create response
 	accessTokenResponse, err := NewAccessTokenResponse(
 		accessToken,
 		refreshToken,
-		s.cnf.Oauth.AccessTokenLifetime,
+		grantDTO.Tenant.AccessTokenLifetime,
 		tokentypes.Bearer,
-		"",
+		jwt,
 	)
 	if err != nil {
 		return nil, err
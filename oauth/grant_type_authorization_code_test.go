@@ -17,13 +17,12 @@ import (
 
 func (suite *OauthTestSuite) TestAuthorizationCodeGrantEmptyNotFound() {
 	// Prepare a request
-	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
-	assert.NoError(suite.T(), err, "Request setup should not get an error")
-	r.SetBasicAuth("test_client_1", "test_secret")
-	r.PostForm = url.Values{
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/token", tokenRequestBody(url.Values{
 		"grant_type": {"authorization_code"},
 		"code":       {""},
-	}
+	}))
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
 
 	// Serve the request
 	w := httptest.NewRecorder()
@@ -40,13 +39,12 @@ func (suite *OauthTestSuite) TestAuthorizationCodeGrantEmptyNotFound() {
 
 func (suite *OauthTestSuite) TestAuthorizationCodeGrantBogusNotFound() {
 	// Prepare a request
-	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
-	assert.NoError(suite.T(), err, "Request setup should not get an error")
-	r.SetBasicAuth("test_client_1", "test_secret")
-	r.PostForm = url.Values{
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/token", tokenRequestBody(url.Values{
 		"grant_type": {"authorization_code"},
 		"code":       {"bogus"},
-	}
+	}))
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
 
 	// Serve the request
 	w := httptest.NewRecorder()
@@ -78,14 +76,13 @@ func (suite *OauthTestSuite) TestAuthorizationCodeGrantExpired() {
 	assert.NoError(suite.T(), err, "Inserting test data failed")
 
 	// Prepare a request
-	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
-	assert.NoError(suite.T(), err, "Request setup should not get an error")
-	r.SetBasicAuth("test_client_1", "test_secret")
-	r.PostForm = url.Values{
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/token", tokenRequestBody(url.Values{
 		"grant_type":   {"authorization_code"},
 		"code":         {"test_code"},
 		"redirect_uri": {"https://www.example.com"},
-	}
+	}))
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
 
 	// Serve the request
 	w := httptest.NewRecorder()
@@ -117,14 +114,13 @@ func (suite *OauthTestSuite) TestAuthorizationCodeGrantInvalidRedirectURI() {
 	assert.NoError(suite.T(), err, "Inserting test data failed")
 
 	// Prepare a request
-	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
-	assert.NoError(suite.T(), err, "Request setup should not get an error")
-	r.SetBasicAuth("test_client_1", "test_secret")
-	r.PostForm = url.Values{
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/token", tokenRequestBody(url.Values{
 		"grant_type":   {"authorization_code"},
 		"code":         {"test_code"},
 		"redirect_uri": {"https://bogus"},
-	}
+	}))
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
 
 	// Serve the request
 	w := httptest.NewRecorder()
@@ -156,14 +152,13 @@ func (suite *OauthTestSuite) TestAuthorizationCodeGrant() {
 	assert.NoError(suite.T(), err, "Inserting test data failed")
 
 	// Prepare a request
-	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
-	assert.NoError(suite.T(), err, "Request setup should not get an error")
-	r.SetBasicAuth("test_client_1", "test_secret")
-	r.PostForm = url.Values{
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/token", tokenRequestBody(url.Values{
 		"grant_type":   {"authorization_code"},
 		"code":         {"test_code"},
 		"redirect_uri": {"https://www.example.com"},
-	}
+	}))
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
 
 	// Serve the request
 	w := httptest.NewRecorder()
@@ -178,14 +173,13 @@ func (suite *OauthTestSuite) TestAuthorizationCodeGrant() {
 
 	// Check the response
 	expected := &oauth.AccessTokenResponse{
-		UserID:       accessToken.UserID.String,
 		AccessToken:  accessToken.Token,
 		ExpiresIn:    3600,
 		TokenType:    tokentypes.Bearer,
 		Scope:        "read_write",
 		RefreshToken: refreshToken.Token,
 	}
-	testutil.TestResponseObject(suite.T(), w, expected, 200)
+	suite.testIDTokenResponse(w, expected)
 
 	// The authorization code should get deleted after use
 	assert.True(suite.T(), suite.db.Unscoped().
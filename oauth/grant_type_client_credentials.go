@@ -1,23 +1,26 @@
 package oauth
 
 import (
+	"github.com/RichardKnop/go-oauth2-server/audit"
 	"github.com/RichardKnop/go-oauth2-server/models"
 	"github.com/RichardKnop/go-oauth2-server/oauth/tokentypes"
 )
 
 func (s *Service) clientCredentialsGrant(grantDTO *GrantDTO, client *models.OauthClient) (*AccessTokenResponse, error) {
 	// Get the scope string
-	scope, err := s.GetScope(grantDTO.Scope)
+	scope, err := s.getClientScope(client, grantDTO.Scope)
 	if err != nil {
 		return nil, err
 	}
 
 	// Create a new access token
-	accessToken, err := s.GrantAccessToken(
+	accessToken, err := s.grantAccessToken(
 		client,
-		nil,                             // empty user
-		s.cnf.Oauth.AccessTokenLifetime, // expires in
+		nil,                                 // empty user
+		grantDTO.Tenant.AccessTokenLifetime, // expires in
 		scope,
+		grantDTO.Binding,
+		audit.TokenIssued,
 	)
 	if err != nil {
 		return nil, err
@@ -27,7 +30,7 @@ func (s *Service) clientCredentialsGrant(grantDTO *GrantDTO, client *models.Oaut
 	accessTokenResponse, err := NewAccessTokenResponse(
 		accessToken,
 		nil, // refresh token
-		s.cnf.Oauth.AccessTokenLifetime,
+		grantDTO.Tenant.AccessTokenLifetime,
 		tokentypes.Bearer,
 		"",
 	)
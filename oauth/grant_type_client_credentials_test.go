@@ -14,13 +14,12 @@ import (
 
 func (suite *OauthTestSuite) TestClientCredentialsGrant() {
 	// Prepare a request
-	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
-	assert.NoError(suite.T(), err, "Request setup should not get an error")
-	r.SetBasicAuth("test_client_1", "test_secret")
-	r.PostForm = url.Values{
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/token", tokenRequestBody(url.Values{
 		"grant_type": {"client_credentials"},
 		"scope":      {"read_write"},
-	}
+	}))
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
 
 	// Serve the request
 	w := httptest.NewRecorder()
@@ -0,0 +1,46 @@
+package oauth
+
+import (
+	"github.com/RichardKnop/go-oauth2-server/models"
+)
+
+func (s *Service) mfaOTPGrant(grantDTO *GrantDTO, client *models.OauthClient) (*AccessTokenResponse, error) {
+	// Fetch the pending login
+	challenge, user, err := s.getMFAChallengeUser(grantDTO.MFAToken, client)
+	if err != nil {
+		return nil, err
+	}
+	grantDTO.UserID = user.ID
+
+	// Verify the code of the user's authenticator
+	if err := s.verifyOTP(user, grantDTO.OTP); err != nil {
+		if err == ErrInvalidOTP {
+			s.recordMFAFailure(grantDTO.MFAToken)
+		}
+		return nil, err
+	}
+
+	return s.completeMFALogin(grantDTO, client, user, challenge)
+}
+
+func (s *Service) mfaRecoveryCodeGrant(grantDTO *GrantDTO, client *models.OauthClient) (*AccessTokenResponse, error) {
+	// Fetch the pending login
+	challenge, user, err := s.getMFAChallengeUser(grantDTO.MFAToken, client)
+	if err != nil {
+		return nil, err
+	}
+	grantDTO.UserID = user.ID
+	if !user.MFAEnabled {
+		return nil, ErrMFANotEnrolled
+	}
+
+	// Recovery codes can only be used once
+	if err := s.useRecoveryCode(user, grantDTO.RecoveryCode); err != nil {
+		if err == ErrInvalidRecoveryCode {
+			s.recordMFAFailure(grantDTO.MFAToken)
+		}
+		return nil, err
+	}
+
+	return s.completeMFALogin(grantDTO, client, user, challenge)
+}
@@ -2,6 +2,8 @@ package oauth
 
 import (
 	"errors"
+
+	"github.com/RichardKnop/go-oauth2-server/audit"
 	"github.com/RichardKnop/go-oauth2-server/models"
 	"github.com/RichardKnop/go-oauth2-server/oauth/tokentypes"
 )
@@ -13,29 +15,50 @@ var (
 
 func (s *Service) passwordGrant(grantDTO *GrantDTO, client *models.OauthClient) (*AccessTokenResponse, error) {
 	// Get the scope string
-	scope, err := s.GetScope(grantDTO.Scope)
+	scope, err := s.getClientScope(client, grantDTO.Scope)
 	if err != nil {
 		return nil, err
 	}
 
-	// Authenticate the user
+	// Refuse locked out accounts and client IPs before hashing the password
+	if err := s.checkLoginLockout(client.TenantID, grantDTO.Username, grantDTO.ClientIP); err != nil {
+		return nil, err
+	}
+
+	// Authenticate the user in the client's tenant
 	// username is account or phone
-	user, err := s.AuthUser(grantDTO.Username, grantDTO.Password, grantDTO.TenantID)
+	user, err := s.AuthUser(grantDTO.Username, grantDTO.Password, client.TenantID)
+	if err == ErrPasswordExpired {
+		// The password was right, the user must change it first
+		return nil, err
+	}
 	if err != nil {
+		if err := s.recordLoginFailure(client.TenantID, grantDTO.Username, grantDTO.ClientIP); err != nil {
+			s.logger.Errorf("Recording failed login failed: %s", err)
+		}
 		// For security reasons, return a general error message
 		return nil, ErrInvalidUsernameOrPassword
 	}
+	grantDTO.UserID = user.ID
+	if err := s.clearLoginFailures(client.TenantID, grantDTO.Username); err != nil {
+		s.logger.Errorf("Clearing failed logins failed: %s", err)
+	}
+
+	// Users with MFA continue with the mfa_token
+	if mfaRequired(grantDTO.Tenant, user) {
+		return nil, s.newMFARequiredError(client, user, scope)
+	}
 
 	// Log in the user
 	// oauth access token
-	accessToken, refreshToken, err := s.Login(client, user, scope)
+	accessToken, refreshToken, err := s.login(grantDTO.Tenant, client, user, scope, grantDTO.Binding, audit.TokenIssued)
 	if err != nil {
 		return nil, err
 	}
 
 	var jwt string
 	if s.cnf.Oauth.Jwt {
-		jwt, err = s.GrantJWT(user, s.cnf.Oauth.AccessTokenLifetime, scope, accessToken.Token)
+		jwt, err = s.GrantJWT(user, grantDTO.Tenant.AccessTokenLifetime, accessToken.Scope, accessToken)
 		if err != nil {
 			return nil, err
 		}
@@ -45,7 +68,7 @@ func (s *Service) passwordGrant(grantDTO *GrantDTO, client *models.OauthClient)
 	accessTokenResponse, err := NewAccessTokenResponse(
 		accessToken,
 		refreshToken,
-		s.cnf.Oauth.AccessTokenLifetime,
+		grantDTO.Tenant.AccessTokenLifetime,
 		tokentypes.Bearer,
 		jwt,
 	)
@@ -8,21 +8,19 @@ import (
 	"github.com/RichardKnop/go-oauth2-server/models"
 	"github.com/RichardKnop/go-oauth2-server/oauth"
 	"github.com/RichardKnop/go-oauth2-server/oauth/tokentypes"
-	"github.com/RichardKnop/go-oauth2-server/test-util"
 	"github.com/stretchr/testify/assert"
 )
 
 func (suite *OauthTestSuite) TestPasswordGrant() {
 	// Prepare a request
-	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
-	assert.NoError(suite.T(), err, "Request setup should not get an error")
-	r.SetBasicAuth("test_client_1", "test_secret")
-	r.PostForm = url.Values{
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/token", tokenRequestBody(url.Values{
 		"grant_type": {"password"},
 		"username":   {"test@user"},
 		"password":   {"test_password"},
 		"scope":      {"read_write"},
-	}
+	}))
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
 
 	// Serve the request
 	w := httptest.NewRecorder()
@@ -43,5 +41,5 @@ func (suite *OauthTestSuite) TestPasswordGrant() {
 		Scope:        "read_write",
 		RefreshToken: refreshToken.Token,
 	}
-	testutil.TestResponseObject(suite.T(), w, expected, 200)
+	suite.testIDTokenResponse(w, expected)
 }
@@ -0,0 +1,69 @@
+package oauth
+
+import (
+	"github.com/RichardKnop/go-oauth2-server/audit"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth/tokentypes"
+)
+
+func (s *Service) passwordlessOTPGrant(grantDTO *GrantDTO, client *models.OauthClient) (*AccessTokenResponse, error) {
+	// Get the scope string
+	scope, err := s.getClientScope(client, grantDTO.Scope)
+	if err != nil {
+		return nil, err
+	}
+
+	// Wrong codes count towards the login lockout like wrong passwords
+	if err := s.checkLoginLockout(client.TenantID, grantDTO.Username, grantDTO.ClientIP); err != nil {
+		return nil, err
+	}
+
+	// Verify the code sent to the phone, username is the phone
+	if err := s.verifyLoginCode(client.TenantID, grantDTO.Username, grantDTO.OTP); err != nil {
+		if err == ErrInvalidLoginCode {
+			if err := s.recordLoginFailure(client.TenantID, grantDTO.Username, grantDTO.ClientIP); err != nil {
+				s.logger.Errorf("Recording failed login failed: %s", err)
+			}
+		}
+		return nil, err
+	}
+	user, err := s.FindUserByPhoneAndTenantID(grantDTO.Username, client.TenantID)
+	if err != nil {
+		return nil, ErrInvalidLoginCode
+	}
+	grantDTO.UserID = user.ID
+	if user.Disabled {
+		return nil, ErrUserDisabled
+	}
+	if err := s.clearLoginFailures(client.TenantID, grantDTO.Username); err != nil {
+		s.logger.Errorf("Clearing failed logins failed: %s", err)
+	}
+
+	// Users with MFA continue with the mfa_token
+	if mfaRequired(grantDTO.Tenant, user) {
+		return nil, s.newMFARequiredError(client, user, scope)
+	}
+
+	// Log in the user
+	accessToken, refreshToken, err := s.login(grantDTO.Tenant, client, user, scope, grantDTO.Binding, audit.TokenIssued)
+	if err != nil {
+		return nil, err
+	}
+
+	var jwt string
+	if s.cnf.Oauth.Jwt {
+		jwt, err = s.GrantJWT(user, grantDTO.Tenant.AccessTokenLifetime, accessToken.Scope, accessToken)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Create response
+	return NewAccessTokenResponse(
+		accessToken,
+		refreshToken,
+		grantDTO.Tenant.AccessTokenLifetime,
+		tokentypes.Bearer,
+		jwt,
+	)
+}
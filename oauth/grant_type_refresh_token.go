@@ -1,6 +1,7 @@
 package oauth
 
 import (
+	"github.com/RichardKnop/go-oauth2-server/audit"
 	"github.com/RichardKnop/go-oauth2-server/models"
 	"github.com/RichardKnop/go-oauth2-server/oauth/tokentypes"
 )
@@ -12,6 +13,16 @@ func (s *Service) refreshTokenGrant(grantDTO *GrantDTO, client *models.OauthClie
 		return nil, err
 	}
 
+	// DPoP bound refresh tokens need a proof of the same key
+	if theRefreshToken.JKT.Valid &&
+		(grantDTO.Binding == nil || grantDTO.Binding.JKT != theRefreshToken.JKT.String) {
+		return nil, ErrRefreshTokenBindingMismatch
+	}
+
+	if theRefreshToken.User != nil {
+		grantDTO.UserID = theRefreshToken.User.ID
+	}
+
 	// Get the scope
 	scope, err := s.getRefreshTokenScope(theRefreshToken, grantDTO.Scope)
 	if err != nil {
@@ -19,10 +30,13 @@ func (s *Service) refreshTokenGrant(grantDTO *GrantDTO, client *models.OauthClie
 	}
 
 	// Log in the user
-	accessToken, refreshToken, err := s.Login(
+	accessToken, refreshToken, err := s.login(
+		grantDTO.Tenant,
 		theRefreshToken.Client,
 		theRefreshToken.User,
 		scope,
+		grantDTO.Binding,
+		audit.TokenRefreshed,
 	)
 	if err != nil {
 		return nil, err
@@ -32,7 +46,7 @@ func (s *Service) refreshTokenGrant(grantDTO *GrantDTO, client *models.OauthClie
 	accessTokenResponse, err := NewAccessTokenResponse(
 		accessToken,
 		refreshToken,
-		s.cnf.Oauth.AccessTokenLifetime,
+		grantDTO.Tenant.AccessTokenLifetime,
 		tokentypes.Bearer,
 		"",
 	)
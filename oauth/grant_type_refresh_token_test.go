@@ -10,19 +10,19 @@ import (
 	"github.com/RichardKnop/go-oauth2-server/oauth"
 	"github.com/RichardKnop/go-oauth2-server/oauth/tokentypes"
 	"github.com/RichardKnop/go-oauth2-server/test-util"
+	"github.com/RichardKnop/go-oauth2-server/util"
 	"github.com/RichardKnop/uuid"
 	"github.com/stretchr/testify/assert"
 )
 
 func (suite *OauthTestSuite) TestRefreshTokenGrantEmptyNotFound() {
 	// Prepare a request
-	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
-	assert.NoError(suite.T(), err, "Request setup should not get an error")
-	r.SetBasicAuth("test_client_1", "test_secret")
-	r.PostForm = url.Values{
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/token", tokenRequestBody(url.Values{
 		"grant_type":    {"refresh_token"},
 		"refresh_token": {""},
-	}
+	}))
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
 
 	// Serve the request
 	w := httptest.NewRecorder()
@@ -39,13 +39,12 @@ func (suite *OauthTestSuite) TestRefreshTokenGrantEmptyNotFound() {
 
 func (suite *OauthTestSuite) TestRefreshTokenGrantBogusNotFound() {
 	// Prepare a request
-	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
-	assert.NoError(suite.T(), err, "Request setup should not get an error")
-	r.SetBasicAuth("test_client_1", "test_secret")
-	r.PostForm = url.Values{
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/token", tokenRequestBody(url.Values{
 		"grant_type":    {"refresh_token"},
 		"refresh_token": {"bogus_token"},
-	}
+	}))
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
 
 	// Serve the request
 	w := httptest.NewRecorder()
@@ -60,6 +59,43 @@ func (suite *OauthTestSuite) TestRefreshTokenGrantBogusNotFound() {
 	)
 }
 
+func (suite *OauthTestSuite) TestRefreshTokenGrantDPoPProofRequired() {
+	// Insert a test refresh token bound to a DPoP key
+	err := suite.db.Create(&models.OauthRefreshToken{
+		MyGormModel: models.MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		Token:     "test_dpop_token",
+		ExpiresAt: time.Now().UTC().Add(+10 * time.Second),
+		Client:    suite.clients[0],
+		User:      suite.users[0],
+		Scope:     "read_write",
+		JKT:       util.StringOrNull("0ZcOCORZNYy-DWpqq30jZyJGHTN0d2HglBV3uiguA4I"),
+	}).Error
+	assert.NoError(suite.T(), err, "Inserting test data failed")
+
+	// Prepare a request without a DPoP proof
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/token", tokenRequestBody(url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {"test_dpop_token"},
+	}))
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
+
+	// Serve the request
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+
+	// Check the response
+	testutil.TestResponseForError(
+		suite.T(),
+		w,
+		oauth.ErrRefreshTokenBindingMismatch.Error(),
+		400,
+	)
+}
+
 func (suite *OauthTestSuite) TestRefreshTokenGrantExipired() {
 	// Insert a test refresh token
 	err := suite.db.Create(&models.OauthRefreshToken{
@@ -76,14 +112,13 @@ func (suite *OauthTestSuite) TestRefreshTokenGrantExipired() {
 	assert.NoError(suite.T(), err, "Inserting test data failed")
 
 	// Prepare a request
-	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
-	assert.NoError(suite.T(), err, "Request setup should not get an error")
-	r.SetBasicAuth("test_client_1", "test_secret")
-	r.PostForm = url.Values{
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/token", tokenRequestBody(url.Values{
 		"grant_type":    {"refresh_token"},
 		"refresh_token": {"test_token"},
 		"scope":         {"read read_write"},
-	}
+	}))
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
 
 	// Serve the request
 	w := httptest.NewRecorder()
@@ -114,14 +149,13 @@ func (suite *OauthTestSuite) TestRefreshTokenGrantScopeCannotBeGreater() {
 	assert.NoError(suite.T(), err, "Inserting test data failed")
 
 	// Prepare a request
-	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
-	assert.NoError(suite.T(), err, "Request setup should not get an error")
-	r.SetBasicAuth("test_client_1", "test_secret")
-	r.PostForm = url.Values{
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/token", tokenRequestBody(url.Values{
 		"grant_type":    {"refresh_token"},
 		"refresh_token": {"test_token"},
 		"scope":         {"read read_write"},
-	}
+	}))
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
 
 	// Serve the request
 	w := httptest.NewRecorder()
@@ -152,13 +186,12 @@ func (suite *OauthTestSuite) TestRefreshTokenGrantDefaultsToOriginalScope() {
 	assert.NoError(suite.T(), err, "Inserting test data failed")
 
 	// Make a request
-	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
-	assert.NoError(suite.T(), err, "Request setup should not get an error")
-	r.SetBasicAuth("test_client_1", "test_secret")
-	r.PostForm = url.Values{
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/token", tokenRequestBody(url.Values{
 		"grant_type":    {"refresh_token"},
 		"refresh_token": {"test_token"},
-	}
+	}))
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
 
 	// Serve the request
 	w := httptest.NewRecorder()
@@ -171,7 +204,6 @@ func (suite *OauthTestSuite) TestRefreshTokenGrantDefaultsToOriginalScope() {
 
 	// Check the response body
 	expected := &oauth.AccessTokenResponse{
-		UserID:       accessToken.UserID.String,
 		AccessToken:  accessToken.Token,
 		ExpiresIn:    3600,
 		TokenType:    tokentypes.Bearer,
@@ -197,14 +229,13 @@ func (suite *OauthTestSuite) TestRefreshTokenGrant() {
 	assert.NoError(suite.T(), err, "Inserting test data failed")
 
 	// Make a request
-	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
-	assert.NoError(suite.T(), err, "Request setup should not get an error")
-	r.SetBasicAuth("test_client_1", "test_secret")
-	r.PostForm = url.Values{
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/token", tokenRequestBody(url.Values{
 		"grant_type":    {"refresh_token"},
 		"refresh_token": {"test_token"},
 		"scope":         {"read_write"},
-	}
+	}))
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
 
 	// Serve the request
 	w := httptest.NewRecorder()
@@ -217,7 +248,6 @@ func (suite *OauthTestSuite) TestRefreshTokenGrant() {
 
 	// Check the response
 	expected := &oauth.AccessTokenResponse{
-		UserID:       accessToken.UserID.String,
 		AccessToken:  accessToken.Token,
 		ExpiresIn:    3600,
 		TokenType:    tokentypes.Bearer,
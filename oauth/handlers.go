@@ -4,10 +4,19 @@ import (
 	"encoding/json"
 	"errors"
 	"io/ioutil"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth/authmethods"
+	"github.com/RichardKnop/go-oauth2-server/oauth/passwordpolicy"
+	"github.com/RichardKnop/go-oauth2-server/tracing"
+	"github.com/RichardKnop/go-oauth2-server/util"
 	"github.com/RichardKnop/go-oauth2-server/util/response"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 var (
@@ -23,16 +32,36 @@ type GrantDTO struct {
 	Username     string
 	TenantID     string `json:"tenant_id"`
 	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
 	Secret       string
 	Scope        string
 	Code         string
 	RedirectURI  string `json:"redirect_uri"`
 	RefreshToken string `json:"refresh_token"`
+	CodeVerifier string `json:"code_verifier"`
+	// Client assertion authentication (RFC 7523)
+	ClientAssertionType string `json:"client_assertion_type"`
+	ClientAssertion     string `json:"client_assertion"`
+	// Second factor of a login pending MFA
+	MFAToken     string `json:"mfa_token"`
+	OTP          string `json:"otp"`
+	RecoveryCode string `json:"recovery_code"`
+	// Binding is the key issued access tokens are bound to, set by the handler
+	Binding *TokenBinding `json:"-"`
+	// Tenant is the config of the client's tenant, set by the handler
+	Tenant *TenantConfig `json:"-"`
+	// ClientIP is the IP failed logins are counted for, set by the handler
+	ClientIP string `json:"-"`
+	// UserID is the user tokens are issued to, set by the grant
+	UserID string `json:"-"`
 }
 
 // tokensHandler handles all OAuth 2.0 grant types
 // (POST /v1/oauth/token)
 func (s *Service) tokensHandler(w http.ResponseWriter, r *http.Request) {
+	// Trace the queries of the request
+	s = s.withContext(r.Context())
+
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		response.Error(w, err.Error(), getErrStatusCode(err))
@@ -47,11 +76,14 @@ func (s *Service) tokensHandler(w http.ResponseWriter, r *http.Request) {
 	grantType := grantDTO.GrantType
 
 	// Map of grant types against handler functions
-	grantTypes := map[string]func(grantDTO *GrantDTO, client *models.OauthClient) (*AccessTokenResponse, error){
-		"authorization_code": s.authorizationCodeGrant,
-		"password":           s.passwordGrant,
-		"client_credentials": s.clientCredentialsGrant,
-		"refresh_token":      s.refreshTokenGrant,
+	grantTypes := map[string]func(s *Service, grantDTO *GrantDTO, client *models.OauthClient) (*AccessTokenResponse, error){
+		"authorization_code":     (*Service).authorizationCodeGrant,
+		"password":               (*Service).passwordGrant,
+		"client_credentials":     (*Service).clientCredentialsGrant,
+		"refresh_token":          (*Service).refreshTokenGrant,
+		PasswordlessOTPGrantType: (*Service).passwordlessOTPGrant,
+		MFAOTPGrantType:          (*Service).mfaOTPGrant,
+		MFARecoveryCodeGrantType: (*Service).mfaRecoveryCodeGrant,
 	}
 
 	// Check the grant type
@@ -62,18 +94,62 @@ func (s *Service) tokensHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Client auth
-	client, err := s.GetClient(grantDTO.ClientID)
+	client, err := s.tokenEndpointClient(r, &grantDTO)
 	if err != nil {
+		tenantID, _ := requestTenantID(r, grantDTO.TenantID)
+		s.recordClientAuthFailure(r, grantDTO.ClientID, tenantID, err)
+		observeAuthFailure(err)
 		response.UnauthorizedError(w, err.Error())
 		return
 	}
 
-	// Grant processing
-	resp, err := grantHandler(&grantDTO, client)
+	// The client's tenant must be active and allow the grant type
+	grantDTO.TenantID, err = requestTenantID(r, grantDTO.TenantID)
 	if err != nil {
 		response.Error(w, err.Error(), getErrStatusCode(err))
 		return
 	}
+	grantDTO.Tenant, err = s.getClientTenantConfig(client, grantDTO.TenantID)
+	if err != nil {
+		response.Error(w, err.Error(), getErrStatusCode(err))
+		return
+	}
+	// MFA grants complete a login of a grant that was already allowed
+	mfaGrant := isMFAGrantType(grantType)
+	if !mfaGrant && !grantDTO.Tenant.AllowsGrantType(grantType) {
+		response.Error(w, ErrGrantTypeNotAllowedForTenant.Error(), getErrStatusCode(ErrGrantTypeNotAllowedForTenant))
+		return
+	}
+
+	// Registered clients are limited to their grant types
+	if !mfaGrant && !clientAllowsGrantType(client, grantType) {
+		response.Error(w, ErrUnauthorizedGrantType.Error(), getErrStatusCode(ErrUnauthorizedGrantType))
+		return
+	}
+
+	// Bind access tokens to the client certificate or DPoP key
+	grantDTO.Binding, err = s.newTokenBinding(r, client)
+	if err != nil {
+		response.Error(w, err.Error(), getErrStatusCode(err))
+		return
+	}
+
+	// Grant processing
+	grantDTO.ClientIP = util.GetClientIP(r)
+	ctx, span := tracing.Start(
+		r.Context(),
+		"oauth.grant "+grantType,
+		attribute.String("oauth.grant_type", grantType),
+		attribute.String("oauth.client_id", client.Key),
+	)
+	resp, err := grantHandler(s.withContext(ctx), &grantDTO, client)
+	tracing.End(span, err)
+	s.recordGrant(r, &grantDTO, client, resp, err)
+	observeGrant(&grantDTO, client, err)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
 
 	// Write response to json
 	response.WriteJSON(w, resp, 200)
@@ -82,6 +158,9 @@ func (s *Service) tokensHandler(w http.ResponseWriter, r *http.Request) {
 // introspectHandler handles OAuth 2.0 introspect request
 // (POST /v1/oauth/introspect)
 func (s *Service) introspectHandler(w http.ResponseWriter, r *http.Request) {
+	// Trace the queries of the request
+	s = s.withContext(r.Context())
+
 	// Client auth
 	client, err := s.basicAuthClient(r)
 	if err != nil {
@@ -89,6 +168,12 @@ func (s *Service) introspectHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Clients can only introspect on their tenant's issuer
+	if err := checkPathTenant(r, client); err != nil {
+		response.Error(w, err.Error(), getErrStatusCode(err))
+		return
+	}
+
 	// Introspect the token
 	resp, err := s.introspectToken(r, client)
 	if err != nil {
@@ -105,18 +190,116 @@ func (s *Service) introspectHandler(w http.ResponseWriter, r *http.Request) {
 func (s *Service) revokeHandler(w http.ResponseWriter, r *http.Request) {
 	r.ParseForm()
 	token := r.PostFormValue("token")
-	if err := s.revokeToken(token); err != nil {
+	if err := s.revokeToken(r, token); err != nil {
 		response.WriteJSON(w, err.Error(), getErrStatusCode(err))
 	}
 	response.WriteJSON(w, nil, 200)
 }
 
-// Get client credentials from basic auth and try to authenticate client
+// Authenticates the client of a token request with a client assertion if one
+// was sent or a client certificate if the client registered for mutual TLS.
+// Public clients can only redeem authorization codes with a PKCE verifier,
+// the other clients authenticate with their secret, sent with basic auth or
+// in the body
+func (s *Service) tokenEndpointClient(r *http.Request, grantDTO *GrantDTO) (*models.OauthClient, error) {
+	secret := grantDTO.ClientSecret
+	if secret == "" {
+		secret = grantDTO.Secret
+	}
+	if clientID, basicSecret, ok := r.BasicAuth(); ok {
+		if grantDTO.ClientID != "" && grantDTO.ClientID != clientID {
+			return nil, ErrInvalidClientIDOrSecret
+		}
+		grantDTO.ClientID, secret = clientID, basicSecret
+	}
+
+	if grantDTO.ClientAssertionType != "" || grantDTO.ClientAssertion != "" {
+		return s.AuthClientAssertion(
+			grantDTO.ClientID,
+			grantDTO.ClientAssertionType,
+			grantDTO.ClientAssertion,
+		)
+	}
+
+	client, err := s.GetClient(grantDTO.ClientID)
+	if err != nil {
+		// For security reasons, return a general error message
+		return nil, ErrInvalidClientIDOrSecret
+	}
+
+	switch {
+	// Clients registered for mutual TLS must present their certificate
+	case authmethods.IsTLS(client.TokenEndpointAuthMethod):
+		return s.AuthClientCertificate(grantDTO.ClientID, peerCertificates(r))
+	// Public clients prove they requested the code with the PKCE verifier,
+	// which only matches codes requested with a challenge
+	case client.TokenEndpointAuthMethod == authmethods.None:
+		if grantDTO.GrantType != "authorization_code" || grantDTO.CodeVerifier == "" {
+			return nil, ErrClientAuthMethodNotAllowed
+		}
+		return client, nil
+	// Clients registered for JWT auth must always present an assertion
+	case !authmethods.UsesSecret(client.TokenEndpointAuthMethod):
+		return nil, ErrClientAuthMethodNotAllowed
+	}
+
+	if _, err := s.AuthClient(grantDTO.ClientID, secret); err != nil {
+		// For security reasons, return a general error message
+		return nil, ErrInvalidClientIDOrSecret
+	}
+	return client, nil
+}
+
+// Authenticates the client of a request, failures are recorded in the audit
+// log and counted
 func (s *Service) basicAuthClient(r *http.Request) (*models.OauthClient, error) {
+	client, err := s.requestClient(r)
+	if err != nil {
+		clientID, _, ok := r.BasicAuth()
+		if !ok {
+			clientID = r.PostForm.Get("client_id")
+		}
+		s.recordClientAuthFailure(r, clientID, pathTenantID(r), err)
+		observeAuthFailure(err)
+	}
+	return client, err
+}
+
+// Get client credentials from basic auth, a client assertion in the form or
+// the TLS client certificate and try to authenticate client
+func (s *Service) requestClient(r *http.Request) (*models.OauthClient, error) {
 	// Get client credentials from basic auth
 	clientID, secret, ok := r.BasicAuth()
 	if !ok {
-		return nil, ErrInvalidClientIDOrSecret
+		if err := r.ParseForm(); err != nil {
+			return nil, ErrInvalidClientIDOrSecret
+		}
+
+		// Authenticate the client with its TLS certificate
+		if r.PostForm.Get("client_assertion_type") == "" {
+			chain := peerCertificates(r)
+			if len(chain) == 0 || r.PostForm.Get("client_id") == "" {
+				return nil, ErrInvalidClientIDOrSecret
+			}
+			client, err := s.AuthClientCertificate(r.PostForm.Get("client_id"), chain)
+			if err != nil {
+				// For security reasons, return a general error message
+				return nil, ErrInvalidClientIDOrSecret
+			}
+			return client, nil
+		}
+
+		// Authenticate the client with a JWT assertion
+		client, err := s.AuthClientAssertion(
+			r.PostForm.Get("client_id"),
+			r.PostForm.Get("client_assertion_type"),
+			r.PostForm.Get("client_assertion"),
+		)
+		if err != nil {
+			// For security reasons, return a general error message
+			return nil, ErrInvalidClientIDOrSecret
+		}
+		return client, nil
 	}
 
 	// Authenticate the client
@@ -129,10 +312,612 @@ func (s *Service) basicAuthClient(r *http.Request) (*models.OauthClient, error)
 	return client, nil
 }
 
+// parHandler handles pushed authorization requests (RFC 9126)
+// (POST /v1/oauth/par)
+func (s *Service) parHandler(w http.ResponseWriter, r *http.Request) {
+	// Parse the form so r.PostForm becomes available, client auth only
+	// parses it for clients not using basic auth
+	if err := r.ParseForm(); err != nil {
+		response.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Client auth
+	client, err := s.basicAuthClient(r)
+	if err != nil {
+		response.UnauthorizedError(w, err.Error())
+		return
+	}
+
+	// Clients can only push requests to their tenant's issuer
+	if err := checkPathTenant(r, client); err != nil {
+		response.Error(w, err.Error(), getErrStatusCode(err))
+		return
+	}
+
+	// A request_uri cannot itself be pushed
+	if r.PostForm.Get("request_uri") != "" {
+		response.Error(w, ErrRequestURINotAllowed.Error(), getErrStatusCode(ErrRequestURINotAllowed))
+		return
+	}
+
+	// Validate and store the authorization request
+	requestURI, err := s.PushAuthorizationRequest(client, NewAuthorizationRequest(r.PostForm))
+	if err != nil {
+		response.Error(w, err.Error(), getErrStatusCode(err))
+		return
+	}
+
+	// Write response to json
+	response.WriteJSON(w, map[string]interface{}{
+		"request_uri": requestURI,
+		"expires_in":  s.cnf.Oauth.PushedAuthRequestLifetime,
+	}, http.StatusCreated)
+}
+
+// endSessionHandler logs the user agent out of its SSO session and of the
+// clients using it (GET or POST /v1/oauth/logout)
+func (s *Service) endSessionHandler(w http.ResponseWriter, r *http.Request) {
+	// Parse the form so r.Form becomes available
+	if err := r.ParseForm(); err != nil {
+		response.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logout, err := s.ValidateLogoutRequest(NewLogoutRequest(r.Form))
+	if err != nil {
+		response.Error(w, err.Error(), getErrStatusCode(err))
+		return
+	}
+	// Clients can only log out through their tenant's issuer
+	if logout.Client != nil {
+		if err := checkPathTenant(r, logout.Client); err != nil {
+			response.Error(w, err.Error(), getErrStatusCode(err))
+			return
+		}
+	}
+
+	// The SSO session of the user agent is ended, unless the ID token hint
+	// belongs to another user
+	userID, sessionID := logout.UserID, logout.SessionID
+	if s.sessionService != nil {
+		s.sessionService.SetSessionService(r, w)
+		if err := s.sessionService.StartSession(); err != nil {
+			response.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		ssoSession, _ := s.sessionService.GetSSOSession()
+
+		// Any site can link here, so unless the ID token hint is of the
+		// session's user, the user confirms with a form posted with the
+		// session's CSRF token
+		hintMatches := ssoSession != nil && userID != "" && userID == ssoSession.UserID
+		confirmed := r.Method == http.MethodPost &&
+			s.sessionService.CheckCSRFToken(r.PostForm.Get(csrfTokenField))
+		if !hintMatches && !confirmed {
+			csrfToken, err := s.sessionService.GetCSRFToken()
+			if err != nil {
+				response.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			writeLogoutConfirmPage(w, r, NewLogoutRequest(r.Form), csrfToken)
+			return
+		}
+
+		if ssoSession != nil && (userID == "" || userID == ssoSession.UserID) {
+			userID, sessionID = ssoSession.UserID, ssoSession.ID
+			if err := s.sessionService.ClearSSOSession(); err != nil {
+				response.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	var frontchannelURIs []string
+	if sessionID != "" {
+		frontchannelURIs, err = s.EndSession(userID, sessionID)
+		if err != nil {
+			response.Error(w, err.Error(), getErrStatusCode(err))
+			return
+		}
+	}
+
+	writeLogoutPage(w, r, logout.RedirectURI, frontchannelURIs)
+}
+
+// ChangePasswordRequest is the body of password change requests
+type ChangePasswordRequest struct {
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	NewPassword string `json:"new_password"`
+}
+
+// changePasswordHandler lets users change their password, including
+// expired passwords the password grant refuses
+// (POST /v1/oauth/password)
+func (s *Service) changePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	// Client auth
+	client, err := s.basicAuthClient(r)
+	if err != nil {
+		response.UnauthorizedError(w, err.Error())
+		return
+	}
+
+	// Users are looked up in the tenant of the client
+	if err := checkPathTenant(r, client); err != nil {
+		response.Error(w, err.Error(), getErrStatusCode(err))
+		return
+	}
+
+	changePasswordRequest := new(ChangePasswordRequest)
+	if err := json.NewDecoder(r.Body).Decode(changePasswordRequest); err != nil {
+		response.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = s.ChangePassword(
+		client.TenantID,
+		changePasswordRequest.Username,
+		changePasswordRequest.Password,
+		changePasswordRequest.NewPassword,
+		util.GetClientIP(r),
+	)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// PasswordlessStartRequest is the body of login code requests
+type PasswordlessStartRequest struct {
+	Phone string `json:"phone_number"`
+}
+
+// passwordlessStartHandler sends a login code to the phone of a user
+// (POST /v1/oauth/passwordless/start)
+func (s *Service) passwordlessStartHandler(w http.ResponseWriter, r *http.Request) {
+	// Client auth
+	client, err := s.basicAuthClient(r)
+	if err != nil {
+		response.UnauthorizedError(w, err.Error())
+		return
+	}
+
+	// Users are looked up in the tenant of the client
+	if err := checkPathTenant(r, client); err != nil {
+		response.Error(w, err.Error(), getErrStatusCode(err))
+		return
+	}
+
+	// Only clients allowed to log in with the code can send one
+	tenant, err := s.GetTenantConfig(client.TenantID)
+	if err != nil {
+		response.Error(w, err.Error(), getErrStatusCode(err))
+		return
+	}
+	if !tenant.AllowsGrantType(PasswordlessOTPGrantType) {
+		response.Error(w, ErrGrantTypeNotAllowedForTenant.Error(), getErrStatusCode(ErrGrantTypeNotAllowedForTenant))
+		return
+	}
+	if !clientAllowsGrantType(client, PasswordlessOTPGrantType) {
+		response.Error(w, ErrUnauthorizedGrantType.Error(), getErrStatusCode(ErrUnauthorizedGrantType))
+		return
+	}
+
+	startRequest := new(PasswordlessStartRequest)
+	if err := json.NewDecoder(r.Body).Decode(startRequest); err != nil {
+		response.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.SendLoginCode(client.TenantID, startRequest.Phone); err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// EmailVerificationRequest is the body of email verification requests, an
+// email address replaces the user's current one
+type EmailVerificationRequest struct {
+	Email string `json:"email"`
+}
+
+// EmailTokenRequest is the body of requests confirming an emailed link
+type EmailTokenRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password,omitempty"`
+}
+
+// PasswordResetRequest is the body of password reset requests
+type PasswordResetRequest struct {
+	Email string `json:"email"`
+}
+
+// emailVerificationHandler emails a confirmation link to the user's email
+// address (POST /v1/oauth/email/verification)
+func (s *Service) emailVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := s.requestUser(r)
+	if err != nil {
+		response.UnauthorizedError(w, err.Error())
+		return
+	}
+
+	verificationRequest := new(EmailVerificationRequest)
+	if err := json.NewDecoder(r.Body).Decode(verificationRequest); err != nil {
+		response.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if verificationRequest.Email != "" {
+		if err := s.SetEmail(user, verificationRequest.Email); err != nil {
+			response.Error(w, err.Error(), getErrStatusCode(err))
+			return
+		}
+	}
+
+	if err := s.SendEmailConfirmation(user); err != nil {
+		response.Error(w, err.Error(), getErrStatusCode(err))
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// emailConfirmHandler verifies the email address of a confirmation link
+// (POST /v1/oauth/email/verification/confirm)
+func (s *Service) emailConfirmHandler(w http.ResponseWriter, r *http.Request) {
+	tokenRequest := new(EmailTokenRequest)
+	if err := json.NewDecoder(r.Body).Decode(tokenRequest); err != nil {
+		response.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := s.ConfirmEmail(tokenRequest.Token); err != nil {
+		response.Error(w, err.Error(), getErrStatusCode(err))
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// passwordResetHandler emails a password reset link
+// (POST /v1/oauth/password/reset)
+func (s *Service) passwordResetHandler(w http.ResponseWriter, r *http.Request) {
+	// Client auth
+	client, err := s.basicAuthClient(r)
+	if err != nil {
+		response.UnauthorizedError(w, err.Error())
+		return
+	}
+
+	// Users are looked up in the tenant of the client
+	if err := checkPathTenant(r, client); err != nil {
+		response.Error(w, err.Error(), getErrStatusCode(err))
+		return
+	}
+
+	resetRequest := new(PasswordResetRequest)
+	if err := json.NewDecoder(r.Body).Decode(resetRequest); err != nil {
+		response.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.RequestPasswordReset(client.TenantID, resetRequest.Email); err != nil {
+		response.Error(w, err.Error(), getErrStatusCode(err))
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// passwordResetConfirmHandler sets a new password with the token of a
+// password reset link (POST /v1/oauth/password/reset/confirm)
+func (s *Service) passwordResetConfirmHandler(w http.ResponseWriter, r *http.Request) {
+	tokenRequest := new(EmailTokenRequest)
+	if err := json.NewDecoder(r.Body).Decode(tokenRequest); err != nil {
+		response.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.ResetPasswordWithToken(tokenRequest.Token, tokenRequest.NewPassword); err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// MFARequest is the body of authenticator enrollment requests, users not
+// enrolled yet authenticate with the mfa_token of their pending login
+type MFARequest struct {
+	MFAToken string `json:"mfa_token"`
+	OTP      string `json:"otp"`
+}
+
+// RecoveryCodesResponse returns newly generated recovery codes
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// mfaEnrollHandler starts an authenticator enrollment
+// (POST /v1/oauth/mfa/totp)
+func (s *Service) mfaEnrollHandler(w http.ResponseWriter, r *http.Request) {
+	mfaRequest := new(MFARequest)
+	if err := json.NewDecoder(r.Body).Decode(mfaRequest); err != nil {
+		response.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	user, err := s.mfaRequestUser(r, mfaRequest.MFAToken)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	enrollment, err := s.BeginTOTPEnrollment(user)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, enrollment, http.StatusOK)
+}
+
+// mfaConfirmHandler completes an authenticator enrollment with its first code
+// (POST /v1/oauth/mfa/totp/confirm)
+func (s *Service) mfaConfirmHandler(w http.ResponseWriter, r *http.Request) {
+	mfaRequest := new(MFARequest)
+	if err := json.NewDecoder(r.Body).Decode(mfaRequest); err != nil {
+		response.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	user, err := s.mfaRequestUser(r, mfaRequest.MFAToken)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	codes, err := s.ConfirmTOTPEnrollment(user, mfaRequest.OTP)
+	if err != nil {
+		if err == ErrInvalidOTP && mfaRequest.MFAToken != "" {
+			s.recordMFAFailure(mfaRequest.MFAToken)
+		}
+		writeUserError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, &RecoveryCodesResponse{RecoveryCodes: codes}, http.StatusOK)
+}
+
+// mfaRecoveryCodesHandler replaces the recovery codes of an enrolled user
+// (POST /v1/oauth/mfa/recovery-codes)
+func (s *Service) mfaRecoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+	mfaRequest := new(MFARequest)
+	if err := json.NewDecoder(r.Body).Decode(mfaRequest); err != nil {
+		response.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Only logged in users, the mfa_token cannot replace the second factor
+	user, err := s.mfaRequestUser(r, "")
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	codes, err := s.RegenerateRecoveryCodes(user, mfaRequest.OTP)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, &RecoveryCodesResponse{RecoveryCodes: codes}, http.StatusOK)
+}
+
+// listConsentsHandler lists the clients the user granted access
+// (GET /v1/oauth/consents)
+func (s *Service) listConsentsHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := s.requestUser(r)
+	if err != nil {
+		response.UnauthorizedError(w, err.Error())
+		return
+	}
+
+	consents, err := s.ListConsents(user)
+	if err != nil {
+		response.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response.WriteJSON(w, NewConsentsResponse(consents), http.StatusOK)
+}
+
+// revokeConsentHandler revokes the access the user granted a client
+// (DELETE /v1/oauth/consents/{client_id})
+func (s *Service) revokeConsentHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := s.requestUser(r)
+	if err != nil {
+		response.UnauthorizedError(w, err.Error())
+		return
+	}
+
+	if err := s.RevokeConsent(user, mux.Vars(r)["client_id"]); err != nil {
+		response.Error(w, err.Error(), getErrStatusCode(err))
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// mfaRequestUser returns the user of an MFA request, authenticated with an
+// access token or, while not enrolled, the mfa_token of a pending login
+func (s *Service) mfaRequestUser(r *http.Request, mfaToken string) (*models.OauthUser, error) {
+	if mfaToken != "" {
+		challenge, err := s.getMFAChallenge(mfaToken)
+		if err != nil {
+			return nil, err
+		}
+		user, err := s.FindUserByID(challenge.UserID)
+		if err != nil {
+			return nil, ErrInvalidMFAToken
+		}
+		// Enrolled users must not replace their authenticator without it
+		if user.MFAEnabled {
+			return nil, ErrMFAAlreadyEnrolled
+		}
+		return user, nil
+	}
+
+	return s.requestUser(r)
+}
+
+// requestUser returns the user of the request's access token
+func (s *Service) requestUser(r *http.Request) (*models.OauthUser, error) {
+	accessToken, err := s.AuthenticateRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	if !accessToken.UserID.Valid {
+		return nil, ErrUserNotFound
+	}
+	return s.FindUserByID(accessToken.UserID.String)
+}
+
+// writeUserError writes errors of user logins and password changes, with
+// the violations of password policy errors, the mfa_token of logins pending
+// MFA and Retry-After of lockouts and rate limits
+func writeUserError(w http.ResponseWriter, err error) {
+	switch e := err.(type) {
+	case *passwordpolicy.Error:
+		response.WriteJSON(w, e, http.StatusBadRequest)
+	case *MFARequiredError:
+		response.WriteJSON(w, e, getErrStatusCode(err))
+	case *LockoutError:
+		writeRetryAfter(w, e.RetryAfter)
+		response.Error(w, err.Error(), getErrStatusCode(err))
+	case *LoginCodeRateLimitError:
+		writeRetryAfter(w, e.RetryAfter)
+		response.Error(w, err.Error(), getErrStatusCode(err))
+	default:
+		response.Error(w, err.Error(), getErrStatusCode(err))
+	}
+}
+
+// writeRetryAfter sets the Retry-After header in whole seconds
+func writeRetryAfter(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+}
+
+// registerHandler handles dynamic client registration (RFC 7591)
+// (POST /v1/oauth/register)
+func (s *Service) registerHandler(w http.ResponseWriter, r *http.Request) {
+	metadata := new(ClientMetadata)
+	if err := json.NewDecoder(r.Body).Decode(metadata); err != nil {
+		response.Error(w, ErrInvalidClientMetadata.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Clients registered on a tenant's issuer belong to the tenant
+	tenantID, err := requestTenantID(r, metadata.TenantID)
+	if err != nil {
+		response.Error(w, err.Error(), getErrStatusCode(err))
+		return
+	}
+	metadata.TenantID = tenantID
+
+	// The initial access token is optional for tenants open for registration
+	initialAccessToken, _ := util.ParseBearerToken(r)
+	registration, err := s.RegisterClient(string(initialAccessToken), metadata)
+	if err == ErrInitialAccessTokenRequired {
+		response.UnauthorizedError(w, err.Error())
+		return
+	}
+	if err != nil {
+		response.Error(w, err.Error(), getErrStatusCode(err))
+		return
+	}
+	registration.RegistrationClientURI = util.GetRequestURI(r) + "/" + registration.ClientID
+
+	// Write response to json
+	response.WriteJSON(w, registration, http.StatusCreated)
+}
+
+// registrationHandler reads, updates and deletes a client registration (RFC 7592)
+// (GET, PUT, DELETE /v1/oauth/register/{client_id})
+func (s *Service) registrationHandler(w http.ResponseWriter, r *http.Request) {
+	// Client auth with the registration access token
+	token, err := util.ParseBearerToken(r)
+	if err != nil {
+		response.UnauthorizedError(w, ErrInvalidRegistrationAccessToken.Error())
+		return
+	}
+	client, err := s.AuthClientRegistration(mux.Vars(r)["client_id"], string(token))
+	if err != nil {
+		response.UnauthorizedError(w, err.Error())
+		return
+	}
+	if err := checkPathTenant(r, client); err != nil {
+		response.Error(w, err.Error(), getErrStatusCode(err))
+		return
+	}
+
+	var registration *ClientRegistration
+	switch r.Method {
+	case http.MethodGet:
+		registration = NewClientRegistration(client)
+	case http.MethodPut:
+		metadata := new(ClientRegistration)
+		if err := json.NewDecoder(r.Body).Decode(metadata); err != nil {
+			response.Error(w, ErrInvalidClientMetadata.Error(), http.StatusBadRequest)
+			return
+		}
+		if metadata.ClientID != client.Key {
+			response.Error(w, ErrInvalidClientMetadata.Error(), http.StatusBadRequest)
+			return
+		}
+		registration, err = s.UpdateClientRegistration(client, &metadata.ClientMetadata)
+		if err != nil {
+			response.Error(w, err.Error(), getErrStatusCode(err))
+			return
+		}
+	case http.MethodDelete:
+		if err := s.DeleteClientRegistration(client); err != nil {
+			response.Error(w, err.Error(), getErrStatusCode(err))
+			return
+		}
+		response.NoContent(w)
+		return
+	}
+	registration.RegistrationClientURI = util.GetRequestURI(r)
+
+	// Write response to json
+	response.WriteJSON(w, registration, http.StatusOK)
+}
+
+// jwksHandler publishes the public keys of the issuer
+// (GET /.well-known/jwks.json, GET /t/{tenant}/.well-known/jwks.json)
 func (s *Service) jwksHandler(w http.ResponseWriter, r *http.Request) {
-	if jwks, err := s.JWKs(); err != nil {
+	if _, err := s.GetTenantConfig(mux.Vars(r)["tenant"]); err != nil {
+		response.Error(w, err.Error(), getErrStatusCode(err))
+		return
+	}
+	if jwks, err := s.TenantJWKs(mux.Vars(r)["tenant"]); err != nil {
 		response.Error(w, err.Error(), getErrStatusCode(err))
 	} else {
 		response.WriteJSON(w, jwks, 200)
 	}
 }
+
+// discoveryHandler publishes the authorization server metadata of the issuer
+// (GET /.well-known/openid-configuration, GET /t/{tenant}/.well-known/openid-configuration)
+func (s *Service) discoveryHandler(w http.ResponseWriter, r *http.Request) {
+	document, err := s.NewDiscoveryDocument(mux.Vars(r)["tenant"])
+	if err != nil {
+		response.Error(w, err.Error(), getErrStatusCode(err))
+		return
+	}
+
+	// Write response to json
+	response.WriteJSON(w, document, http.StatusOK)
+}
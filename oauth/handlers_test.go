@@ -1,20 +1,23 @@
 package oauth_test
 
 import (
+	"bytes"
+	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 
 	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/authmethods"
 	"github.com/RichardKnop/go-oauth2-server/test-util"
 	"github.com/stretchr/testify/assert"
 )
 
 func (suite *OauthTestSuite) TestTokensHandlerClientAuthenticationRequired() {
 	// Prepare a request
-	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/token", tokenRequestBody(url.Values{"grant_type": {"client_credentials"}}))
 	assert.NoError(suite.T(), err, "Request setup should not get an error")
-	r.PostForm = url.Values{"grant_type": {"client_credentials"}}
 
 	// Serve the request
 	w := httptest.NewRecorder()
@@ -29,12 +32,57 @@ func (suite *OauthTestSuite) TestTokensHandlerClientAuthenticationRequired() {
 	)
 }
 
+func (suite *OauthTestSuite) TestTokensHandlerInvalidClientSecret() {
+	// Prepare a request
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/token", tokenRequestBody(url.Values{"grant_type": {"client_credentials"}}))
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "bogus")
+
+	// Serve the request
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+
+	// Check the response
+	testutil.TestResponseForError(
+		suite.T(),
+		w,
+		oauth.ErrInvalidClientIDOrSecret.Error(),
+		401,
+	)
+}
+
+func (suite *OauthTestSuite) TestTokensHandlerPublicClientRequiresPKCE() {
+	// Register a public client
+	client, err := suite.service.CreateClient("test_public_client", "", "https://www.example.com", "")
+	assert.NoError(suite.T(), err)
+	client.TokenEndpointAuthMethod = authmethods.None
+	assert.NoError(suite.T(), suite.db.Save(client).Error)
+
+	// Prepare a request
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/token", tokenRequestBody(url.Values{
+		"grant_type": {"client_credentials"},
+		"client_id":  {"test_public_client"},
+	}))
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+
+	// Serve the request
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+
+	// Check the response
+	testutil.TestResponseForError(
+		suite.T(),
+		w,
+		oauth.ErrClientAuthMethodNotAllowed.Error(),
+		401,
+	)
+}
+
 func (suite *OauthTestSuite) TestTokensHandlerInvalidGrantType() {
 	// Make a request
-	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/token", tokenRequestBody(url.Values{"grant_type": {"bogus"}}))
 	assert.NoError(suite.T(), err, "Request setup should not get an error")
 	r.SetBasicAuth("test_client", "test_secret")
-	r.PostForm = url.Values{"grant_type": {"bogus"}}
 
 	// Serve the request
 	w := httptest.NewRecorder()
@@ -67,3 +115,25 @@ func (suite *OauthTestSuite) TestIntrospectHandlerClientAuthenticationRequired()
 		401,
 	)
 }
+
+// tokenRequestBody encodes token request parameters as the JSON body the
+// token endpoint expects
+func tokenRequestBody(values url.Values) io.Reader {
+	params := make(map[string]string, len(values))
+	for key := range values {
+		params[key] = values.Get(key)
+	}
+	body, _ := json.Marshal(params)
+	return bytes.NewReader(body)
+}
+
+// testIDTokenResponse checks the response of a grant that logs a user in,
+// which carries an ID token along with the access token
+func (suite *OauthTestSuite) testIDTokenResponse(w *httptest.ResponseRecorder, expected *oauth.AccessTokenResponse) {
+	assert.Equal(suite.T(), 200, w.Code, w.Body.String())
+	actual := new(oauth.AccessTokenResponse)
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), actual))
+	assert.NotEmpty(suite.T(), actual.IDToken)
+	expected.IDToken = actual.IDToken
+	assert.Equal(suite.T(), expected, actual)
+}
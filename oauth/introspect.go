@@ -2,9 +2,11 @@ package oauth
 
 import (
 	"errors"
+	"gopkg.in/square/go-jose.v2"
 	"gopkg.in/square/go-jose.v2/jwt"
 	"net/http"
 
+	"github.com/RichardKnop/go-oauth2-server/metrics"
 	"github.com/RichardKnop/go-oauth2-server/models"
 	"github.com/RichardKnop/go-oauth2-server/oauth/tokentypes"
 )
@@ -47,13 +49,19 @@ func (s *Service) introspectToken(r *http.Request, client *models.OauthClient) (
 
 	switch tokenTypeHint {
 	case AccessTokenHint:
-		accessToken, err := s.Authenticate(token)
+		accessToken, err := s.authenticate(token)
+		// Tokens of other tenants are not visible to the client
+		if err == nil && accessToken.TenantID != client.TenantID {
+			err = ErrAccessTokenNotFound
+		}
+		metrics.Introspection(err == nil)
 		if err != nil {
 			return nil, err
 		}
 		return s.NewIntrospectResponseFromAccessToken(accessToken)
 	case RefreshTokenHint:
 		refreshToken, err := s.GetValidRefreshToken(token, client)
+		metrics.Introspection(err == nil)
 		if err != nil {
 			return nil, err
 		}
@@ -63,15 +71,21 @@ func (s *Service) introspectToken(r *http.Request, client *models.OauthClient) (
 		if err != nil {
 			return nil, err
 		}
-		publicKey, err := s.getJWKPublicKey()
+		// Only the keys of the client's tenant can verify the token
+		publicKey, err := s.getJWTVerificationKey(jwt, client.TenantID)
 		if err != nil {
 			return nil, err
 		}
 		jsonWebToken := make(map[string]interface{})
-		if err := jwt.Claims(publicKey, &jsonWebToken); err != nil {
-			return nil, err
+		if err := jwt.Claims(publicKey.Key, &jsonWebToken); err != nil {
+			return nil, ErrInvalidToken
+		}
+		accessToken, err := s.authenticate(jsonWebToken["jti"].(string))
+		// Tokens of other tenants are not visible to the client
+		if err == nil && accessToken.TenantID != client.TenantID {
+			err = ErrAccessTokenNotFound
 		}
-		accessToken, err := s.Authenticate(jsonWebToken["jti"].(string))
+		metrics.Introspection(err == nil)
 		if err != nil {
 			return nil, err
 		}
@@ -88,6 +102,10 @@ func (s *Service) NewIntrospectResponseFromAccessToken(accessToken *models.Oauth
 		Scope:     accessToken.Scope,
 		TokenType: tokentypes.Bearer,
 		ExpiresAt: int(accessToken.ExpiresAt.Unix()),
+		Cnf:       newConfirmation(accessToken),
+	}
+	if accessToken.JKT.Valid {
+		introspectResponse.TokenType = tokentypes.DPoP
 	}
 
 	if accessToken.ClientID.Valid {
@@ -102,7 +120,7 @@ func (s *Service) NewIntrospectResponseFromAccessToken(accessToken *models.Oauth
 
 	if accessToken.UserID.Valid {
 		user := new(models.OauthUser)
-		notFound := s.db.Select("username").Where("id = ?", accessToken.UserID.String).
+		notFound := s.db.Select("id, name, tenant_id").Where("id = ?", accessToken.UserID.String).
 			First(user, accessToken.UserID.String).RecordNotFound()
 		if notFound {
 			return nil, ErrUserNotFound
@@ -110,6 +128,12 @@ func (s *Service) NewIntrospectResponseFromAccessToken(accessToken *models.Oauth
 		introspectResponse.Name = user.Name
 		introspectResponse.UserID = user.ID
 		introspectResponse.TenantID = user.TenantID
+
+		userRoles, err := s.getUserRoleNames(accessToken.UserID.String)
+		if err != nil {
+			return nil, err
+		}
+		introspectResponse.Roles = userRoles
 	}
 
 	return introspectResponse, nil
@@ -136,7 +160,7 @@ func (s *Service) NewIntrospectResponseFromRefreshToken(refreshToken *models.Oau
 
 	if refreshToken.UserID.Valid {
 		user := new(models.OauthUser)
-		notFound := s.db.Select("username").Where("id = ?", refreshToken.UserID.String).
+		notFound := s.db.Select("id, name, tenant_id").Where("id = ?", refreshToken.UserID.String).
 			First(user, refreshToken.UserID.String).RecordNotFound()
 		if notFound {
 			return nil, ErrUserNotFound
@@ -144,7 +168,29 @@ func (s *Service) NewIntrospectResponseFromRefreshToken(refreshToken *models.Oau
 		introspectResponse.Name = user.Name
 		introspectResponse.UserID = user.ID
 		introspectResponse.TenantID = user.TenantID
+
+		userRoles, err := s.getUserRoleNames(refreshToken.UserID.String)
+		if err != nil {
+			return nil, err
+		}
+		introspectResponse.Roles = userRoles
 	}
 
 	return introspectResponse, nil
 }
+
+// getJWTVerificationKey returns the tenant's public key the JWT was signed with
+func (s *Service) getJWTVerificationKey(token *jwt.JSONWebToken, tenantID string) (*jose.JSONWebKey, error) {
+	jwks, err := s.TenantJWKs(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if len(token.Headers) != 1 {
+		return nil, ErrInvalidToken
+	}
+	keys := jwks.Key(token.Headers[0].KeyID)
+	if len(keys) == 0 {
+		return nil, ErrInvalidToken
+	}
+	return &keys[0], nil
+}
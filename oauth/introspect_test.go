@@ -35,7 +35,9 @@ func (suite *OauthTestSuite) TestNewIntrospectResponseFromAccessToken() {
 		TokenType: tokentypes.Bearer,
 		ExpiresAt: int(accessToken.ExpiresAt.Unix()),
 		ClientID:  suite.clients[0].Key,
-		Username:  suite.users[0].Username,
+		Name:      suite.users[0].Name,
+		UserID:    suite.users[0].ID,
+		TenantID:  suite.users[0].TenantID,
 	}
 
 	actual, err := suite.service.NewIntrospectResponseFromAccessToken(accessToken)
@@ -49,7 +51,9 @@ func (suite *OauthTestSuite) TestNewIntrospectResponseFromAccessToken() {
 	assert.Equal(suite.T(), expected, actual)
 
 	accessToken.UserID = util.StringOrNull("")
-	expected.Username = ""
+	expected.Name = ""
+	expected.UserID = ""
+	expected.TenantID = ""
 	actual, err = suite.service.NewIntrospectResponseFromAccessToken(accessToken)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), expected, actual)
@@ -75,7 +79,9 @@ func (suite *OauthTestSuite) TestNewIntrospectResponseFromRefreshToken() {
 		TokenType: tokentypes.Bearer,
 		ExpiresAt: int(refreshToken.ExpiresAt.Unix()),
 		ClientID:  suite.clients[0].Key,
-		Username:  suite.users[0].Username,
+		Name:      suite.users[0].Name,
+		UserID:    suite.users[0].ID,
+		TenantID:  suite.users[0].TenantID,
 	}
 
 	actual, err := suite.service.NewIntrospectResponseFromRefreshToken(refreshToken)
@@ -89,7 +95,9 @@ func (suite *OauthTestSuite) TestNewIntrospectResponseFromRefreshToken() {
 	assert.Equal(suite.T(), expected, actual)
 
 	refreshToken.UserID = util.StringOrNull("")
-	expected.Username = ""
+	expected.Name = ""
+	expected.UserID = ""
+	expected.TenantID = ""
 	actual, err = suite.service.NewIntrospectResponseFromRefreshToken(refreshToken)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), expected, actual)
@@ -111,7 +119,7 @@ func (suite *OauthTestSuite) TestHandleIntrospectMissingToken() {
 		suite.T(),
 		w,
 		oauth.ErrTokenMissing.Error(),
-		400,
+		404,
 	)
 }
 
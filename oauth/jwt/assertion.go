@@ -0,0 +1,168 @@
+package jwt
+
+import (
+	"errors"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+var (
+	// ErrAssertionMalformed ...
+	ErrAssertionMalformed = errors.New("assertion is not a valid signed JWT")
+	// ErrAssertionAlgorithmNotAllowed ...
+	ErrAssertionAlgorithmNotAllowed = errors.New("assertion signing algorithm not allowed")
+	// ErrAssertionKeyNotFound ...
+	ErrAssertionKeyNotFound = errors.New("no key matches the assertion signature")
+	// ErrAssertionKeyIDUnknown ...
+	ErrAssertionKeyIDUnknown = errors.New("no key has the assertion key ID")
+	// ErrAssertionMissingClaims ...
+	ErrAssertionMissingClaims = errors.New("assertion must contain iss, sub, aud, exp and jti claims")
+	// ErrAssertionLifetimeTooLong ...
+	ErrAssertionLifetimeTooLong = errors.New("assertion lifetime too long")
+)
+
+var (
+	// AsymmetricAlgorithms are accepted for private_key_jwt assertions
+	AsymmetricAlgorithms = []jose.SignatureAlgorithm{
+		jose.RS256, jose.RS384, jose.RS512,
+		jose.PS256, jose.PS384, jose.PS512,
+		jose.ES256, jose.ES384, jose.ES512,
+	}
+	// SymmetricAlgorithms are accepted for client_secret_jwt assertions
+	SymmetricAlgorithms = []jose.SignatureAlgorithm{
+		jose.HS256, jose.HS384, jose.HS512,
+	}
+)
+
+// AssertionExpectations defines what a client assertion is validated against
+type AssertionExpectations struct {
+	// ClientID must match both iss and sub claims
+	ClientID string
+	// Audiences lists the token endpoint URLs, the aud claim must contain
+	// one of them
+	Audiences []string
+	// MaxLifetime caps exp relative to Now (and to iat when present)
+	MaxLifetime time.Duration
+	// Algorithms lists signing algorithms allowed for the assertion
+	Algorithms []jose.SignatureAlgorithm
+	// Now is the time used for validation, defaults to time.Now()
+	Now time.Time
+}
+
+// VerifyClientAssertion verifies a RFC 7523 client assertion signed by one of
+// the keys and returns its claims
+func VerifyClientAssertion(raw string, keys *jose.JSONWebKeySet, expected *AssertionExpectations) (*jwt.Claims, error) {
+	token, err := jwt.ParseSigned(raw)
+	if err != nil || len(token.Headers) != 1 {
+		return nil, ErrAssertionMalformed
+	}
+	header := token.Headers[0]
+
+	if !algorithmAllowed(header.Algorithm, expected.Algorithms) {
+		return nil, ErrAssertionAlgorithmNotAllowed
+	}
+
+	// Try the key identified by kid, or every key when kid is omitted
+	candidates := keys.Keys
+	if header.KeyID != "" {
+		candidates = keys.Key(header.KeyID)
+		if len(candidates) == 0 {
+			return nil, ErrAssertionKeyIDUnknown
+		}
+	}
+	claims := new(jwt.Claims)
+	verified := false
+	for _, key := range candidates {
+		if key.Algorithm != "" && key.Algorithm != header.Algorithm {
+			continue
+		}
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		if err := token.Claims(verificationKey(key), claims); err == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, ErrAssertionKeyNotFound
+	}
+
+	if claims.Issuer == "" || claims.Subject == "" || len(claims.Audience) == 0 ||
+		claims.Expiry == nil || claims.ID == "" {
+		return nil, ErrAssertionMissingClaims
+	}
+
+	now := expected.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	if err := claims.Validate(jwt.Expected{
+		Issuer:  expected.ClientID,
+		Subject: expected.ClientID,
+		Time:    now,
+	}); err != nil {
+		return nil, err
+	}
+	if !audienceAllowed(claims.Audience, expected.Audiences) {
+		return nil, jwt.ErrInvalidAudience
+	}
+
+	// Short lived assertions only, so the jti replay cache stays small
+	if expected.MaxLifetime > 0 {
+		if claims.Expiry.Time().Sub(now) > expected.MaxLifetime {
+			return nil, ErrAssertionLifetimeTooLong
+		}
+		if claims.IssuedAt != nil && claims.Expiry.Time().Sub(claims.IssuedAt.Time()) > expected.MaxLifetime {
+			return nil, ErrAssertionLifetimeTooLong
+		}
+	}
+
+	return claims, nil
+}
+
+// UnverifiedIssuer returns the iss claim without verifying the signature,
+// it is used to look up the keys the assertion must then be verified with
+func UnverifiedIssuer(raw string) (string, error) {
+	token, err := jwt.ParseSigned(raw)
+	if err != nil {
+		return "", ErrAssertionMalformed
+	}
+	claims := new(jwt.Claims)
+	if err := token.UnsafeClaimsWithoutVerification(claims); err != nil {
+		return "", ErrAssertionMalformed
+	}
+	if claims.Issuer == "" {
+		return "", ErrAssertionMissingClaims
+	}
+	return claims.Issuer, nil
+}
+
+func audienceAllowed(audience jwt.Audience, allowed []string) bool {
+	for _, a := range allowed {
+		if a != "" && audience.Contains(a) {
+			return true
+		}
+	}
+	return false
+}
+
+func algorithmAllowed(alg string, allowed []jose.SignatureAlgorithm) bool {
+	for _, a := range allowed {
+		if string(a) == alg {
+			return true
+		}
+	}
+	return false
+}
+
+// verificationKey returns the public part of asymmetric keys so a client
+// which mistakenly registered a private JWK can still be verified
+func verificationKey(key jose.JSONWebKey) interface{} {
+	if _, ok := key.Key.([]byte); ok {
+		return key.Key
+	}
+	return key.Public().Key
+}
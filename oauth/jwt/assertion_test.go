@@ -0,0 +1,144 @@
+package jwt_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth/jwt"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/square/go-jose.v2"
+	josejwt "gopkg.in/square/go-jose.v2/jwt"
+)
+
+const testAudience = "https://auth.example.com/v1/oauth/token"
+
+func signAssertion(t *testing.T, key interface{}, alg jose.SignatureAlgorithm, kid string, claims josejwt.Claims) string {
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: alg, Key: jose.JSONWebKey{Key: key, KeyID: kid}},
+		(&jose.SignerOptions{}).WithType("JWT"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := josejwt.Signed(signer).Claims(claims).CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func validClaims(now time.Time) josejwt.Claims {
+	return josejwt.Claims{
+		Issuer:   "test_client_1",
+		Subject:  "test_client_1",
+		Audience: josejwt.Audience{testAudience},
+		Expiry:   josejwt.NewNumericDate(now.Add(time.Minute)),
+		IssuedAt: josejwt.NewNumericDate(now),
+		ID:       "jti-1",
+	}
+}
+
+func TestVerifyClientAssertionPrivateKeyJWT(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: &privateKey.PublicKey, KeyID: "k1", Use: "sig"},
+	}}
+	now := time.Now()
+	expected := &jwt.AssertionExpectations{
+		ClientID:    "test_client_1",
+		Audiences:   []string{testAudience},
+		MaxLifetime: 5 * time.Minute,
+		Algorithms:  jwt.AsymmetricAlgorithms,
+		Now:         now,
+	}
+
+	// Valid assertion
+	raw := signAssertion(t, privateKey, jose.RS256, "k1", validClaims(now))
+	claims, err := jwt.VerifyClientAssertion(raw, keys, expected)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "jti-1", claims.ID)
+	}
+
+	// Issuer can be read before verification
+	issuer, err := jwt.UnverifiedIssuer(raw)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "test_client_1", issuer)
+	}
+
+	// Wrong audience
+	c := validClaims(now)
+	c.Audience = josejwt.Audience{"https://evil.example.com/token"}
+	_, err = jwt.VerifyClientAssertion(signAssertion(t, privateKey, jose.RS256, "k1", c), keys, expected)
+	assert.Error(t, err)
+
+	// Any of the expected audiences will do
+	c = validClaims(now)
+	c.Audience = josejwt.Audience{"https://auth.example.com/t/acme/v1/oauth/token"}
+	_, err = jwt.VerifyClientAssertion(signAssertion(t, privateKey, jose.RS256, "k1", c), keys, &jwt.AssertionExpectations{
+		ClientID:   "test_client_1",
+		Audiences:  []string{testAudience, "https://auth.example.com/t/acme/v1/oauth/token"},
+		Algorithms: jwt.AsymmetricAlgorithms,
+		Now:        now,
+	})
+	assert.NoError(t, err)
+
+	// Expiry too far in the future
+	c = validClaims(now)
+	c.Expiry = josejwt.NewNumericDate(now.Add(time.Hour))
+	_, err = jwt.VerifyClientAssertion(signAssertion(t, privateKey, jose.RS256, "k1", c), keys, expected)
+	assert.Equal(t, jwt.ErrAssertionLifetimeTooLong, err)
+
+	// Missing jti
+	c = validClaims(now)
+	c.ID = ""
+	_, err = jwt.VerifyClientAssertion(signAssertion(t, privateKey, jose.RS256, "k1", c), keys, expected)
+	assert.Equal(t, jwt.ErrAssertionMissingClaims, err)
+
+	// Signed by an unknown key
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = jwt.VerifyClientAssertion(signAssertion(t, otherKey, jose.RS256, "k1", validClaims(now)), keys, expected)
+	assert.Equal(t, jwt.ErrAssertionKeyNotFound, err)
+
+	// Signed by a key with an unknown key ID
+	_, err = jwt.VerifyClientAssertion(signAssertion(t, otherKey, jose.RS256, "k2", validClaims(now)), keys, expected)
+	assert.Equal(t, jwt.ErrAssertionKeyIDUnknown, err)
+
+	// HMAC algorithms are not allowed for private_key_jwt
+	_, err = jwt.VerifyClientAssertion(signAssertion(t, []byte("0123456789abcdef0123456789abcdef"), jose.HS256, "k1", validClaims(now)), keys, expected)
+	assert.Equal(t, jwt.ErrAssertionAlgorithmNotAllowed, err)
+}
+
+func TestVerifyClientAssertionClientSecretJWT(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	keys := &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: secret}}}
+	now := time.Now()
+	expected := &jwt.AssertionExpectations{
+		ClientID:    "test_client_1",
+		Audiences:   []string{testAudience},
+		MaxLifetime: 5 * time.Minute,
+		Algorithms:  jwt.SymmetricAlgorithms,
+		Now:         now,
+	}
+
+	// Valid assertion without kid
+	_, err := jwt.VerifyClientAssertion(signAssertion(t, secret, jose.HS256, "", validClaims(now)), keys, expected)
+	assert.NoError(t, err)
+
+	// Issuer must be the client
+	c := validClaims(now)
+	c.Issuer = "test_client_2"
+	_, err = jwt.VerifyClientAssertion(signAssertion(t, secret, jose.HS256, "", c), keys, expected)
+	assert.Error(t, err)
+
+	// Garbage input
+	_, err = jwt.VerifyClientAssertion("not.a.jwt", keys, expected)
+	assert.Equal(t, jwt.ErrAssertionMalformed, err)
+}
@@ -6,6 +6,31 @@ import (
 
 type Claims struct {
 	jwtgo.StandardClaims
-	Scope string `json:"scope,omitempty"`
-	TenantID string `json:"tenantId, omitempty"`
+	Scope    string   `json:"scope,omitempty"`
+	TenantID string   `json:"tenantId,omitempty"`
+	Roles    []string `json:"roles,omitempty"`
+	// AuthTime and AMR tell when and how the user authenticated
+	AuthTime int64    `json:"auth_time,omitempty"`
+	AMR      []string `json:"amr,omitempty"`
+	// SessionID is the SSO session the user logged in through
+	SessionID string        `json:"sid,omitempty"`
+	Cnf       *Confirmation `json:"cnf,omitempty"`
+}
+
+// Confirmation is the cnf claim binding a token to a proof-of-possession key
+type Confirmation struct {
+	// X5tS256 is the SHA-256 thumbprint of a client certificate (RFC 8705)
+	X5tS256 string `json:"x5t#S256,omitempty"`
+	// JKT is the SHA-256 JWK thumbprint of a DPoP proof key (RFC 9449)
+	JKT string `json:"jkt,omitempty"`
+}
+
+// BackchannelLogoutEvent identifies logout tokens in their events claim
+const BackchannelLogoutEvent = "http://schemas.openid.net/event/backchannel-logout"
+
+// LogoutClaims are the claims of a back-channel logout token
+type LogoutClaims struct {
+	jwtgo.StandardClaims
+	SessionID string                 `json:"sid,omitempty"`
+	Events    map[string]interface{} `json:"events"`
 }
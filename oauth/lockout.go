@@ -0,0 +1,205 @@
+package oauth
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/audit"
+	"github.com/RichardKnop/go-oauth2-server/models"
+)
+
+const (
+	lockoutPrefix        = "lockout:"
+	lockoutFailuresKey   = "failures:"
+	lockoutLockedKey     = "locked:"
+	lockoutLevelKey      = "level:"
+	lockoutAccountPrefix = "account:"
+	lockoutIPPrefix      = "ip:"
+)
+
+var (
+	// ErrLoginLockedOut ...
+	ErrLoginLockedOut = errors.New("Too many failed logins, try again later")
+)
+
+// LockoutError is returned by the password grant while the account or the
+// client IP is locked out
+type LockoutError struct {
+	RetryAfter time.Duration
+}
+
+// Error returns the message of ErrLoginLockedOut
+func (e *LockoutError) Error() string {
+	return ErrLoginLockedOut.Error()
+}
+
+// checkLoginLockout returns a LockoutError while the account or the client
+// IP is locked out, it is checked before the password is hashed
+func (s *Service) checkLoginLockout(tenantID, username, clientIP string) error {
+	var retryAfter time.Duration
+	for _, subject := range lockoutSubjects(tenantID, username, clientIP) {
+		ttl, err := s.redis.PTTL(lockoutPrefix + lockoutLockedKey + subject.key).Result()
+		if err != nil {
+			return err
+		}
+		if ttl > retryAfter {
+			retryAfter = ttl
+		}
+	}
+	if retryAfter > 0 {
+		return &LockoutError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// recordLoginFailure counts a failed login of the account and the client IP,
+// locking out whichever reached its maximum failures. Every lockout within
+// the failure window doubles the next one, up to the maximum duration.
+func (s *Service) recordLoginFailure(tenantID, username, clientIP string) error {
+	window := time.Duration(s.cnf.Lockout.FailureWindow) * time.Second
+	for _, subject := range lockoutSubjects(tenantID, username, clientIP) {
+		maxFailures := s.cnf.Lockout.AccountMaxFailures
+		if !subject.account {
+			maxFailures = s.cnf.Lockout.IPMaxFailures
+		}
+		if maxFailures < 1 || s.cnf.Lockout.Duration < 1 {
+			continue
+		}
+
+		failuresKey := lockoutPrefix + lockoutFailuresKey + subject.key
+		failures, err := s.redis.Incr(failuresKey).Result()
+		if err != nil {
+			return err
+		}
+		if failures == 1 {
+			s.redis.Expire(failuresKey, window)
+		}
+		if failures < int64(maxFailures) {
+			continue
+		}
+
+		levelKey := lockoutPrefix + lockoutLevelKey + subject.key
+		level, err := s.redis.Incr(levelKey).Result()
+		if err != nil {
+			return err
+		}
+		duration := lockoutDuration(
+			time.Duration(s.cnf.Lockout.Duration)*time.Second,
+			time.Duration(s.cnf.Lockout.MaxDuration)*time.Second,
+			level,
+		)
+		pipe := s.redis.TxPipeline()
+		pipe.Set(lockoutPrefix+lockoutLockedKey+subject.key, level, duration)
+		pipe.Expire(levelKey, duration+window)
+		pipe.Del(failuresKey)
+		if _, err := pipe.Exec(); err != nil {
+			return err
+		}
+
+		s.logger.Warn(
+			"audit: login locked out",
+			"subject", subject.key,
+			"duration", duration,
+			"failures", failures,
+			"tenant_id", tenantID,
+			"client_ip", clientIP,
+		)
+		s.recordEvent(newLockoutEvent(tenantID, username, clientIP, subject, duration, failures))
+	}
+	return nil
+}
+
+// clearLoginFailures forgets the failed logins of an account after a
+// successful login, failures of the client IP are kept
+func (s *Service) clearLoginFailures(tenantID, username string) error {
+	subject := accountLockoutKey(tenantID, username)
+	return s.redis.Del(
+		lockoutPrefix+lockoutFailuresKey+subject,
+		lockoutPrefix+lockoutLevelKey+subject,
+	).Err()
+}
+
+// UnlockUser lifts the lockout of a user's account and phone and forgets
+// their failed logins
+func (s *Service) UnlockUser(user *models.OauthUser) error {
+	var keys []string
+	for _, username := range []string{user.Account, user.Phone} {
+		if username == "" {
+			continue
+		}
+		subject := accountLockoutKey(user.TenantID, username)
+		keys = append(keys,
+			lockoutPrefix+lockoutFailuresKey+subject,
+			lockoutPrefix+lockoutLevelKey+subject,
+			lockoutPrefix+lockoutLockedKey+subject,
+		)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	if err := s.redis.Del(keys...).Err(); err != nil {
+		return err
+	}
+
+	s.logger.Info("audit: login lockout lifted", "user_id", user.ID, "tenant_id", user.TenantID)
+	event := audit.NewEvent(audit.LoginUnlocked, nil)
+	event.TenantID = user.TenantID
+	event.Details["user_id"] = user.ID
+	s.recordEvent(event)
+	return nil
+}
+
+// newLockoutEvent returns the event of locking out an account or client IP
+func newLockoutEvent(tenantID, username, clientIP string, subject lockoutSubject, duration time.Duration, failures int64) *audit.Event {
+	event := audit.NewEvent(audit.LoginLockedOut, nil)
+	event.TenantID = tenantID
+	event.IP = clientIP
+	if subject.account {
+		event.Details["subject"] = "account"
+		event.Details["username"] = username
+	} else {
+		event.Details["subject"] = "ip"
+	}
+	event.Details["duration"] = duration.String()
+	event.Details["failures"] = strconv.FormatInt(failures, 10)
+	return event
+}
+
+// lockoutSubject is an account or client IP failed logins are counted for
+type lockoutSubject struct {
+	key     string
+	account bool
+}
+
+// lockoutSubjects returns the account and, when known, client IP subjects
+// of a login
+func lockoutSubjects(tenantID, username, clientIP string) []lockoutSubject {
+	subjects := []lockoutSubject{
+		{key: accountLockoutKey(tenantID, username), account: true},
+	}
+	if clientIP != "" {
+		subjects = append(subjects, lockoutSubject{key: lockoutIPPrefix + clientIP})
+	}
+	return subjects
+}
+
+// accountLockoutKey returns the subject key of an account in a tenant
+func accountLockoutKey(tenantID, username string) string {
+	return lockoutAccountPrefix + tenantID + ":" + username
+}
+
+// lockoutDuration returns the duration of the nth consecutive lockout
+func lockoutDuration(base, max time.Duration, level int64) time.Duration {
+	if max < base {
+		max = base
+	}
+	duration := base
+	for i := int64(1); i < level && duration < max; i++ {
+		duration *= 2
+	}
+	if duration > max {
+		duration = max
+	}
+	return duration
+}
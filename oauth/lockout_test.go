@@ -0,0 +1,100 @@
+package oauth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/audit"
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestPasswordGrantLockout() {
+	lockout := suite.cnf.Lockout
+	defer func() { suite.cnf.Lockout = lockout }()
+	suite.cnf.Lockout = config.LockoutConfig{
+		AccountMaxFailures: 2,
+		FailureWindow:      60,
+		Duration:           60,
+		MaxDuration:        600,
+	}
+
+	user, err := suite.service.CreateUser(roles.User, "test@lockout", "correct_horse_battery", "")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	defer suite.service.UnlockUser(user)
+
+	passwordGrant := func(password string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/token", strings.NewReader(
+			`{"grant_type": "password", "username": "test@lockout", "password": "`+password+`", "scope": "read"}`,
+		))
+		assert.NoError(suite.T(), err, "Request setup should not get an error")
+		r.SetBasicAuth("test_client_1", "test_secret")
+		w := httptest.NewRecorder()
+		suite.router.ServeHTTP(w, r)
+		return w
+	}
+
+	sink, done := suite.useRecordingSink()
+	defer done()
+
+	// Failed logins up to the maximum lock out the account
+	for i := 0; i < 2; i++ {
+		w := passwordGrant("bogus")
+		assert.NotEqual(suite.T(), http.StatusTooManyRequests, w.Code)
+	}
+	lockedOut := sink.eventsOfType(audit.LoginLockedOut)
+	if assert.Len(suite.T(), lockedOut, 1) {
+		assert.Equal(suite.T(), "account", lockedOut[0].Details["subject"])
+		assert.Equal(suite.T(), "test@lockout", lockedOut[0].Details["username"])
+		assert.Equal(suite.T(), "1m0s", lockedOut[0].Details["duration"])
+	}
+
+	// Even the right password is refused while locked out
+	w := passwordGrant("correct_horse_battery")
+	assert.Equal(suite.T(), http.StatusTooManyRequests, w.Code)
+	assert.Equal(suite.T(), "60", w.Header().Get("Retry-After"))
+
+	// Unlocked accounts can log in again
+	assert.NoError(suite.T(), suite.service.UnlockUser(user))
+	unlocked := sink.eventsOfType(audit.LoginUnlocked)
+	if assert.Len(suite.T(), unlocked, 1) {
+		assert.Equal(suite.T(), user.ID, unlocked[0].Details["user_id"])
+	}
+	w = passwordGrant("correct_horse_battery")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+}
+
+func (suite *OauthTestSuite) TestLoginUserLockout() {
+	lockout := suite.cnf.Lockout
+	defer func() { suite.cnf.Lockout = lockout }()
+	suite.cnf.Lockout = config.LockoutConfig{
+		AccountMaxFailures: 2,
+		FailureWindow:      60,
+		Duration:           60,
+		MaxDuration:        600,
+	}
+
+	user, err := suite.service.CreateUser(roles.User, "test@weblockout", "correct_horse_battery", "")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	defer suite.service.UnlockUser(user)
+
+	loggedIn, err := suite.service.LoginUser("", "test@weblockout", "correct_horse_battery", "127.0.0.1")
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), user.ID, loggedIn.ID)
+	}
+
+	// Web logins count towards the same lockout as the password grant
+	for i := 0; i < 2; i++ {
+		_, err = suite.service.LoginUser("", "test@weblockout", "bogus", "127.0.0.1")
+		assert.Equal(suite.T(), oauth.ErrInvalidUsernameOrPassword, err)
+	}
+	_, err = suite.service.LoginUser("", "test@weblockout", "correct_horse_battery", "127.0.0.1")
+	assert.IsType(suite.T(), new(oauth.LockoutError), err)
+}
@@ -1,28 +1,54 @@
 package oauth
 
 import (
+	"github.com/RichardKnop/go-oauth2-server/audit"
 	"github.com/RichardKnop/go-oauth2-server/models"
 )
 
 // Login creates an access token and refresh token for a user (logs him/her in)
 func (s *Service) Login(client *models.OauthClient, user *models.OauthUser, scope string) (*models.OauthAccessToken, *models.OauthRefreshToken, error) {
+	tenant, err := s.GetTenantConfig(client.TenantID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.login(tenant, client, user, scope, nil, audit.TokenIssued)
+}
+
+// login is Login using the tenant's token lifetimes, with the access token
+// bound to a key if binding is not nil. eventType is the webhook event of
+// the new access token
+func (s *Service) login(tenant *TenantConfig, client *models.OauthClient, user *models.OauthUser, scope string, binding *TokenBinding, eventType string) (*models.OauthAccessToken, *models.OauthRefreshToken, error) {
+	// The user's roles cap the scope of both tokens
+	scope, err := s.getUserScope(user, scope)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Create a new access token
-	accessToken, err := s.GrantAccessToken(
+	accessToken, err := s.grantAccessToken(
 		client,
 		user,
-		s.cnf.Oauth.AccessTokenLifetime, // expires in
+		tenant.AccessTokenLifetime, // expires in
 		scope,
+		binding,
+		eventType,
 	)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	// Create or retrieve a refresh token
-	refreshToken, err := s.GetOrCreateRefreshToken(
+	// Create or retrieve a refresh token, bound to the same DPoP key as the
+	// access token (RFC 9449 section 5)
+	var jkt string
+	if binding != nil {
+		jkt = binding.JKT
+	}
+	refreshToken, err := s.getOrCreateRefreshToken(
 		client,
 		user,
-		s.cnf.Oauth.RefreshTokenLifetime, // expires in
+		tenant.RefreshTokenLifetime, // expires in
 		scope,
+		jkt,
 	)
 	if err != nil {
 		return nil, nil, err
@@ -0,0 +1,191 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+const (
+	// PasswordlessOTPGrantType logs in with a code sent to the user's phone
+	PasswordlessOTPGrantType = "http://auth0.com/oauth/grant-type/passwordless/otp"
+
+	loginCodePrefix         = "login_code:"
+	loginCodeAttemptsPrefix = "login_code_attempts:"
+	loginCodeCooldownPrefix = "login_code_cooldown:"
+	loginCodeSendsPrefix    = "login_code_sends:"
+)
+
+var (
+	// ErrInvalidPhone ...
+	ErrInvalidPhone = errors.New("Invalid phone number")
+	// ErrInvalidLoginCode ...
+	ErrInvalidLoginCode = errors.New("Invalid or expired login code")
+	// ErrLoginCodeRateLimited ...
+	ErrLoginCodeRateLimited = errors.New("Too many login codes requested, try again later")
+)
+
+// LoginCodeRateLimitError is returned while a phone cannot get another
+// login code
+type LoginCodeRateLimitError struct {
+	RetryAfter time.Duration
+}
+
+// Error returns the message of ErrLoginCodeRateLimited
+func (e *LoginCodeRateLimitError) Error() string {
+	return ErrLoginCodeRateLimited.Error()
+}
+
+// SendLoginCode sends a one-time login code to the phone of a user. Phones
+// without an active user are rate limited the same but get no message, so
+// the response does not tell which phones are registered.
+func (s *Service) SendLoginCode(tenantID, phone string) error {
+	phone = strings.TrimSpace(phone)
+	if phone == "" {
+		return ErrInvalidPhone
+	}
+	if s.smsSender == nil {
+		return ErrSMSSenderNotConfigured
+	}
+	if err := s.checkLoginCodeRateLimit(tenantID, phone); err != nil {
+		return err
+	}
+
+	user, err := s.FindUserByPhoneAndTenantID(phone, tenantID)
+	if err != nil || user.Disabled {
+		return nil
+	}
+
+	code, err := newLoginCode(s.cnf.SMS.CodeLength)
+	if err != nil {
+		return err
+	}
+
+	// A new code replaces the previous one and its failed attempts
+	subject := loginCodeSubject(tenantID, phone)
+	lifetime := time.Duration(s.cnf.SMS.CodeLifetime) * time.Second
+	pipe := s.redis.TxPipeline()
+	pipe.Set(loginCodePrefix+subject, hashLoginCode(code), lifetime)
+	pipe.Del(loginCodeAttemptsPrefix + subject)
+	if _, err := pipe.Exec(); err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("Your login code is %s, it expires in %d minutes", code, (s.cnf.SMS.CodeLifetime+59)/60)
+	if err := s.smsSender.SendSMS(phone, message); err != nil {
+		s.redis.Del(loginCodePrefix + subject)
+		return err
+	}
+	return nil
+}
+
+// checkLoginCodeRateLimit counts a login code requested for a phone,
+// refusing it within the send interval or above the hourly maximum
+func (s *Service) checkLoginCodeRateLimit(tenantID, phone string) error {
+	subject := loginCodeSubject(tenantID, phone)
+	if s.cnf.SMS.SendInterval > 0 {
+		cooldownKey := loginCodeCooldownPrefix + subject
+		interval := time.Duration(s.cnf.SMS.SendInterval) * time.Second
+		fresh, err := s.redis.SetNX(cooldownKey, 1, interval).Result()
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return &LoginCodeRateLimitError{RetryAfter: s.redis.PTTL(cooldownKey).Val()}
+		}
+	}
+
+	if s.cnf.SMS.MaxSendsPerHour > 0 {
+		sendsKey := loginCodeSendsPrefix + subject
+		sends, err := s.redis.Incr(sendsKey).Result()
+		if err != nil {
+			return err
+		}
+		if sends == 1 {
+			s.redis.Expire(sendsKey, time.Hour)
+		}
+		if sends > int64(s.cnf.SMS.MaxSendsPerHour) {
+			s.logger.Warn("audit: login codes rate limited", "phone", phone, "tenant_id", tenantID)
+			return &LoginCodeRateLimitError{RetryAfter: s.redis.PTTL(sendsKey).Val()}
+		}
+	}
+	return nil
+}
+
+// verifyLoginCode checks the login code sent to a phone, which can only be
+// used once and is revoked after the maximum wrong attempts. The attempt is
+// counted before the code is compared, so concurrent guesses cannot get
+// past the limit
+func (s *Service) verifyLoginCode(tenantID, phone, code string) error {
+	subject := loginCodeSubject(tenantID, strings.TrimSpace(phone))
+	codeKey := loginCodePrefix + subject
+	attemptsKey := loginCodeAttemptsPrefix + subject
+
+	hash, err := s.redis.Get(codeKey).Result()
+	if err != nil {
+		return ErrInvalidLoginCode
+	}
+
+	attempts, err := s.redis.Incr(attemptsKey).Result()
+	if err != nil {
+		return err
+	}
+	if attempts == 1 {
+		s.redis.Expire(attemptsKey, time.Duration(s.cnf.SMS.CodeLifetime)*time.Second)
+	}
+	if attempts > int64(s.cnf.SMS.MaxAttempts) {
+		s.redis.Del(codeKey, attemptsKey)
+		return ErrInvalidLoginCode
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(hashLoginCode(code))) != 1 {
+		if attempts == int64(s.cnf.SMS.MaxAttempts) {
+			s.redis.Del(codeKey, attemptsKey)
+		}
+		return ErrInvalidLoginCode
+	}
+
+	// Only the first request using the code gets to log in
+	deleted, err := s.redis.Del(codeKey).Result()
+	if err != nil {
+		return err
+	}
+	if deleted != 1 {
+		return ErrInvalidLoginCode
+	}
+	s.redis.Del(attemptsKey)
+	return nil
+}
+
+// loginCodeSubject returns the key of a phone in a tenant
+func loginCodeSubject(tenantID, phone string) string {
+	return tenantID + ":" + phone
+}
+
+// newLoginCode returns a random code of n digits
+func newLoginCode(n int) (string, error) {
+	if n < 1 || n > 18 {
+		n = 6
+	}
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+	value, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", n, value.Int64()), nil
+}
+
+// hashLoginCode returns the SHA256 hash of a login code. It only keeps the
+// codes out of plain sight: a code has few digits, so its hash is reversed
+// instantly and read access to Redis must be treated as access to the live
+// codes. The short lifetime and the attempt limit protect the codes
+func hashLoginCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
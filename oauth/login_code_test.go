@@ -0,0 +1,156 @@
+package oauth_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/log"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingSMSSender keeps the last message sent to each phone
+type recordingSMSSender struct {
+	messages map[string]string
+}
+
+func (s *recordingSMSSender) SendSMS(phone, message string) error {
+	s.messages[phone] = message
+	return nil
+}
+
+var loginCodePattern = regexp.MustCompile(`\d{6}`)
+
+func (suite *OauthTestSuite) TestPasswordlessOTPGrant() {
+	sender := &recordingSMSSender{messages: make(map[string]string)}
+	suite.service.UseSMSSender(sender)
+
+	user, err := suite.service.CreateUser(roles.User, "test@passwordless", "correct_horse_battery", "")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	assert.NoError(suite.T(), suite.service.UpdateUser(user, "", "", "5550100"))
+
+	start := func(phone string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/passwordless/start", strings.NewReader(
+			`{"phone_number": "`+phone+`"}`,
+		))
+		assert.NoError(suite.T(), err, "Request setup should not get an error")
+		r.SetBasicAuth("test_client_1", "test_secret")
+		w := httptest.NewRecorder()
+		suite.router.ServeHTTP(w, r)
+		return w
+	}
+	otpGrant := func(code string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/token", strings.NewReader(
+			`{"grant_type": "`+oauth.PasswordlessOTPGrantType+`", "username": "5550100", "otp": "`+code+`", "scope": "read"}`,
+		))
+		assert.NoError(suite.T(), err, "Request setup should not get an error")
+		r.SetBasicAuth("test_client_1", "test_secret")
+		w := httptest.NewRecorder()
+		suite.router.ServeHTTP(w, r)
+		return w
+	}
+
+	// Unknown phones get the same response but no message
+	w := start("5550199")
+	assert.Equal(suite.T(), http.StatusNoContent, w.Code)
+	assert.Empty(suite.T(), sender.messages["5550199"])
+
+	w = start("5550100")
+	assert.Equal(suite.T(), http.StatusNoContent, w.Code)
+	code := loginCodePattern.FindString(sender.messages["5550100"])
+	if !assert.NotEmpty(suite.T(), code) {
+		return
+	}
+
+	// Another code cannot be requested right away
+	w = start("5550100")
+	assert.Equal(suite.T(), http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(suite.T(), w.Header().Get("Retry-After"))
+
+	// Wrong codes are refused, the sent code logs in once
+	w = otpGrant("bogus")
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	w = otpGrant(code)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	w = otpGrant(code)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	// The code is revoked after the maximum wrong attempts, below the
+	// failures that lock the account out
+	suite.cnf.SMS.MaxAttempts = 2
+	defer func() { suite.cnf.SMS.MaxAttempts = 5 }()
+	suite.redis.FastForward(time.Duration(suite.cnf.SMS.SendInterval) * time.Second)
+	w = start("5550100")
+	assert.Equal(suite.T(), http.StatusNoContent, w.Code)
+	code = loginCodePattern.FindString(sender.messages["5550100"])
+	for i := 0; i < suite.cnf.SMS.MaxAttempts; i++ {
+		w = otpGrant("bogus")
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	}
+	w = otpGrant(code)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
+
+func TestFileSMSSender(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sms")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	sender := oauth.NewFileSMSSender(filepath.Join(dir, "sms.log"))
+	assert.NoError(t, sender.SendSMS("5550100", "Your login code is 123456"))
+	assert.NoError(t, sender.SendSMS("5550101", "Your login code is 654321"))
+
+	data, err := ioutil.ReadFile(sender.Path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if assert.Len(t, lines, 2) {
+		assert.True(t, strings.HasSuffix(lines[0], "\t5550100\tYour login code is 123456"))
+		assert.True(t, strings.HasSuffix(lines[1], "\t5550101\tYour login code is 654321"))
+	}
+}
+
+func TestValidateSMSConfig(t *testing.T) {
+	cnf := &config.Config{}
+	assert.NoError(t, oauth.ValidateSMSConfig(cnf))
+
+	// The log sender would write live codes to the log
+	cnf.SMS.Sender = oauth.LogSMSSenderName
+	assert.Equal(t, oauth.ErrSMSLogSenderNotAllowed, oauth.ValidateSMSConfig(cnf))
+	cnf.IsDevelopment = true
+	assert.NoError(t, oauth.ValidateSMSConfig(cnf))
+
+	cnf.SMS.Sender = oauth.FileSMSSenderName
+	assert.Equal(t, oauth.ErrSMSFileMissing, oauth.ValidateSMSConfig(cnf))
+	cnf.SMS.File = "sms.log"
+	assert.NoError(t, oauth.ValidateSMSConfig(cnf))
+
+	cnf.SMS.Sender = "bogus"
+	assert.Error(t, oauth.ValidateSMSConfig(cnf))
+}
+
+func TestLogSMSSender(t *testing.T) {
+	buf := new(bytes.Buffer)
+	assert.NoError(t, log.Configure(log.Options{Format: "json", Output: buf}))
+	defer log.Configure(log.Options{})
+
+	// The login code is never logged
+	assert.NoError(t, new(oauth.LogSMSSender).SendSMS("5550100", "Your login code is 123456"))
+	assert.Contains(t, buf.String(), "5550100")
+	assert.NotContains(t, buf.String(), "123456")
+}
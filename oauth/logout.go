@@ -0,0 +1,462 @@
+package oauth
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/audit"
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth/jwt"
+	"github.com/RichardKnop/go-oauth2-server/session"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/RichardKnop/uuid"
+	jwtgo "github.com/dgrijalva/jwt-go"
+	josejwt "gopkg.in/square/go-jose.v2/jwt"
+)
+
+const (
+	logoutTokenLifetime = 2 * time.Minute
+
+	// Back-channel logout tokens are posted by a fixed number of workers,
+	// logouts arriving while the queue is full are dropped
+	backchannelLogoutWorkers   = 4
+	backchannelLogoutQueueSize = 1000
+
+	// csrfTokenField is the form field of the logout confirmation's CSRF token
+	csrfTokenField = "csrf_token"
+)
+
+var (
+	// ErrInvalidIDTokenHint ...
+	ErrInvalidIDTokenHint = errors.New("Invalid ID token hint")
+	// ErrInvalidPostLogoutRedirectURI ...
+	ErrInvalidPostLogoutRedirectURI = errors.New("Invalid post logout redirect URI")
+	// ErrInsecureLogoutURI ...
+	ErrInsecureLogoutURI = errors.New("Client logout URIs must be public https URLs")
+)
+
+// logoutPage loads the front-channel logout URIs of the clients in hidden
+// iframes, then continues to the post logout redirect URI if there is one
+var logoutPage = template.Must(template.New("logout").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>Logged out</title>
+{{if .RedirectURI}}<meta http-equiv="refresh" content="2;url={{.RedirectURI}}">{{end}}
+</head>
+<body>
+<p>You have been logged out.</p>
+{{range .FrontchannelURIs}}<iframe src="{{.}}" style="display:none"></iframe>
+{{end}}</body>
+</html>
+`))
+
+// logoutConfirmPage asks the user to confirm logging out, posting the
+// parameters of the logout request back with the CSRF token
+var logoutConfirmPage = template.Must(template.New("logout_confirm").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>Log out</title>
+</head>
+<body>
+<form method="post" action="{{.Action}}">
+<p>Do you want to log out?</p>
+<input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
+{{range $name, $value := .Params}}<input type="hidden" name="{{$name}}" value="{{$value}}">
+{{end}}<button type="submit">Log out</button>
+</form>
+</body>
+</html>
+`))
+
+// LogoutRequest holds the parameters of an RP-initiated logout request
+type LogoutRequest struct {
+	IDTokenHint           string
+	ClientID              string
+	PostLogoutRedirectURI string
+	State                 string
+}
+
+// NewLogoutRequest reads logout request parameters
+func NewLogoutRequest(values url.Values) *LogoutRequest {
+	return &LogoutRequest{
+		IDTokenHint:           values.Get("id_token_hint"),
+		ClientID:              values.Get("client_id"),
+		PostLogoutRedirectURI: values.Get("post_logout_redirect_uri"),
+		State:                 values.Get("state"),
+	}
+}
+
+// Logout is a validated logout request
+type Logout struct {
+	// Client sent the request, nil if unknown
+	Client *models.OauthClient
+	// UserID and SessionID are the sub and sid of the ID token hint
+	UserID    string
+	SessionID string
+	// RedirectURI is where to send the user agent afterwards, with the state
+	RedirectURI string
+}
+
+// ValidateLogoutRequest verifies the ID token hint and checks the post
+// logout redirect URI was registered by the client
+func (s *Service) ValidateLogoutRequest(req *LogoutRequest) (*Logout, error) {
+	logout := new(Logout)
+	if req.IDTokenHint != "" {
+		claims, client, err := s.verifyIDTokenHint(req.IDTokenHint)
+		if err != nil {
+			return nil, err
+		}
+		if req.ClientID != "" && !strings.EqualFold(req.ClientID, client.Key) {
+			return nil, ErrInvalidIDTokenHint
+		}
+		logout.Client = client
+		logout.UserID = claims.Subject
+		logout.SessionID = claims.SessionID
+	} else if req.ClientID != "" {
+		client, err := s.FindClientByClientID(req.ClientID)
+		if err != nil {
+			return nil, err
+		}
+		logout.Client = client
+	}
+
+	if req.PostLogoutRedirectURI == "" {
+		return logout, nil
+	}
+	// Only URIs registered by the client are redirected to
+	if logout.Client == nil ||
+		!util.StringInSlice(req.PostLogoutRedirectURI, strings.Fields(logout.Client.PostLogoutRedirectURIs)) {
+		return nil, ErrInvalidPostLogoutRedirectURI
+	}
+	redirectURI, err := url.Parse(req.PostLogoutRedirectURI)
+	if err != nil {
+		return nil, ErrInvalidPostLogoutRedirectURI
+	}
+	if req.State != "" {
+		query := redirectURI.Query()
+		query.Set("state", req.State)
+		redirectURI.RawQuery = query.Encode()
+	}
+	logout.RedirectURI = redirectURI.String()
+
+	return logout, nil
+}
+
+// EndSession logs the user of an SSO session out of the clients which got
+// authorization codes through it: the tokens the clients hold for the user
+// are revoked and back-channel logout tokens are sent to them. The returned
+// front-channel logout URIs are for the user agent to load.
+func (s *Service) EndSession(userID, sessionID string) ([]string, error) {
+	var sessionClients []*models.OauthSessionClient
+	err := s.db.Preload("Client").
+		Where("session_id = ? AND user_id = ?", sessionID, userID).
+		Find(&sessionClients).Error
+	if err != nil {
+		return nil, err
+	}
+	user := &models.OauthUser{ID: userID}
+
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	var tokens []string
+	var events []*audit.Event
+	for _, sessionClient := range sessionClients {
+		revoked, err := s.revokeUserClientTokens(tx, user, sessionClient.Client)
+		if err != nil {
+			tx.Rollback() // rollback the transaction
+			return nil, err
+		}
+		tokens = append(tokens, revoked...)
+		if len(revoked) == 0 {
+			continue
+		}
+		event := newTokensRevokedEvent(
+			&models.OauthUser{ID: userID, TenantID: sessionClient.TenantID},
+			sessionClient.Client,
+			"logout",
+			len(revoked),
+		)
+		if err := s.enqueueEvent(tx, event); err != nil {
+			tx.Rollback() // rollback the transaction
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	err = tx.Where("session_id = ?", sessionID).Delete(new(models.OauthSessionClient)).Error
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+	s.removeAccessTokensRedis(tokens)
+	for _, event := range events {
+		s.recordEvent(event)
+	}
+
+	var frontchannelURIs []string
+	for _, sessionClient := range sessionClients {
+		client := sessionClient.Client
+		if client.BackchannelLogoutURI.Valid {
+			s.queueBackchannelLogout(client, userID, sessionID)
+		}
+		if client.FrontchannelLogoutURI.Valid {
+			frontchannelURI, err := s.frontchannelLogoutURI(client, sessionID)
+			if err != nil {
+				s.logger.Errorf("Front-channel logout URI of client %s: %s", client.Key, err)
+				continue
+			}
+			frontchannelURIs = append(frontchannelURIs, frontchannelURI)
+		}
+	}
+
+	s.logger.Info("audit: user logged out", "user_id", userID, "session_id", sessionID, "clients", len(sessionClients))
+	return frontchannelURIs, nil
+}
+
+// UseSessionService sets the session service the end session endpoint
+// clears the SSO session of the user agent with
+func (s *Service) UseSessionService(sessionService session.ServiceInterface) {
+	s.sessionService = sessionService
+}
+
+// verifyIDTokenHint verifies the signature of an ID token the server issued
+// to a client, expired tokens are valid hints
+func (s *Service) verifyIDTokenHint(idTokenHint string) (*jwt.Claims, *models.OauthClient, error) {
+	token, err := josejwt.ParseSigned(idTokenHint)
+	if err != nil {
+		return nil, nil, ErrInvalidIDTokenHint
+	}
+
+	// The audience tells whose tenant keys the token is verified with
+	unverified := new(jwt.Claims)
+	if err := token.UnsafeClaimsWithoutVerification(unverified); err != nil {
+		return nil, nil, ErrInvalidIDTokenHint
+	}
+	client, err := s.FindClientByClientID(unverified.Audience)
+	if err != nil {
+		return nil, nil, ErrInvalidIDTokenHint
+	}
+	publicKey, err := s.getJWTVerificationKey(token, client.TenantID)
+	if err != nil {
+		return nil, nil, ErrInvalidIDTokenHint
+	}
+
+	claims := new(jwt.Claims)
+	if err := token.Claims(publicKey.Key, claims); err != nil {
+		return nil, nil, ErrInvalidIDTokenHint
+	}
+	if claims.TenantID != client.TenantID || claims.Subject == "" {
+		return nil, nil, ErrInvalidIDTokenHint
+	}
+	return claims, client, nil
+}
+
+// backchannelLogout is a logout token waiting to be posted to a client
+type backchannelLogout struct {
+	clientKey   string
+	uri         string
+	logoutToken string
+}
+
+// newBackchannelLogoutClient returns the client logout tokens are posted
+// with. Back-channel logout URIs are registered by clients, so it only
+// connects to public addresses and does not follow redirects
+func newBackchannelLogoutClient(cnf *config.Config) *http.Client {
+	return util.NewPublicHTTPClient(time.Duration(cnf.Oauth.BackchannelLogoutTimeout) * time.Second)
+}
+
+// queueBackchannelLogout signs a logout token for a client and queues it for
+// the back-channel logout workers
+func (s *Service) queueBackchannelLogout(client *models.OauthClient, userID, sessionID string) {
+	logoutToken, err := s.newLogoutToken(client, userID, sessionID)
+	if err != nil {
+		s.logger.Errorf("Logout token for client %s: %s", client.Key, err)
+		return
+	}
+
+	s.lazy.backchannelLogoutsOnce.Do(s.startBackchannelLogoutWorkers)
+	select {
+	case s.lazy.backchannelLogouts <- &backchannelLogout{
+		clientKey:   client.Key,
+		uri:         client.BackchannelLogoutURI.String,
+		logoutToken: logoutToken,
+	}:
+	default:
+		s.logger.Errorf("Back-channel logout of client %s dropped, too many logouts are queued", client.Key)
+	}
+}
+
+// startBackchannelLogoutWorkers starts the workers posting queued logout
+// tokens, they run until the service is closed
+func (s *Service) startBackchannelLogoutWorkers() {
+	queue := make(chan *backchannelLogout, backchannelLogoutQueueSize)
+	stop := make(chan struct{})
+	s.lazy.backchannelLogouts = queue
+	s.lazy.backchannelLogoutsStop = stop
+	for i := 0; i < backchannelLogoutWorkers; i++ {
+		s.lazy.backchannelLogoutsWG.Add(1)
+		go func() {
+			defer s.lazy.backchannelLogoutsWG.Done()
+			for {
+				select {
+				case logout := <-queue:
+					s.sendBackchannelLogout(logout, stop)
+				case <-stop:
+					return
+				}
+			}
+		}()
+	}
+}
+
+// stopBackchannelLogoutWorkers stops the workers, logouts still queued are
+// dropped
+func (s *Service) stopBackchannelLogoutWorkers() {
+	// Workers are not started anymore once the service is closed
+	s.lazy.backchannelLogoutsOnce.Do(func() {})
+	if s.lazy.backchannelLogoutsStop != nil {
+		close(s.lazy.backchannelLogoutsStop)
+		s.lazy.backchannelLogoutsWG.Wait()
+		s.lazy.backchannelLogoutsStop = nil
+	}
+}
+
+// sendBackchannelLogout posts a logout token to the back-channel logout URI
+// of a client, failed requests are retried with an increasing delay
+func (s *Service) sendBackchannelLogout(logout *backchannelLogout, stop <-chan struct{}) {
+	// Clients registered before https was required are refused
+	if !isHTTPSURL(logout.uri) {
+		s.logger.Errorf("Back-channel logout of client %s: %s", logout.clientKey, ErrInsecureLogoutURI)
+		return
+	}
+
+	delay := time.Second
+	for attempt := 0; attempt <= s.cnf.Oauth.BackchannelLogoutRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(delay):
+			case <-stop:
+				return
+			}
+			delay *= 2
+		}
+		resp, err := s.backchannelLogoutClient.PostForm(
+			logout.uri,
+			url.Values{"logout_token": {logout.logoutToken}},
+		)
+		if errors.Is(err, util.ErrInternalAddress) {
+			s.logger.Errorf("Back-channel logout of client %s: %s", logout.clientKey, ErrInsecureLogoutURI)
+			return
+		}
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusNoContent {
+				return
+			}
+			err = fmt.Errorf("status %d", resp.StatusCode)
+		}
+		s.logger.Warnf("Back-channel logout of client %s failed: %s", logout.clientKey, err)
+	}
+	s.logger.Errorf("Back-channel logout of client %s gave up after %d attempts",
+		logout.clientKey, s.cnf.Oauth.BackchannelLogoutRetries+1)
+}
+
+// newLogoutToken returns a signed back-channel logout token for a client
+func (s *Service) newLogoutToken(client *models.OauthClient, userID, sessionID string) (string, error) {
+	tenant, err := s.GetTenantConfig(client.TenantID)
+	if err != nil {
+		return "", err
+	}
+	privateJwk, err := s.getJWKPrivateKey(client.TenantID)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := &jwt.LogoutClaims{
+		StandardClaims: jwtgo.StandardClaims{
+			Audience:  client.Key,
+			ExpiresAt: now.Add(logoutTokenLifetime).Unix(),
+			Id:        uuid.New(),
+			IssuedAt:  now.Unix(),
+			Issuer:    tenant.Issuer,
+			Subject:   userID,
+		},
+		SessionID: sessionID,
+		Events:    map[string]interface{}{jwt.BackchannelLogoutEvent: struct{}{}},
+	}
+	token := jwtgo.NewWithClaims(jwtgo.SigningMethodRS256, claims)
+	token.Header["kid"] = privateJwk.Public().KeyID
+	token.Header["typ"] = "logout+jwt"
+	return token.SignedString(privateJwk.Key.(*rsa.PrivateKey))
+}
+
+// frontchannelLogoutURI returns the front-channel logout URI of a client with
+// the iss and sid parameters identifying the session
+func (s *Service) frontchannelLogoutURI(client *models.OauthClient, sessionID string) (string, error) {
+	tenant, err := s.GetTenantConfig(client.TenantID)
+	if err != nil {
+		return "", err
+	}
+	frontchannelURI, err := url.Parse(client.FrontchannelLogoutURI.String)
+	if err != nil {
+		return "", err
+	}
+	query := frontchannelURI.Query()
+	query.Set("iss", tenant.Issuer)
+	query.Set("sid", sessionID)
+	frontchannelURI.RawQuery = query.Encode()
+	return frontchannelURI.String(), nil
+}
+
+// writeLogoutConfirmPage writes the form confirming a logout request, it
+// cannot be framed so other sites cannot trick the user into submitting it
+func writeLogoutConfirmPage(w http.ResponseWriter, r *http.Request, req *LogoutRequest, csrfToken string) {
+	params := map[string]string{}
+	for name, value := range map[string]string{
+		"id_token_hint":            req.IDTokenHint,
+		"client_id":                req.ClientID,
+		"post_logout_redirect_uri": req.PostLogoutRedirectURI,
+		"state":                    req.State,
+	} {
+		if value != "" {
+			params[name] = value
+		}
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "frame-ancestors 'none'")
+	logoutConfirmPage.Execute(w, map[string]interface{}{
+		"Action":    r.URL.Path,
+		"CSRFToken": csrfToken,
+		"Params":    params,
+	})
+}
+
+// writeLogoutPage writes the page loading the front-channel logout URIs,
+// or redirects right away when there are none
+func writeLogoutPage(w http.ResponseWriter, r *http.Request, redirectURI string, frontchannelURIs []string) {
+	if len(frontchannelURIs) == 0 && redirectURI != "" {
+		http.Redirect(w, r, redirectURI, http.StatusFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	logoutPage.Execute(w, map[string]interface{}{
+		"RedirectURI":      redirectURI,
+		"FrontchannelURIs": frontchannelURIs,
+	})
+}
@@ -0,0 +1,181 @@
+package oauth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
+	"github.com/RichardKnop/go-oauth2-server/session"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/gorilla/sessions"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestValidateLogoutRequest() {
+	client := suite.clients[0]
+	user, err := suite.service.CreateUser(roles.User, "test@logout_request", "correct_horse_battery", "")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	suite.db.Model(client).UpdateColumn("post_logout_redirect_uris", "https://www.example.com/logged-out")
+	defer suite.db.Model(client).UpdateColumn("post_logout_redirect_uris", "")
+
+	accessToken, _, err := suite.service.Login(client, user, "read")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	idToken, err := suite.service.GrantJWT(user, 3600, "read", accessToken)
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+
+	// The ID token hint identifies the client and user
+	logout, err := suite.service.ValidateLogoutRequest(&oauth.LogoutRequest{
+		IDTokenHint:           idToken,
+		PostLogoutRedirectURI: "https://www.example.com/logged-out",
+		State:                 "some_state",
+	})
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), client.Key, logout.Client.Key)
+		assert.Equal(suite.T(), user.ID, logout.UserID)
+		assert.Equal(suite.T(), "https://www.example.com/logged-out?state=some_state", logout.RedirectURI)
+	}
+
+	// Only registered URIs are redirected to
+	_, err = suite.service.ValidateLogoutRequest(&oauth.LogoutRequest{
+		IDTokenHint:           idToken,
+		PostLogoutRedirectURI: "https://evil.example.com",
+	})
+	assert.Equal(suite.T(), oauth.ErrInvalidPostLogoutRedirectURI, err)
+	_, err = suite.service.ValidateLogoutRequest(&oauth.LogoutRequest{
+		PostLogoutRedirectURI: "https://www.example.com/logged-out",
+	})
+	assert.Equal(suite.T(), oauth.ErrInvalidPostLogoutRedirectURI, err)
+
+	// Hints must be signed by the server and match the client
+	_, err = suite.service.ValidateLogoutRequest(&oauth.LogoutRequest{IDTokenHint: idToken + "x"})
+	assert.Equal(suite.T(), oauth.ErrInvalidIDTokenHint, err)
+	_, err = suite.service.ValidateLogoutRequest(&oauth.LogoutRequest{
+		IDTokenHint: idToken,
+		ClientID:    suite.clients[1].Key,
+	})
+	assert.Equal(suite.T(), oauth.ErrInvalidIDTokenHint, err)
+}
+
+func (suite *OauthTestSuite) TestEndSession() {
+	client := suite.clients[0]
+	user, err := suite.service.CreateUser(roles.User, "test@end_session", "correct_horse_battery", "")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	assert.NoError(suite.T(), suite.service.GrantConsent(client, user, "read"))
+
+	// The client is told about the logout on both channels
+	logoutTokens := make(chan string, 1)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		logoutTokens <- r.PostForm.Get("logout_token")
+	}))
+	defer server.Close()
+	suite.service.UseBackchannelLogoutClient(server.Client())
+	defer suite.service.UseBackchannelLogoutClient(nil)
+	suite.db.Model(client).UpdateColumns(map[string]interface{}{
+		"backchannel_logout_uri":  util.StringOrNull(server.URL),
+		"frontchannel_logout_uri": util.StringOrNull("https://www.example.com/frontchannel"),
+	})
+	defer suite.db.Model(client).UpdateColumns(map[string]interface{}{
+		"backchannel_logout_uri":  nil,
+		"frontchannel_logout_uri": nil,
+	})
+
+	ssoSession := &session.SSOSession{
+		ID:       "test_session_id",
+		UserID:   user.ID,
+		TenantID: user.TenantID,
+		AuthTime: time.Now(),
+		AMR:      []string{"pwd"},
+	}
+	_, err = suite.service.GrantAuthorizationCodeForRequest(client, user, &oauth.AuthorizationRequest{
+		ClientID:     client.Key,
+		ResponseType: "code",
+		RedirectURI:  "https://www.example.com",
+		Scope:        "read",
+	}, ssoSession)
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	accessToken, _, err := suite.service.Login(client, user, "read")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+
+	frontchannelURIs, err := suite.service.EndSession(user.ID, ssoSession.ID)
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	if assert.Len(suite.T(), frontchannelURIs, 1) {
+		frontchannelURI, err := url.Parse(frontchannelURIs[0])
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), ssoSession.ID, frontchannelURI.Query().Get("sid"))
+	}
+	select {
+	case logoutToken := <-logoutTokens:
+		assert.NotEmpty(suite.T(), logoutToken)
+	case <-time.After(5 * time.Second):
+		assert.Fail(suite.T(), "Back-channel logout not received")
+	}
+
+	// The tokens of the session's clients are revoked
+	_, err = suite.service.Authenticate(accessToken.Token)
+	assert.Equal(suite.T(), oauth.ErrAccessTokenNotFound, err)
+	var count int
+	suite.db.Model(new(models.OauthSessionClient)).Where("session_id = ?", ssoSession.ID).Count(&count)
+	assert.Equal(suite.T(), 0, count)
+}
+
+func (suite *OauthTestSuite) TestEndSessionHandlerConfirmation() {
+	sessionService := session.NewService(suite.cnf, sessions.NewCookieStore([]byte("test_secret")))
+	suite.service.UseSessionService(sessionService)
+	defer suite.service.UseSessionService(nil)
+
+	// Without an ID token hint the user is asked to confirm
+	r, err := http.NewRequest("GET", "http://1.2.3.4/v1/oauth/logout", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Equal(suite.T(), "DENY", w.Header().Get("X-Frame-Options"))
+	matches := regexp.MustCompile(`name="csrf_token" value="([^"]+)"`).FindStringSubmatch(w.Body.String())
+	if !assert.Len(suite.T(), matches, 2) {
+		return
+	}
+	cookies := w.Result().Cookies()
+
+	// Posting without the CSRF token asks again
+	r, err = http.NewRequest("POST", "http://1.2.3.4/v1/oauth/logout", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.PostForm = url.Values{}
+	for _, cookie := range cookies {
+		r.AddCookie(cookie)
+	}
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	assert.Contains(suite.T(), w.Body.String(), `name="csrf_token"`)
+
+	// Posting with the CSRF token logs out
+	r, err = http.NewRequest("POST", "http://1.2.3.4/v1/oauth/logout", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.PostForm = url.Values{"csrf_token": {matches[1]}}
+	for _, cookie := range cookies {
+		r.AddCookie(cookie)
+	}
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "You have been logged out.")
+}
@@ -0,0 +1,112 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidHeader ...
+	ErrInvalidHeader = errors.New("Email header contains a line break")
+)
+
+// Message is a plain text email
+type Message struct {
+	From    string
+	To      string
+	Subject string
+	Body    string
+}
+
+// Bytes returns the message in RFC 5322 format
+func (m *Message) Bytes() ([]byte, error) {
+	for _, header := range []string{m.From, m.To, m.Subject} {
+		if strings.ContainsAny(header, "\r\n") {
+			return nil, ErrInvalidHeader
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", m.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", m.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().UTC().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.Replace(m.Body, "\n", "\r\n", -1))
+	return buf.Bytes(), nil
+}
+
+// Mailer delivers emails
+type Mailer interface {
+	Send(msg *Message) error
+}
+
+// SMTPMailer sends emails through an SMTP server, authenticating when a
+// username is set
+type SMTPMailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+}
+
+// NewSMTPMailer returns a new SMTPMailer instance
+func NewSMTPMailer(host string, port int, username, password string) *SMTPMailer {
+	return &SMTPMailer{Host: host, Port: port, Username: username, Password: password}
+}
+
+// Send sends the message
+func (m *SMTPMailer) Send(msg *Message) error {
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+	addr := m.Host + ":" + strconv.Itoa(m.Port)
+	return smtp.SendMail(addr, auth, msg.From, []string{msg.To}, data)
+}
+
+// FileMailer drops every email as an .eml file into a directory instead of
+// sending it, for local development and tests
+type FileMailer struct {
+	Dir string
+}
+
+// NewFileMailer returns a new FileMailer instance
+func NewFileMailer(dir string) *FileMailer {
+	return &FileMailer{Dir: dir}
+}
+
+// Send writes the message to a new file
+func (m *FileMailer) Send(msg *Message) error {
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.Dir, 0700); err != nil {
+		return err
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), hex.EncodeToString(suffix))
+	return ioutil.WriteFile(filepath.Join(m.Dir, name), data, 0600)
+}
@@ -0,0 +1,50 @@
+package mail
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageBytes(t *testing.T) {
+	msg := &Message{
+		From:    "no-reply@example.com",
+		To:      "john@example.com",
+		Subject: "Héllo",
+		Body:    "line 1\nline 2\n",
+	}
+	data, err := msg.Bytes()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Contains(t, string(data), "From: no-reply@example.com\r\n")
+	assert.Contains(t, string(data), "To: john@example.com\r\n")
+	assert.Contains(t, string(data), "Subject: =?utf-8?q?H=C3=A9llo?=\r\n")
+	assert.True(t, strings.HasSuffix(string(data), "\r\n\r\nline 1\r\nline 2\r\n"))
+
+	// Line breaks cannot inject headers
+	msg.To = "john@example.com\r\nBcc: jane@example.com"
+	_, err = msg.Bytes()
+	assert.Equal(t, ErrInvalidHeader, err)
+}
+
+func TestFileMailer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mail")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	mailer := NewFileMailer(filepath.Join(dir, "drop"))
+	msg := &Message{From: "no-reply@example.com", To: "john@example.com", Subject: "Test", Body: "body"}
+	assert.NoError(t, mailer.Send(msg))
+	assert.NoError(t, mailer.Send(msg))
+
+	files, err := filepath.Glob(filepath.Join(dir, "drop", "*.eml"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+}
@@ -0,0 +1,55 @@
+package mail
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// DefaultTemplatesDir holds the bundled email templates
+const DefaultTemplatesDir = "oauth/mail/templates"
+
+// Names of the bundled templates
+const (
+	EmailConfirmationTemplate = "email_confirmation"
+	PasswordResetTemplate     = "password_reset"
+)
+
+var (
+	// ErrTemplateWithoutSubject ...
+	ErrTemplateWithoutSubject = errors.New("Email template must start with a Subject line")
+)
+
+// Templates renders emails from text templates named <name>.txt. A template
+// starts with a "Subject: ..." line, the body follows after a blank line.
+type Templates struct {
+	templates *template.Template
+}
+
+// LoadTemplates parses the templates of a directory
+func LoadTemplates(dir string) (*Templates, error) {
+	templates, err := template.ParseGlob(filepath.Join(dir, "*.txt"))
+	if err != nil {
+		return nil, err
+	}
+	return &Templates{templates: templates}, nil
+}
+
+// Render returns the message of a template, without sender and recipient
+func (t *Templates) Render(name string, data interface{}) (*Message, error) {
+	var buf bytes.Buffer
+	if err := t.templates.ExecuteTemplate(&buf, name+".txt", data); err != nil {
+		return nil, err
+	}
+
+	parts := strings.SplitN(buf.String(), "\n\n", 2)
+	if len(parts) != 2 || !strings.HasPrefix(parts[0], "Subject: ") || strings.Contains(parts[0], "\n") {
+		return nil, ErrTemplateWithoutSubject
+	}
+	return &Message{
+		Subject: strings.TrimSpace(strings.TrimPrefix(parts[0], "Subject: ")),
+		Body:    parts[1],
+	}, nil
+}
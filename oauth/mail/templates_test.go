@@ -0,0 +1,53 @@
+package mail
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBundledTemplates(t *testing.T) {
+	templates, err := LoadTemplates("templates")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	data := map[string]string{
+		"Email":     "john@example.com",
+		"Link":      "https://example.com/confirm?token=abc",
+		"ExpiresIn": "24 hours",
+	}
+	for _, name := range []string{EmailConfirmationTemplate, PasswordResetTemplate} {
+		msg, err := templates.Render(name, data)
+		if !assert.NoError(t, err, name) {
+			continue
+		}
+		assert.NotEmpty(t, msg.Subject, name)
+		assert.Contains(t, msg.Body, "https://example.com/confirm?token=abc", name)
+		assert.Contains(t, msg.Body, "john@example.com", name)
+		assert.Contains(t, msg.Body, "24 hours", name)
+	}
+}
+
+func TestTemplateWithoutSubject(t *testing.T) {
+	dir, err := ioutil.TempDir("", "templates")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "bogus.txt"), []byte("Hello {{.}}"), 0600)
+	assert.NoError(t, err)
+	templates, err := LoadTemplates(dir)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = templates.Render("bogus", "world")
+	assert.Equal(t, ErrTemplateWithoutSubject, err)
+
+	_, err = templates.Render("missing", nil)
+	assert.Error(t, err)
+}
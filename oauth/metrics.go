@@ -0,0 +1,60 @@
+package oauth
+
+import (
+	"github.com/RichardKnop/go-oauth2-server/metrics"
+	"github.com/RichardKnop/go-oauth2-server/models"
+)
+
+// failureReasons maps authentication errors to the reason label of the
+// failed authentications metric
+var failureReasons = map[error]string{
+	ErrClientNotFound:             "invalid_client",
+	ErrInvalidClientSecret:        "invalid_client",
+	ErrInvalidClientIDOrSecret:    "invalid_client",
+	ErrInvalidClientAssertion:     "invalid_client_assertion",
+	ErrInvalidClientAssertionType: "invalid_client_assertion",
+	ErrClientAssertionReplayed:    "invalid_client_assertion",
+	ErrClientAuthMethodNotAllowed: "auth_method_not_allowed",
+	ErrUserNotFound:               "invalid_credentials",
+	ErrInvalidUserPassword:        "invalid_credentials",
+	ErrInvalidUsernameOrPassword:  "invalid_credentials",
+	ErrUserPasswordNotSet:         "invalid_credentials",
+	ErrUserDisabled:               "user_disabled",
+	ErrLoginLockedOut:             "locked_out",
+	ErrPasswordExpired:            "password_expired",
+	ErrInvalidLoginCode:           "invalid_login_code",
+	ErrInvalidMFAToken:            "invalid_mfa",
+	ErrInvalidOTP:                 "invalid_mfa",
+	ErrInvalidRecoveryCode:        "invalid_mfa",
+	ErrMFAAttemptsExceeded:        "invalid_mfa",
+	ErrTokenMissing:               "token_missing",
+	ErrAccessTokenNotFound:        "token_not_found",
+	ErrAccessTokenExpired:         "token_expired",
+	ErrTokenBindingMismatch:       "token_binding_mismatch",
+	ErrInvalidDPoPProof:           "invalid_dpop_proof",
+	ErrDPoPProofReplayed:          "invalid_dpop_proof",
+}
+
+// observeGrant counts the tokens a grant issued, or the failed login of a
+// grant logging a user in
+func observeGrant(grantDTO *GrantDTO, client *models.OauthClient, err error) {
+	if err == nil {
+		metrics.TokenIssued(grantDTO.GrantType, client.TenantID)
+		return
+	}
+	// Users asked for a second factor have not failed to log in
+	if _, ok := err.(*MFARequiredError); ok || !isLoginGrantType(grantDTO.GrantType) {
+		return
+	}
+	observeAuthFailure(err)
+}
+
+// observeAuthFailure counts a failed client, user or access token
+// authentication
+func observeAuthFailure(err error) {
+	reason, ok := failureReasons[err]
+	if !ok {
+		reason = "other"
+	}
+	metrics.AuthenticationFailed(reason)
+}
@@ -0,0 +1,481 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/audit"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth/tokentypes"
+	"github.com/RichardKnop/go-oauth2-server/oauth/totp"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/RichardKnop/uuid"
+	"github.com/go-redis/redis/v7"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// MFAOTPGrantType completes a login with a code of the user's authenticator
+	MFAOTPGrantType = "http://auth0.com/oauth/grant-type/mfa-otp"
+	// MFARecoveryCodeGrantType completes a login with a recovery code
+	MFARecoveryCodeGrantType = "http://auth0.com/oauth/grant-type/mfa-recovery-code"
+
+	mfaTokenPrefix        = "mfa_token:"
+	mfaAttemptsPrefix     = "mfa_attempts:"
+	mfaUsedOTPPrefix      = "mfa_used:"
+	mfaUserAttemptsPrefix = "mfa_user_attempts:"
+
+	recoveryCodeLength = 10
+)
+
+var (
+	// ErrMFARequired ...
+	ErrMFARequired = errors.New("Multi-factor authentication required")
+	// ErrInvalidMFAToken ...
+	ErrInvalidMFAToken = errors.New("Invalid or expired mfa_token")
+	// ErrInvalidOTP ...
+	ErrInvalidOTP = errors.New("Invalid one-time password")
+	// ErrInvalidRecoveryCode ...
+	ErrInvalidRecoveryCode = errors.New("Invalid recovery code")
+	// ErrMFANotEnrolled ...
+	ErrMFANotEnrolled = errors.New("User has not enrolled an authenticator")
+	// ErrMFAAlreadyEnrolled ...
+	ErrMFAAlreadyEnrolled = errors.New("User has already enrolled an authenticator")
+	// ErrMFAEnrollmentNotStarted ...
+	ErrMFAEnrollmentNotStarted = errors.New("Authenticator enrollment has not been started")
+	// ErrMFAAttemptsExceeded ...
+	ErrMFAAttemptsExceeded = errors.New("Too many wrong codes, log in again")
+
+	recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+)
+
+// MFARequiredError is returned by grants when the user must complete a
+// second factor, the mfa_token identifies the pending login
+type MFARequiredError struct {
+	MFAToken string
+	// EnrollmentRequired is true when the user must enroll an authenticator
+	// with the mfa_token first
+	EnrollmentRequired bool
+}
+
+// Error returns the message of ErrMFARequired
+func (e *MFARequiredError) Error() string {
+	return ErrMFARequired.Error()
+}
+
+// MarshalJSON encodes the error like other error responses, with the
+// mfa_token: {"error":"mfa_required","error_description":"...","mfa_token":"..."}
+func (e *MFARequiredError) MarshalJSON() ([]byte, error) {
+	body := map[string]interface{}{
+		"error":             "mfa_required",
+		"error_description": e.Error(),
+		"mfa_token":         e.MFAToken,
+	}
+	if e.EnrollmentRequired {
+		body["enrollment_required"] = true
+	}
+	return json.Marshal(body)
+}
+
+// TOTPEnrollment is the secret of a started authenticator enrollment
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// mfaChallenge is the login pending a second factor, stored for the
+// lifetime of its mfa_token
+type mfaChallenge struct {
+	UserID   string `json:"user_id"`
+	ClientID string `json:"client_id"`
+	TenantID string `json:"tenant_id"`
+	Scope    string `json:"scope"`
+}
+
+// mfaRequired returns true if the user must complete a second factor to log
+// in, users with an authenticator always do
+func mfaRequired(tenant *TenantConfig, user *models.OauthUser) bool {
+	return user.MFAEnabled || user.MFARequired || tenant.RequireMFA
+}
+
+// isMFAGrantType returns true for the grant types completing a pending login
+func isMFAGrantType(grantType string) bool {
+	return grantType == MFAOTPGrantType || grantType == MFARecoveryCodeGrantType
+}
+
+// newMFARequiredError stores a pending login of the user and returns the
+// error carrying its mfa_token
+func (s *Service) newMFARequiredError(client *models.OauthClient, user *models.OauthUser, scope string) error {
+	data, err := json.Marshal(&mfaChallenge{
+		UserID:   user.ID,
+		ClientID: client.ID,
+		TenantID: client.TenantID,
+		Scope:    scope,
+	})
+	if err != nil {
+		return err
+	}
+
+	mfaToken := uuid.New()
+	lifetime := time.Duration(s.cnf.MFA.TokenLifetime) * time.Second
+	if err := s.redis.Set(mfaTokenPrefix+mfaToken, data, lifetime).Err(); err != nil {
+		return err
+	}
+
+	return &MFARequiredError{MFAToken: mfaToken, EnrollmentRequired: !user.MFAEnabled}
+}
+
+// getMFAChallenge returns the pending login of an mfa_token
+func (s *Service) getMFAChallenge(mfaToken string) (*mfaChallenge, error) {
+	if mfaToken == "" {
+		return nil, ErrInvalidMFAToken
+	}
+	data, err := s.redis.Get(mfaTokenPrefix + mfaToken).Bytes()
+	if err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+	challenge := new(mfaChallenge)
+	if err := json.Unmarshal(data, challenge); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// getMFAChallengeUser returns the pending login of an mfa_token issued to
+// the client and its user
+func (s *Service) getMFAChallengeUser(mfaToken string, client *models.OauthClient) (*mfaChallenge, *models.OauthUser, error) {
+	challenge, err := s.getMFAChallenge(mfaToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	if challenge.ClientID != client.ID {
+		return nil, nil, ErrInvalidMFAToken
+	}
+	user, err := s.FindUserByID(challenge.UserID)
+	if err != nil {
+		return nil, nil, ErrInvalidMFAToken
+	}
+	if user.Disabled {
+		return nil, nil, ErrUserDisabled
+	}
+	return challenge, user, nil
+}
+
+// recordMFAFailure counts a wrong code for an mfa_token, which is revoked
+// after the maximum attempts
+func (s *Service) recordMFAFailure(mfaToken string) {
+	attemptsKey := mfaAttemptsPrefix + mfaToken
+	attempts, err := s.redis.Incr(attemptsKey).Result()
+	if err != nil {
+		s.logger.Errorf("Recording failed MFA attempt failed: %s", err)
+		return
+	}
+	if attempts == 1 {
+		s.redis.Expire(attemptsKey, time.Duration(s.cnf.MFA.TokenLifetime)*time.Second)
+	}
+	if attempts >= int64(s.cnf.MFA.MaxAttempts) {
+		s.redis.Del(mfaTokenPrefix+mfaToken, attemptsKey)
+	}
+}
+
+// verifyOTP checks a code of the user's authenticator, every code is only
+// accepted once
+func (s *Service) verifyOTP(user *models.OauthUser, code string) error {
+	if !user.MFAEnabled || !user.TOTPSecret.Valid {
+		return ErrMFANotEnrolled
+	}
+	return s.useOTP(user, code)
+}
+
+// useOTP checks a code of the user's secret and marks it as used, so a code
+// confirming an enrollment cannot complete a login as well
+func (s *Service) useOTP(user *models.OauthUser, code string) error {
+	step, ok := totp.Validate(user.TOTPSecret.String, code, time.Now())
+	if !ok {
+		return ErrInvalidOTP
+	}
+
+	// A code stays valid for the skew around its time step
+	lifetime := time.Duration((2*totp.Skew+1)*totp.Period) * time.Second
+	key := fmt.Sprintf("%s%s:%d", mfaUsedOTPPrefix, user.ID, step)
+	fresh, err := s.redis.SetNX(key, 1, lifetime).Result()
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrInvalidOTP
+	}
+	return nil
+}
+
+// useRecoveryCode marks one of the user's unused recovery codes as used
+func (s *Service) useRecoveryCode(user *models.OauthUser, code string) error {
+	result := s.db.Model(new(models.OauthRecoveryCode)).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, hashRecoveryCode(code)).
+		UpdateColumn("used_at", time.Now().UTC())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != 1 {
+		return ErrInvalidRecoveryCode
+	}
+	return nil
+}
+
+// MFARequired returns true if the user must complete a second factor to log
+// in, because the user or the user's tenant requires it or the user has an
+// authenticator
+func (s *Service) MFARequired(user *models.OauthUser) (bool, error) {
+	tenant, err := s.GetTenantConfig(user.TenantID)
+	if err != nil {
+		return false, err
+	}
+	return mfaRequired(tenant, user), nil
+}
+
+// VerifyMFACode checks the second factor of a user logging in through the
+// web pages, a code of the user's authenticator or else a recovery code.
+// After the maximum wrong codes the user gets ErrMFAAttemptsExceeded and
+// must log in with the password again.
+func (s *Service) VerifyMFACode(user *models.OauthUser, otp, recoveryCode string) error {
+	attemptsKey := mfaUserAttemptsPrefix + user.ID
+	attempts, err := s.redis.Get(attemptsKey).Int64()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	if attempts >= int64(s.cnf.MFA.MaxAttempts) {
+		return ErrMFAAttemptsExceeded
+	}
+
+	if recoveryCode != "" {
+		if !user.MFAEnabled {
+			return ErrMFANotEnrolled
+		}
+		err = s.useRecoveryCode(user, recoveryCode)
+	} else {
+		err = s.verifyOTP(user, otp)
+	}
+	if err == ErrInvalidOTP || err == ErrInvalidRecoveryCode {
+		pipe := s.redis.TxPipeline()
+		pipe.Incr(attemptsKey)
+		pipe.Expire(attemptsKey, time.Duration(s.cnf.MFA.TokenLifetime)*time.Second)
+		if _, err := pipe.Exec(); err != nil {
+			s.logger.Errorf("Recording failed MFA attempt failed: %s", err)
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	s.redis.Del(attemptsKey)
+	return nil
+}
+
+// BeginTOTPEnrollment generates a new authenticator secret for the user,
+// enrollment completes once a code of it is confirmed
+func (s *Service) BeginTOTPEnrollment(user *models.OauthUser) (*TOTPEnrollment, error) {
+	if user.MFAEnabled {
+		return nil, ErrMFAAlreadyEnrolled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(user).UpdateColumn("totp_secret", secret).Error; err != nil {
+		return nil, err
+	}
+	user.TOTPSecret = util.StringOrNull(secret)
+
+	account := user.Account
+	if account == "" {
+		account = user.Phone
+	}
+	return &TOTPEnrollment{
+		Secret: secret,
+		URI:    totp.URI(s.cnf.MFA.Issuer, account, secret),
+	}, nil
+}
+
+// ConfirmTOTPEnrollment enables MFA for the user once a code of the new
+// secret is valid, returning the user's recovery codes
+func (s *Service) ConfirmTOTPEnrollment(user *models.OauthUser, code string) ([]string, error) {
+	if user.MFAEnabled {
+		return nil, ErrMFAAlreadyEnrolled
+	}
+	if !user.TOTPSecret.Valid {
+		return nil, ErrMFAEnrollmentNotStarted
+	}
+	if err := s.useOTP(user, code); err != nil {
+		return nil, err
+	}
+
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	if err := tx.Model(user).UpdateColumn("mfa_enabled", true).Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+	codes, err := s.replaceRecoveryCodes(tx, user)
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+	user.MFAEnabled = true
+
+	s.logger.Info("audit: authenticator enrolled", "user_id", user.ID, "tenant_id", user.TenantID)
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes, a code of the
+// user's authenticator is required
+func (s *Service) RegenerateRecoveryCodes(user *models.OauthUser, code string) ([]string, error) {
+	if err := s.verifyOTP(user, code); err != nil {
+		return nil, err
+	}
+
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	codes, err := s.replaceRecoveryCodes(tx, user)
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// ResetMFA removes the user's authenticator and recovery codes, users who
+// are required to use MFA enroll again on their next login
+func (s *Service) ResetMFA(user *models.OauthUser) error {
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	err := tx.Model(user).UpdateColumns(map[string]interface{}{
+		"totp_secret": nil,
+		"mfa_enabled": false,
+	}).Error
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+	if err := tx.Where("user_id = ?", user.ID).Delete(new(models.OauthRecoveryCode)).Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+	user.TOTPSecret = util.StringOrNull("")
+	user.MFAEnabled = false
+
+	s.logger.Info("audit: MFA reset", "user_id", user.ID, "tenant_id", user.TenantID)
+	return nil
+}
+
+// SetMFARequired requires or stops requiring MFA for the user
+func (s *Service) SetMFARequired(user *models.OauthUser, required bool) error {
+	if err := s.db.Model(user).UpdateColumn("mfa_required", required).Error; err != nil {
+		return err
+	}
+	user.MFARequired = required
+	return nil
+}
+
+// replaceRecoveryCodes deletes the user's recovery codes and stores hashes
+// of new ones, returning the new codes
+func (s *Service) replaceRecoveryCodes(db *gorm.DB, user *models.OauthUser) ([]string, error) {
+	if err := db.Where("user_id = ?", user.ID).Delete(new(models.OauthRecoveryCode)).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, s.cnf.MFA.RecoveryCodes)
+	for i := range codes {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		recoveryCode := &models.OauthRecoveryCode{
+			UserID:    user.ID,
+			CodeHash:  hashRecoveryCode(code),
+			CreatedAt: time.Now().UTC(),
+		}
+		if err := db.Create(recoveryCode).Error; err != nil {
+			return nil, err
+		}
+		codes[i] = code
+	}
+	return codes, nil
+}
+
+// newRecoveryCode returns a random code formatted as xxxxx-xxxxx
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))[:recoveryCodeLength]
+	return code[:recoveryCodeLength/2] + "-" + code[recoveryCodeLength/2:], nil
+}
+
+// hashRecoveryCode returns the SHA256 hash of a recovery code, ignoring
+// case, dashes and spaces users may type differently
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// completeMFALogin issues the tokens of a pending login once the second
+// factor is verified, the mfa_token cannot be used again
+func (s *Service) completeMFALogin(grantDTO *GrantDTO, client *models.OauthClient, user *models.OauthUser, challenge *mfaChallenge) (*AccessTokenResponse, error) {
+	s.redis.Del(mfaTokenPrefix+grantDTO.MFAToken, mfaAttemptsPrefix+grantDTO.MFAToken)
+
+	// Log in the user
+	accessToken, refreshToken, err := s.login(grantDTO.Tenant, client, user, challenge.Scope, grantDTO.Binding, audit.TokenIssued)
+	if err != nil {
+		return nil, err
+	}
+
+	var jwt string
+	if s.cnf.Oauth.Jwt {
+		jwt, err = s.GrantJWT(user, grantDTO.Tenant.AccessTokenLifetime, accessToken.Scope, accessToken)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Create response
+	return NewAccessTokenResponse(
+		accessToken,
+		refreshToken,
+		grantDTO.Tenant.AccessTokenLifetime,
+		tokentypes.Bearer,
+		jwt,
+	)
+}
@@ -0,0 +1,233 @@
+package oauth_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
+	"github.com/RichardKnop/go-oauth2-server/oauth/totp"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestMFAOTPGrant() {
+	user, err := suite.service.CreateUser(roles.User, "test@mfa", "correct_horse_battery", "")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	secret := suite.enrollTOTP(user)
+
+	// The password grant asks for the second factor
+	w := suite.mfaTokenRequest(`{"grant_type": "password", "username": "test@mfa", "password": "correct_horse_battery", "scope": "read"}`)
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+	mfaToken := suite.mfaToken(w)
+
+	// Wrong codes are refused
+	w = suite.mfaTokenRequest(`{"grant_type": "` + oauth.MFAOTPGrantType + `", "mfa_token": "` + mfaToken + `", "otp": "000000"}`)
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+	// The code of the authenticator completes the login
+	code, err := totp.Code(secret, time.Now())
+	assert.NoError(suite.T(), err)
+	w = suite.mfaTokenRequest(`{"grant_type": "` + oauth.MFAOTPGrantType + `", "mfa_token": "` + mfaToken + `", "otp": "` + code + `"}`)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	// Neither the mfa_token nor the code can be used again
+	w = suite.mfaTokenRequest(`{"grant_type": "` + oauth.MFAOTPGrantType + `", "mfa_token": "` + mfaToken + `", "otp": "` + code + `"}`)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	w = suite.mfaTokenRequest(`{"grant_type": "password", "username": "test@mfa", "password": "correct_horse_battery", "scope": "read"}`)
+	mfaToken = suite.mfaToken(w)
+	w = suite.mfaTokenRequest(`{"grant_type": "` + oauth.MFAOTPGrantType + `", "mfa_token": "` + mfaToken + `", "otp": "` + code + `"}`)
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+}
+
+func (suite *OauthTestSuite) TestMFARecoveryCodeGrant() {
+	user, err := suite.service.CreateUser(roles.User, "test@recovery", "correct_horse_battery", "")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	secret := suite.enrollTOTP(user)
+
+	// Regenerating recovery codes replaces the old ones
+	code, err := totp.Code(secret, time.Now())
+	assert.NoError(suite.T(), err)
+	codes, err := suite.service.RegenerateRecoveryCodes(user, code)
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	assert.Len(suite.T(), codes, suite.cnf.MFA.RecoveryCodes)
+	var count int
+	suite.db.Model(new(models.OauthRecoveryCode)).Where("user_id = ?", user.ID).Count(&count)
+	assert.Equal(suite.T(), suite.cnf.MFA.RecoveryCodes, count)
+
+	recoveryCodeGrant := func(recoveryCode string) int {
+		w := suite.mfaTokenRequest(`{"grant_type": "password", "username": "test@recovery", "password": "correct_horse_battery", "scope": "read"}`)
+		mfaToken := suite.mfaToken(w)
+		w = suite.mfaTokenRequest(`{"grant_type": "` + oauth.MFARecoveryCodeGrantType + `", "mfa_token": "` + mfaToken + `", "recovery_code": "` + recoveryCode + `"}`)
+		return w.Code
+	}
+
+	// Recovery codes are accepted once, whatever their case
+	assert.Equal(suite.T(), http.StatusOK, recoveryCodeGrant(strings.ToUpper(codes[0])))
+	assert.Equal(suite.T(), http.StatusForbidden, recoveryCodeGrant(codes[0]))
+	assert.Equal(suite.T(), http.StatusOK, recoveryCodeGrant(codes[1]))
+}
+
+func (suite *OauthTestSuite) TestMFARequiredEnrollment() {
+	user, err := suite.service.CreateUser(roles.User, "test@mfarequired", "correct_horse_battery", "")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	assert.NoError(suite.T(), suite.service.SetMFARequired(user, true))
+
+	// Users required to use MFA enroll with the mfa_token
+	w := suite.mfaTokenRequest(`{"grant_type": "password", "username": "test@mfarequired", "password": "correct_horse_battery", "scope": "read"}`)
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"enrollment_required":true`)
+	mfaToken := suite.mfaToken(w)
+
+	w = suite.mfaRequest("/v1/oauth/mfa/totp", `{"mfa_token": "`+mfaToken+`"}`)
+	if !assert.Equal(suite.T(), http.StatusOK, w.Code) {
+		return
+	}
+	enrollment := new(oauth.TOTPEnrollment)
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), enrollment))
+	assert.True(suite.T(), strings.HasPrefix(enrollment.URI, "otpauth://totp/"))
+
+	code, err := totp.Code(enrollment.Secret, time.Now())
+	assert.NoError(suite.T(), err)
+	w = suite.mfaRequest("/v1/oauth/mfa/totp/confirm", `{"mfa_token": "`+mfaToken+`", "otp": "`+code+`"}`)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	// Enrolled users cannot replace their authenticator with the mfa_token
+	w = suite.mfaRequest("/v1/oauth/mfa/totp", `{"mfa_token": "`+mfaToken+`"}`)
+	assert.Equal(suite.T(), http.StatusConflict, w.Code)
+
+	// The enrollment code cannot be used again, the pending login completes
+	// with a later code of the new authenticator
+	w = suite.mfaTokenRequest(`{"grant_type": "` + oauth.MFAOTPGrantType + `", "mfa_token": "` + mfaToken + `", "otp": "` + code + `"}`)
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+	code, err = totp.Code(enrollment.Secret, time.Now().Add(totp.Period*time.Second))
+	assert.NoError(suite.T(), err)
+	w = suite.mfaTokenRequest(`{"grant_type": "` + oauth.MFAOTPGrantType + `", "mfa_token": "` + mfaToken + `", "otp": "` + code + `"}`)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+}
+
+func (suite *OauthTestSuite) TestResetMFA() {
+	user, err := suite.service.CreateUser(roles.User, "test@mfareset", "correct_horse_battery", "")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	suite.enrollTOTP(user)
+
+	assert.NoError(suite.T(), suite.service.ResetMFA(user))
+	user, err = suite.service.FindUserByID(user.ID)
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	assert.False(suite.T(), user.MFAEnabled)
+	assert.False(suite.T(), user.TOTPSecret.Valid)
+	var count int
+	suite.db.Model(new(models.OauthRecoveryCode)).Where("user_id = ?", user.ID).Count(&count)
+	assert.Equal(suite.T(), 0, count)
+
+	// Users without MFA log in with their password only
+	w := suite.mfaTokenRequest(`{"grant_type": "password", "username": "test@mfareset", "password": "correct_horse_battery", "scope": "read"}`)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+}
+
+func (suite *OauthTestSuite) TestVerifyMFACode() {
+	user, err := suite.service.CreateUser(roles.User, "test@webmfa", "correct_horse_battery", "")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	mfaRequired, err := suite.service.MFARequired(user)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), mfaRequired)
+
+	secret := suite.enrollTOTP(user)
+	mfaRequired, err = suite.service.MFARequired(user)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), mfaRequired)
+
+	// Wrong codes up to the maximum lock the second factor until the user
+	// logs in again
+	for i := 0; i < suite.cnf.MFA.MaxAttempts; i++ {
+		err = suite.service.VerifyMFACode(user, "000000", "")
+		assert.Equal(suite.T(), oauth.ErrInvalidOTP, err)
+	}
+	code, err := totp.Code(secret, time.Now())
+	assert.NoError(suite.T(), err)
+	err = suite.service.VerifyMFACode(user, code, "")
+	assert.Equal(suite.T(), oauth.ErrMFAAttemptsExceeded, err)
+
+	suite.service.ResetMFA(user)
+}
+
+func (suite *OauthTestSuite) TestConfirmTOTPEnrollmentCodeUsedOnce() {
+	user, err := suite.service.CreateUser(roles.User, "test@enrollreplay", "correct_horse_battery", "")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	enrollment, err := suite.service.BeginTOTPEnrollment(user)
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	code, err := totp.Code(enrollment.Secret, time.Now())
+	assert.NoError(suite.T(), err)
+	_, err = suite.service.ConfirmTOTPEnrollment(user, code)
+	assert.NoError(suite.T(), err)
+
+	// The code confirming the enrollment is not accepted as a second factor
+	assert.Equal(suite.T(), oauth.ErrInvalidOTP, suite.service.VerifyMFACode(user, code, ""))
+	_, err = suite.service.RegenerateRecoveryCodes(user, code)
+	assert.Equal(suite.T(), oauth.ErrInvalidOTP, err)
+}
+
+// enrollTOTP enrolls an authenticator for the user, returning its secret.
+// It confirms with the code of the previous time step, leaving the current
+// code unused for the test.
+func (suite *OauthTestSuite) enrollTOTP(user *models.OauthUser) string {
+	enrollment, err := suite.service.BeginTOTPEnrollment(user)
+	if !assert.NoError(suite.T(), err) {
+		return ""
+	}
+	code, err := totp.Code(enrollment.Secret, time.Now().Add(-totp.Period*time.Second))
+	assert.NoError(suite.T(), err)
+	codes, err := suite.service.ConfirmTOTPEnrollment(user, code)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), codes, suite.cnf.MFA.RecoveryCodes)
+	return enrollment.Secret
+}
+
+func (suite *OauthTestSuite) mfaTokenRequest(body string) *httptest.ResponseRecorder {
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/token", strings.NewReader(body))
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	return w
+}
+
+func (suite *OauthTestSuite) mfaRequest(path, body string) *httptest.ResponseRecorder {
+	r, err := http.NewRequest("POST", "http://1.2.3.4"+path, strings.NewReader(body))
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	return w
+}
+
+// mfaToken returns the mfa_token of an mfa_required response
+func (suite *OauthTestSuite) mfaToken(w *httptest.ResponseRecorder) string {
+	var body struct {
+		Error    string `json:"error"`
+		MFAToken string `json:"mfa_token"`
+	}
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(suite.T(), "mfa_required", body.Error)
+	return body.MFAToken
+}
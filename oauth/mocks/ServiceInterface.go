@@ -2,10 +2,18 @@ package mocks
 
 import "github.com/RichardKnop/go-oauth2-server/oauth"
 import "github.com/stretchr/testify/mock"
+import "github.com/RichardKnop/go-oauth2-server/oauth/mail"
+import "net/url"
+import "net/http"
+import "crypto/x509"
+import "gopkg.in/square/go-jose.v2"
 
+import "github.com/RichardKnop/go-oauth2-server/audit"
 import "github.com/RichardKnop/go-oauth2-server/config"
 import "github.com/RichardKnop/go-oauth2-server/models"
+import "github.com/RichardKnop/go-oauth2-server/session"
 import "github.com/RichardKnop/go-oauth2-server/util/routes"
+import "github.com/RichardKnop/go-oauth2-server/webhook"
 import "github.com/gorilla/mux"
 import "github.com/jinzhu/gorm"
 
@@ -27,9 +35,11 @@ func (_m *ServiceInterface) GetConfig() *config.Config {
 
 	return r0
 }
+
 func (_m *ServiceInterface) RestrictToRoles(allowedRoles ...string) {
 	_m.Called(allowedRoles)
 }
+
 func (_m *ServiceInterface) IsRoleAllowed(role string) bool {
 	ret := _m.Called(role)
 
@@ -42,6 +52,7 @@ func (_m *ServiceInterface) IsRoleAllowed(role string) bool {
 
 	return r0
 }
+
 func (_m *ServiceInterface) GetRoutes() []routes.Route {
 	ret := _m.Called()
 
@@ -56,9 +67,11 @@ func (_m *ServiceInterface) GetRoutes() []routes.Route {
 
 	return r0
 }
+
 func (_m *ServiceInterface) RegisterRoutes(router *mux.Router, prefix string) {
 	_m.Called(router, prefix)
 }
+
 func (_m *ServiceInterface) ClientExists(clientID string) bool {
 	ret := _m.Called(clientID)
 
@@ -71,6 +84,7 @@ func (_m *ServiceInterface) ClientExists(clientID string) bool {
 
 	return r0
 }
+
 func (_m *ServiceInterface) FindClientByClientID(clientID string) (*models.OauthClient, error) {
 	ret := _m.Called(clientID)
 
@@ -92,6 +106,7 @@ func (_m *ServiceInterface) FindClientByClientID(clientID string) (*models.Oauth
 
 	return r0, r1
 }
+
 func (_m *ServiceInterface) CreateClient(clientID string, secret string, redirectURI string, tenantID string) (*models.OauthClient, error) {
 	ret := _m.Called(clientID, secret, redirectURI, tenantID)
 
@@ -113,6 +128,7 @@ func (_m *ServiceInterface) CreateClient(clientID string, secret string, redirec
 
 	return r0, r1
 }
+
 func (_m *ServiceInterface) CreateClientTx(tx *gorm.DB, clientID string, secret string, redirectURI string, tenantID string) (*models.OauthClient, error) {
 	ret := _m.Called(tx, clientID, secret, redirectURI, tenantID)
 
@@ -134,6 +150,7 @@ func (_m *ServiceInterface) CreateClientTx(tx *gorm.DB, clientID string, secret
 
 	return r0, r1
 }
+
 func (_m *ServiceInterface) AuthClient(clientID string, secret string) (*models.OauthClient, error) {
 	ret := _m.Called(clientID, secret)
 
@@ -155,6 +172,51 @@ func (_m *ServiceInterface) AuthClient(clientID string, secret string) (*models.
 
 	return r0, r1
 }
+
+func (_m *ServiceInterface) AuthClientAssertion(clientID string, assertionType string, assertion string) (*models.OauthClient, error) {
+	ret := _m.Called(clientID, assertionType, assertion)
+
+	var r0 *models.OauthClient
+	if rf, ok := ret.Get(0).(func(string, string, string) *models.OauthClient); ok {
+		r0 = rf(clientID, assertionType, assertion)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthClient)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, string) error); ok {
+		r1 = rf(clientID, assertionType, assertion)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) AuthClientCertificate(clientID string, chain []*x509.Certificate) (*models.OauthClient, error) {
+	ret := _m.Called(clientID, chain)
+
+	var r0 *models.OauthClient
+	if rf, ok := ret.Get(0).(func(string, []*x509.Certificate) *models.OauthClient); ok {
+		r0 = rf(clientID, chain)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthClient)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, []*x509.Certificate) error); ok {
+		r1 = rf(clientID, chain)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 func (_m *ServiceInterface) UserExists(username string, tenantID string) bool {
 	ret := _m.Called(username)
 
@@ -167,6 +229,7 @@ func (_m *ServiceInterface) UserExists(username string, tenantID string) bool {
 
 	return r0
 }
+
 func (_m *ServiceInterface) FindUserByUsername(username string) (*models.OauthUser, error) {
 	ret := _m.Called(username)
 
@@ -188,12 +251,13 @@ func (_m *ServiceInterface) FindUserByUsername(username string) (*models.OauthUs
 
 	return r0, r1
 }
-func (_m *ServiceInterface) CreateUser(roleID string, username string, password string) (*models.OauthUser, error) {
-	ret := _m.Called(roleID, username, password)
+
+func (_m *ServiceInterface) FindUserByAccountAndTenantID(account string, tenantID string) (*models.OauthUser, error) {
+	ret := _m.Called(account, tenantID)
 
 	var r0 *models.OauthUser
-	if rf, ok := ret.Get(0).(func(string, string, string) *models.OauthUser); ok {
-		r0 = rf(roleID, username, password)
+	if rf, ok := ret.Get(0).(func(string, string) *models.OauthUser); ok {
+		r0 = rf(account, tenantID)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*models.OauthUser)
@@ -201,20 +265,138 @@ func (_m *ServiceInterface) CreateUser(roleID string, username string, password
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string, string, string) error); ok {
-		r1 = rf(roleID, username, password)
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(account, tenantID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) FindUserByPhoneAndTenantID(phone string, tenantID string) (*models.OauthUser, error) {
+	ret := _m.Called(phone, tenantID)
+
+	var r0 *models.OauthUser
+	if rf, ok := ret.Get(0).(func(string, string) *models.OauthUser); ok {
+		r0 = rf(phone, tenantID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthUser)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(phone, tenantID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) FindUserByEmailAndTenantID(email string, tenantID string) (*models.OauthUser, error) {
+	ret := _m.Called(email, tenantID)
+
+	var r0 *models.OauthUser
+	if rf, ok := ret.Get(0).(func(string, string) *models.OauthUser); ok {
+		r0 = rf(email, tenantID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthUser)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(email, tenantID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) FindUserByID(userID string) (*models.OauthUser, error) {
+	ret := _m.Called(userID)
+
+	var r0 *models.OauthUser
+	if rf, ok := ret.Get(0).(func(string) *models.OauthUser); ok {
+		r0 = rf(userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthUser)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) ListUsers(tenantID string, offset int, limit int) ([]*models.OauthUser, int, error) {
+	ret := _m.Called(tenantID, offset, limit)
+
+	var r0 []*models.OauthUser
+	if rf, ok := ret.Get(0).(func(string, int, int) []*models.OauthUser); ok {
+		r0 = rf(tenantID, offset, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.OauthUser)
+		}
+	}
+
+	var r1 int
+	if rf, ok := ret.Get(1).(func(string, int, int) int); ok {
+		r1 = rf(tenantID, offset, limit)
+	} else {
+		r1 = ret.Get(1).(int)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string, int, int) error); ok {
+		r2 = rf(tenantID, offset, limit)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+func (_m *ServiceInterface) CreateUser(roleID string, account string, password string, tenantID string) (*models.OauthUser, error) {
+	ret := _m.Called(roleID, account, password, tenantID)
+
+	var r0 *models.OauthUser
+	if rf, ok := ret.Get(0).(func(string, string, string, string) *models.OauthUser); ok {
+		r0 = rf(roleID, account, password, tenantID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthUser)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, string, string) error); ok {
+		r1 = rf(roleID, account, password, tenantID)
 	} else {
 		r1 = ret.Error(1)
 	}
 
 	return r0, r1
 }
-func (_m *ServiceInterface) CreateUserTx(tx *gorm.DB, roleID string, username string, password string) (*models.OauthUser, error) {
-	ret := _m.Called(tx, roleID, username, password)
+
+func (_m *ServiceInterface) CreateUserTx(tx *gorm.DB, roleID string, account string, password string, tenantID string) (*models.OauthUser, error) {
+	ret := _m.Called(tx, roleID, account, password, tenantID)
 
 	var r0 *models.OauthUser
-	if rf, ok := ret.Get(0).(func(*gorm.DB, string, string, string) *models.OauthUser); ok {
-		r0 = rf(tx, roleID, username, password)
+	if rf, ok := ret.Get(0).(func(*gorm.DB, string, string, string, string) *models.OauthUser); ok {
+		r0 = rf(tx, roleID, account, password, tenantID)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*models.OauthUser)
@@ -222,14 +404,28 @@ func (_m *ServiceInterface) CreateUserTx(tx *gorm.DB, roleID string, username st
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(*gorm.DB, string, string, string) error); ok {
-		r1 = rf(tx, roleID, username, password)
+	if rf, ok := ret.Get(1).(func(*gorm.DB, string, string, string, string) error); ok {
+		r1 = rf(tx, roleID, account, password, tenantID)
 	} else {
 		r1 = ret.Error(1)
 	}
 
 	return r0, r1
 }
+
+func (_m *ServiceInterface) UpdateUser(user *models.OauthUser, name string, account string, phone string) error {
+	ret := _m.Called(user, name, account, phone)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthUser, string, string, string) error); ok {
+		r0 = rf(user, name, account, phone)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 func (_m *ServiceInterface) SetPassword(user *models.OauthUser, password string) error {
 	ret := _m.Called(user, password)
 
@@ -242,6 +438,7 @@ func (_m *ServiceInterface) SetPassword(user *models.OauthUser, password string)
 
 	return r0
 }
+
 func (_m *ServiceInterface) SetPasswordTx(tx *gorm.DB, user *models.OauthUser, password string) error {
 	ret := _m.Called(tx, user, password)
 
@@ -254,6 +451,7 @@ func (_m *ServiceInterface) SetPasswordTx(tx *gorm.DB, user *models.OauthUser, p
 
 	return r0
 }
+
 func (_m *ServiceInterface) UpdateUsername(user *models.OauthUser, username string) error {
 	ret := _m.Called(user, username)
 
@@ -266,6 +464,7 @@ func (_m *ServiceInterface) UpdateUsername(user *models.OauthUser, username stri
 
 	return r0
 }
+
 func (_m *ServiceInterface) UpdateUsernameTx(db *gorm.DB, user *models.OauthUser, username string) error {
 	ret := _m.Called(db, user, username)
 
@@ -278,160 +477,1176 @@ func (_m *ServiceInterface) UpdateUsernameTx(db *gorm.DB, user *models.OauthUser
 
 	return r0
 }
-func (_m *ServiceInterface) AuthUser(username string, thePassword string) (*models.OauthUser, error) {
-	ret := _m.Called(username, thePassword)
 
-	var r0 *models.OauthUser
-	if rf, ok := ret.Get(0).(func(string, string) *models.OauthUser); ok {
-		r0 = rf(username, thePassword)
-	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*models.OauthUser)
-		}
-	}
+func (_m *ServiceInterface) ChangePassword(tenantID string, username string, password string, newPassword string, clientIP string) error {
+	ret := _m.Called(tenantID, username, password, newPassword, clientIP)
 
-	var r1 error
-	if rf, ok := ret.Get(1).(func(string, string) error); ok {
-		r1 = rf(username, thePassword)
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, string, string, string) error); ok {
+		r0 = rf(tenantID, username, password, newPassword, clientIP)
 	} else {
-		r1 = ret.Error(1)
+		r0 = ret.Error(0)
 	}
 
-	return r0, r1
+	return r0
 }
-func (_m *ServiceInterface) GetScope(requestedScope string) (string, error) {
-	ret := _m.Called(requestedScope)
+
+func (_m *ServiceInterface) ResetPassword(user *models.OauthUser) (string, error) {
+	ret := _m.Called(user)
 
 	var r0 string
-	if rf, ok := ret.Get(0).(func(string) string); ok {
-		r0 = rf(requestedScope)
+	if rf, ok := ret.Get(0).(func(*models.OauthUser) string); ok {
+		r0 = rf(user)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(requestedScope)
+	if rf, ok := ret.Get(1).(func(*models.OauthUser) error); ok {
+		r1 = rf(user)
 	} else {
 		r1 = ret.Error(1)
 	}
 
 	return r0, r1
 }
-func (_m *ServiceInterface) Login(client *models.OauthClient, user *models.OauthUser, scope string) (*models.OauthAccessToken, *models.OauthRefreshToken, error) {
-	ret := _m.Called(client, user, scope)
 
-	var r0 *models.OauthAccessToken
-	if rf, ok := ret.Get(0).(func(*models.OauthClient, *models.OauthUser, string) *models.OauthAccessToken); ok {
-		r0 = rf(client, user, scope)
+func (_m *ServiceInterface) SetUserDisabled(user *models.OauthUser, disabled bool) error {
+	ret := _m.Called(user, disabled)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthUser, bool) error); ok {
+		r0 = rf(user, disabled)
 	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*models.OauthAccessToken)
-		}
+		r0 = ret.Error(0)
 	}
 
-	var r1 *models.OauthRefreshToken
-	if rf, ok := ret.Get(1).(func(*models.OauthClient, *models.OauthUser, string) *models.OauthRefreshToken); ok {
-		r1 = rf(client, user, scope)
+	return r0
+}
+
+func (_m *ServiceInterface) DeleteUser(user *models.OauthUser) error {
+	ret := _m.Called(user)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthUser) error); ok {
+		r0 = rf(user)
 	} else {
-		if ret.Get(1) != nil {
-			r1 = ret.Get(1).(*models.OauthRefreshToken)
-		}
+		r0 = ret.Error(0)
 	}
 
-	var r2 error
-	if rf, ok := ret.Get(2).(func(*models.OauthClient, *models.OauthUser, string) error); ok {
-		r2 = rf(client, user, scope)
+	return r0
+}
+
+func (_m *ServiceInterface) UnlockUser(user *models.OauthUser) error {
+	ret := _m.Called(user)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthUser) error); ok {
+		r0 = rf(user)
 	} else {
-		r2 = ret.Error(2)
+		r0 = ret.Error(0)
 	}
 
-	return r0, r1, r2
+	return r0
 }
-func (_m *ServiceInterface) GrantAuthorizationCode(client *models.OauthClient, user *models.OauthUser, expiresIn int, redirectURI string, scope string) (*models.OauthAuthorizationCode, error) {
-	ret := _m.Called(client, user, expiresIn, redirectURI, scope)
 
-	var r0 *models.OauthAuthorizationCode
-	if rf, ok := ret.Get(0).(func(*models.OauthClient, *models.OauthUser, int, string, string) *models.OauthAuthorizationCode); ok {
-		r0 = rf(client, user, expiresIn, redirectURI, scope)
+func (_m *ServiceInterface) FindRoleByID(roleID string) (*models.OauthRole, error) {
+	ret := _m.Called(roleID)
+
+	var r0 *models.OauthRole
+	if rf, ok := ret.Get(0).(func(string) *models.OauthRole); ok {
+		r0 = rf(roleID)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*models.OauthAuthorizationCode)
+			r0 = ret.Get(0).(*models.OauthRole)
 		}
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(*models.OauthClient, *models.OauthUser, int, string, string) error); ok {
-		r1 = rf(client, user, expiresIn, redirectURI, scope)
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(roleID)
 	} else {
 		r1 = ret.Error(1)
 	}
 
 	return r0, r1
 }
-func (_m *ServiceInterface) GrantAccessToken(client *models.OauthClient, user *models.OauthUser, expiresIn int, scope string) (*models.OauthAccessToken, error) {
-	ret := _m.Called(client, user, expiresIn, scope)
 
-	var r0 *models.OauthAccessToken
-	if rf, ok := ret.Get(0).(func(*models.OauthClient, *models.OauthUser, int, string) *models.OauthAccessToken); ok {
-		r0 = rf(client, user, expiresIn, scope)
+func (_m *ServiceInterface) ListRoles(tenantID string) ([]*models.OauthRole, error) {
+	ret := _m.Called(tenantID)
+
+	var r0 []*models.OauthRole
+	if rf, ok := ret.Get(0).(func(string) []*models.OauthRole); ok {
+		r0 = rf(tenantID)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*models.OauthAccessToken)
+			r0 = ret.Get(0).([]*models.OauthRole)
 		}
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(*models.OauthClient, *models.OauthUser, int, string) error); ok {
-		r1 = rf(client, user, expiresIn, scope)
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(tenantID)
 	} else {
 		r1 = ret.Error(1)
 	}
 
 	return r0, r1
 }
-func (_m *ServiceInterface) GetOrCreateRefreshToken(client *models.OauthClient, user *models.OauthUser, expiresIn int, scope string) (*models.OauthRefreshToken, error) {
-	ret := _m.Called(client, user, expiresIn, scope)
 
-	var r0 *models.OauthRefreshToken
-	if rf, ok := ret.Get(0).(func(*models.OauthClient, *models.OauthUser, int, string) *models.OauthRefreshToken); ok {
-		r0 = rf(client, user, expiresIn, scope)
+func (_m *ServiceInterface) CreateRole(tenantID string, name string, description string, permissions []string, scope string) (*models.OauthRole, error) {
+	ret := _m.Called(tenantID, name, description, permissions, scope)
+
+	var r0 *models.OauthRole
+	if rf, ok := ret.Get(0).(func(string, string, string, []string, string) *models.OauthRole); ok {
+		r0 = rf(tenantID, name, description, permissions, scope)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*models.OauthRefreshToken)
+			r0 = ret.Get(0).(*models.OauthRole)
 		}
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(*models.OauthClient, *models.OauthUser, int, string) error); ok {
-		r1 = rf(client, user, expiresIn, scope)
+	if rf, ok := ret.Get(1).(func(string, string, string, []string, string) error); ok {
+		r1 = rf(tenantID, name, description, permissions, scope)
 	} else {
 		r1 = ret.Error(1)
 	}
 
 	return r0, r1
 }
-func (_m *ServiceInterface) GetValidRefreshToken(token string, client *models.OauthClient) (*models.OauthRefreshToken, error) {
-	ret := _m.Called(token, client)
 
-	var r0 *models.OauthRefreshToken
-	if rf, ok := ret.Get(0).(func(string, *models.OauthClient) *models.OauthRefreshToken); ok {
-		r0 = rf(token, client)
-	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*models.OauthRefreshToken)
-		}
-	}
+func (_m *ServiceInterface) UpdateRole(role *models.OauthRole, name string, description string, permissions []string, scope string) error {
+	ret := _m.Called(role, name, description, permissions, scope)
 
-	var r1 error
-	if rf, ok := ret.Get(1).(func(string, *models.OauthClient) error); ok {
-		r1 = rf(token, client)
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthRole, string, string, []string, string) error); ok {
+		r0 = rf(role, name, description, permissions, scope)
 	} else {
-		r1 = ret.Error(1)
+		r0 = ret.Error(0)
 	}
 
-	return r0, r1
+	return r0
 }
+
+func (_m *ServiceInterface) DeleteRole(role *models.OauthRole) error {
+	ret := _m.Called(role)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthRole) error); ok {
+		r0 = rf(role)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+func (_m *ServiceInterface) GetUserRoles(user *models.OauthUser) ([]*models.OauthRole, error) {
+	ret := _m.Called(user)
+
+	var r0 []*models.OauthRole
+	if rf, ok := ret.Get(0).(func(*models.OauthUser) []*models.OauthRole); ok {
+		r0 = rf(user)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.OauthRole)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthUser) error); ok {
+		r1 = rf(user)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) AssignRole(user *models.OauthUser, roleID string) error {
+	ret := _m.Called(user, roleID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthUser, string) error); ok {
+		r0 = rf(user, roleID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+func (_m *ServiceInterface) UnassignRole(user *models.OauthUser, roleID string) error {
+	ret := _m.Called(user, roleID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthUser, string) error); ok {
+		r0 = rf(user, roleID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+func (_m *ServiceInterface) UserHasRole(user *models.OauthUser, roleID string) (bool, error) {
+	ret := _m.Called(user, roleID)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(*models.OauthUser, string) bool); ok {
+		r0 = rf(user, roleID)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthUser, string) error); ok {
+		r1 = rf(user, roleID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) UserHasPermission(user *models.OauthUser, permission string) (bool, error) {
+	ret := _m.Called(user, permission)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(*models.OauthUser, string) bool); ok {
+		r0 = rf(user, permission)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthUser, string) error); ok {
+		r1 = rf(user, permission)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) BeginTOTPEnrollment(user *models.OauthUser) (*oauth.TOTPEnrollment, error) {
+	ret := _m.Called(user)
+
+	var r0 *oauth.TOTPEnrollment
+	if rf, ok := ret.Get(0).(func(*models.OauthUser) *oauth.TOTPEnrollment); ok {
+		r0 = rf(user)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*oauth.TOTPEnrollment)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthUser) error); ok {
+		r1 = rf(user)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) ConfirmTOTPEnrollment(user *models.OauthUser, code string) ([]string, error) {
+	ret := _m.Called(user, code)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(*models.OauthUser, string) []string); ok {
+		r0 = rf(user, code)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthUser, string) error); ok {
+		r1 = rf(user, code)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) RegenerateRecoveryCodes(user *models.OauthUser, code string) ([]string, error) {
+	ret := _m.Called(user, code)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(*models.OauthUser, string) []string); ok {
+		r0 = rf(user, code)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthUser, string) error); ok {
+		r1 = rf(user, code)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) ResetMFA(user *models.OauthUser) error {
+	ret := _m.Called(user)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthUser) error); ok {
+		r0 = rf(user)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+func (_m *ServiceInterface) SetMFARequired(user *models.OauthUser, required bool) error {
+	ret := _m.Called(user, required)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthUser, bool) error); ok {
+		r0 = rf(user, required)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+func (_m *ServiceInterface) MFARequired(user *models.OauthUser) (bool, error) {
+	ret := _m.Called(user)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(*models.OauthUser) bool); ok {
+		r0 = rf(user)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthUser) error); ok {
+		r1 = rf(user)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) VerifyMFACode(user *models.OauthUser, otp string, recoveryCode string) error {
+	ret := _m.Called(user, otp, recoveryCode)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthUser, string, string) error); ok {
+		r0 = rf(user, otp, recoveryCode)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+func (_m *ServiceInterface) SetEmail(user *models.OauthUser, email string) error {
+	ret := _m.Called(user, email)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthUser, string) error); ok {
+		r0 = rf(user, email)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+func (_m *ServiceInterface) SendEmailConfirmation(user *models.OauthUser) error {
+	ret := _m.Called(user)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthUser) error); ok {
+		r0 = rf(user)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+func (_m *ServiceInterface) ConfirmEmail(token string) (*models.OauthUser, error) {
+	ret := _m.Called(token)
+
+	var r0 *models.OauthUser
+	if rf, ok := ret.Get(0).(func(string) *models.OauthUser); ok {
+		r0 = rf(token)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthUser)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(token)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) RequestPasswordReset(tenantID string, email string) error {
+	ret := _m.Called(tenantID, email)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(tenantID, email)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+func (_m *ServiceInterface) ResetPasswordWithToken(token string, newPassword string) error {
+	ret := _m.Called(token, newPassword)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(token, newPassword)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+func (_m *ServiceInterface) UseMailer(mailer mail.Mailer) {
+	_m.Called(mailer)
+}
+
+func (_m *ServiceInterface) SendLoginCode(tenantID string, phone string) error {
+	ret := _m.Called(tenantID, phone)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(tenantID, phone)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+func (_m *ServiceInterface) UseSMSSender(sender oauth.SMSSender) {
+	_m.Called(sender)
+}
+
+func (_m *ServiceInterface) UseSessionService(sessionService session.ServiceInterface) {
+	_m.Called(sessionService)
+}
+
+func (_m *ServiceInterface) UseAuditService(auditService audit.ServiceInterface) {
+	_m.Called(auditService)
+}
+
+func (_m *ServiceInterface) UseWebhookService(webhookService webhook.ServiceInterface) {
+	_m.Called(webhookService)
+}
+
+func (_m *ServiceInterface) AuthUser(username string, thePassword string, tenantID string) (*models.OauthUser, error) {
+	ret := _m.Called(username, thePassword, tenantID)
+
+	var r0 *models.OauthUser
+	if rf, ok := ret.Get(0).(func(string, string, string) *models.OauthUser); ok {
+		r0 = rf(username, thePassword, tenantID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthUser)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, string) error); ok {
+		r1 = rf(username, thePassword, tenantID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) LoginUser(tenantID string, username string, password string, clientIP string) (*models.OauthUser, error) {
+	ret := _m.Called(tenantID, username, password, clientIP)
+
+	var r0 *models.OauthUser
+	if rf, ok := ret.Get(0).(func(string, string, string, string) *models.OauthUser); ok {
+		r0 = rf(tenantID, username, password, clientIP)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthUser)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, string, string) error); ok {
+		r1 = rf(tenantID, username, password, clientIP)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) GetScope(requestedScope string) (string, error) {
+	ret := _m.Called(requestedScope)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(requestedScope)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(requestedScope)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) GetDefaultScope() string {
+	ret := _m.Called()
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+func (_m *ServiceInterface) ScopeExists(requestedScope string) bool {
+	ret := _m.Called(requestedScope)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string) bool); ok {
+		r0 = rf(requestedScope)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+func (_m *ServiceInterface) Login(client *models.OauthClient, user *models.OauthUser, scope string) (*models.OauthAccessToken, *models.OauthRefreshToken, error) {
+	ret := _m.Called(client, user, scope)
+
+	var r0 *models.OauthAccessToken
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, *models.OauthUser, string) *models.OauthAccessToken); ok {
+		r0 = rf(client, user, scope)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthAccessToken)
+		}
+	}
+
+	var r1 *models.OauthRefreshToken
+	if rf, ok := ret.Get(1).(func(*models.OauthClient, *models.OauthUser, string) *models.OauthRefreshToken); ok {
+		r1 = rf(client, user, scope)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*models.OauthRefreshToken)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(*models.OauthClient, *models.OauthUser, string) error); ok {
+		r2 = rf(client, user, scope)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+func (_m *ServiceInterface) GrantAuthorizationCode(client *models.OauthClient, user *models.OauthUser, expiresIn int, redirectURI string, scope string) (*models.OauthAuthorizationCode, error) {
+	ret := _m.Called(client, user, expiresIn, redirectURI, scope)
+
+	var r0 *models.OauthAuthorizationCode
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, *models.OauthUser, int, string, string) *models.OauthAuthorizationCode); ok {
+		r0 = rf(client, user, expiresIn, redirectURI, scope)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthAuthorizationCode)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthClient, *models.OauthUser, int, string, string) error); ok {
+		r1 = rf(client, user, expiresIn, redirectURI, scope)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) GetMissingConsent(client *models.OauthClient, user *models.OauthUser, scope string) (string, error) {
+	ret := _m.Called(client, user, scope)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, *models.OauthUser, string) string); ok {
+		r0 = rf(client, user, scope)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthClient, *models.OauthUser, string) error); ok {
+		r1 = rf(client, user, scope)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) SetSkipConsent(client *models.OauthClient, skip bool) error {
+	ret := _m.Called(client, skip)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, bool) error); ok {
+		r0 = rf(client, skip)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+func (_m *ServiceInterface) GrantConsent(client *models.OauthClient, user *models.OauthUser, scope string) error {
+	ret := _m.Called(client, user, scope)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, *models.OauthUser, string) error); ok {
+		r0 = rf(client, user, scope)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+func (_m *ServiceInterface) ListConsents(user *models.OauthUser) ([]*models.OauthConsent, error) {
+	ret := _m.Called(user)
+
+	var r0 []*models.OauthConsent
+	if rf, ok := ret.Get(0).(func(*models.OauthUser) []*models.OauthConsent); ok {
+		r0 = rf(user)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.OauthConsent)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthUser) error); ok {
+		r1 = rf(user)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) RevokeConsent(user *models.OauthUser, clientID string) error {
+	ret := _m.Called(user, clientID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthUser, string) error); ok {
+		r0 = rf(user, clientID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+func (_m *ServiceInterface) GrantAuthorizationCodeForRequest(client *models.OauthClient, user *models.OauthUser, req *oauth.AuthorizationRequest, ssoSession *session.SSOSession) (*models.OauthAuthorizationCode, error) {
+	ret := _m.Called(client, user, req, ssoSession)
+
+	var r0 *models.OauthAuthorizationCode
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, *models.OauthUser, *oauth.AuthorizationRequest, *session.SSOSession) *models.OauthAuthorizationCode); ok {
+		r0 = rf(client, user, req, ssoSession)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthAuthorizationCode)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthClient, *models.OauthUser, *oauth.AuthorizationRequest, *session.SSOSession) error); ok {
+		r1 = rf(client, user, req, ssoSession)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) ValidateAuthorizationRequest(client *models.OauthClient, req *oauth.AuthorizationRequest) error {
+	ret := _m.Called(client, req)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, *oauth.AuthorizationRequest) error); ok {
+		r0 = rf(client, req)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+func (_m *ServiceInterface) PushAuthorizationRequest(client *models.OauthClient, req *oauth.AuthorizationRequest) (string, error) {
+	ret := _m.Called(client, req)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, *oauth.AuthorizationRequest) string); ok {
+		r0 = rf(client, req)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthClient, *oauth.AuthorizationRequest) error); ok {
+		r1 = rf(client, req)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) GetPushedAuthorizationRequest(client *models.OauthClient, requestURI string) (*oauth.AuthorizationRequest, error) {
+	ret := _m.Called(client, requestURI)
+
+	var r0 *oauth.AuthorizationRequest
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, string) *oauth.AuthorizationRequest); ok {
+		r0 = rf(client, requestURI)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*oauth.AuthorizationRequest)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthClient, string) error); ok {
+		r1 = rf(client, requestURI)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) ResolveAuthorizationRequest(values url.Values) (*models.OauthClient, *oauth.AuthorizationRequest, error) {
+	ret := _m.Called(values)
+
+	var r0 *models.OauthClient
+	if rf, ok := ret.Get(0).(func(url.Values) *models.OauthClient); ok {
+		r0 = rf(values)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthClient)
+		}
+	}
+
+	var r1 *oauth.AuthorizationRequest
+	if rf, ok := ret.Get(1).(func(url.Values) *oauth.AuthorizationRequest); ok {
+		r1 = rf(values)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*oauth.AuthorizationRequest)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(url.Values) error); ok {
+		r2 = rf(values)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+func (_m *ServiceInterface) RegisterClient(initialAccessToken string, metadata *oauth.ClientMetadata) (*oauth.ClientRegistration, error) {
+	ret := _m.Called(initialAccessToken, metadata)
+
+	var r0 *oauth.ClientRegistration
+	if rf, ok := ret.Get(0).(func(string, *oauth.ClientMetadata) *oauth.ClientRegistration); ok {
+		r0 = rf(initialAccessToken, metadata)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*oauth.ClientRegistration)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, *oauth.ClientMetadata) error); ok {
+		r1 = rf(initialAccessToken, metadata)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) AuthClientRegistration(clientID string, registrationAccessToken string) (*models.OauthClient, error) {
+	ret := _m.Called(clientID, registrationAccessToken)
+
+	var r0 *models.OauthClient
+	if rf, ok := ret.Get(0).(func(string, string) *models.OauthClient); ok {
+		r0 = rf(clientID, registrationAccessToken)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthClient)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(clientID, registrationAccessToken)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) UpdateClientRegistration(client *models.OauthClient, metadata *oauth.ClientMetadata) (*oauth.ClientRegistration, error) {
+	ret := _m.Called(client, metadata)
+
+	var r0 *oauth.ClientRegistration
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, *oauth.ClientMetadata) *oauth.ClientRegistration); ok {
+		r0 = rf(client, metadata)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*oauth.ClientRegistration)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthClient, *oauth.ClientMetadata) error); ok {
+		r1 = rf(client, metadata)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) DeleteClientRegistration(client *models.OauthClient) error {
+	ret := _m.Called(client)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthClient) error); ok {
+		r0 = rf(client)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+func (_m *ServiceInterface) AuthorizeSSOSession(client *models.OauthClient, req *oauth.AuthorizationRequest, ssoSession *session.SSOSession) (*models.OauthUser, error) {
+	ret := _m.Called(client, req, ssoSession)
+
+	var r0 *models.OauthUser
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, *oauth.AuthorizationRequest, *session.SSOSession) *models.OauthUser); ok {
+		r0 = rf(client, req, ssoSession)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthUser)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthClient, *oauth.AuthorizationRequest, *session.SSOSession) error); ok {
+		r1 = rf(client, req, ssoSession)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) ValidateLogoutRequest(req *oauth.LogoutRequest) (*oauth.Logout, error) {
+	ret := _m.Called(req)
+
+	var r0 *oauth.Logout
+	if rf, ok := ret.Get(0).(func(*oauth.LogoutRequest) *oauth.Logout); ok {
+		r0 = rf(req)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*oauth.Logout)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*oauth.LogoutRequest) error); ok {
+		r1 = rf(req)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) EndSession(userID string, sessionID string) ([]string, error) {
+	ret := _m.Called(userID, sessionID)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(string, string) []string); ok {
+		r0 = rf(userID, sessionID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(userID, sessionID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) FindTenantByID(tenantID string) (*models.Tenant, error) {
+	ret := _m.Called(tenantID)
+
+	var r0 *models.Tenant
+	if rf, ok := ret.Get(0).(func(string) *models.Tenant); ok {
+		r0 = rf(tenantID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Tenant)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(tenantID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) GetTenantConfig(tenantID string) (*oauth.TenantConfig, error) {
+	ret := _m.Called(tenantID)
+
+	var r0 *oauth.TenantConfig
+	if rf, ok := ret.Get(0).(func(string) *oauth.TenantConfig); ok {
+		r0 = rf(tenantID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*oauth.TenantConfig)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(tenantID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) RegisterWellKnownRoutes(router *mux.Router) {
+	_m.Called(router)
+}
+
+func (_m *ServiceInterface) ClearUserTokens(userSession *session.UserSession) {
+	_m.Called(userSession)
+}
+
+func (_m *ServiceInterface) Close() {
+	_m.Called()
+}
+
+func (_m *ServiceInterface) JWKs() (*jose.JSONWebKeySet, error) {
+	ret := _m.Called()
+
+	var r0 *jose.JSONWebKeySet
+	if rf, ok := ret.Get(0).(func() *jose.JSONWebKeySet); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*jose.JSONWebKeySet)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) TenantJWKs(tenantID string) (*jose.JSONWebKeySet, error) {
+	ret := _m.Called(tenantID)
+
+	var r0 *jose.JSONWebKeySet
+	if rf, ok := ret.Get(0).(func(string) *jose.JSONWebKeySet); ok {
+		r0 = rf(tenantID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*jose.JSONWebKeySet)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(tenantID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) RotateSigningKey(tenantID string) (string, error) {
+	ret := _m.Called(tenantID)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(tenantID)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(tenantID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) NewDiscoveryDocument(tenantID string) (*oauth.DiscoveryDocument, error) {
+	ret := _m.Called(tenantID)
+
+	var r0 *oauth.DiscoveryDocument
+	if rf, ok := ret.Get(0).(func(string) *oauth.DiscoveryDocument); ok {
+		r0 = rf(tenantID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*oauth.DiscoveryDocument)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(tenantID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) GrantAccessToken(client *models.OauthClient, user *models.OauthUser, expiresIn int, scope string) (*models.OauthAccessToken, error) {
+	ret := _m.Called(client, user, expiresIn, scope)
+
+	var r0 *models.OauthAccessToken
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, *models.OauthUser, int, string) *models.OauthAccessToken); ok {
+		r0 = rf(client, user, expiresIn, scope)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthAccessToken)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthClient, *models.OauthUser, int, string) error); ok {
+		r1 = rf(client, user, expiresIn, scope)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) GetOrCreateRefreshToken(client *models.OauthClient, user *models.OauthUser, expiresIn int, scope string) (*models.OauthRefreshToken, error) {
+	ret := _m.Called(client, user, expiresIn, scope)
+
+	var r0 *models.OauthRefreshToken
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, *models.OauthUser, int, string) *models.OauthRefreshToken); ok {
+		r0 = rf(client, user, expiresIn, scope)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthRefreshToken)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthClient, *models.OauthUser, int, string) error); ok {
+		r1 = rf(client, user, expiresIn, scope)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) GetValidRefreshToken(token string, client *models.OauthClient) (*models.OauthRefreshToken, error) {
+	ret := _m.Called(token, client)
+
+	var r0 *models.OauthRefreshToken
+	if rf, ok := ret.Get(0).(func(string, *models.OauthClient) *models.OauthRefreshToken); ok {
+		r0 = rf(token, client)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthRefreshToken)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, *models.OauthClient) error); ok {
+		r1 = rf(token, client)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 func (_m *ServiceInterface) Authenticate(token string) (*models.OauthAccessToken, error) {
 	ret := _m.Called(token)
 
@@ -453,6 +1668,29 @@ func (_m *ServiceInterface) Authenticate(token string) (*models.OauthAccessToken
 
 	return r0, r1
 }
+
+func (_m *ServiceInterface) AuthenticateRequest(r *http.Request) (*models.OauthAccessToken, error) {
+	ret := _m.Called(r)
+
+	var r0 *models.OauthAccessToken
+	if rf, ok := ret.Get(0).(func(*http.Request) *models.OauthAccessToken); ok {
+		r0 = rf(r)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthAccessToken)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*http.Request) error); ok {
+		r1 = rf(r)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 func (_m *ServiceInterface) NewIntrospectResponseFromAccessToken(accessToken *models.OauthAccessToken) (*oauth.IntrospectResponse, error) {
 	ret := _m.Called(accessToken)
 
@@ -474,6 +1712,7 @@ func (_m *ServiceInterface) NewIntrospectResponseFromAccessToken(accessToken *mo
 
 	return r0, r1
 }
+
 func (_m *ServiceInterface) NewIntrospectResponseFromRefreshToken(refreshToken *models.OauthRefreshToken) (*oauth.IntrospectResponse, error) {
 	ret := _m.Called(refreshToken)
 
@@ -0,0 +1,100 @@
+package mtls
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+
+	"gopkg.in/square/go-jose.v2"
+)
+
+var (
+	// ErrCertificateMissing ...
+	ErrCertificateMissing = errors.New("client certificate missing")
+	// ErrCertificateNotTrusted ...
+	ErrCertificateNotTrusted = errors.New("client certificate not trusted")
+	// ErrCertificateSubjectMismatch ...
+	ErrCertificateSubjectMismatch = errors.New("client certificate subject does not match registration")
+	// ErrCertificateNotRegistered ...
+	ErrCertificateNotRegistered = errors.New("client certificate not registered")
+	// ErrNoCertificatesInFile ...
+	ErrNoCertificatesInFile = errors.New("no PEM certificates found in file")
+)
+
+// Thumbprint returns the base64url encoded SHA-256 hash of the DER encoded
+// certificate, the x5t#S256 confirmation method of RFC 8705
+func Thumbprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// LoadCertPool reads PEM encoded CA certificates from a file
+func LoadCertPool(fileName string) (*x509.CertPool, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, ErrNoCertificatesInFile
+	}
+	return pool, nil
+}
+
+// VerifyPKI verifies the leaf certificate chains up to one of the roots
+// (tls_client_auth) and matches the registered subject DN or DNS SAN
+func VerifyPKI(chain []*x509.Certificate, roots *x509.CertPool, subjectDN, sanDNS string) error {
+	if len(chain) == 0 {
+		return ErrCertificateMissing
+	}
+	if roots == nil {
+		return ErrCertificateNotTrusted
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	leaf := chain[0]
+	_, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return ErrCertificateNotTrusted
+	}
+
+	// RFC 8705 requires exactly one of the subject metadata values to match
+	if subjectDN != "" && leaf.Subject.String() == subjectDN {
+		return nil
+	}
+	if sanDNS != "" {
+		for _, name := range leaf.DNSNames {
+			if name == sanDNS {
+				return nil
+			}
+		}
+	}
+	return ErrCertificateSubjectMismatch
+}
+
+// VerifySelfSigned checks the leaf certificate is one of the certificates
+// registered in the client's JWKs (self_signed_tls_client_auth)
+func VerifySelfSigned(chain []*x509.Certificate, keys *jose.JSONWebKeySet) error {
+	if len(chain) == 0 {
+		return ErrCertificateMissing
+	}
+	leaf := chain[0]
+	for _, key := range keys.Keys {
+		for _, cert := range key.Certificates {
+			if bytes.Equal(cert.Raw, leaf.Raw) {
+				return nil
+			}
+		}
+	}
+	return ErrCertificateNotRegistered
+}
@@ -0,0 +1,94 @@
+package mtls_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth/mtls"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/square/go-jose.v2"
+)
+
+func newCertificate(t *testing.T, template, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func TestVerifyPKI(t *testing.T) {
+	ca, caKey := newCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil, nil)
+	leaf, _ := newCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "test_client_1", Organization: []string{"Example"}},
+		DNSNames:     []string{"client.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca, caKey)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	chain := []*x509.Certificate{leaf}
+
+	// Matching subject DN
+	assert.NoError(t, mtls.VerifyPKI(chain, roots, "CN=test_client_1,O=Example", ""))
+
+	// Matching DNS SAN
+	assert.NoError(t, mtls.VerifyPKI(chain, roots, "", "client.example.com"))
+
+	// Nothing matches the registration
+	assert.Equal(t, mtls.ErrCertificateSubjectMismatch, mtls.VerifyPKI(chain, roots, "CN=test_client_2", "other.example.com"))
+
+	// Untrusted CA
+	assert.Equal(t, mtls.ErrCertificateNotTrusted, mtls.VerifyPKI(chain, x509.NewCertPool(), "CN=test_client_1,O=Example", ""))
+
+	// No certificate presented
+	assert.Equal(t, mtls.ErrCertificateMissing, mtls.VerifyPKI(nil, roots, "CN=test_client_1,O=Example", ""))
+}
+
+func TestVerifySelfSigned(t *testing.T) {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test_client_1"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	cert, key := newCertificate(t, template, nil, nil)
+	other, _ := newCertificate(t, template, nil, nil)
+	keys := &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: &key.PublicKey, Certificates: []*x509.Certificate{cert}},
+	}}
+
+	assert.NoError(t, mtls.VerifySelfSigned([]*x509.Certificate{cert}, keys))
+	assert.Equal(t, mtls.ErrCertificateNotRegistered, mtls.VerifySelfSigned([]*x509.Certificate{other}, keys))
+
+	// Thumbprints are unpadded base64url SHA-256 hashes
+	assert.Len(t, mtls.Thumbprint(cert), 43)
+	assert.NotEqual(t, mtls.Thumbprint(cert), mtls.Thumbprint(other))
+}
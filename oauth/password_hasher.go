@@ -0,0 +1,53 @@
+package oauth
+
+import (
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/util/password"
+)
+
+// newPasswordVerifier returns a verifier hashing user passwords with the
+// configured scheme and verifying hashes of every scheme, so legacy hashes
+// keep working until their users log in again
+func newPasswordVerifier(cnf *config.Config) *password.Verifier {
+	hashers := map[string]password.PasswordHasher{
+		password.Argon2id: newArgon2idHasher(cnf),
+		password.Bcrypt:   newBcryptHasher(cnf),
+		password.V2:       password.NewV2Hasher(cnf.Oauth.PasswordSecret, cnf.Oauth.PasswordSalt),
+	}
+
+	defaultHasher, ok := hashers[cnf.Oauth.PasswordHasher]
+	if !ok {
+		logger.Errorf("%s: %q, using %s", password.ErrUnknownScheme, cnf.Oauth.PasswordHasher, password.Argon2id)
+		defaultHasher = hashers[password.Argon2id]
+	}
+
+	others := make([]password.PasswordHasher, 0, len(hashers)-1)
+	for _, scheme := range []string{password.Argon2id, password.Bcrypt, password.V2} {
+		if hashers[scheme] != defaultHasher {
+			others = append(others, hashers[scheme])
+		}
+	}
+	return password.NewVerifier(defaultHasher, others...)
+}
+
+// newArgon2idHasher returns the argon2id hasher with the configured
+// parameters, unset parameters use the defaults
+func newArgon2idHasher(cnf *config.Config) *password.Argon2idHasher {
+	time, memory, threads := cnf.Oauth.Argon2Time, cnf.Oauth.Argon2Memory, cnf.Oauth.Argon2Threads
+	if time < 1 {
+		time = 1
+	}
+	if memory < 1 {
+		memory = 64 * 1024
+	}
+	if threads < 1 {
+		threads = 4
+	}
+	return password.NewArgon2idHasher(uint32(time), uint32(memory), uint8(threads))
+}
+
+// newBcryptHasher returns the bcrypt hasher with the configured cost, client
+// secrets are always hashed with it
+func newBcryptHasher(cnf *config.Config) *password.BcryptHasher {
+	return password.NewBcryptHasher(cnf.Oauth.BcryptCost)
+}
@@ -0,0 +1,70 @@
+package oauth_test
+
+import (
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/passwordpolicy"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestPasswordHistory() {
+	history := suite.cnf.Oauth.PasswordHistory
+	defer func() { suite.cnf.Oauth.PasswordHistory = history }()
+	suite.cnf.Oauth.PasswordHistory = 3
+
+	user, err := suite.service.CreateUser(roles.User, "test@history", "first_password", "")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	assert.NoError(suite.T(), suite.service.SetPassword(user, "second_password"))
+	assert.NoError(suite.T(), suite.service.SetPassword(user, "third_password"))
+
+	// The current and the two previous passwords cannot be reused
+	for _, password := range []string{"first_password", "second_password", "third_password"} {
+		err = suite.service.SetPassword(user, password)
+		if assert.IsType(suite.T(), new(passwordpolicy.Error), err, password) {
+			assert.True(suite.T(), err.(*passwordpolicy.Error).Has(passwordpolicy.CodeReused))
+		}
+	}
+
+	// Older passwords drop out of the history
+	assert.NoError(suite.T(), suite.service.SetPassword(user, "fourth_password"))
+	assert.NoError(suite.T(), suite.service.SetPassword(user, "first_password"))
+
+	var count int
+	suite.db.Model(new(models.OauthPasswordHistory)).Where("user_id = ?", user.ID).Count(&count)
+	assert.Equal(suite.T(), 2, count)
+}
+
+func (suite *OauthTestSuite) TestPasswordMaxAge() {
+	maxAge := suite.cnf.Oauth.PasswordMaxAge
+	defer func() { suite.cnf.Oauth.PasswordMaxAge = maxAge }()
+	suite.cnf.Oauth.PasswordMaxAge = 90
+
+	user, err := suite.service.CreateUser(roles.User, "test@maxage", "correct_horse_battery", "")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	_, err = suite.service.AuthUser("test@maxage", "correct_horse_battery", "")
+	assert.NoError(suite.T(), err)
+
+	// Passwords older than the maximum age must be changed
+	changedAt := time.Now().UTC().Add(-91 * 24 * time.Hour)
+	err = suite.db.Model(user).UpdateColumn("password_changed_at", changedAt).Error
+	assert.NoError(suite.T(), err)
+	_, err = suite.service.AuthUser("test@maxage", "correct_horse_battery", "")
+	assert.Equal(suite.T(), oauth.ErrPasswordExpired, err)
+
+	// Wrong passwords are not told the password expired
+	_, err = suite.service.AuthUser("test@maxage", "bogus", "")
+	assert.Equal(suite.T(), oauth.ErrInvalidUserPassword, err)
+
+	// Changing the password lets the user log in again
+	err = suite.service.ChangePassword("", "test@maxage", "correct_horse_battery", "new_password", "")
+	assert.NoError(suite.T(), err)
+	_, err = suite.service.AuthUser("test@maxage", "new_password", "")
+	assert.NoError(suite.T(), err)
+}
@@ -0,0 +1,76 @@
+package passwordpolicy
+
+import (
+	"bufio"
+	_ "embed" // for the bundled denylist
+	"io"
+	"os"
+	"strings"
+)
+
+// commonPasswords is the bundled list of common passwords
+//
+//go:embed common-passwords.txt
+var commonPasswords string
+
+// Denylist is a set of passwords that are never accepted, compared case
+// insensitively
+type Denylist struct {
+	passwords map[string]struct{}
+}
+
+// NewDenylist returns a new Denylist of the passwords
+func NewDenylist(passwords []string) *Denylist {
+	denylist := &Denylist{passwords: make(map[string]struct{}, len(passwords))}
+	for _, password := range passwords {
+		denylist.passwords[strings.ToLower(password)] = struct{}{}
+	}
+	return denylist
+}
+
+// DefaultDenylist returns the bundled denylist of common passwords
+func DefaultDenylist() *Denylist {
+	denylist, err := readDenylist(strings.NewReader(commonPasswords))
+	if err != nil {
+		panic(err)
+	}
+	return denylist
+}
+
+// LoadDenylist reads a denylist file with one password per line, blank
+// lines and lines starting with # are skipped
+func LoadDenylist(path string) (*Denylist, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return readDenylist(file)
+}
+
+func readDenylist(r io.Reader) (*Denylist, error) {
+	var passwords []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		passwords = append(passwords, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return NewDenylist(passwords), nil
+}
+
+// Contains returns true if the password is denylisted
+func (d *Denylist) Contains(password string) bool {
+	_, ok := d.passwords[strings.ToLower(password)]
+	return ok
+}
+
+// Len returns the number of denylisted passwords
+func (d *Denylist) Len() int {
+	return len(d.passwords)
+}
@@ -0,0 +1,34 @@
+package passwordpolicy_test
+
+import (
+	"testing"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth/passwordpolicy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadDenylist(t *testing.T) {
+	// The bundled list, relative to this package
+	denylist, err := passwordpolicy.LoadDenylist("common-passwords.txt")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, denylist.Len() > 100)
+	assert.True(t, denylist.Contains("password"))
+	assert.True(t, denylist.Contains("QWERTY123"))
+	assert.False(t, denylist.Contains("# Common passwords that are never accepted, one per line."))
+	assert.False(t, denylist.Contains("correct horse battery staple"))
+
+	_, err = passwordpolicy.LoadDenylist("bogus.txt")
+	assert.Error(t, err)
+}
+
+func TestDefaultDenylist(t *testing.T) {
+	// The bundled list is embedded, it does not depend on the working directory
+	denylist, err := passwordpolicy.LoadDenylist("common-passwords.txt")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, denylist.Len(), passwordpolicy.DefaultDenylist().Len())
+	assert.True(t, passwordpolicy.DefaultDenylist().Contains("password"))
+}
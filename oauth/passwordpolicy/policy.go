@@ -0,0 +1,169 @@
+package passwordpolicy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Violation codes reported to clients
+const (
+	CodeTooShort               = "password_too_short"
+	CodeTooFewCharacterClasses = "password_too_few_character_classes"
+	CodeDenylisted             = "password_denylisted"
+	CodeContainsUserInfo       = "password_contains_user_info"
+	CodeReused                 = "password_reused"
+)
+
+// HardMinLength is the shortest password accepted whatever the configured
+// minimum length, so a tenant cannot allow empty passwords
+const HardMinLength = 8
+
+// minUserInfoLength is the shortest account or phone checked for, shorter
+// values would reject too many passwords
+const minUserInfoLength = 3
+
+// Violation is a rule of the policy a password breaks
+type Violation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error is returned when a password breaks the policy
+type Error struct {
+	Violations []Violation `json:"violations"`
+}
+
+// Error returns a summary of the violations
+func (e *Error) Error() string {
+	return "Password does not satisfy the password policy"
+}
+
+// MarshalJSON encodes the error like other error responses, with the
+// violations: {"error":"...","violations":[...]}
+func (e *Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"error":      e.Error(),
+		"violations": e.Violations,
+	})
+}
+
+// Has returns true if one of the violations has the code
+func (e *Error) Has(code string) bool {
+	for _, violation := range e.Violations {
+		if violation.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Policy is the password policy of a tenant
+type Policy struct {
+	// MinLength is raised to HardMinLength when lower
+	MinLength int
+	// MinCharacterClasses is how many of lower case, upper case, digits and
+	// other characters a password needs
+	MinCharacterClasses int
+	// Denylist of common passwords, nil allows every password
+	Denylist *Denylist
+	// DisallowUserInfo rejects passwords containing the account or phone
+	DisallowUserInfo bool
+	// HistorySize is how many previous passwords cannot be reused
+	HistorySize int
+	// MaxAge after which a password must be changed, 0 never expires
+	MaxAge time.Duration
+}
+
+// Check returns the violations of a password, userInfo holds the account
+// and phone of the user. Reuse of previous passwords is checked by the
+// caller, which has the password hashes.
+func (p *Policy) Check(password string, userInfo ...string) []Violation {
+	var violations []Violation
+
+	minLength := p.MinLength
+	if minLength < HardMinLength {
+		minLength = HardMinLength
+	}
+	if len([]rune(password)) < minLength {
+		violations = append(violations, Violation{
+			Code:    CodeTooShort,
+			Message: fmt.Sprintf("Password must be at least %d characters long", minLength),
+		})
+	}
+
+	if characterClasses(password) < p.MinCharacterClasses {
+		violations = append(violations, Violation{
+			Code: CodeTooFewCharacterClasses,
+			Message: fmt.Sprintf(
+				"Password must contain %d of lower case, upper case, digits and other characters",
+				p.MinCharacterClasses,
+			),
+		})
+	}
+
+	if p.Denylist != nil && p.Denylist.Contains(password) {
+		violations = append(violations, Violation{
+			Code:    CodeDenylisted,
+			Message: "Password is too common",
+		})
+	}
+
+	if p.DisallowUserInfo && containsUserInfo(password, userInfo) {
+		violations = append(violations, Violation{
+			Code:    CodeContainsUserInfo,
+			Message: "Password must not contain the account or phone",
+		})
+	}
+
+	return violations
+}
+
+// Expired returns true if a password changed at changedAt must be changed
+func (p *Policy) Expired(changedAt time.Time) bool {
+	return p.MaxAge > 0 && time.Since(changedAt) > p.MaxAge
+}
+
+// ReusedViolation returns the violation of reusing a previous password
+func ReusedViolation(historySize int) Violation {
+	return Violation{
+		Code:    CodeReused,
+		Message: fmt.Sprintf("Password must differ from the last %d passwords", historySize),
+	}
+}
+
+func characterClasses(password string) int {
+	var lower, upper, digit, other int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			other = 1
+		}
+	}
+	return lower + upper + digit + other
+}
+
+func containsUserInfo(password string, userInfo []string) bool {
+	password = strings.ToLower(password)
+	for _, info := range userInfo {
+		info = strings.ToLower(info)
+		// The local part of email accounts counts too
+		if i := strings.Index(info, "@"); i >= minUserInfoLength {
+			if strings.Contains(password, info[:i]) {
+				return true
+			}
+		}
+		if len(info) >= minUserInfoLength && strings.Contains(password, info) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package passwordpolicy_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth/passwordpolicy"
+	"github.com/stretchr/testify/assert"
+)
+
+func codes(violations []passwordpolicy.Violation) []string {
+	var codes []string
+	for _, violation := range violations {
+		codes = append(codes, violation.Code)
+	}
+	return codes
+}
+
+func TestCheck(t *testing.T) {
+	policy := &passwordpolicy.Policy{
+		MinLength:           8,
+		MinCharacterClasses: 3,
+		Denylist:            passwordpolicy.NewDenylist([]string{"Password1!"}),
+		DisallowUserInfo:    true,
+	}
+
+	testCases := []struct {
+		password string
+		expected []string
+	}{
+		{"Tr0ub4dor&3", nil},
+		{"Tr0ub&", []string{passwordpolicy.CodeTooShort}},
+		{"troubadorandthree", []string{passwordpolicy.CodeTooFewCharacterClasses}},
+		{"PASSWORD1!", []string{passwordpolicy.CodeDenylisted}},
+		{"Jane.Doe-2024", []string{passwordpolicy.CodeContainsUserInfo}},
+		{"x5551234567X!", []string{passwordpolicy.CodeContainsUserInfo}},
+		{"jane.doe", []string{
+			passwordpolicy.CodeTooFewCharacterClasses,
+			passwordpolicy.CodeContainsUserInfo,
+		}},
+		{"abc", []string{
+			passwordpolicy.CodeTooShort,
+			passwordpolicy.CodeTooFewCharacterClasses,
+		}},
+	}
+	for _, testCase := range testCases {
+		violations := policy.Check(testCase.password, "jane.doe@example.com", "5551234567")
+		assert.Equal(t, testCase.expected, codes(violations), testCase.password)
+	}
+
+	// Rules that are not configured are not enforced
+	assert.Empty(t, new(passwordpolicy.Policy).Check("abcdefgh", "a@example.com"))
+
+	// The minimum length cannot be configured below the hard minimum
+	for _, minLength := range []int{0, 1, passwordpolicy.HardMinLength - 1} {
+		policy := &passwordpolicy.Policy{MinLength: minLength}
+		assert.Equal(t, []string{passwordpolicy.CodeTooShort}, codes(policy.Check("")))
+		assert.Equal(t, []string{passwordpolicy.CodeTooShort}, codes(policy.Check("abcdefg")))
+	}
+}
+
+func TestExpired(t *testing.T) {
+	policy := &passwordpolicy.Policy{MaxAge: 24 * time.Hour}
+	assert.False(t, policy.Expired(time.Now().Add(-time.Hour)))
+	assert.True(t, policy.Expired(time.Now().Add(-25*time.Hour)))
+
+	// Passwords never expire without a maximum age
+	assert.False(t, new(passwordpolicy.Policy).Expired(time.Now().Add(-10000*time.Hour)))
+}
+
+func TestErrorJSON(t *testing.T) {
+	err := &passwordpolicy.Error{Violations: []passwordpolicy.Violation{
+		passwordpolicy.ReusedViolation(3),
+	}}
+	assert.True(t, err.Has(passwordpolicy.CodeReused))
+	assert.False(t, err.Has(passwordpolicy.CodeTooShort))
+
+	data, jsonErr := json.Marshal(err)
+	if assert.NoError(t, jsonErr) {
+		assert.JSONEq(t, `{
+			"error": "Password does not satisfy the password policy",
+			"violations": [{"code": "password_reused", "message": "Password must differ from the last 3 passwords"}]
+		}`, string(data))
+	}
+}
@@ -0,0 +1,48 @@
+package pkce
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"regexp"
+)
+
+const (
+	// MethodS256 hashes the verifier with SHA-256, it is the only method
+	// accepted since plain challenges leak the verifier (RFC 9700 2.1.1)
+	MethodS256 = "S256"
+)
+
+var (
+	// ErrInvalidCodeChallenge ...
+	ErrInvalidCodeChallenge = errors.New("Invalid code challenge")
+	// ErrInvalidCodeChallengeMethod ...
+	ErrInvalidCodeChallengeMethod = errors.New("Invalid code challenge method")
+
+	// Both challenges and verifiers are 43-128 unreserved characters (RFC 7636)
+	codeRegex = regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`)
+)
+
+// ValidateChallenge checks the code challenge and method of an authorization
+// request, the method must be S256 as an empty one means plain
+func ValidateChallenge(challenge, method string) error {
+	if method != MethodS256 {
+		return ErrInvalidCodeChallengeMethod
+	}
+	if !codeRegex.MatchString(challenge) {
+		return ErrInvalidCodeChallenge
+	}
+	return nil
+}
+
+// Verify returns true if the verifier sent to the token endpoint matches the
+// challenge sent with the authorization request
+func Verify(challenge, method, verifier string) bool {
+	if method != MethodS256 || !codeRegex.MatchString(verifier) {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
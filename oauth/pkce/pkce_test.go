@@ -0,0 +1,29 @@
+package pkce_test
+
+import (
+	"testing"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth/pkce"
+	"github.com/stretchr/testify/assert"
+)
+
+// Example from RFC 7636 appendix B
+const (
+	testVerifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	testChallenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+)
+
+func TestValidateChallenge(t *testing.T) {
+	assert.NoError(t, pkce.ValidateChallenge(testChallenge, pkce.MethodS256))
+	assert.Equal(t, pkce.ErrInvalidCodeChallengeMethod, pkce.ValidateChallenge(testChallenge, ""))
+	assert.Equal(t, pkce.ErrInvalidCodeChallengeMethod, pkce.ValidateChallenge(testChallenge, "plain"))
+	assert.Equal(t, pkce.ErrInvalidCodeChallengeMethod, pkce.ValidateChallenge(testChallenge, "S512"))
+	assert.Equal(t, pkce.ErrInvalidCodeChallenge, pkce.ValidateChallenge("too_short", pkce.MethodS256))
+}
+
+func TestVerify(t *testing.T) {
+	assert.True(t, pkce.Verify(testChallenge, pkce.MethodS256, testVerifier))
+	assert.False(t, pkce.Verify(testChallenge, pkce.MethodS256, testChallenge))
+	assert.False(t, pkce.Verify(testVerifier, "plain", testVerifier))
+	assert.False(t, pkce.Verify(testChallenge, pkce.MethodS256, ""))
+}
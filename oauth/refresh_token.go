@@ -20,6 +20,18 @@ var (
 // GetOrCreateRefreshToken retrieves an existing refresh token, if expired,
 // the token gets deleted and new refresh token is created
 func (s *Service) GetOrCreateRefreshToken(client *models.OauthClient, user *models.OauthUser, expiresIn int, scope string) (*models.OauthRefreshToken, error) {
+	return s.getOrCreateRefreshToken(client, user, expiresIn, scope, "")
+}
+
+// getOrCreateRefreshToken is GetOrCreateRefreshToken for a refresh token
+// bound to the DPoP key with the thumbprint jkt, unbound if it is empty.
+// Tokens bound to another key or none are never returned.
+func (s *Service) getOrCreateRefreshToken(client *models.OauthClient, user *models.OauthUser, expiresIn int, scope, jkt string) (*models.OauthRefreshToken, error) {
+	// Tokens are never issued across tenants
+	if err := checkUserTenant(client, user); err != nil {
+		return nil, err
+	}
+
 	// Try to fetch an existing refresh token first
 	refreshToken := new(models.OauthRefreshToken)
 	query := models.OauthRefreshTokenPreload(s.db).Where("client_id = ?", client.ID)
@@ -28,6 +40,11 @@ func (s *Service) GetOrCreateRefreshToken(client *models.OauthClient, user *mode
 	} else {
 		query = query.Where("user_id IS NULL")
 	}
+	if jkt != "" {
+		query = query.Where("jkt = ?", jkt)
+	} else {
+		query = query.Where("jkt IS NULL")
+	}
 	found := !query.First(refreshToken).RecordNotFound()
 
 	// Check if the token is expired, if found
@@ -44,6 +61,7 @@ func (s *Service) GetOrCreateRefreshToken(client *models.OauthClient, user *mode
 	// Create a new refresh token if it expired or was not found
 	if expired || !found {
 		refreshToken = models.NewOauthRefreshToken(client, user, expiresIn, scope)
+		refreshToken.JKT = util.StringOrNull(jkt)
 		if err := s.db.Create(refreshToken).Error; err != nil {
 			return nil, err
 		}
@@ -2,6 +2,9 @@ package oauth
 
 import (
 	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth/jwt"
+	"github.com/RichardKnop/go-oauth2-server/oauth/tokentypes"
+	"github.com/RichardKnop/go-oauth2-server/util"
 )
 
 // AccessTokenResponse ...
@@ -24,6 +27,10 @@ type IntrospectResponse struct {
 	TenantID  string `json:"tenant_id,omitempty"`
 	TokenType string `json:"token_type,omitempty"`
 	ExpiresAt int    `json:"exp,omitempty"`
+	// Roles are the names of the user's roles
+	Roles []string `json:"roles,omitempty"`
+
+	Cnf *jwt.Confirmation `json:"cnf,omitempty"`
 }
 
 // NewAccessTokenResponse ...
@@ -34,6 +41,10 @@ func NewAccessTokenResponse(accessToken *models.OauthAccessToken, refreshToken *
 		TokenType:   theTokenType,
 		Scope:       accessToken.Scope,
 	}
+	// Tokens bound to a DPoP key can only be used with the DPoP scheme
+	if accessToken.JKT.Valid {
+		response.TokenType = tokentypes.DPoP
+	}
 	if jwt != "" {
 		response.IDToken = jwt
 	}
@@ -49,3 +60,33 @@ func NewJWTResponse(jwk string, lifetime int, theTokenType string, scope string)
 	}
 	return response
 }
+
+// ConsentResponse is a client the user granted access
+type ConsentResponse struct {
+	ClientID   string `json:"client_id"`
+	ClientName string `json:"client_name,omitempty"`
+	Scope      string `json:"scope"`
+	GrantedAt  string `json:"granted_at"`
+}
+
+// ConsentsResponse lists the consents of a user
+type ConsentsResponse struct {
+	Consents []*ConsentResponse `json:"consents"`
+}
+
+// NewConsentsResponse creates new ConsentsResponse instance
+func NewConsentsResponse(consents []*models.OauthConsent) *ConsentsResponse {
+	response := &ConsentsResponse{Consents: make([]*ConsentResponse, 0, len(consents))}
+	for _, consent := range consents {
+		consentResponse := &ConsentResponse{
+			Scope:     consent.Scope,
+			GrantedAt: util.FormatTime(&consent.GrantedAt),
+		}
+		if consent.Client != nil {
+			consentResponse.ClientID = consent.Client.Key
+			consentResponse.ClientName = consent.Client.Name
+		}
+		response.Consents = append(response.Consents, consentResponse)
+	}
+	return response
+}
@@ -0,0 +1,296 @@
+package oauth
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
+	"github.com/RichardKnop/uuid"
+	"github.com/jinzhu/gorm"
+)
+
+const maxRoleNameLength = 50
+
+var (
+	// ErrRoleNotFound ...
+	ErrRoleNotFound = errors.New("Role not found")
+	// ErrInvalidRoleName ...
+	ErrInvalidRoleName = errors.New("Invalid role name")
+	// ErrRoleNameTaken ...
+	ErrRoleNameTaken = errors.New("Role name taken")
+	// ErrBuiltInRole ...
+	ErrBuiltInRole = errors.New("Built-in roles cannot be changed")
+)
+
+// FindRoleByID looks up a role by ID
+func (s *Service) FindRoleByID(roleID string) (*models.OauthRole, error) {
+	role := new(models.OauthRole)
+	notFound := s.db.Where("id = ?", roleID).First(role).RecordNotFound()
+
+	// Not found
+	if notFound {
+		return nil, ErrRoleNotFound
+	}
+
+	return role, nil
+}
+
+// ListRoles returns the built-in roles followed by the custom roles of a
+// tenant
+func (s *Service) ListRoles(tenantID string) ([]*models.OauthRole, error) {
+	var tenantRoles []*models.OauthRole
+	err := s.db.Where("tenant_id IS NULL OR tenant_id = ?", tenantID).
+		Order("tenant_id IS NOT NULL, name").Find(&tenantRoles).Error
+	if err != nil {
+		return nil, err
+	}
+	return tenantRoles, nil
+}
+
+// CreateRole saves a new custom role of a tenant
+func (s *Service) CreateRole(tenantID, name, description string, permissions []string, scope string) (*models.OauthRole, error) {
+	// The tenant must exist and be active
+	if _, err := s.GetTenantConfig(tenantID); err != nil {
+		return nil, err
+	}
+
+	role := &models.OauthRole{
+		ID:       strings.Replace(uuid.New(), "-", "", -1),
+		TenantID: sql.NullString{String: tenantID, Valid: true},
+	}
+	if err := s.setRoleFields(role, name, description, permissions, scope); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(role).Error; err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("audit: role created", "role_id", role.ID, "role", role.Name, "tenant_id", tenantID)
+	return role, nil
+}
+
+// UpdateRole replaces the name, description, permissions and scope of a
+// custom role, scope changes apply to tokens granted afterwards
+func (s *Service) UpdateRole(role *models.OauthRole, name, description string, permissions []string, scope string) error {
+	if role.IsBuiltIn() {
+		return ErrBuiltInRole
+	}
+
+	updated := *role
+	if err := s.setRoleFields(&updated, name, description, permissions, scope); err != nil {
+		return err
+	}
+	err := s.db.Model(role).UpdateColumns(map[string]interface{}{
+		"name":        updated.Name,
+		"description": updated.Description,
+		"permissions": updated.Permissions,
+		"scope":       updated.Scope,
+		"updated_at":  time.Now().UTC(),
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("audit: role updated", "role_id", role.ID, "role", role.Name, "tenant_id", role.TenantID.String)
+	return nil
+}
+
+// DeleteRole deletes a custom role and unassigns it from its users
+func (s *Service) DeleteRole(role *models.OauthRole) error {
+	if role.IsBuiltIn() {
+		return ErrBuiltInRole
+	}
+
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	err := tx.Model(new(models.OauthUser)).Where("role_id = ?", role.ID).
+		UpdateColumn("role_id", nil).Error
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+	if err := tx.Where("role_id = ?", role.ID).Delete(new(models.OauthUserRole)).Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+	if err := tx.Unscoped().Delete(role).Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	s.logger.Info("audit: role deleted", "role_id", role.ID, "role", role.Name, "tenant_id", role.TenantID.String)
+	return nil
+}
+
+// GetUserRoles returns the roles assigned to a user
+func (s *Service) GetUserRoles(user *models.OauthUser) ([]*models.OauthRole, error) {
+	var userRoles []*models.OauthRole
+	err := s.db.Joins("JOIN oauth_user_roles ON oauth_user_roles.role_id = oauth_roles.id").
+		Where("oauth_user_roles.user_id = ?", user.ID).
+		Order("oauth_roles.name").Find(&userRoles).Error
+	if err != nil {
+		return nil, err
+	}
+	return userRoles, nil
+}
+
+// AssignRole assigns a built-in role or a custom role of the user's tenant
+// to a user, assigning a role twice does nothing
+func (s *Service) AssignRole(user *models.OauthUser, roleID string) error {
+	role, err := s.findTenantRole(s.db, roleID, user.TenantID)
+	if err != nil {
+		return err
+	}
+	if err := assignRole(s.db, user, role); err != nil {
+		return err
+	}
+
+	s.logger.Info("audit: role assigned", "role_id", role.ID, "user_id", user.ID, "tenant_id", user.TenantID)
+	return nil
+}
+
+// UnassignRole removes a role from a user, including the role the user was
+// created with
+func (s *Service) UnassignRole(user *models.OauthUser, roleID string) error {
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	err := tx.Where("user_id = ? AND role_id = ?", user.ID, roleID).
+		Delete(new(models.OauthUserRole)).Error
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+	if user.RoleID.String == roleID {
+		if err := tx.Model(user).UpdateColumn("role_id", nil).Error; err != nil {
+			tx.Rollback() // rollback the transaction
+			return err
+		}
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+	if user.RoleID.String == roleID {
+		user.RoleID = sql.NullString{}
+	}
+
+	s.logger.Info("audit: role unassigned", "role_id", roleID, "user_id", user.ID, "tenant_id", user.TenantID)
+	return nil
+}
+
+// UserHasRole returns true if the role is assigned to the user
+func (s *Service) UserHasRole(user *models.OauthUser, roleID string) (bool, error) {
+	var count int
+	err := s.db.Model(new(models.OauthUserRole)).
+		Where("user_id = ? AND role_id = ?", user.ID, roleID).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// UserHasPermission returns true if any role of the user has the permission
+func (s *Service) UserHasPermission(user *models.OauthUser, permission string) (bool, error) {
+	userRoles, err := s.GetUserRoles(user)
+	if err != nil {
+		return false, err
+	}
+	for _, role := range userRoles {
+		for _, rolePermission := range roles.SplitPermissions(role.Permissions) {
+			if rolePermission == permission {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// getUserRoleNames returns the names of a user's roles, for the roles claim
+func (s *Service) getUserRoleNames(userID string) ([]string, error) {
+	var names []string
+	err := s.db.Model(new(models.OauthRole)).
+		Joins("JOIN oauth_user_roles ON oauth_user_roles.role_id = oauth_roles.id").
+		Where("oauth_user_roles.user_id = ?", userID).
+		Order("oauth_roles.name").Pluck("oauth_roles.name", &names).Error
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// findTenantRole looks up a role users of the tenant can be assigned
+func (s *Service) findTenantRole(db *gorm.DB, roleID, tenantID string) (*models.OauthRole, error) {
+	role := new(models.OauthRole)
+	notFound := db.Where("id = ?", roleID).
+		Where("tenant_id IS NULL OR tenant_id = ?", tenantID).
+		First(role).RecordNotFound()
+
+	// Not found
+	if notFound {
+		return nil, ErrRoleNotFound
+	}
+
+	return role, nil
+}
+
+// setRoleFields validates and sets the editable fields of a custom role
+func (s *Service) setRoleFields(role *models.OauthRole, name, description string, permissions []string, scope string) error {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxRoleNameLength {
+		return ErrInvalidRoleName
+	}
+
+	// Names are unique within the tenant and the built-in roles
+	var count int
+	err := s.db.Model(new(models.OauthRole)).
+		Where("tenant_id IS NULL OR tenant_id = ?", role.TenantID.String).
+		Where("LOWER(name) = LOWER(?) AND id <> ?", name, role.ID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrRoleNameTaken
+	}
+
+	joinedPermissions, err := roles.JoinPermissions(permissions)
+	if err != nil {
+		return err
+	}
+
+	scope = strings.Join(strings.Fields(scope), " ")
+	if scope != "" && !s.ScopeExists(scope) {
+		return ErrInvalidScope
+	}
+
+	role.Name = name
+	role.Description = strings.TrimSpace(description)
+	role.Permissions = joinedPermissions
+	role.Scope = scope
+	return nil
+}
+
+// assignRole saves a role assignment unless it exists
+func assignRole(db *gorm.DB, user *models.OauthUser, role *models.OauthRole) error {
+	userRole := &models.OauthUserRole{
+		TenantID:  user.TenantID,
+		UserID:    user.ID,
+		RoleID:    role.ID,
+		CreatedAt: time.Now().UTC(),
+	}
+	return db.Where("user_id = ? AND role_id = ?", user.ID, role.ID).
+		FirstOrCreate(userRole).Error
+}
@@ -0,0 +1,117 @@
+package oauth_test
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestCustomRoles() {
+	role, err := suite.service.CreateRole("", "Auditor", "Reads reports", []string{"reports:read", "reports:read"}, "read")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	assert.Len(suite.T(), role.ID, 32)
+	assert.False(suite.T(), role.IsBuiltIn())
+	assert.Equal(suite.T(), "reports:read", role.Permissions)
+
+	// Names are unique within the tenant and the built-in roles
+	_, err = suite.service.CreateRole("", "auditor", "", nil, "")
+	assert.Equal(suite.T(), oauth.ErrRoleNameTaken, err)
+	_, err = suite.service.CreateRole("", "Superuser", "", nil, "")
+	assert.Equal(suite.T(), oauth.ErrRoleNameTaken, err)
+	_, err = suite.service.CreateRole("", "Writer", "", nil, "bogus")
+	assert.Equal(suite.T(), oauth.ErrInvalidScope, err)
+	_, err = suite.service.CreateRole("", "Writer", "", []string{"reports write"}, "")
+	assert.Equal(suite.T(), roles.ErrInvalidPermission, err)
+
+	// Built-in roles cannot be changed
+	superuser, err := suite.service.FindRoleByID(roles.Superuser)
+	if assert.NoError(suite.T(), err) {
+		assert.True(suite.T(), superuser.IsBuiltIn())
+		assert.Equal(suite.T(), oauth.ErrBuiltInRole, suite.service.UpdateRole(superuser, "Root", "", nil, ""))
+		assert.Equal(suite.T(), oauth.ErrBuiltInRole, suite.service.DeleteRole(superuser))
+	}
+
+	tenantRoles, err := suite.service.ListRoles("")
+	if assert.NoError(suite.T(), err) {
+		names := make([]string, len(tenantRoles))
+		for i, tenantRole := range tenantRoles {
+			names[i] = tenantRole.Name
+		}
+		assert.Equal(suite.T(), []string{"Superuser", "User", "Auditor"}, names)
+	}
+
+	// Users can be created with a custom role
+	user, err := suite.service.CreateUser(role.ID, "test@auditor", "correct_horse_battery", "")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	assert.NoError(suite.T(), suite.service.AssignRole(user, roles.User))
+	assert.NoError(suite.T(), suite.service.AssignRole(user, roles.User))
+	userRoles, err := suite.service.GetUserRoles(user)
+	if assert.NoError(suite.T(), err) {
+		assert.Len(suite.T(), userRoles, 2)
+	}
+	hasPermission, err := suite.service.UserHasPermission(user, "reports:read")
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), hasPermission)
+
+	// Deleting the role unassigns it
+	assert.NoError(suite.T(), suite.service.DeleteRole(role))
+	hasRole, err := suite.service.UserHasRole(user, role.ID)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), hasRole)
+	user, err = suite.service.FindUserByID(user.ID)
+	if assert.NoError(suite.T(), err) {
+		assert.False(suite.T(), user.RoleID.Valid)
+	}
+}
+
+func (suite *OauthTestSuite) TestRoleScopeCapsGrantedScope() {
+	role, err := suite.service.CreateRole("", "Reader", "", nil, "read")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	user, err := suite.service.CreateUser(roles.User, "test@reader", "correct_horse_battery", "")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+
+	passwordGrant := func() *oauth.AccessTokenResponse {
+		w := suite.mfaTokenRequest(`{"grant_type": "password", "username": "test@reader", "password": "correct_horse_battery", "scope": "read read_write"}`)
+		if !assert.Equal(suite.T(), http.StatusOK, w.Code) {
+			return nil
+		}
+		response := new(oauth.AccessTokenResponse)
+		assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), response))
+		return response
+	}
+
+	// The built-in user role has no scope and does not cap it
+	if response := passwordGrant(); response != nil {
+		assert.Equal(suite.T(), "read read_write", response.Scope)
+	}
+
+	// The scope of an assigned role caps it
+	assert.NoError(suite.T(), suite.service.AssignRole(user, role.ID))
+	response := passwordGrant()
+	if response == nil {
+		return
+	}
+	assert.Equal(suite.T(), "read", response.Scope)
+
+	// Introspection lists the user's roles
+	accessToken := new(models.OauthAccessToken)
+	if !assert.NoError(suite.T(), suite.db.Where("token = ?", response.AccessToken).First(accessToken).Error) {
+		return
+	}
+	introspection, err := suite.service.NewIntrospectResponseFromAccessToken(accessToken)
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), []string{"Reader", "User"}, introspection.Roles)
+	}
+}
@@ -0,0 +1,39 @@
+package roles
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrInvalidPermission ...
+	ErrInvalidPermission = errors.New("Invalid permission")
+
+	permissionPattern = regexp.MustCompile(`^[A-Za-z0-9_.:/*-]{1,100}$`)
+)
+
+// JoinPermissions validates permissions and returns them sorted and without
+// duplicates as a space delimited string
+func JoinPermissions(permissions []string) (string, error) {
+	seen := make(map[string]bool, len(permissions))
+	joined := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		if !permissionPattern.MatchString(permission) {
+			return "", ErrInvalidPermission
+		}
+		if seen[permission] {
+			continue
+		}
+		seen[permission] = true
+		joined = append(joined, permission)
+	}
+	sort.Strings(joined)
+	return strings.Join(joined, " "), nil
+}
+
+// SplitPermissions returns the permissions of a space delimited string
+func SplitPermissions(permissions string) []string {
+	return strings.Fields(permissions)
+}
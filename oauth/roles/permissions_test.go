@@ -0,0 +1,29 @@
+package roles
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJoinPermissions(t *testing.T) {
+	joined, err := JoinPermissions([]string{"users:write", "billing:read", "users:write"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "billing:read users:write", joined)
+	}
+
+	joined, err = JoinPermissions(nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "", joined)
+	}
+
+	for _, permission := range []string{"", "users write", "users\twrite", "café"} {
+		_, err = JoinPermissions([]string{permission})
+		assert.Equal(t, ErrInvalidPermission, err, permission)
+	}
+}
+
+func TestSplitPermissions(t *testing.T) {
+	assert.Equal(t, []string{"billing:read", "users:write"}, SplitPermissions("billing:read users:write"))
+	assert.Empty(t, SplitPermissions(""))
+}
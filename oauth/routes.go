@@ -11,7 +11,28 @@ const (
 	introspectResource = "introspect"
 	introspectPath     = "/" + introspectResource
 	revokePath         = "/revoke"
+	parPath            = "/par"
+	endSessionPath     = "/logout"
+	passwordPath       = "/password"
+	passwordResetPath  = "/password/reset"
+	resetConfirmPath   = "/password/reset/confirm"
+	emailVerifyPath    = "/email/verification"
+	emailConfirmPath   = "/email/verification/confirm"
+	passwordlessPath   = "/passwordless/start"
+	mfaTOTPPath        = "/mfa/totp"
+	mfaConfirmPath     = "/mfa/totp/confirm"
+	mfaRecoveryPath    = "/mfa/recovery-codes"
+	consentsPath       = "/consents"
+	consentPath        = "/consents/{client_id}"
+	registerPath       = "/register"
+	registrationPath   = "/register/{client_id}"
 	jwksPath           = "/.well-known/jwks.json"
+	discoveryPath      = "/.well-known/openid-configuration"
+
+	// TenantPathPrefix prefixes the issuer path of a tenant
+	TenantPathPrefix = "/t/"
+	// TenantRoutePrefix is the route prefix of tenant issuers
+	TenantRoutePrefix = TenantPathPrefix + "{tenant}"
 )
 
 // RegisterRoutes registers route handlers for the oauth service
@@ -20,6 +41,13 @@ func (s *Service) RegisterRoutes(router *mux.Router, prefix string) {
 	routes.AddRoutes(s.GetRoutes(), subRouter)
 }
 
+// RegisterWellKnownRoutes registers the discovery and JWKS documents of the
+// default issuer and of tenant issuers
+func (s *Service) RegisterWellKnownRoutes(router *mux.Router) {
+	routes.AddRoutes(s.getWellKnownRoutes(), router)
+	routes.AddRoutes(s.getWellKnownRoutes(), router.PathPrefix(TenantRoutePrefix).Subrouter())
+}
+
 // GetRoutes returns []routes.Route slice for the oauth service
 func (s *Service) GetRoutes() []routes.Route {
 	return []routes.Route{
@@ -41,6 +69,114 @@ func (s *Service) GetRoutes() []routes.Route {
 			Pattern:     revokePath,
 			HandlerFunc: s.revokeHandler,
 		},
+		{
+			Name:        "oauth_par",
+			Method:      "POST",
+			Pattern:     parPath,
+			HandlerFunc: s.parHandler,
+		},
+		{
+			Name:        "oauth_password",
+			Method:      "POST",
+			Pattern:     passwordPath,
+			HandlerFunc: s.changePasswordHandler,
+		},
+		{
+			Name:        "oauth_password_reset",
+			Method:      "POST",
+			Pattern:     passwordResetPath,
+			HandlerFunc: s.passwordResetHandler,
+		},
+		{
+			Name:        "oauth_password_reset_confirm",
+			Method:      "POST",
+			Pattern:     resetConfirmPath,
+			HandlerFunc: s.passwordResetConfirmHandler,
+		},
+		{
+			Name:        "oauth_email_verification",
+			Method:      "POST",
+			Pattern:     emailVerifyPath,
+			HandlerFunc: s.emailVerificationHandler,
+		},
+		{
+			Name:        "oauth_email_verification_confirm",
+			Method:      "POST",
+			Pattern:     emailConfirmPath,
+			HandlerFunc: s.emailConfirmHandler,
+		},
+		{
+			Name:        "oauth_passwordless_start",
+			Method:      "POST",
+			Pattern:     passwordlessPath,
+			HandlerFunc: s.passwordlessStartHandler,
+		},
+		{
+			Name:        "oauth_mfa_totp",
+			Method:      "POST",
+			Pattern:     mfaTOTPPath,
+			HandlerFunc: s.mfaEnrollHandler,
+		},
+		{
+			Name:        "oauth_mfa_totp_confirm",
+			Method:      "POST",
+			Pattern:     mfaConfirmPath,
+			HandlerFunc: s.mfaConfirmHandler,
+		},
+		{
+			Name:        "oauth_mfa_recovery_codes",
+			Method:      "POST",
+			Pattern:     mfaRecoveryPath,
+			HandlerFunc: s.mfaRecoveryCodesHandler,
+		},
+		{
+			Name:        "oauth_consents_list",
+			Method:      "GET",
+			Pattern:     consentsPath,
+			HandlerFunc: s.listConsentsHandler,
+		},
+		{
+			Name:        "oauth_consents_revoke",
+			Method:      "DELETE",
+			Pattern:     consentPath,
+			HandlerFunc: s.revokeConsentHandler,
+		},
+		{
+			Name:        "oauth_register",
+			Method:      "POST",
+			Pattern:     registerPath,
+			HandlerFunc: s.registerHandler,
+		},
+		{
+			Name:        "oauth_registration_read",
+			Method:      "GET",
+			Pattern:     registrationPath,
+			HandlerFunc: s.registrationHandler,
+		},
+		{
+			Name:        "oauth_registration_update",
+			Method:      "PUT",
+			Pattern:     registrationPath,
+			HandlerFunc: s.registrationHandler,
+		},
+		{
+			Name:        "oauth_registration_delete",
+			Method:      "DELETE",
+			Pattern:     registrationPath,
+			HandlerFunc: s.registrationHandler,
+		},
+		{
+			Name:        "oauth_end_session",
+			Method:      "GET",
+			Pattern:     endSessionPath,
+			HandlerFunc: s.endSessionHandler,
+		},
+		{
+			Name:        "oauth_end_session_post",
+			Method:      "POST",
+			Pattern:     endSessionPath,
+			HandlerFunc: s.endSessionHandler,
+		},
 		{
 			Name:        "jwks",
 			Method:      "GET",
@@ -49,3 +185,21 @@ func (s *Service) GetRoutes() []routes.Route {
 		},
 	}
 }
+
+// getWellKnownRoutes returns the routes registered relative to an issuer
+func (s *Service) getWellKnownRoutes() []routes.Route {
+	return []routes.Route{
+		{
+			Name:        "discovery",
+			Method:      "GET",
+			Pattern:     discoveryPath,
+			HandlerFunc: s.discoveryHandler,
+		},
+		{
+			Name:        "issuer_jwks",
+			Method:      "GET",
+			Pattern:     jwksPath,
+			HandlerFunc: s.jwksHandler,
+		},
+	}
+}
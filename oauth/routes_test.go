@@ -10,7 +10,7 @@ import (
 func (suite *OauthTestSuite) TestTokensRouteIsValid() {
 	r, err := http.NewRequest(
 		"POST",
-		"http://1.2.3.4/v1/oauth/tokens",
+		"http://1.2.3.4/v1/oauth/token",
 		nil,
 	)
 	assert.NoError(suite.T(), err, "New request should not cause an error")
@@ -19,7 +19,7 @@ func (suite *OauthTestSuite) TestTokensRouteIsValid() {
 	match := new(mux.RouteMatch)
 	suite.router.Match(r, match)
 	if assert.NotNil(suite.T(), match.Route, "Expected to find a route match") {
-		assert.Equal(suite.T(), "oauth_tokens", match.Route.GetName(), "Expected route to be matched")
+		assert.Equal(suite.T(), "oauth_token", match.Route.GetName(), "Expected route to be matched")
 	}
 }
 
@@ -38,3 +38,23 @@ func (suite *OauthTestSuite) TestIntrospectRouteIsValid() {
 		assert.Equal(suite.T(), "oauth_introspect", match.Route.GetName(), "Expected route to be matched")
 	}
 }
+
+func (suite *OauthTestSuite) TestTenantDiscoveryRouteIsValid() {
+	router := mux.NewRouter()
+	suite.service.RegisterWellKnownRoutes(router)
+
+	r, err := http.NewRequest(
+		"GET",
+		"http://1.2.3.4/t/tenant_a/.well-known/openid-configuration",
+		nil,
+	)
+	assert.NoError(suite.T(), err, "New request should not cause an error")
+
+	// Check the routing
+	match := new(mux.RouteMatch)
+	router.Match(r, match)
+	if assert.NotNil(suite.T(), match.Route, "Expected to find a route match") {
+		assert.Equal(suite.T(), "discovery", match.Route.GetName(), "Expected route to be matched")
+		assert.Equal(suite.T(), "tenant_a", match.Vars["tenant"])
+	}
+}
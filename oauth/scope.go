@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util"
 )
 
 var (
@@ -30,6 +31,56 @@ func (s *Service) GetScope(requestedScope string) (string, error) {
 	return "", ErrInvalidScope
 }
 
+// getClientScope is GetScope limited to the scope registered for the client,
+// which is also the default scope of such clients
+func (s *Service) getClientScope(client *models.OauthClient, requestedScope string) (string, error) {
+	if !client.Scope.Valid {
+		return s.GetScope(requestedScope)
+	}
+
+	if requestedScope == "" {
+		return client.Scope.String, nil
+	}
+	if !util.SpaceDelimitedStringNotGreater(requestedScope, client.Scope.String) {
+		return "", ErrInvalidScope
+	}
+	return s.GetScope(requestedScope)
+}
+
+// getUserScope caps a scope to the scopes mapped to the user's roles. Roles
+// without a scope do not grant any, users none of whose roles have a scope
+// are not capped.
+func (s *Service) getUserScope(user *models.OauthUser, scope string) (string, error) {
+	if user == nil {
+		return scope, nil
+	}
+	userRoles, err := s.GetUserRoles(user)
+	if err != nil {
+		return "", err
+	}
+
+	allowed := make(map[string]bool)
+	for _, role := range userRoles {
+		for _, roleScope := range strings.Fields(role.Scope) {
+			allowed[roleScope] = true
+		}
+	}
+	if len(allowed) == 0 {
+		return scope, nil
+	}
+
+	var capped []string
+	for _, requested := range strings.Fields(scope) {
+		if allowed[requested] {
+			capped = append(capped, requested)
+		}
+	}
+	if len(capped) == 0 {
+		return "", ErrInvalidScope
+	}
+	return strings.Join(capped, " "), nil
+}
+
 // GetDefaultScope returns the default scope
 func (s *Service) GetDefaultScope() string {
 	// Fetch default scopes
@@ -1,27 +1,80 @@
 package oauth
 
 import (
+	"crypto/x509"
+	"net/http"
+	"sync"
+
+	"github.com/RichardKnop/go-oauth2-server/audit"
 	"github.com/RichardKnop/go-oauth2-server/config"
-	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
+	"github.com/RichardKnop/go-oauth2-server/log"
+	"github.com/RichardKnop/go-oauth2-server/oauth/mail"
+	"github.com/RichardKnop/go-oauth2-server/oauth/passwordpolicy"
+	"github.com/RichardKnop/go-oauth2-server/session"
+	"github.com/RichardKnop/go-oauth2-server/util/password"
+	"github.com/RichardKnop/go-oauth2-server/webhook"
 	"github.com/go-redis/redis/v7"
 	"github.com/jinzhu/gorm"
 )
 
+var logger = log.New("oauth")
+
 // Service struct keeps objects to avoid passing them around
 type Service struct {
-	cnf          *config.Config
-	db           *gorm.DB
-	redis        *redis.Client
-	allowedRoles []string
+	cnf   *config.Config
+	db    *gorm.DB
+	redis *redis.Client
+
+	passwords    *password.Verifier
+	secretHasher *password.BcryptHasher
+
+	smsSender SMSSender
+
+	sessionService session.ServiceInterface
+	auditService   audit.ServiceInterface
+	webhookService webhook.ServiceInterface
+
+	mailer mail.Mailer
+
+	backchannelLogoutClient *http.Client
+
+	passwordDenylist *passwordpolicy.Denylist
+
+	logger *log.Logger
+
+	lazy *lazyState
+}
+
+// lazyState is loaded on first use and shared by the copies of the service
+// made for traced requests
+type lazyState struct {
+	clientCAs     *x509.CertPool
+	clientCAsOnce sync.Once
+
+	mailTemplates     *mail.Templates
+	mailTemplatesErr  error
+	mailTemplatesOnce sync.Once
+
+	backchannelLogouts     chan *backchannelLogout
+	backchannelLogoutsOnce sync.Once
+	backchannelLogoutsStop chan struct{}
+	backchannelLogoutsWG   sync.WaitGroup
 }
 
 // NewService returns a new Service instance
 func NewService(cnf *config.Config, db *gorm.DB, redisClient *redis.Client) *Service {
 	return &Service{
-		cnf:          cnf,
-		db:           db,
-		redis:        redisClient,
-		allowedRoles: []string{roles.Superuser, roles.User},
+		cnf:                     cnf,
+		db:                      db,
+		redis:                   redisClient,
+		passwords:               newPasswordVerifier(cnf),
+		secretHasher:            newBcryptHasher(cnf),
+		smsSender:               newSMSSender(cnf),
+		mailer:                  newMailer(cnf),
+		passwordDenylist:        newPasswordDenylist(cnf),
+		backchannelLogoutClient: newBackchannelLogoutClient(cnf),
+		logger:                  logger,
+		lazy:                    new(lazyState),
 	}
 }
 
@@ -31,4 +84,6 @@ func (s *Service) GetConfig() *config.Config {
 }
 
 // Close stops any running services
-func (s *Service) Close() {}
+func (s *Service) Close() {
+	s.stopBackchannelLogoutWorkers()
+}
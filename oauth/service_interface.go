@@ -1,10 +1,17 @@
 package oauth
 
 import (
+	"crypto/x509"
+	"net/http"
+	"net/url"
+
+	"github.com/RichardKnop/go-oauth2-server/audit"
 	"github.com/RichardKnop/go-oauth2-server/config"
 	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth/mail"
 	"github.com/RichardKnop/go-oauth2-server/session"
 	"github.com/RichardKnop/go-oauth2-server/util/routes"
+	"github.com/RichardKnop/go-oauth2-server/webhook"
 	"github.com/gorilla/mux"
 	"github.com/jinzhu/gorm"
 	"gopkg.in/square/go-jose.v2"
@@ -16,28 +23,96 @@ type ServiceInterface interface {
 	GetConfig() *config.Config
 	GetRoutes() []routes.Route
 	RegisterRoutes(router *mux.Router, prefix string)
+	RegisterWellKnownRoutes(router *mux.Router)
 	ClientExists(clientID string) bool
 	FindClientByClientID(clientID string) (*models.OauthClient, error)
 	CreateClient(clientID, secret, redirectURI string, tenantID string) (*models.OauthClient, error)
 	CreateClientTx(tx *gorm.DB, clientID, secret, redirectURI string, tenantID string) (*models.OauthClient, error)
 	AuthClient(clientID, secret string) (*models.OauthClient, error)
+	AuthClientAssertion(clientID, assertionType, assertion string) (*models.OauthClient, error)
+	AuthClientCertificate(clientID string, chain []*x509.Certificate) (*models.OauthClient, error)
 	UserExists(username string, tenantID string) bool
 	FindUserByUsername(username string) (*models.OauthUser, error)
 	FindUserByAccountAndTenantID(account string, tenantID string) (*models.OauthUser, error)
 	FindUserByPhoneAndTenantID(phone string, tenantID string) (*models.OauthUser, error)
+	FindUserByEmailAndTenantID(email string, tenantID string) (*models.OauthUser, error)
+	FindUserByID(userID string) (*models.OauthUser, error)
+	ListUsers(tenantID string, offset, limit int) ([]*models.OauthUser, int, error)
+	CreateUser(roleID, account, password string, tenantID string) (*models.OauthUser, error)
+	CreateUserTx(tx *gorm.DB, roleID, account, password string, tenantID string) (*models.OauthUser, error)
+	UpdateUser(user *models.OauthUser, name, account, phone string) error
+	SetPassword(user *models.OauthUser, password string) error
+	SetPasswordTx(tx *gorm.DB, user *models.OauthUser, password string) error
+	ChangePassword(tenantID, username, password, newPassword, clientIP string) error
+	ResetPassword(user *models.OauthUser) (string, error)
+	SetUserDisabled(user *models.OauthUser, disabled bool) error
+	DeleteUser(user *models.OauthUser) error
+	UnlockUser(user *models.OauthUser) error
+	FindRoleByID(roleID string) (*models.OauthRole, error)
+	ListRoles(tenantID string) ([]*models.OauthRole, error)
+	CreateRole(tenantID, name, description string, permissions []string, scope string) (*models.OauthRole, error)
+	UpdateRole(role *models.OauthRole, name, description string, permissions []string, scope string) error
+	DeleteRole(role *models.OauthRole) error
+	GetUserRoles(user *models.OauthUser) ([]*models.OauthRole, error)
+	AssignRole(user *models.OauthUser, roleID string) error
+	UnassignRole(user *models.OauthUser, roleID string) error
+	UserHasRole(user *models.OauthUser, roleID string) (bool, error)
+	UserHasPermission(user *models.OauthUser, permission string) (bool, error)
+	BeginTOTPEnrollment(user *models.OauthUser) (*TOTPEnrollment, error)
+	ConfirmTOTPEnrollment(user *models.OauthUser, code string) ([]string, error)
+	RegenerateRecoveryCodes(user *models.OauthUser, code string) ([]string, error)
+	ResetMFA(user *models.OauthUser) error
+	SetMFARequired(user *models.OauthUser, required bool) error
+	MFARequired(user *models.OauthUser) (bool, error)
+	VerifyMFACode(user *models.OauthUser, otp, recoveryCode string) error
+	SetEmail(user *models.OauthUser, email string) error
+	SendEmailConfirmation(user *models.OauthUser) error
+	ConfirmEmail(token string) (*models.OauthUser, error)
+	RequestPasswordReset(tenantID, email string) error
+	ResetPasswordWithToken(token, newPassword string) error
+	UseMailer(mailer mail.Mailer)
+	SendLoginCode(tenantID, phone string) error
+	UseSMSSender(sender SMSSender)
+	UseSessionService(sessionService session.ServiceInterface)
+	UseAuditService(auditService audit.ServiceInterface)
+	UseWebhookService(webhookService webhook.ServiceInterface)
 	AuthUser(username, thePassword string, tenantID string) (*models.OauthUser, error)
+	LoginUser(tenantID, username, password, clientIP string) (*models.OauthUser, error)
 	GetScope(requestedScope string) (string, error)
 	GetDefaultScope() string
 	ScopeExists(requestedScope string) bool
 	Login(client *models.OauthClient, user *models.OauthUser, scope string) (*models.OauthAccessToken, *models.OauthRefreshToken, error)
 	GrantAuthorizationCode(client *models.OauthClient, user *models.OauthUser, expiresIn int, redirectURI, scope string) (*models.OauthAuthorizationCode, error)
+	GetMissingConsent(client *models.OauthClient, user *models.OauthUser, scope string) (string, error)
+	GrantConsent(client *models.OauthClient, user *models.OauthUser, scope string) error
+	SetSkipConsent(client *models.OauthClient, skip bool) error
+	ListConsents(user *models.OauthUser) ([]*models.OauthConsent, error)
+	RevokeConsent(user *models.OauthUser, clientID string) error
+	GrantAuthorizationCodeForRequest(client *models.OauthClient, user *models.OauthUser, req *AuthorizationRequest, ssoSession *session.SSOSession) (*models.OauthAuthorizationCode, error)
+	ValidateAuthorizationRequest(client *models.OauthClient, req *AuthorizationRequest) error
+	PushAuthorizationRequest(client *models.OauthClient, req *AuthorizationRequest) (string, error)
+	GetPushedAuthorizationRequest(client *models.OauthClient, requestURI string) (*AuthorizationRequest, error)
+	ResolveAuthorizationRequest(values url.Values) (*models.OauthClient, *AuthorizationRequest, error)
+	AuthorizeSSOSession(client *models.OauthClient, req *AuthorizationRequest, ssoSession *session.SSOSession) (*models.OauthUser, error)
+	ValidateLogoutRequest(req *LogoutRequest) (*Logout, error)
+	EndSession(userID, sessionID string) ([]string, error)
+	FindTenantByID(tenantID string) (*models.Tenant, error)
+	GetTenantConfig(tenantID string) (*TenantConfig, error)
+	RegisterClient(initialAccessToken string, metadata *ClientMetadata) (*ClientRegistration, error)
+	AuthClientRegistration(clientID, registrationAccessToken string) (*models.OauthClient, error)
+	UpdateClientRegistration(client *models.OauthClient, metadata *ClientMetadata) (*ClientRegistration, error)
+	DeleteClientRegistration(client *models.OauthClient) error
 	GrantAccessToken(client *models.OauthClient, user *models.OauthUser, expiresIn int, scope string) (*models.OauthAccessToken, error)
 	GetOrCreateRefreshToken(client *models.OauthClient, user *models.OauthUser, expiresIn int, scope string) (*models.OauthRefreshToken, error)
 	GetValidRefreshToken(token string, client *models.OauthClient) (*models.OauthRefreshToken, error)
 	Authenticate(token string) (*models.OauthAccessToken, error)
+	AuthenticateRequest(r *http.Request) (*models.OauthAccessToken, error)
 	NewIntrospectResponseFromAccessToken(accessToken *models.OauthAccessToken) (*IntrospectResponse, error)
 	NewIntrospectResponseFromRefreshToken(refreshToken *models.OauthRefreshToken) (*IntrospectResponse, error)
 	ClearUserTokens(userSession *session.UserSession)
 	Close()
 	JWKs() (*jose.JSONWebKeySet, error)
+	TenantJWKs(tenantID string) (*jose.JSONWebKeySet, error)
+	RotateSigningKey(tenantID string) (string, error)
+	NewDiscoveryDocument(tenantID string) (*DiscoveryDocument, error)
 }
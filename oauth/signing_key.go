@@ -0,0 +1,219 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/audit"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/uuid"
+	"gopkg.in/square/go-jose.v2"
+)
+
+const (
+	jwkSID           = "oauth-jwk"
+	jwkPrivatePrefix = "private"
+	jwkPublicPrefix  = "public"
+	jwkKeySize       = 2048
+
+	// The first key pair of a tenant is generated under a lock, requests
+	// waiting for it poll until it is released
+	jwkLockPrefix  = "jwk_lock:"
+	jwkLockTimeout = 30 * time.Second
+	jwkLockPoll    = 100 * time.Millisecond
+)
+
+// getTenantJWKs returns the tenant's keys, newest first. Existing tenants
+// other than the default one get a new RSA key pair when they have none yet,
+// so tokens of one tenant never verify against another tenant's keys.
+func (s *Service) getTenantJWKs(tenantID string) ([]models.OauthJwk, error) {
+	oauthJwks, err := s.findTenantJWKs(tenantID)
+	if err != nil || len(oauthJwks) > 0 || tenantID == "" {
+		return oauthJwks, err
+	}
+
+	// Keys are never generated for unknown tenants
+	if _, err := s.FindTenantByID(tenantID); err != nil {
+		return nil, err
+	}
+	return s.generateFirstTenantJWKs(tenantID)
+}
+
+// findTenantJWKs returns the stored keys of the tenant, newest first
+func (s *Service) findTenantJWKs(tenantID string) ([]models.OauthJwk, error) {
+	var oauthJwks []models.OauthJwk
+	err := s.db.Where("sid = ? AND tenant_id = ?", jwkSID, tenantID).
+		Order("created_at desc").Find(&oauthJwks).Error
+	if err != nil {
+		return nil, err
+	}
+	return oauthJwks, nil
+}
+
+// generateFirstTenantJWKs generates the first key pair of a tenant. The first
+// requests of a tenant can arrive together on any server, so generation is
+// serialised with a lock in Redis and the other requests wait for its keys
+func (s *Service) generateFirstTenantJWKs(tenantID string) ([]models.OauthJwk, error) {
+	lockKey := jwkLockPrefix + tenantID
+	deadline := time.Now().Add(jwkLockTimeout)
+	for {
+		acquired, err := s.redis.SetNX(lockKey, 1, jwkLockTimeout).Result()
+		if err != nil {
+			return nil, err
+		}
+		if acquired {
+			break
+		}
+		if time.Now().After(deadline) {
+			return nil, ErrJwkPrivateKeyNotFound
+		}
+		time.Sleep(jwkLockPoll)
+		oauthJwks, err := s.findTenantJWKs(tenantID)
+		if err != nil || len(oauthJwks) > 0 {
+			return oauthJwks, err
+		}
+	}
+	defer s.redis.Del(lockKey)
+
+	// The keys may have been stored just before the lock was acquired
+	oauthJwks, err := s.findTenantJWKs(tenantID)
+	if err != nil || len(oauthJwks) > 0 {
+		return oauthJwks, err
+	}
+	return s.generateTenantJWKs(tenantID, "first_key")
+}
+
+// RotateSigningKey creates a new signing key for the tenant and returns its
+// key ID. Tokens are signed with the new key from now on, the previous keys
+// stay published so tokens they signed still verify
+func (s *Service) RotateSigningKey(tenantID string) (string, error) {
+	if tenantID != "" {
+		if _, err := s.FindTenantByID(tenantID); err != nil {
+			return "", err
+		}
+	}
+	oauthJwks, err := s.generateTenantJWKs(tenantID, "rotated")
+	if err != nil {
+		return "", err
+	}
+	return jwkKeyID(oauthJwks), nil
+}
+
+// generateTenantJWKs creates and stores a new RSA key pair for the tenant,
+// the reason goes into the key_rotated event
+func (s *Service) generateTenantJWKs(tenantID, reason string) ([]models.OauthJwk, error) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, jwkKeySize)
+	if err != nil {
+		return nil, err
+	}
+	privateJwk := jose.JSONWebKey{
+		Key:       rsaKey,
+		KeyID:     uuid.New(),
+		Algorithm: string(jose.RS256),
+		Use:       "sig",
+	}
+	publicJwk := privateJwk.Public()
+
+	oauthJwks := make([]models.OauthJwk, 0, 2)
+	for prefix, jwk := range map[string]jose.JSONWebKey{
+		jwkPrivatePrefix: privateJwk,
+		jwkPublicPrefix:  publicJwk,
+	} {
+		data, err := jwk.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		oauthJwks = append(oauthJwks, models.OauthJwk{
+			SID:       jwkSID,
+			KID:       prefix + "-" + jwk.KeyID,
+			KeyData:   string(data),
+			TenantID:  tenantID,
+			CreatedAt: time.Now().UTC(),
+		})
+	}
+
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	for i := range oauthJwks {
+		if err := tx.Create(&oauthJwks[i]).Error; err != nil {
+			tx.Rollback() // rollback the transaction
+			return nil, err
+		}
+	}
+	event := audit.NewEvent(audit.KeyRotated, nil)
+	event.TenantID = tenantID
+	event.Details["kid"] = privateJwk.KeyID
+	event.Details["reason"] = reason
+	if err := s.enqueueEvent(tx, event); err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+	s.recordEvent(event)
+
+	return oauthJwks, nil
+}
+
+// jwkKeyID returns the key ID of a generated key pair
+func jwkKeyID(oauthJwks []models.OauthJwk) string {
+	for _, oauthJwk := range oauthJwks {
+		if strings.HasPrefix(oauthJwk.KID, jwkPublicPrefix+"-") {
+			return strings.TrimPrefix(oauthJwk.KID, jwkPublicPrefix+"-")
+		}
+	}
+	return ""
+}
+
+// getJWKPrivateKey returns the tenant's newest signing key
+func (s *Service) getJWKPrivateKey(tenantID string) (*jose.JSONWebKey, error) {
+	oauthJwks, err := s.getTenantJWKs(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	for _, oauthJwk := range oauthJwks {
+		if strings.HasPrefix(oauthJwk.KID, jwkPrivatePrefix) {
+			var key = &jose.JSONWebKey{}
+			if err := key.UnmarshalJSON([]byte(oauthJwk.KeyData)); err != nil {
+				return nil, err
+			}
+			return key, nil
+		}
+	}
+	return nil, ErrJwkPrivateKeyNotFound
+}
+
+// TenantJWKs returns the public keys of a tenant, the empty tenant ID is the
+// default tenant
+func (s *Service) TenantJWKs(tenantID string) (*jose.JSONWebKeySet, error) {
+	oauthJwks, err := s.getTenantJWKs(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	jwks := new(jose.JSONWebKeySet)
+	for _, oauthJwk := range oauthJwks {
+		if strings.HasPrefix(oauthJwk.KID, jwkPublicPrefix) {
+			var key = jose.JSONWebKey{}
+			if err := key.UnmarshalJSON([]byte(oauthJwk.KeyData)); err != nil {
+				return nil, err
+			}
+			jwks.Keys = append(jwks.Keys, key)
+		}
+	}
+	if len(jwks.Keys) == 0 {
+		return nil, ErrJwkPublicKeyNotFound
+	}
+	return jwks, nil
+}
+
+// JWKs returns the public keys of the default tenant
+func (s *Service) JWKs() (*jose.JSONWebKeySet, error) {
+	return s.TenantJWKs("")
+}
@@ -0,0 +1,114 @@
+package oauth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestTenantJWKs() {
+	for _, tenantID := range []string{"tenant_a", "tenant_b"} {
+		err := suite.db.Create(&models.Tenant{
+			ID:     tenantID,
+			Name:   tenantID,
+			Status: models.TenantStatusActive,
+		}).Error
+		assert.NoError(suite.T(), err)
+	}
+	defer suite.db.Unscoped().Where("tenant_id <> ?", "").Delete(new(models.OauthJwk))
+
+	// Tenants get their own key pair on first use
+	jwksA, err := suite.service.TenantJWKs("tenant_a")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	assert.Len(suite.T(), jwksA.Keys, 1)
+	assert.True(suite.T(), jwksA.Keys[0].IsPublic())
+
+	// The same keys are returned afterwards
+	again, err := suite.service.TenantJWKs("tenant_a")
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), jwksA.Keys[0].KeyID, again.Keys[0].KeyID)
+	}
+
+	// Other tenants never share them
+	jwksB, err := suite.service.TenantJWKs("tenant_b")
+	if assert.NoError(suite.T(), err) {
+		assert.NotEqual(suite.T(), jwksA.Keys[0].KeyID, jwksB.Keys[0].KeyID)
+	}
+
+	// Unknown tenants get no keys
+	_, err = suite.service.TenantJWKs("tenant_unknown")
+	assert.Equal(suite.T(), oauth.ErrTenantNotFound, err)
+	_, err = suite.service.RotateSigningKey("tenant_unknown")
+	assert.Equal(suite.T(), oauth.ErrTenantNotFound, err)
+	var count int
+	suite.db.Model(new(models.OauthJwk)).Where("tenant_id = ?", "tenant_unknown").Count(&count)
+	assert.Equal(suite.T(), 0, count)
+}
+
+func (suite *OauthTestSuite) TestTenantJWKsConcurrentFirstUse() {
+	err := suite.db.Create(&models.Tenant{
+		ID:     "tenant_c",
+		Name:   "tenant_c",
+		Status: models.TenantStatusActive,
+	}).Error
+	assert.NoError(suite.T(), err)
+	defer suite.db.Unscoped().Where("tenant_id <> ?", "").Delete(new(models.OauthJwk))
+
+	// Concurrent first requests share a single key pair
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := suite.service.TenantJWKs("tenant_c")
+			assert.NoError(suite.T(), err)
+		}()
+	}
+	wg.Wait()
+	var count int
+	suite.db.Model(new(models.OauthJwk)).Where("tenant_id = ?", "tenant_c").Count(&count)
+	assert.Equal(suite.T(), 2, count)
+}
+
+func (suite *OauthTestSuite) TestNewDiscoveryDocument() {
+	err := suite.db.Create(&models.Tenant{
+		ID:     "tenant_a",
+		Name:   "Tenant A",
+		Status: models.TenantStatusActive,
+	}).Error
+	assert.NoError(suite.T(), err)
+
+	// Without an issuer URL there is nothing to build the endpoints from
+	_, err = suite.service.NewDiscoveryDocument("tenant_a")
+	assert.Equal(suite.T(), oauth.ErrIssuerNotConfigured, err)
+
+	suite.cnf.Oauth.Issuer = "https://auth.example.com"
+	defer func() { suite.cnf.Oauth.Issuer = "" }()
+
+	document, err := suite.service.NewDiscoveryDocument("tenant_a")
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), "https://auth.example.com/t/tenant_a", document.Issuer)
+		assert.Equal(suite.T(), "https://auth.example.com/t/tenant_a/.well-known/jwks.json", document.JWKSURI)
+		assert.Equal(suite.T(), "https://auth.example.com/t/tenant_a/v1/oauth/token", document.TokenEndpoint)
+	}
+
+	// Forwarded headers of the discovery request are not trusted
+	r, err := http.NewRequest("GET", "http://1.2.3.4/t/tenant_a/.well-known/openid-configuration", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.Header.Set("X-Forwarded-Proto", "http")
+	r.Host = "evil.example.com"
+	router := mux.NewRouter()
+	suite.service.RegisterWellKnownRoutes(router)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"token_endpoint":"https://auth.example.com/t/tenant_a/v1/oauth/token"`)
+	assert.NotContains(suite.T(), w.Body.String(), "evil.example.com")
+}
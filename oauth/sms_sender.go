@@ -0,0 +1,112 @@
+package oauth
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+)
+
+const (
+	// LogSMSSenderName names the sender writing messages to the log
+	LogSMSSenderName = "log"
+	// FileSMSSenderName names the sender appending messages to a file
+	FileSMSSenderName = "file"
+)
+
+var (
+	// ErrSMSSenderNotConfigured ...
+	ErrSMSSenderNotConfigured = errors.New("No SMS sender is configured")
+	// ErrSMSFileMissing ...
+	ErrSMSFileMissing = errors.New("The file SMS sender needs a file")
+	// ErrSMSLogSenderNotAllowed ...
+	ErrSMSLogSenderNotAllowed = errors.New("The log SMS sender is only allowed in development")
+)
+
+// SMSSender delivers text messages to phones, plug in a provider with
+// UseSMSSender
+type SMSSender interface {
+	SendSMS(phone, message string) error
+}
+
+// LogSMSSender writes messages to the log instead of sending them, it is
+// only allowed in development
+type LogSMSSender struct{}
+
+// SendSMS logs that a message was sent, the message itself holds a live
+// login code and is never logged
+func (s *LogSMSSender) SendSMS(phone, message string) error {
+	logger.Info("SMS sent", "phone", phone, "length", len(message))
+	return nil
+}
+
+// FileSMSSender appends messages to a file instead of sending them, one
+// line per message, for local development and tests
+type FileSMSSender struct {
+	Path string
+	mu   sync.Mutex
+}
+
+// NewFileSMSSender returns a new FileSMSSender instance
+func NewFileSMSSender(path string) *FileSMSSender {
+	return &FileSMSSender{Path: path}
+}
+
+// SendSMS appends the message to the file
+func (s *FileSMSSender) SendSMS(phone, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = fmt.Fprintf(file, "%s\t%s\t%s\n", time.Now().UTC().Format(time.RFC3339), phone, message)
+	return err
+}
+
+// ValidateSMSConfig returns an error for an unknown sender, the file sender
+// without a file and the log sender outside of development, which would
+// write live login codes to the log
+func ValidateSMSConfig(cnf *config.Config) error {
+	switch cnf.SMS.Sender {
+	case "":
+	case FileSMSSenderName:
+		if cnf.SMS.File == "" {
+			return ErrSMSFileMissing
+		}
+	case LogSMSSenderName:
+		if !cnf.IsDevelopment {
+			return ErrSMSLogSenderNotAllowed
+		}
+	default:
+		return fmt.Errorf("Unknown SMS sender %q", cnf.SMS.Sender)
+	}
+	return nil
+}
+
+// newSMSSender returns the configured sender, nil if there is none or the
+// config is invalid
+func newSMSSender(cnf *config.Config) SMSSender {
+	if err := ValidateSMSConfig(cnf); err != nil {
+		logger.Errorf("Invalid SMS config, login codes are disabled: %s", err)
+		return nil
+	}
+	switch cnf.SMS.Sender {
+	case FileSMSSenderName:
+		return NewFileSMSSender(cnf.SMS.File)
+	case LogSMSSenderName:
+		return new(LogSMSSender)
+	}
+	return nil
+}
+
+// UseSMSSender replaces the configured SMS sender
+func (s *Service) UseSMSSender(sender SMSSender) {
+	s.smsSender = sender
+}
@@ -0,0 +1,139 @@
+package oauth
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/session"
+)
+
+const (
+	// PromptNone asks for the request to fail rather than show any page
+	PromptNone = "none"
+	// PromptLogin asks for the user to log in again
+	PromptLogin = "login"
+	// PromptConsent asks for the consent screen even if the user consented
+	PromptConsent = "consent"
+	// PromptSelectAccount asks to let the user pick an account
+	PromptSelectAccount = "select_account"
+)
+
+var (
+	// ErrLoginRequired ...
+	ErrLoginRequired = errors.New("User login required")
+	// ErrInvalidPrompt ...
+	ErrInvalidPrompt = errors.New("Invalid prompt")
+	// ErrInvalidMaxAge ...
+	ErrInvalidMaxAge = errors.New("Invalid max age")
+)
+
+// AuthorizeSSOSession decides if an authorization request can be completed
+// for the user of an SSO session without showing any page. It returns the
+// user when it can, ErrLoginRequired when the user must log in and
+// ErrConsentRequired when the user must consent to the scope.
+//
+// Once the user logged in or consented, the authorization code is granted
+// with GrantAuthorizationCodeForRequest without asking again
+func (s *Service) AuthorizeSSOSession(client *models.OauthClient, req *AuthorizationRequest, ssoSession *session.SSOSession) (*models.OauthUser, error) {
+	if req.HasPrompt(PromptLogin) || req.HasPrompt(PromptSelectAccount) {
+		return nil, ErrLoginRequired
+	}
+
+	// SSO sessions are shared by the clients of a single tenant
+	if ssoSession == nil || ssoSession.TenantID != client.TenantID {
+		return nil, ErrLoginRequired
+	}
+	if req.MaxAge != "" {
+		maxAge, err := strconv.Atoi(req.MaxAge)
+		if err != nil || maxAge < 0 {
+			return nil, ErrInvalidMaxAge
+		}
+		if time.Since(ssoSession.AuthTime) > time.Duration(maxAge)*time.Second {
+			return nil, ErrLoginRequired
+		}
+	}
+
+	// The user must still be able to log in
+	user, err := s.FindUserByID(ssoSession.UserID)
+	if err != nil || user.Disabled || user.TenantID != client.TenantID {
+		return nil, ErrLoginRequired
+	}
+	tenant, err := s.GetTenantConfig(client.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	// Sessions started before MFA was required lack the second factor
+	if mfaRequired(tenant, user) && !hasAMR(ssoSession, "otp") {
+		return nil, ErrLoginRequired
+	}
+
+	if req.HasPrompt(PromptConsent) {
+		return nil, ErrConsentRequired
+	}
+	missing, err := s.GetMissingConsent(client, user, req.Scope)
+	if err != nil {
+		return nil, err
+	}
+	if missing != "" {
+		return nil, ErrConsentRequired
+	}
+
+	return user, nil
+}
+
+// HasPrompt returns true if the prompt parameter contains the value
+func (req *AuthorizationRequest) HasPrompt(value string) bool {
+	for _, prompt := range strings.Fields(req.Prompt) {
+		if prompt == value {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthorizationErrorCode returns the error code redirected to the client
+// when an authorization request with prompt=none cannot be completed
+func AuthorizationErrorCode(err error) string {
+	switch err {
+	case ErrLoginRequired:
+		return "login_required"
+	case ErrConsentRequired:
+		return "consent_required"
+	case ErrInvalidScope:
+		return "invalid_scope"
+	case ErrInvalidPrompt, ErrInvalidMaxAge, ErrInvalidResponseType, ErrInvalidRedirectURI:
+		return "invalid_request"
+	}
+	return "server_error"
+}
+
+// validatePrompt checks the prompt parameter only has known values, none
+// cannot be combined with others
+func validatePrompt(prompt string) error {
+	values := strings.Fields(prompt)
+	for _, value := range values {
+		switch value {
+		case PromptLogin, PromptConsent, PromptSelectAccount:
+		case PromptNone:
+			if len(values) > 1 {
+				return ErrInvalidPrompt
+			}
+		default:
+			return ErrInvalidPrompt
+		}
+	}
+	return nil
+}
+
+// hasAMR returns true if the SSO session user authenticated with the method
+func hasAMR(ssoSession *session.SSOSession, method string) bool {
+	for _, amr := range ssoSession.AMR {
+		if amr == method {
+			return true
+		}
+	}
+	return false
+}
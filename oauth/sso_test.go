@@ -0,0 +1,103 @@
+package oauth_test
+
+import (
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
+	"github.com/RichardKnop/go-oauth2-server/session"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestAuthorizeSSOSession() {
+	client := suite.clients[0]
+	user, err := suite.service.CreateUser(roles.User, "test@sso", "correct_horse_battery", "")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	assert.NoError(suite.T(), suite.service.GrantConsent(client, user, "read"))
+	ssoSession := &session.SSOSession{
+		UserID:   user.ID,
+		TenantID: user.TenantID,
+		AuthTime: time.Now().Add(-10 * time.Minute),
+		AMR:      []string{"pwd"},
+	}
+	newRequest := func(prompt, maxAge string) *oauth.AuthorizationRequest {
+		return &oauth.AuthorizationRequest{
+			ClientID:     client.Key,
+			ResponseType: "code",
+			RedirectURI:  "https://www.example.com",
+			Scope:        "read",
+			Prompt:       prompt,
+			MaxAge:       maxAge,
+		}
+	}
+
+	// Without an SSO session the user logs in
+	_, err = suite.service.AuthorizeSSOSession(client, newRequest("none", ""), nil)
+	assert.Equal(suite.T(), oauth.ErrLoginRequired, err)
+	assert.Equal(suite.T(), "login_required", oauth.AuthorizationErrorCode(err))
+
+	// The SSO session skips logging in
+	authorizedUser, err := suite.service.AuthorizeSSOSession(client, newRequest("none", ""), ssoSession)
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), user.ID, authorizedUser.ID)
+	}
+
+	// Unless the client asks for a login or a more recent one
+	_, err = suite.service.AuthorizeSSOSession(client, newRequest("login", ""), ssoSession)
+	assert.Equal(suite.T(), oauth.ErrLoginRequired, err)
+	_, err = suite.service.AuthorizeSSOSession(client, newRequest("", "300"), ssoSession)
+	assert.Equal(suite.T(), oauth.ErrLoginRequired, err)
+	_, err = suite.service.AuthorizeSSOSession(client, newRequest("", "3600"), ssoSession)
+	assert.NoError(suite.T(), err)
+
+	// Consent is asked for when requested or missing
+	_, err = suite.service.AuthorizeSSOSession(client, newRequest("consent", ""), ssoSession)
+	assert.Equal(suite.T(), oauth.ErrConsentRequired, err)
+	req := newRequest("none", "")
+	req.Scope = "read read_write"
+	_, err = suite.service.AuthorizeSSOSession(client, req, ssoSession)
+	assert.Equal(suite.T(), oauth.ErrConsentRequired, err)
+	assert.Equal(suite.T(), "consent_required", oauth.AuthorizationErrorCode(err))
+
+	// Sessions of other tenants are ignored
+	otherTenant := *ssoSession
+	otherTenant.TenantID = "other"
+	_, err = suite.service.AuthorizeSSOSession(client, newRequest("", ""), &otherTenant)
+	assert.Equal(suite.T(), oauth.ErrLoginRequired, err)
+
+	// The code remembers when and how the user authenticated
+	authorizationCode, err := suite.service.GrantAuthorizationCodeForRequest(client, user, newRequest("", ""), ssoSession)
+	if assert.NoError(suite.T(), err) && assert.NotNil(suite.T(), authorizationCode.AuthTime) {
+		assert.Equal(suite.T(), ssoSession.AuthTime.Unix(), authorizationCode.AuthTime.Unix())
+		assert.Equal(suite.T(), "pwd", authorizationCode.AMR)
+	}
+}
+
+func (suite *OauthTestSuite) TestValidateAuthorizationRequestPrompt() {
+	client := suite.clients[0]
+	for prompt, expected := range map[string]error{
+		"":               nil,
+		"none":           nil,
+		"login consent":  nil,
+		"none login":     oauth.ErrInvalidPrompt,
+		"unknown_prompt": oauth.ErrInvalidPrompt,
+	} {
+		req := &oauth.AuthorizationRequest{
+			ResponseType: "code",
+			RedirectURI:  "https://www.example.com",
+			Scope:        "read",
+			Prompt:       prompt,
+		}
+		assert.Equal(suite.T(), expected, suite.service.ValidateAuthorizationRequest(client, req), prompt)
+	}
+
+	req := &oauth.AuthorizationRequest{
+		ResponseType: "code",
+		RedirectURI:  "https://www.example.com",
+		Scope:        "read",
+		MaxAge:       "-1",
+	}
+	assert.Equal(suite.T(), oauth.ErrInvalidMaxAge, suite.service.ValidateAuthorizationRequest(client, req))
+}
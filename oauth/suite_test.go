@@ -9,12 +9,16 @@ import (
 	"github.com/RichardKnop/go-oauth2-server/models"
 	"github.com/RichardKnop/go-oauth2-server/oauth"
 	"github.com/RichardKnop/go-oauth2-server/test-util"
+	"github.com/alicebob/miniredis"
+	"github.com/go-redis/redis/v7"
 	"github.com/gorilla/mux"
 	"github.com/jinzhu/gorm"
 	"github.com/stretchr/testify/suite"
 )
 
 var (
+	logger = log.New("oauth_test")
+
 	testDbUser = "go_oauth2_server"
 	testDbName = "go_oauth2_server_oauth_test"
 
@@ -32,7 +36,7 @@ var (
 
 func init() {
 	if err := os.Chdir("../"); err != nil {
-		log.ERROR.Fatal(err)
+		logger.Fatalf("%s", err)
 	}
 }
 
@@ -41,6 +45,7 @@ type OauthTestSuite struct {
 	suite.Suite
 	cnf     *config.Config
 	db      *gorm.DB
+	redis   *miniredis.Miniredis
 	service *oauth.Service
 	clients []*models.OauthClient
 	users   []*models.OauthUser
@@ -62,24 +67,37 @@ func (suite *OauthTestSuite) SetupSuite() {
 		testFixtures,
 	)
 	if err != nil {
-		log.ERROR.Fatal(err)
+		logger.Fatalf("%s", err)
 	}
 	suite.db = db
 
 	// Fetch test client
 	suite.clients = make([]*models.OauthClient, 0)
 	if err := suite.db.Order("created_at").Find(&suite.clients).Error; err != nil {
-		log.ERROR.Fatal(err)
+		logger.Fatalf("%s", err)
 	}
 
 	// Fetch test users
 	suite.users = make([]*models.OauthUser, 0)
 	if err := suite.db.Order("created_at").Find(&suite.users).Error; err != nil {
-		log.ERROR.Fatal(err)
+		logger.Fatalf("%s", err)
+	}
+
+	// Login codes, lockouts and other short lived state is kept in Redis
+	suite.redis, err = miniredis.Run()
+	if err != nil {
+		logger.Fatalf("%s", err)
 	}
 
 	// Initialise the service
-	suite.service = oauth.NewService(suite.cnf, suite.db)
+	suite.service = oauth.NewService(suite.cnf, suite.db, redis.NewClient(&redis.Options{
+		Addr: suite.redis.Addr(),
+	}))
+
+	// The default tenant's signing key is provisioned up front
+	if _, err := suite.service.RotateSigningKey(""); err != nil {
+		logger.Fatalf("%s", err)
+	}
 
 	// Register routes
 	suite.router = mux.NewRouter()
@@ -89,7 +107,7 @@ func (suite *OauthTestSuite) SetupSuite() {
 // The TearDownSuite method will be run by testify once, at the very
 // end of the testing suite, after all tests have been run.
 func (suite *OauthTestSuite) TearDownSuite() {
-	//
+	suite.redis.Close()
 }
 
 // The SetupTest method will be run before every test in the suite.
@@ -106,6 +124,8 @@ func (suite *OauthTestSuite) TearDownTest() {
 	suite.db.Unscoped().Delete(new(models.OauthAccessToken))
 	suite.db.Unscoped().Not("id", []string{"1", "2"}).Delete(new(models.OauthUser))
 	suite.db.Unscoped().Not("id", []string{"1", "2", "3"}).Delete(new(models.OauthClient))
+	suite.db.Unscoped().Delete(new(models.Tenant))
+	suite.redis.FlushAll()
 }
 
 // TestOauthTestSuite ...
@@ -0,0 +1,249 @@
+package oauth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth/passwordpolicy"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/gorilla/mux"
+)
+
+var (
+	// ErrTenantNotFound ...
+	ErrTenantNotFound = errors.New("Tenant not found")
+	// ErrTenantSuspended ...
+	ErrTenantSuspended = errors.New("Tenant suspended")
+	// ErrTenantMismatch ...
+	ErrTenantMismatch = errors.New("Tenant does not match the client")
+	// ErrGrantTypeNotAllowedForTenant ...
+	ErrGrantTypeNotAllowedForTenant = errors.New("Grant type not allowed for the tenant")
+)
+
+// TenantConfig is the oauth configuration in effect for a tenant, the global
+// config with the tenant's overrides applied
+type TenantConfig struct {
+	TenantID             string
+	AccessTokenLifetime  int
+	RefreshTokenLifetime int
+	AuthCodeLifetime     int
+	Issuer               string
+	// GrantTypes allowed for the tenant, empty allows every grant type
+	GrantTypes       []string
+	PasswordPolicy   *passwordpolicy.Policy
+	OpenRegistration bool
+	// RequireMFA makes every user enroll a second factor
+	RequireMFA bool
+	Branding   *Branding
+}
+
+// Branding customizes the web pages of a tenant
+type Branding struct {
+	Name            string
+	LogoURL         string
+	PrimaryColor    string
+	BackgroundColor string
+	// TemplatesDir holds templates replacing the default pages, empty uses
+	// the default pages only
+	TemplatesDir    string
+	DefaultLanguage string
+}
+
+// FindTenantByID looks up a tenant by ID
+func (s *Service) FindTenantByID(tenantID string) (*models.Tenant, error) {
+	tenant := new(models.Tenant)
+	notFound := s.db.Where("id = ?", tenantID).First(tenant).RecordNotFound()
+
+	// Not found
+	if notFound {
+		return nil, ErrTenantNotFound
+	}
+
+	return tenant, nil
+}
+
+// GetTenantConfig returns the config of an active tenant, the empty tenant ID
+// is the default tenant which uses the global config
+func (s *Service) GetTenantConfig(tenantID string) (*TenantConfig, error) {
+	tenantConfig := &TenantConfig{
+		TenantID:             tenantID,
+		AccessTokenLifetime:  s.cnf.Oauth.AccessTokenLifetime,
+		RefreshTokenLifetime: s.cnf.Oauth.RefreshTokenLifetime,
+		AuthCodeLifetime:     s.cnf.Oauth.AuthCodeLifetime,
+		Issuer:               s.cnf.Oauth.Issuer,
+		PasswordPolicy: &passwordpolicy.Policy{
+			MinLength:           s.cnf.Oauth.MinPasswordLength,
+			MinCharacterClasses: s.cnf.Oauth.PasswordMinClasses,
+			DisallowUserInfo:    s.cnf.Oauth.PasswordDisallowUserInfo,
+			HistorySize:         s.cnf.Oauth.PasswordHistory,
+			MaxAge:              days(s.cnf.Oauth.PasswordMaxAge),
+		},
+		RequireMFA: s.cnf.MFA.Required,
+		Branding: &Branding{
+			LogoURL:         s.cnf.Web.LogoURL,
+			PrimaryColor:    s.cnf.Web.PrimaryColor,
+			BackgroundColor: s.cnf.Web.BackgroundColor,
+			DefaultLanguage: s.cnf.Web.DefaultLanguage,
+		},
+	}
+	useDenylist := s.cnf.Oauth.PasswordDenylist
+	if tenantID == "" {
+		if useDenylist {
+			tenantConfig.PasswordPolicy.Denylist = s.passwordDenylist
+		}
+		return tenantConfig, nil
+	}
+
+	tenant, err := s.FindTenantByID(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if !tenant.IsActive() {
+		return nil, ErrTenantSuspended
+	}
+
+	if tenant.AccessTokenLifetime.Valid {
+		tenantConfig.AccessTokenLifetime = int(tenant.AccessTokenLifetime.Int64)
+	}
+	if tenant.RefreshTokenLifetime.Valid {
+		tenantConfig.RefreshTokenLifetime = int(tenant.RefreshTokenLifetime.Int64)
+	}
+	if tenant.AuthCodeLifetime.Valid {
+		tenantConfig.AuthCodeLifetime = int(tenant.AuthCodeLifetime.Int64)
+	}
+	tenantConfig.Issuer = tenantIssuer(s.cnf.Oauth.Issuer, tenantID)
+	if tenant.Issuer.Valid {
+		tenantConfig.Issuer = tenant.Issuer.String
+	}
+	if tenant.GrantTypes != "" {
+		tenantConfig.GrantTypes = strings.Split(tenant.GrantTypes, " ")
+	}
+	policy := tenantConfig.PasswordPolicy
+	if tenant.MinPasswordLength.Valid {
+		policy.MinLength = int(tenant.MinPasswordLength.Int64)
+	}
+	if tenant.PasswordMinClasses.Valid {
+		policy.MinCharacterClasses = int(tenant.PasswordMinClasses.Int64)
+	}
+	if tenant.PasswordDisallowUserInfo.Valid {
+		policy.DisallowUserInfo = tenant.PasswordDisallowUserInfo.Bool
+	}
+	if tenant.PasswordHistory.Valid {
+		policy.HistorySize = int(tenant.PasswordHistory.Int64)
+	}
+	if tenant.PasswordMaxAge.Valid {
+		policy.MaxAge = days(int(tenant.PasswordMaxAge.Int64))
+	}
+	if tenant.PasswordDenylist.Valid {
+		useDenylist = tenant.PasswordDenylist.Bool
+	}
+	if useDenylist {
+		policy.Denylist = s.passwordDenylist
+	}
+	tenantConfig.OpenRegistration = tenant.OpenRegistration
+	if tenant.RequireMFA.Valid {
+		tenantConfig.RequireMFA = tenant.RequireMFA.Bool
+	}
+	branding := tenantConfig.Branding
+	branding.Name = tenant.Name
+	if tenant.LogoURL.Valid {
+		branding.LogoURL = tenant.LogoURL.String
+	}
+	if tenant.PrimaryColor.Valid {
+		branding.PrimaryColor = tenant.PrimaryColor.String
+	}
+	if tenant.BackgroundColor.Valid {
+		branding.BackgroundColor = tenant.BackgroundColor.String
+	}
+	if tenant.TemplatesDir.Valid {
+		branding.TemplatesDir = tenant.TemplatesDir.String
+	}
+	if tenant.DefaultLanguage.Valid {
+		branding.DefaultLanguage = tenant.DefaultLanguage.String
+	}
+
+	return tenantConfig, nil
+}
+
+// ValidatePasswordDenylist returns an error if the configured denylist file
+// cannot be loaded, the bundled list is always available
+func ValidatePasswordDenylist(cnf *config.Config) error {
+	if cnf.Oauth.PasswordDenylistFile == "" {
+		return nil
+	}
+	_, err := passwordpolicy.LoadDenylist(cnf.Oauth.PasswordDenylistFile)
+	return err
+}
+
+// newPasswordDenylist loads the configured denylist, the bundled list is
+// used if there is none or it cannot be loaded so common passwords are
+// always rejected
+func newPasswordDenylist(cnf *config.Config) *passwordpolicy.Denylist {
+	if cnf.Oauth.PasswordDenylistFile == "" {
+		return passwordpolicy.DefaultDenylist()
+	}
+	denylist, err := passwordpolicy.LoadDenylist(cnf.Oauth.PasswordDenylistFile)
+	if err != nil {
+		logger.Errorf("Loading password denylist failed, using the bundled list: %s", err)
+		return passwordpolicy.DefaultDenylist()
+	}
+	return denylist
+}
+
+// AllowsGrantType returns true if the grant type is allowed for the tenant
+func (c *TenantConfig) AllowsGrantType(grantType string) bool {
+	return len(c.GrantTypes) == 0 || util.StringInSlice(grantType, c.GrantTypes)
+}
+
+// getClientTenantConfig returns the config of the client's tenant, a tenant
+// sent with the request must be the client's tenant
+func (s *Service) getClientTenantConfig(client *models.OauthClient, requestedTenantID string) (*TenantConfig, error) {
+	if requestedTenantID != "" && requestedTenantID != client.TenantID {
+		return nil, ErrTenantMismatch
+	}
+	return s.GetTenantConfig(client.TenantID)
+}
+
+// checkUserTenant makes sure a user belongs to the client's tenant
+func checkUserTenant(client *models.OauthClient, user *models.OauthUser) error {
+	if user != nil && user.TenantID != client.TenantID {
+		return ErrTenantMismatch
+	}
+	return nil
+}
+
+// days returns a number of days as a duration
+func days(n int) time.Duration {
+	return time.Duration(n) * 24 * time.Hour
+}
+
+// tenantIssuer returns the default issuer of a tenant, the tenant path under
+// the global issuer
+func tenantIssuer(issuer, tenantID string) string {
+	return strings.TrimRight(issuer, "/") + TenantPathPrefix + tenantID
+}
+
+// requestTenantID returns the tenant of a request made on a tenant's issuer
+// path, a tenant ID sent with the request must match it
+func requestTenantID(r *http.Request, requestedTenantID string) (string, error) {
+	pathTenantID, ok := mux.Vars(r)["tenant"]
+	if !ok {
+		return requestedTenantID, nil
+	}
+	if requestedTenantID != "" && requestedTenantID != pathTenantID {
+		return "", ErrTenantMismatch
+	}
+	return pathTenantID, nil
+}
+
+// checkPathTenant makes sure a client calls its own tenant's issuer path
+func checkPathTenant(r *http.Request, client *models.OauthClient) error {
+	if pathTenantID, ok := mux.Vars(r)["tenant"]; ok && pathTenantID != client.TenantID {
+		return ErrTenantMismatch
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package oauth_test
+
+import (
+	"database/sql"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestGetTenantConfig() {
+	// The default tenant uses the global config
+	tenantConfig, err := suite.service.GetTenantConfig("")
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), suite.cnf.Oauth.AccessTokenLifetime, tenantConfig.AccessTokenLifetime)
+		assert.True(suite.T(), tenantConfig.AllowsGrantType("password"))
+		assert.Equal(suite.T(), suite.cnf.Web.PrimaryColor, tenantConfig.Branding.PrimaryColor)
+	}
+
+	// Unknown tenants are rejected
+	_, err = suite.service.GetTenantConfig("bogus")
+	assert.Equal(suite.T(), oauth.ErrTenantNotFound, err)
+
+	// Tenant overrides are applied
+	err = suite.db.Create(&models.Tenant{
+		ID:                  "test_tenant",
+		Name:                "Test Tenant",
+		Status:              models.TenantStatusActive,
+		AccessTokenLifetime: sql.NullInt64{Int64: 60, Valid: true},
+		Issuer:              sql.NullString{String: "https://test-tenant.example.com", Valid: true},
+		GrantTypes:          "client_credentials",
+		LogoURL:             sql.NullString{String: "https://test-tenant.example.com/logo.png", Valid: true},
+		DefaultLanguage:     sql.NullString{String: "zh", Valid: true},
+	}).Error
+	assert.NoError(suite.T(), err)
+	tenantConfig, err = suite.service.GetTenantConfig("test_tenant")
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), 60, tenantConfig.AccessTokenLifetime)
+		assert.Equal(suite.T(), suite.cnf.Oauth.RefreshTokenLifetime, tenantConfig.RefreshTokenLifetime)
+		assert.Equal(suite.T(), "https://test-tenant.example.com", tenantConfig.Issuer)
+		assert.True(suite.T(), tenantConfig.AllowsGrantType("client_credentials"))
+		assert.False(suite.T(), tenantConfig.AllowsGrantType("password"))
+		assert.Equal(suite.T(), "Test Tenant", tenantConfig.Branding.Name)
+		assert.Equal(suite.T(), "https://test-tenant.example.com/logo.png", tenantConfig.Branding.LogoURL)
+		assert.Equal(suite.T(), suite.cnf.Web.PrimaryColor, tenantConfig.Branding.PrimaryColor)
+		assert.Equal(suite.T(), "zh", tenantConfig.Branding.DefaultLanguage)
+	}
+
+	// Suspended tenants are rejected
+	err = suite.db.Model(new(models.Tenant)).Where("id = ?", "test_tenant").
+		Update("status", models.TenantStatusSuspended).Error
+	assert.NoError(suite.T(), err)
+	_, err = suite.service.GetTenantConfig("test_tenant")
+	assert.Equal(suite.T(), oauth.ErrTenantSuspended, err)
+}
+
+func (suite *OauthTestSuite) TestGrantAccessTokenAcrossTenants() {
+	client, err := suite.service.CreateClient(
+		"test_tenant_client", // client ID
+		"test_secret",        // secret
+		"",                   // redirect URI
+		"other_tenant",
+	)
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+
+	// Users cannot get tokens for clients of another tenant
+	_, err = suite.service.GrantAccessToken(client, suite.users[0], 3600, "read")
+	assert.Equal(suite.T(), oauth.ErrTenantMismatch, err)
+}
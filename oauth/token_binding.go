@@ -0,0 +1,165 @@
+package oauth
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth/dpop"
+	"github.com/RichardKnop/go-oauth2-server/oauth/jwt"
+	"github.com/RichardKnop/go-oauth2-server/oauth/mtls"
+	"github.com/RichardKnop/go-oauth2-server/tracing"
+	"github.com/RichardKnop/go-oauth2-server/util"
+)
+
+const (
+	dpopJTIPrefix = "dpop_jti:"
+)
+
+var (
+	// ErrTokenBindingMismatch ...
+	ErrTokenBindingMismatch = errors.New("Access token is bound to a different key")
+	// ErrRefreshTokenBindingMismatch ...
+	ErrRefreshTokenBindingMismatch = errors.New("Refresh token is bound to a different key")
+	// ErrInvalidDPoPProof ...
+	ErrInvalidDPoPProof = errors.New("Invalid DPoP proof")
+	// ErrDPoPProofReplayed ...
+	ErrDPoPProofReplayed = errors.New("DPoP proof already used")
+)
+
+// TokenBinding holds the proof-of-possession keys an access token is bound to
+type TokenBinding struct {
+	// CertThumbprint is the x5t#S256 of the client certificate (RFC 8705)
+	CertThumbprint string
+	// JKT is the JWK thumbprint of the DPoP proof key (RFC 9449)
+	JKT string
+}
+
+// newTokenBinding returns the binding for access tokens issued to the client
+// over this request, nil if tokens should be plain bearer tokens
+func (s *Service) newTokenBinding(r *http.Request, client *models.OauthClient) (*TokenBinding, error) {
+	binding := new(TokenBinding)
+
+	chain := peerCertificates(r)
+	if len(chain) > 0 && client.TLSClientCertificateBoundAccessTokens {
+		binding.CertThumbprint = mtls.Thumbprint(chain[0])
+	}
+
+	// A DPoP proof on the token endpoint binds the issued tokens to its key
+	if r.Header.Get(dpop.HeaderName) != "" {
+		proof, err := s.verifyDPoPProof(r, "")
+		if err != nil {
+			return nil, err
+		}
+		binding.JKT = proof.JKT
+	}
+
+	if binding.CertThumbprint == "" && binding.JKT == "" {
+		return nil, nil
+	}
+	return binding, nil
+}
+
+// apply stores the binding on a new access token
+func (b *TokenBinding) apply(accessToken *models.OauthAccessToken) {
+	if b == nil {
+		return
+	}
+	accessToken.CertThumbprint = util.StringOrNull(b.CertThumbprint)
+	accessToken.JKT = util.StringOrNull(b.JKT)
+}
+
+// newConfirmation returns the cnf claim of an access token, nil if unbound
+func newConfirmation(accessToken *models.OauthAccessToken) *jwt.Confirmation {
+	if !accessToken.CertThumbprint.Valid && !accessToken.JKT.Valid {
+		return nil
+	}
+	return &jwt.Confirmation{
+		X5tS256: accessToken.CertThumbprint.String,
+		JKT:     accessToken.JKT.String,
+	}
+}
+
+// verifyDPoPProof verifies the DPoP proof sent with the request and makes
+// sure its jti has not been seen before within the proof window
+func (s *Service) verifyDPoPProof(r *http.Request, accessToken string) (*dpop.Proof, error) {
+	window := time.Duration(s.cnf.Oauth.DPoPProofWindow) * time.Second
+	proof, err := dpop.VerifyProof(r.Header.Get(dpop.HeaderName), &dpop.Expectations{
+		Method:      r.Method,
+		URI:         util.GetRequestURI(r),
+		AccessToken: accessToken,
+		Window:      window,
+	})
+	if err != nil {
+		s.logger.Infof("DPoP proof rejected: %s", err)
+		return nil, ErrInvalidDPoPProof
+	}
+
+	// Proofs are accepted for window either side of now, remember them as long
+	fresh, err := s.redis.SetNX(dpopJTIPrefix+proof.JKT+":"+proof.ID, 1, 2*window).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !fresh {
+		return nil, ErrDPoPProofReplayed
+	}
+
+	return proof, nil
+}
+
+// AuthenticateRequest authenticates the access token of a resource request
+// and checks it is presented with the keys it is bound to: DPoP bound tokens
+// need the DPoP scheme and a valid proof, certificate-bound tokens need the
+// same client certificate on the connection. Failures are counted and the
+// lookup is traced as a child of the request's span
+func (s *Service) AuthenticateRequest(r *http.Request) (*models.OauthAccessToken, error) {
+	ctx, span := tracing.Start(r.Context(), "oauth.authenticate")
+	accessToken, err := s.withContext(ctx).authenticateRequest(r)
+	tracing.End(span, err)
+	if err != nil {
+		observeAuthFailure(err)
+	}
+	return accessToken, err
+}
+
+func (s *Service) authenticateRequest(r *http.Request) (*models.OauthAccessToken, error) {
+	token, err := util.ParseBearerToken(r)
+	isDPoP := false
+	if err != nil {
+		token, err = util.ParseDPoPToken(r)
+		if err != nil {
+			return nil, ErrTokenMissing
+		}
+		isDPoP = true
+	}
+
+	accessToken, err := s.authenticate(string(token))
+	if err != nil {
+		return nil, err
+	}
+
+	// The DPoP scheme is only valid for DPoP bound tokens and vice versa
+	if isDPoP != accessToken.JKT.Valid {
+		return nil, ErrTokenBindingMismatch
+	}
+	if accessToken.JKT.Valid {
+		proof, err := s.verifyDPoPProof(r, string(token))
+		if err != nil {
+			return nil, err
+		}
+		if proof.JKT != accessToken.JKT.String {
+			return nil, ErrTokenBindingMismatch
+		}
+	}
+
+	// Certificate-bound tokens must arrive over a connection with the same certificate
+	if accessToken.CertThumbprint.Valid {
+		chain := peerCertificates(r)
+		if len(chain) == 0 || mtls.Thumbprint(chain[0]) != accessToken.CertThumbprint.String {
+			return nil, ErrTokenBindingMismatch
+		}
+	}
+
+	return accessToken, nil
+}
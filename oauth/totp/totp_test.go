@@ -0,0 +1,88 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rfcSecret is the SHA1 test secret of RFC 6238 appendix B
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(
+	[]byte("12345678901234567890"),
+)
+
+func TestCode(t *testing.T) {
+	// RFC 6238 appendix B, truncated to 6 digits
+	testCases := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tc := range testCases {
+		code, err := Code(rfcSecret, time.Unix(tc.unix, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, tc.code, code, "time %d", tc.unix)
+	}
+
+	_, err := Code("not base32!", time.Now())
+	assert.Equal(t, ErrInvalidSecret, err)
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	code, err := Code(rfcSecret, now)
+	assert.NoError(t, err)
+
+	matched, ok := Validate(rfcSecret, code, now)
+	assert.True(t, ok)
+	assert.Equal(t, now.Unix()/Period, matched)
+
+	// Codes of the previous and next time steps are accepted
+	_, ok = Validate(rfcSecret, code, now.Add(Period*time.Second))
+	assert.True(t, ok)
+	_, ok = Validate(rfcSecret, code, now.Add(-Period*time.Second))
+	assert.True(t, ok)
+
+	// Codes further away are not
+	_, ok = Validate(rfcSecret, code, now.Add(2*Period*time.Second))
+	assert.False(t, ok)
+
+	_, ok = Validate(rfcSecret, "12345", now)
+	assert.False(t, ok)
+	_, ok = Validate("not base32!", code, now)
+	assert.False(t, ok)
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	assert.NoError(t, err)
+	assert.Len(t, secret, 32)
+
+	other, err := GenerateSecret()
+	assert.NoError(t, err)
+	assert.NotEqual(t, secret, other)
+
+	// Lower case secrets with spaces, as typed by users, are accepted
+	code, err := Code(secret, time.Now())
+	assert.NoError(t, err)
+	_, ok := Validate(strings.ToLower(secret[:4]+" "+secret[4:]), code, time.Now())
+	assert.True(t, ok)
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("Example Co", "john@example.com", "JBSWY3DPEHPK3PXP")
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Example%20Co:john@example.com?"))
+	assert.Contains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+	assert.Contains(t, uri, "issuer=Example+Co")
+	assert.Contains(t, uri, "digits=6")
+	assert.Contains(t, uri, "period=30")
+}
@@ -0,0 +1,20 @@
+package oauth
+
+import (
+	"context"
+
+	"github.com/RichardKnop/go-oauth2-server/tracing"
+)
+
+// withContext returns a copy of the service whose database queries and Redis
+// commands are traced as children of the span in ctx, and whose log lines
+// carry the request ID of ctx
+func (s *Service) withContext(ctx context.Context) *Service {
+	traced := *s
+	traced.logger = s.logger.WithContext(ctx)
+	traced.db = tracing.WithContext(s.db, ctx)
+	if s.redis != nil {
+		traced.redis = s.redis.WithContext(ctx)
+	}
+	return &traced
+}
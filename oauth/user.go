@@ -2,20 +2,11 @@ package oauth
 
 import (
 	"errors"
-	"fmt"
+
 	"github.com/RichardKnop/go-oauth2-server/models"
-	pass "github.com/RichardKnop/go-oauth2-server/util/password"
 )
 
 var (
-	// MinPasswordLength defines minimum password length
-	MinPasswordLength = 6
-
-	// ErrPasswordTooShort ...
-	ErrPasswordTooShort = fmt.Errorf(
-		"Password must be at least %d characters long",
-		MinPasswordLength,
-	)
 	// ErrUserNotFound ...
 	ErrUserNotFound = errors.New("User not found")
 	// ErrInvalidUserPassword ...
@@ -26,6 +17,8 @@ var (
 	ErrUserPasswordNotSet = errors.New("User password not set")
 	// ErrUsernameTaken ...
 	ErrUsernameTaken = errors.New("Username taken")
+	// ErrPasswordExpired ...
+	ErrPasswordExpired = errors.New("Password expired, it must be changed")
 )
 
 // UserExists returns true if user exists
@@ -34,11 +27,10 @@ func (s *Service) UserExists(username string, tenantID string) bool {
 	return err == nil
 }
 
-// FindUserByUsername looks up a user by username
+// FindUserByUsername looks up a user by username, which is the account
 func (s *Service) FindUserByUsername(username string) (*models.OauthUser, error) {
-	// Usernames are case insensitive
 	user := new(models.OauthUser)
-	notFound := s.db.Where("username = LOWER(?)", username).
+	notFound := s.db.Where("account = ?", username).
 		First(user).RecordNotFound()
 
 	// Not found
@@ -49,19 +41,10 @@ func (s *Service) FindUserByUsername(username string) (*models.OauthUser, error)
 	return user, nil
 }
 
-// FindUserByUsernameAndTenantID looks up a user by username and tenantId
+// FindUserByUsernameAndTenantID looks up a user by username, which is the
+// account, and tenantId
 func (s *Service) FindUserByUsernameAndTenantID(username string, tenantID string) (*models.OauthUser, error) {
-	// Username are case insensitive
-	user := new(models.OauthUser)
-	notFound := s.db.Where("username = LOWER(?) AND tenant_id = ?", username, tenantID).
-		First(user).RecordNotFound()
-
-	// Not found
-	if notFound {
-		return nil, ErrUserNotFound
-	}
-
-	return user, nil
+	return s.FindUserByAccountAndTenantID(username, tenantID)
 }
 
 // FindUserByUsernameAndTenantID looks up a user by username and tenantId
@@ -94,8 +77,56 @@ func (s *Service) FindUserByPhoneAndTenantID(phone string, tenantID string) (*mo
 	return user, nil
 }
 
-// AuthUser authenticates user
+// AuthUser authenticates user, users whose password exceeded the tenant's
+// maximum password age get ErrPasswordExpired
 func (s *Service) AuthUser(username, password string, tenantID string) (*models.OauthUser, error) {
+	user, err := s.authUser(username, password, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Expired passwords must be changed before logging in
+	tenant, err := s.GetTenantConfig(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if user.PasswordChangedAt != nil && tenant.PasswordPolicy.Expired(*user.PasswordChangedAt) {
+		return nil, ErrPasswordExpired
+	}
+
+	return user, nil
+}
+
+// LoginUser authenticates a user logging in through the web pages, locked
+// out accounts and client IPs are refused like on the password grant. Wrong
+// credentials give ErrInvalidUsernameOrPassword.
+func (s *Service) LoginUser(tenantID, username, password, clientIP string) (*models.OauthUser, error) {
+	// Refuse locked out accounts and client IPs before hashing the password
+	if err := s.checkLoginLockout(tenantID, username, clientIP); err != nil {
+		return nil, err
+	}
+
+	user, err := s.AuthUser(username, password, tenantID)
+	if err == ErrPasswordExpired {
+		// The password was right, the user must change it first
+		return nil, err
+	}
+	if err != nil {
+		if err := s.recordLoginFailure(tenantID, username, clientIP); err != nil {
+			s.logger.Errorf("Recording failed login failed: %s", err)
+		}
+		// For security reasons, return a general error message
+		return nil, ErrInvalidUsernameOrPassword
+	}
+	if err := s.clearLoginFailures(tenantID, username); err != nil {
+		s.logger.Errorf("Clearing failed logins failed: %s", err)
+	}
+
+	return user, nil
+}
+
+// authUser verifies the password of a user
+func (s *Service) authUser(username, password string, tenantID string) (*models.OauthUser, error) {
 	// Fetch the user
 	user, err := s.FindUserByAccountAndTenantID(username, tenantID)
 	if err != nil {
@@ -105,21 +136,29 @@ func (s *Service) AuthUser(username, password string, tenantID string) (*models.
 		}
 	}
 
+	// Disabled users cannot log in
+	if user.Disabled {
+		return nil, ErrUserDisabled
+	}
+
 	// Check that the password is set
 	if !user.Password.Valid {
 		return nil, ErrUserPasswordNotSet
 	}
 
 	// Verify the password
-	if pass.VerifyPassword2(
-		user.Password.String,
-		password,
-		user.ID,
-		s.cnf.Oauth.PasswordSecret,
-		s.cnf.Oauth.PasswordSalt,
-	) == false {
+	valid, rehash := s.passwords.Verify(user.Password.String, password, user.ID)
+	if !valid {
 		return nil, ErrInvalidUserPassword
 	}
 
+	// Upgrade hashes of legacy schemes or outdated parameters, the user is
+	// logged in even if that fails
+	if rehash {
+		if err := s.rehashUserPassword(user, password); err != nil {
+			s.logger.Errorf("Rehashing password of user %s failed: %s", user.ID, err)
+		}
+	}
+
 	return user, nil
 }
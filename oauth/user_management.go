@@ -0,0 +1,457 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/audit"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth/passwordpolicy"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/RichardKnop/uuid"
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	// ErrUserDisabled ...
+	ErrUserDisabled = errors.New("User disabled")
+	// ErrPhoneTaken ...
+	ErrPhoneTaken = errors.New("Phone taken")
+	// ErrInvalidRole ...
+	ErrInvalidRole = errors.New("Invalid role")
+)
+
+// FindUserByID looks up a user by ID
+func (s *Service) FindUserByID(userID string) (*models.OauthUser, error) {
+	user := new(models.OauthUser)
+	notFound := s.db.Where("id = ?", userID).First(user).RecordNotFound()
+
+	// Not found
+	if notFound {
+		return nil, ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+// ListUsers returns a page of a tenant's users and the total count
+func (s *Service) ListUsers(tenantID string, offset, limit int) ([]*models.OauthUser, int, error) {
+	var (
+		users []*models.OauthUser
+		count int
+	)
+	query := s.db.Model(new(models.OauthUser)).Where("tenant_id = ?", tenantID)
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("created_at").Offset(offset).Limit(limit).Find(&users).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return users, count, nil
+}
+
+// CreateUser saves a new user to database
+func (s *Service) CreateUser(roleID, account, password string, tenantID string) (*models.OauthUser, error) {
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	user, err := s.createUserCommon(tx, roleID, account, password, tenantID)
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// CreateUserTx saves a new user to database using injected db object
+func (s *Service) CreateUserTx(tx *gorm.DB, roleID, account, password string, tenantID string) (*models.OauthUser, error) {
+	return s.createUserCommon(tx, roleID, account, password, tenantID)
+}
+
+// UpdateUser updates the name, account and phone of a user, empty values are
+// left unchanged
+func (s *Service) UpdateUser(user *models.OauthUser, name, account, phone string) error {
+	if account != "" && account != user.Account {
+		if _, err := s.FindUserByAccountAndTenantID(account, user.TenantID); err == nil {
+			return ErrUsernameTaken
+		}
+		user.Account = account
+	}
+	if phone != "" && phone != user.Phone {
+		if _, err := s.FindUserByPhoneAndTenantID(phone, user.TenantID); err == nil {
+			return ErrPhoneTaken
+		}
+		user.Phone = phone
+	}
+	if name != "" {
+		user.Name = name
+	}
+	return s.db.Model(user).UpdateColumns(map[string]interface{}{
+		"name":    user.Name,
+		"account": user.Account,
+		"phone":   user.Phone,
+		"updated": time.Now().UTC(),
+	}).Error
+}
+
+// SetPassword sets a user password and revokes the user's tokens
+func (s *Service) SetPassword(user *models.OauthUser, password string) error {
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	tokens, event, err := s.setPasswordCommon(tx, user, password)
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	s.removeAccessTokensRedis(tokens)
+	if event != nil {
+		s.recordEvent(event)
+	}
+	return nil
+}
+
+// SetPasswordTx sets a user password in a transaction
+func (s *Service) SetPasswordTx(tx *gorm.DB, user *models.OauthUser, password string) error {
+	tokens, event, err := s.setPasswordCommon(tx, user, password)
+	if err != nil {
+		return err
+	}
+	s.removeAccessTokensRedis(tokens)
+	if event != nil {
+		s.recordEvent(event)
+	}
+	return nil
+}
+
+// ChangePassword sets a new password for a user who knows the current one,
+// failed attempts count towards the login lockout like the password grant
+func (s *Service) ChangePassword(tenantID, username, password, newPassword, clientIP string) error {
+	if err := s.checkLoginLockout(tenantID, username, clientIP); err != nil {
+		return err
+	}
+
+	// Expired passwords can be changed, so the expiry is not checked
+	user, err := s.authUser(username, password, tenantID)
+	if err != nil {
+		if err := s.recordLoginFailure(tenantID, username, clientIP); err != nil {
+			s.logger.Errorf("Recording failed login failed: %s", err)
+		}
+		return ErrInvalidUsernameOrPassword
+	}
+	if err := s.clearLoginFailures(tenantID, username); err != nil {
+		s.logger.Errorf("Clearing failed logins failed: %s", err)
+	}
+
+	return s.SetPassword(user, newPassword)
+}
+
+// ResetPassword sets a random password, returned so it can be handed to the user
+func (s *Service) ResetPassword(user *models.OauthUser) (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	password := base64.RawURLEncoding.EncodeToString(b)
+	if err := s.SetPassword(user, password); err != nil {
+		return "", err
+	}
+	return password, nil
+}
+
+// SetUserDisabled disables or enables a user, disabling revokes the user's tokens
+func (s *Service) SetUserDisabled(user *models.OauthUser, disabled bool) error {
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	if err := tx.Model(user).UpdateColumn("disabled", disabled).Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+	var tokens []string
+	var event *audit.Event
+	if disabled {
+		var err error
+		if tokens, err = s.revokeUserTokens(tx, user); err != nil {
+			tx.Rollback() // rollback the transaction
+			return err
+		}
+		if len(tokens) > 0 {
+			event = newTokensRevokedEvent(user, nil, "user_disabled", len(tokens))
+			if err := s.enqueueEvent(tx, event); err != nil {
+				tx.Rollback() // rollback the transaction
+				return err
+			}
+		}
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	s.removeAccessTokensRedis(tokens)
+	if event != nil {
+		s.recordEvent(event)
+	}
+	return nil
+}
+
+// DeleteUser deletes a user along with the user's tokens
+func (s *Service) DeleteUser(user *models.OauthUser) error {
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	tokens, err := s.revokeUserTokens(tx, user)
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+	var event *audit.Event
+	if len(tokens) > 0 {
+		event = newTokensRevokedEvent(user, nil, "user_deleted", len(tokens))
+		if err := s.enqueueEvent(tx, event); err != nil {
+			tx.Rollback() // rollback the transaction
+			return err
+		}
+	}
+	if err := tx.Delete(user).Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	s.removeAccessTokensRedis(tokens)
+	if event != nil {
+		s.recordEvent(event)
+	}
+	return nil
+}
+
+func (s *Service) createUserCommon(db *gorm.DB, roleID, account, password string, tenantID string) (*models.OauthUser, error) {
+	// The role must be a built-in role or a custom role of the tenant
+	var role *models.OauthRole
+	if roleID != "" {
+		var err error
+		if role, err = s.findTenantRole(db, roleID, tenantID); err != nil {
+			return nil, ErrInvalidRole
+		}
+	}
+
+	// Check the account is not taken in the tenant
+	if account == "" {
+		return nil, ErrCannotSetEmptyUsername
+	}
+	if _, err := s.FindUserByAccountAndTenantID(account, tenantID); err == nil {
+		return nil, ErrUsernameTaken
+	}
+
+	// The tenant must exist and be active
+	tenant, err := s.GetTenantConfig(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	// User IDs are 32 characters long
+	userID := strings.Replace(uuid.New(), "-", "", -1)
+	now := time.Now().UTC()
+	user := &models.OauthUser{
+		ID:                userID,
+		Name:              userID,
+		TenantID:          tenantID,
+		Account:           account,
+		RoleID:            util.StringOrNull(roleID),
+		CreatedAt:         now,
+		UpdatedAt:         now,
+		PasswordChangedAt: &now,
+	}
+	if err := s.checkPasswordPolicy(db, tenant, user, password); err != nil {
+		return nil, err
+	}
+	passwordHash, err := s.passwords.Hash(password, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	user.Password = util.StringOrNull(passwordHash)
+	if err := db.Create(user).Error; err != nil {
+		return nil, err
+	}
+	if role != nil {
+		if err := assignRole(db, user, role); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+func (s *Service) setPasswordCommon(db *gorm.DB, user *models.OauthUser, password string) ([]string, *audit.Event, error) {
+	tenant, err := s.GetTenantConfig(user.TenantID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.checkPasswordPolicy(db, tenant, user, password); err != nil {
+		return nil, nil, err
+	}
+
+	// Keep the previous hash so it cannot be reused
+	if err := s.pushPasswordHistory(db, tenant, user); err != nil {
+		return nil, nil, err
+	}
+
+	passwordHash, err := s.passwords.Hash(password, user.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now().UTC()
+	err = db.Model(user).UpdateColumns(map[string]interface{}{
+		"password":            passwordHash,
+		"password_changed_at": now,
+		"updated":             now,
+	}).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	user.Password = util.StringOrNull(passwordHash)
+	user.PasswordChangedAt = &now
+
+	// Existing sessions must log in again with the new password
+	tokens, err := s.revokeUserTokens(db, user)
+	if err != nil {
+		return nil, nil, err
+	}
+	var event *audit.Event
+	if len(tokens) > 0 {
+		event = newTokensRevokedEvent(user, nil, "password_changed", len(tokens))
+		if err := s.enqueueEvent(db, event); err != nil {
+			return nil, nil, err
+		}
+	}
+	return tokens, event, nil
+}
+
+// rehashUserPassword replaces a verified password's hash with a hash of the
+// configured scheme, the user's tokens stay valid
+func (s *Service) rehashUserPassword(user *models.OauthUser, password string) error {
+	passwordHash, err := s.passwords.Hash(password, user.ID)
+	if err != nil {
+		return err
+	}
+	err = s.db.Model(user).UpdateColumn("password", passwordHash).Error
+	if err != nil {
+		return err
+	}
+	user.Password = util.StringOrNull(passwordHash)
+	return nil
+}
+
+// revokeUserTokens deletes the user's authorization codes, refresh and access
+// tokens, returning the access tokens so they can be removed from Redis
+func (s *Service) revokeUserTokens(db *gorm.DB, user *models.OauthUser) ([]string, error) {
+	var tokens []string
+	err := db.Model(new(models.OauthAccessToken)).Where("user_id = ?", user.ID).
+		Pluck("token", &tokens).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, model := range []interface{}{
+		new(models.OauthAuthorizationCode),
+		new(models.OauthRefreshToken),
+		new(models.OauthAccessToken),
+	} {
+		if err := db.Unscoped().Where("user_id = ?", user.ID).Delete(model).Error; err != nil {
+			return nil, err
+		}
+	}
+	return tokens, nil
+}
+
+// removeAccessTokensRedis removes revoked access tokens from Redis
+func (s *Service) removeAccessTokensRedis(tokens []string) {
+	for _, token := range tokens {
+		s.RemoveAccessTokenRedis(token)
+	}
+}
+
+// checkPasswordPolicy returns a passwordpolicy.Error listing the rules of
+// the tenant's policy the password breaks, including reuse of the current
+// and previous passwords of an existing user
+func (s *Service) checkPasswordPolicy(db *gorm.DB, tenant *TenantConfig, user *models.OauthUser, password string) error {
+	policy := tenant.PasswordPolicy
+	violations := policy.Check(password, user.Account, user.Phone)
+
+	if policy.HistorySize > 0 && user.Password.Valid {
+		hashes := []string{user.Password.String}
+		var history []*models.OauthPasswordHistory
+		err := db.Where("user_id = ?", user.ID).Order("id desc").
+			Limit(policy.HistorySize - 1).Find(&history).Error
+		if err != nil {
+			return err
+		}
+		for _, entry := range history {
+			hashes = append(hashes, entry.Password)
+		}
+		for _, hash := range hashes {
+			if valid, _ := s.passwords.Verify(hash, password, user.ID); valid {
+				violations = append(violations, passwordpolicy.ReusedViolation(policy.HistorySize))
+				break
+			}
+		}
+	}
+
+	if len(violations) > 0 {
+		return &passwordpolicy.Error{Violations: violations}
+	}
+	return nil
+}
+
+// pushPasswordHistory saves the current password hash of a user to the
+// history, keeping as many hashes as the tenant's policy checks
+func (s *Service) pushPasswordHistory(db *gorm.DB, tenant *TenantConfig, user *models.OauthUser) error {
+	historySize := tenant.PasswordPolicy.HistorySize
+	if historySize < 2 || !user.Password.Valid {
+		return nil
+	}
+
+	entry := &models.OauthPasswordHistory{
+		UserID:    user.ID,
+		Password:  user.Password.String,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := db.Create(entry).Error; err != nil {
+		return err
+	}
+
+	// The current password counts towards the history size
+	var keep []uint
+	err := db.Model(new(models.OauthPasswordHistory)).Where("user_id = ?", user.ID).
+		Order("id desc").Limit(historySize-1).Pluck("id", &keep).Error
+	if err != nil {
+		return err
+	}
+	return db.Where("user_id = ? AND id NOT IN (?)", user.ID, keep).
+		Delete(new(models.OauthPasswordHistory)).Error
+}
@@ -0,0 +1,114 @@
+package oauth_test
+
+import (
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/passwordpolicy"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
+	pass "github.com/RichardKnop/go-oauth2-server/util/password"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestCreateUserValidation() {
+	// Passwords below the minimum length are rejected
+	_, err := suite.service.CreateUser(roles.User, "test@newuser", "short", "")
+	if assert.IsType(suite.T(), new(passwordpolicy.Error), err) {
+		assert.True(suite.T(), err.(*passwordpolicy.Error).Has(passwordpolicy.CodeTooShort))
+	}
+
+	// Unknown roles are rejected
+	_, err = suite.service.CreateUser("bogus", "test@newuser", "correct_horse_battery", "")
+	assert.Equal(suite.T(), oauth.ErrInvalidRole, err)
+
+	user, err := suite.service.CreateUser(roles.User, "test@newuser", "correct_horse_battery", "")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	assert.Len(suite.T(), user.ID, 32)
+
+	// Accounts are unique within a tenant
+	_, err = suite.service.CreateUser(roles.User, "test@newuser", "correct_horse_battery", "")
+	assert.Equal(suite.T(), oauth.ErrUsernameTaken, err)
+
+	// The new user can log in
+	_, err = suite.service.AuthUser("test@newuser", "correct_horse_battery", "")
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *OauthTestSuite) TestSetPasswordRevokesTokens() {
+	user, err := suite.service.CreateUser(roles.User, "test@newuser", "correct_horse_battery", "")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	_, err = suite.service.GrantAccessToken(suite.clients[0], user, 3600, "read")
+	assert.NoError(suite.T(), err)
+
+	assert.NoError(suite.T(), suite.service.SetPassword(user, "new_password"))
+
+	// Existing tokens are revoked
+	var count int
+	suite.db.Model(new(models.OauthAccessToken)).Where("user_id = ?", user.ID).Count(&count)
+	assert.Equal(suite.T(), 0, count)
+
+	// Only the new password works
+	_, err = suite.service.AuthUser("test@newuser", "correct_horse_battery", "")
+	assert.Equal(suite.T(), oauth.ErrInvalidUserPassword, err)
+	_, err = suite.service.AuthUser("test@newuser", "new_password", "")
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *OauthTestSuite) TestDisableAndDeleteUser() {
+	user, err := suite.service.CreateUser(roles.User, "test@newuser", "correct_horse_battery", "")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+
+	// Disabled users cannot log in
+	assert.NoError(suite.T(), suite.service.SetUserDisabled(user, true))
+	_, err = suite.service.AuthUser("test@newuser", "correct_horse_battery", "")
+	assert.Equal(suite.T(), oauth.ErrUserDisabled, err)
+
+	// Enabled again they can
+	assert.NoError(suite.T(), suite.service.SetUserDisabled(user, false))
+	_, err = suite.service.AuthUser("test@newuser", "correct_horse_battery", "")
+	assert.NoError(suite.T(), err)
+
+	// Deleted users are gone
+	assert.NoError(suite.T(), suite.service.DeleteUser(user))
+	_, err = suite.service.FindUserByID(user.ID)
+	assert.Equal(suite.T(), oauth.ErrUserNotFound, err)
+}
+
+func (suite *OauthTestSuite) TestAuthUserRehashesLegacyPassword() {
+	user, err := suite.service.CreateUser(roles.User, "test@newuser", "correct_horse_battery", "")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	assert.True(suite.T(), strings.HasPrefix(user.Password.String, "$argon2id$"))
+
+	// Store a legacy V2 hash
+	legacyHash := pass.HashPassword2(
+		"correct_horse_battery",
+		user.ID,
+		suite.cnf.Oauth.PasswordSecret,
+		suite.cnf.Oauth.PasswordSalt,
+	)
+	err = suite.db.Model(user).UpdateColumn("password", legacyHash).Error
+	assert.NoError(suite.T(), err)
+
+	// Logging in upgrades the hash to the configured scheme
+	user, err = suite.service.AuthUser("test@newuser", "correct_horse_battery", "")
+	if assert.NoError(suite.T(), err) {
+		assert.True(suite.T(), strings.HasPrefix(user.Password.String, "$argon2id$"))
+	}
+	user, err = suite.service.FindUserByID(user.ID)
+	if assert.NoError(suite.T(), err) {
+		assert.True(suite.T(), strings.HasPrefix(user.Password.String, "$argon2id$"))
+	}
+
+	// The upgraded hash keeps working
+	_, err = suite.service.AuthUser("test@newuser", "correct_horse_battery", "")
+	assert.NoError(suite.T(), err)
+}
@@ -1,19 +1,20 @@
 package oauth_test
 
 import (
+	"strings"
 	"time"
 
 	"github.com/RichardKnop/go-oauth2-server/models"
 	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/passwordpolicy"
 	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
 	"github.com/RichardKnop/go-oauth2-server/util"
-	pass "github.com/RichardKnop/go-oauth2-server/util/password"
 	"github.com/RichardKnop/uuid"
 	"github.com/stretchr/testify/assert"
 )
 
 func (suite *OauthTestSuite) TestUserExistsFindsValidUser() {
-	validUsername := suite.users[0].Username
+	validUsername := suite.users[0].Account
 	assert.True(suite.T(), suite.service.UserExists(validUsername, ""))
 }
 
@@ -22,67 +23,61 @@ func (suite *OauthTestSuite) TestUserExistsDoesntFindInvalidUser() {
 	assert.False(suite.T(), suite.service.UserExists(invalidUsername, ""))
 }
 
-func (suite *OauthTestSuite) TestUpdateUsernameWorksWithValidEntry() {
+func (suite *OauthTestSuite) TestUpdateUserWorksWithValidEntry() {
 	user, err := suite.service.CreateUser(
-		roles.User,      // role ID
-		"test@newuser",  // username
-		"test_password", // password
+		roles.User,              // role ID
+		"test@newuser",          // account
+		"correct_horse_battery", // password
 		"",
 	)
 
 	assert.NoError(suite.T(), err)
 	assert.NotNil(suite.T(), user)
-	assert.Equal(suite.T(), "test@newuser", user.Username)
+	assert.Equal(suite.T(), "test@newuser", user.Account)
 
-	newUsername := "mynew@email"
+	newAccount := "mynew@email"
 
-	err = suite.service.UpdateUsername(user, newUsername)
+	err = suite.service.UpdateUser(user, "", newAccount, "")
 
 	assert.NoError(suite.T(), err)
 
-	assert.Equal(suite.T(), newUsername, user.Username)
+	assert.Equal(suite.T(), newAccount, user.Account)
 }
 
-func (suite *OauthTestSuite) TestUpdateUsernameTxWorksWithValidEntry() {
+func (suite *OauthTestSuite) TestUpdateUserFailsWithATakenAccount() {
 	user, err := suite.service.CreateUser(
-		roles.User,      // role ID
-		"test@newuser",  // username
-		"test_password", // password
+		roles.User,              // role ID
+		"test@newuser",          // account
+		"correct_horse_battery", // password
 		"",
 	)
 
 	assert.NoError(suite.T(), err)
 	assert.NotNil(suite.T(), user)
-	assert.Equal(suite.T(), "test@newuser", user.Username)
 
-	newUsername := "mynew@email"
+	err = suite.service.UpdateUser(user, "", suite.users[0].Account, "")
 
-	err = suite.service.UpdateUsernameTx(suite.db, user, newUsername)
+	assert.Equal(suite.T(), oauth.ErrUsernameTaken, err)
 
-	assert.NoError(suite.T(), err)
-
-	assert.Equal(suite.T(), newUsername, user.Username)
+	assert.Equal(suite.T(), "test@newuser", user.Account)
 }
 
-func (suite *OauthTestSuite) TestUpdateUsernameFailsWithABlankEntry() {
+func (suite *OauthTestSuite) TestUpdateUserKeepsBlankEntries() {
 	user, err := suite.service.CreateUser(
-		roles.User,      // role ID
-		"test@newuser",  // username
-		"test_password", // password
+		roles.User,              // role ID
+		"test@newuser",          // account
+		"correct_horse_battery", // password
 		"",
 	)
 
 	assert.NoError(suite.T(), err)
 	assert.NotNil(suite.T(), user)
-	assert.Equal(suite.T(), "test@newuser", user.Username)
-
-	newUsername := ""
 
-	err = suite.service.UpdateUsername(user, newUsername)
+	err = suite.service.UpdateUser(user, "", "", "")
 
-	assert.EqualError(suite.T(), err, oauth.ErrCannotSetEmptyUsername.Error())
+	assert.NoError(suite.T(), err)
 
-	assert.NotEqual(suite.T(), newUsername, user.Username)
+	assert.Equal(suite.T(), "test@newuser", user.Account)
 }
 
 func (suite *OauthTestSuite) TestFindUserByUsername() {
@@ -110,18 +105,7 @@ func (suite *OauthTestSuite) TestFindUserByUsername() {
 
 	// Correct user object should be returned
 	if assert.NotNil(suite.T(), user) {
-		assert.Equal(suite.T(), "test@user", user.Username)
-	}
-
-	// Test username case insensitiviness
-	user, err = suite.service.FindUserByUsername("TeSt@UsEr")
-
-	// Error should be nil
-	assert.Nil(suite.T(), err)
-
-	// Correct user object should be returned
-	if assert.NotNil(suite.T(), user) {
-		assert.Equal(suite.T(), "test@user", user.Username)
+		assert.Equal(suite.T(), "test@user", user.Account)
 	}
 }
 
@@ -133,9 +117,9 @@ func (suite *OauthTestSuite) TestCreateUser() {
 
 	// We try to insert a non unique user
 	user, err = suite.service.CreateUser(
-		roles.User,      // role ID
-		"test@user",     // username
-		"test_password", // password
+		roles.User,              // role ID
+		"test@user",             // username
+		"correct_horse_battery", // password
 		"",
 	)
 
@@ -149,25 +133,9 @@ func (suite *OauthTestSuite) TestCreateUser() {
 
 	// We try to insert a unique user
 	user, err = suite.service.CreateUser(
-		roles.User,      // role ID
-		"test@newuser",  // username
-		"test_password", // password
-		"",
-	)
-
-	// Error should be nil
-	assert.Nil(suite.T(), err)
-
-	// Correct user object should be returned
-	if assert.NotNil(suite.T(), user) {
-		assert.Equal(suite.T(), "test@newuser", user.Username)
-	}
-
-	// Test username case insensitivity
-	user, err = suite.service.CreateUser(
-		roles.User,      // role ID
-		"TeSt@NeWuSeR2", // username
-		"test_password", // password
+		roles.User,              // role ID
+		"test@newuser",          // username
+		"correct_horse_battery", // password
 		"",
 	)
 
@@ -176,7 +144,7 @@ func (suite *OauthTestSuite) TestCreateUser() {
 
 	// Correct user object should be returned
 	if assert.NotNil(suite.T(), user) {
-		assert.Equal(suite.T(), "test@newuser2", user.Username)
+		assert.Equal(suite.T(), "test@newuser", user.Account)
 	}
 }
 
@@ -188,13 +156,11 @@ func (suite *OauthTestSuite) TestSetPassword() {
 
 	// Insert a test user without a password
 	user = &models.OauthUser{
-		MyGormModel: models.MyGormModel{
-			ID:        uuid.New(),
-			CreatedAt: time.Now().UTC(),
-		},
-		RoleID:   util.StringOrNull(roles.User),
-		Username: "test@user_nopass",
-		Password: util.StringOrNull(""),
+		ID:        strings.Replace(uuid.New(), "-", "", -1),
+		Name:      "test@user_nopass",
+		Account:   "test@user_nopass",
+		RoleID:    util.StringOrNull(roles.User),
+		CreatedAt: time.Now().UTC(),
 	}
 	err = suite.db.Create(user).Error
 	assert.NoError(suite.T(), err, "Inserting test data failed")
@@ -203,19 +169,20 @@ func (suite *OauthTestSuite) TestSetPassword() {
 	err = suite.service.SetPassword(user, "")
 
 	// Correct error should be returned
-	if assert.NotNil(suite.T(), err) {
-		assert.Equal(suite.T(), oauth.ErrPasswordTooShort, err)
+	if assert.IsType(suite.T(), new(passwordpolicy.Error), err) {
+		assert.True(suite.T(), err.(*passwordpolicy.Error).Has(passwordpolicy.CodeTooShort))
 	}
 
 	// Try changing the password
-	err = suite.service.SetPassword(user, "test_password")
+	err = suite.service.SetPassword(user, "correct_horse_battery")
 
 	// Error should be nil
 	assert.Nil(suite.T(), err)
 
 	// User object should have been updated
-	assert.Equal(suite.T(), "test@user_nopass", user.Username)
-	assert.Nil(suite.T(), pass.VerifyPassword(user.Password.String, "test_password"))
+	assert.Equal(suite.T(), "test@user_nopass", user.Account)
+	_, err = suite.service.AuthUser("test@user_nopass", "correct_horse_battery", "")
+	assert.Nil(suite.T(), err)
 }
 
 func (suite *OauthTestSuite) TestAuthUser() {
@@ -226,13 +193,11 @@ func (suite *OauthTestSuite) TestAuthUser() {
 
 	// Insert a test user without a password
 	err = suite.db.Create(&models.OauthUser{
-		MyGormModel: models.MyGormModel{
-			ID:        uuid.New(),
-			CreatedAt: time.Now().UTC(),
-		},
-		RoleID:   util.StringOrNull(roles.User),
-		Username: "test@user_nopass",
-		Password: util.StringOrNull(""),
+		ID:        strings.Replace(uuid.New(), "-", "", -1),
+		Name:      "test@user_nopass",
+		Account:   "test@user_nopass",
+		RoleID:    util.StringOrNull(roles.User),
+		CreatedAt: time.Now().UTC(),
 	}).Error
 	assert.NoError(suite.T(), err, "Inserting test data failed")
 
@@ -277,18 +242,7 @@ func (suite *OauthTestSuite) TestAuthUser() {
 
 	// Correct user object should be returned
 	if assert.NotNil(suite.T(), user) {
-		assert.Equal(suite.T(), "test@user", user.Username)
-	}
-
-	// Test username case insensitivity
-	user, err = suite.service.AuthUser("TeSt@UsEr", "test_password", "")
-
-	// Error should be nil
-	assert.Nil(suite.T(), err)
-
-	// Correct user object should be returned
-	if assert.NotNil(suite.T(), user) {
-		assert.Equal(suite.T(), "test@user", user.Username)
+		assert.Equal(suite.T(), "test@user", user.Account)
 	}
 }
 
@@ -305,22 +259,14 @@ func (suite *OauthTestSuite) TestBlankPassword() {
 		"",
 	)
 
-	// Error should be nil
-	assert.Nil(suite.T(), err)
-
-	// Correct user object should be returned
-	if assert.NotNil(suite.T(), user) {
-		assert.Equal(suite.T(), "test@user_nopass", user.Username)
-	}
-
-	// When we try to authenticate
-	user, err = suite.service.AuthUser("test@user_nopass", "", "")
-
 	// User object should be nil
 	assert.Nil(suite.T(), user)
 
 	// Correct error should be returned
-	if assert.NotNil(suite.T(), err) {
-		assert.Equal(suite.T(), oauth.ErrUserPasswordNotSet, err)
+	if assert.IsType(suite.T(), new(passwordpolicy.Error), err) {
+		assert.True(suite.T(), err.(*passwordpolicy.Error).Has(passwordpolicy.CodeTooShort))
 	}
+
+	// The user should not have been created
+	assert.False(suite.T(), suite.service.UserExists("test@user_nopass", ""))
 }
@@ -0,0 +1,55 @@
+package oauth_test
+
+import (
+	"encoding/json"
+
+	"github.com/RichardKnop/go-oauth2-server/audit"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/webhook"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestGrantAccessTokenEnqueuesWebhookDelivery() {
+	webhookService := webhook.NewService(suite.cnf, suite.db)
+	suite.service.UseWebhookService(webhookService)
+	defer suite.service.UseWebhookService(nil)
+
+	tenantID := suite.clients[0].TenantID
+	subscription, err := webhookService.CreateSubscription(
+		tenantID,
+		"https://93.184.216.34/hooks",
+		[]string{audit.TokenIssued},
+	)
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	defer webhookService.DeleteSubscription(subscription)
+
+	accessToken, err := suite.service.GrantAccessToken(
+		suite.clients[0], // client
+		suite.users[0],   // user
+		3600,             // expires in
+		"read_write",     // scope
+	)
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+
+	// The delivery was written along with the token
+	var deliveries []*models.WebhookDelivery
+	err = suite.db.Where("subscription_id = ?", subscription.ID).Find(&deliveries).Error
+	if !assert.NoError(suite.T(), err) || !assert.Len(suite.T(), deliveries, 1) {
+		return
+	}
+	delivery := deliveries[0]
+	assert.Equal(suite.T(), models.WebhookDeliveryPending, delivery.Status)
+	assert.Equal(suite.T(), audit.TokenIssued, delivery.EventType)
+	assert.Equal(suite.T(), tenantID, delivery.TenantID)
+
+	event := new(audit.Event)
+	if assert.NoError(suite.T(), json.Unmarshal([]byte(delivery.Payload), event)) {
+		assert.Equal(suite.T(), delivery.EventID, event.ID)
+		assert.Equal(suite.T(), accessToken.UserID.String, event.ActorID)
+		assert.Equal(suite.T(), suite.clients[0].Key, event.ClientID)
+	}
+}
@@ -4,11 +4,16 @@ import (
 	"github.com/go-redis/redis/v7"
 	"reflect"
 
+	"github.com/RichardKnop/go-oauth2-server/admin"
+	"github.com/RichardKnop/go-oauth2-server/audit"
 	"github.com/RichardKnop/go-oauth2-server/config"
 	"github.com/RichardKnop/go-oauth2-server/health"
+	"github.com/RichardKnop/go-oauth2-server/metrics"
 	"github.com/RichardKnop/go-oauth2-server/oauth"
 	"github.com/RichardKnop/go-oauth2-server/session"
-	"github.com/gorilla/sessions"
+	"github.com/RichardKnop/go-oauth2-server/tracing"
+	"github.com/RichardKnop/go-oauth2-server/web"
+	"github.com/RichardKnop/go-oauth2-server/webhook"
 	"github.com/jinzhu/gorm"
 )
 
@@ -20,11 +25,29 @@ var (
 	// HealthService ...
 	HealthService health.ServiceInterface
 
+	// MetricsService ...
+	MetricsService metrics.ServiceInterface
+
+	// TracingService ...
+	TracingService tracing.ServiceInterface
+
 	// OauthService ...
 	OauthService oauth.ServiceInterface
 
 	// SessionService ...
 	SessionService session.ServiceInterface
+
+	// AuditService ...
+	AuditService audit.ServiceInterface
+
+	// WebhookService ...
+	WebhookService webhook.ServiceInterface
+
+	// AdminService ...
+	AdminService admin.ServiceInterface
+
+	// WebService ...
+	WebService web.ServiceInterface
 )
 
 // UseHealthService sets the health service
@@ -32,6 +55,16 @@ func UseHealthService(h health.ServiceInterface) {
 	HealthService = h
 }
 
+// UseMetricsService sets the metrics service
+func UseMetricsService(m metrics.ServiceInterface) {
+	MetricsService = m
+}
+
+// UseTracingService sets the tracing service
+func UseTracingService(t tracing.ServiceInterface) {
+	TracingService = t
+}
+
 // UseOauthService sets the oAuth service
 func UseOauthService(o oauth.ServiceInterface) {
 	OauthService = o
@@ -42,19 +75,87 @@ func UseSessionService(s session.ServiceInterface) {
 	SessionService = s
 }
 
+// UseAuditService sets the audit service
+func UseAuditService(a audit.ServiceInterface) {
+	AuditService = a
+}
+
+// UseWebhookService sets the webhook service
+func UseWebhookService(w webhook.ServiceInterface) {
+	WebhookService = w
+}
+
+// UseAdminService sets the admin service
+func UseAdminService(a admin.ServiceInterface) {
+	AdminService = a
+}
+
+// UseWebService sets the web service
+func UseWebService(w web.ServiceInterface) {
+	WebService = w
+}
+
 // Init starts up all services
 func Init(cnf *config.Config, db *gorm.DB, redisClient *redis.Client) error {
+	// Refuse to start with an SMS sender which would drop or leak codes
+	if err := oauth.ValidateSMSConfig(cnf); err != nil {
+		return err
+	}
+	// Refuse to start without the configured password denylist
+	if err := oauth.ValidatePasswordDenylist(cnf); err != nil {
+		return err
+	}
+
 	if nil == reflect.TypeOf(HealthService) {
 		HealthService = health.NewService(db)
 	}
 
+	if nil == reflect.TypeOf(MetricsService) {
+		MetricsService = metrics.NewService(cnf, db, redisClient)
+	}
+
+	if nil == reflect.TypeOf(TracingService) {
+		TracingService = tracing.NewService(cnf)
+	}
+
+	// Queries and commands run with the context of a request are traced
+	tracing.InstrumentDatabase(db)
+	tracing.InstrumentRedis(redisClient)
+
 	if nil == reflect.TypeOf(OauthService) {
 		OauthService = oauth.NewService(cnf, db, redisClient)
 	}
 
 	if nil == reflect.TypeOf(SessionService) {
-		// note: default session store is CookieStore
-		SessionService = session.NewService(cnf, sessions.NewCookieStore([]byte(cnf.Session.Secret)))
+		SessionService = session.NewService(cnf, session.NewStore(cnf, redisClient))
+	}
+
+	// The end session endpoint clears the SSO session of the user agent
+	OauthService.UseSessionService(SessionService)
+
+	if nil == reflect.TypeOf(AuditService) {
+		AuditService = audit.NewService(cnf, db)
+	}
+
+	// Security events of the oauth endpoints go to the audit log
+	OauthService.UseAuditService(AuditService)
+
+	if nil == reflect.TypeOf(WebhookService) {
+		WebhookService = webhook.NewService(cnf, db)
+	}
+
+	// Token and key changes are written to the webhook outbox in their own
+	// transactions, the other security events as they are recorded
+	OauthService.UseWebhookService(WebhookService)
+	AuditService.UseSink(WebhookService.AuditSink())
+	WebhookService.Start()
+
+	if nil == reflect.TypeOf(AdminService) {
+		AdminService = admin.NewService(cnf, OauthService, SessionService, AuditService, WebhookService)
+	}
+
+	if nil == reflect.TypeOf(WebService) {
+		WebService = web.NewService(cnf, OauthService, SessionService, AuditService)
 	}
 
 	return nil
@@ -63,6 +164,12 @@ func Init(cnf *config.Config, db *gorm.DB, redisClient *redis.Client) error {
 // Close closes any open services
 func Close() {
 	HealthService.Close()
+	MetricsService.Close()
 	OauthService.Close()
 	SessionService.Close()
+	WebhookService.Close()
+	AuditService.Close()
+	AdminService.Close()
+	WebService.Close()
+	TracingService.Close()
 }
@@ -0,0 +1,337 @@
+package session
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/gob"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/go-redis/redis/v7"
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+const (
+	sessionPrefix      = "session:"
+	userSessionsPrefix = "user_sessions:"
+	sessionIDLength    = 32
+)
+
+// SessionInfo describes an active session of a user
+type SessionInfo struct {
+	ID         string
+	ClientID   string
+	TenantID   string
+	IP         string
+	UserAgent  string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+}
+
+// RedisStore keeps sessions in Redis, the cookie only holds the signed
+// session ID. Sessions end after IdleTimeout seconds without requests and
+// AbsoluteTimeout seconds after they started, whichever comes first
+type RedisStore struct {
+	redis           *redis.Client
+	codecs          []securecookie.Codec
+	options         *sessions.Options
+	idleTimeout     time.Duration
+	absoluteTimeout time.Duration
+}
+
+// NewRedisStore returns a new RedisStore instance
+func NewRedisStore(cnf *config.Config, redisClient *redis.Client) *RedisStore {
+	codecs := securecookie.CodecsFromPairs([]byte(cnf.Session.Secret))
+	for _, codec := range codecs {
+		if secureCookie, ok := codec.(*securecookie.SecureCookie); ok {
+			secureCookie.MaxAge(cnf.Session.MaxAge)
+		}
+	}
+	return &RedisStore{
+		redis:  redisClient,
+		codecs: codecs,
+		options: &sessions.Options{
+			Path:     cnf.Session.Path,
+			MaxAge:   cnf.Session.MaxAge,
+			HttpOnly: cnf.Session.HTTPOnly,
+		},
+		idleTimeout:     time.Duration(cnf.Session.IdleTimeout) * time.Second,
+		absoluteTimeout: time.Duration(cnf.Session.AbsoluteTimeout) * time.Second,
+	}
+}
+
+// Get returns a session for the given name after adding it to the registry
+func (s *RedisStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New returns the session of the cookie, or a new session when there is no
+// cookie or its session has ended
+func (s *RedisStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	opts := *s.options
+	session.Options = &opts
+	session.IsNew = true
+
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+	var sessionID string
+	// Tampered or outdated cookies start a new session
+	if err := securecookie.DecodeMulti(name, cookie.Value, &sessionID, s.codecs...); err != nil {
+		return session, nil
+	}
+
+	found, err := s.load(session, sessionID)
+	if err != nil {
+		return session, err
+	}
+	if found {
+		session.ID = sessionID
+		session.IsNew = false
+	}
+	return session, nil
+}
+
+// Save stores the session in Redis and sets the session ID cookie, a
+// negative MaxAge deletes the session
+func (s *RedisStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			if err := s.deleteSession(session.ID); err != nil {
+				return err
+			}
+		}
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	owner := sessionOwner(session)
+
+	now := time.Now().UTC()
+	createdAt := now
+	if session.ID != "" {
+		previous, err := s.redis.HMGet(sessionPrefix+session.ID, "user_id", "created_at").Result()
+		if err != nil {
+			return err
+		}
+		previousUserID, _ := previous[0].(string)
+		if previousUserID != owner.UserID {
+			// A session changing hands gets a new ID, so an ID planted
+			// before logging in is useless afterwards
+			if err := s.deleteSession(session.ID); err != nil {
+				return err
+			}
+			session.ID = ""
+		} else if value, ok := previous[1].(string); ok {
+			if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+				createdAt = parsed
+			}
+		}
+	}
+	if session.ID == "" {
+		sessionID, err := newSessionID()
+		if err != nil {
+			return err
+		}
+		session.ID = sessionID
+	}
+
+	data := new(bytes.Buffer)
+	if err := gob.NewEncoder(data).Encode(session.Values); err != nil {
+		return err
+	}
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.codecs...)
+	if err != nil {
+		return err
+	}
+
+	key := sessionPrefix + session.ID
+	pipe := s.redis.TxPipeline()
+	pipe.HMSet(key, map[string]interface{}{
+		"data":         data.String(),
+		"user_id":      owner.UserID,
+		"client_id":    owner.ClientID,
+		"tenant_id":    owner.TenantID,
+		"ip":           util.GetClientIP(r),
+		"user_agent":   r.UserAgent(),
+		"created_at":   createdAt.Format(time.RFC3339),
+		"last_seen_at": now.Format(time.RFC3339),
+	})
+	if ttl := s.ttl(createdAt); ttl > 0 {
+		pipe.Expire(key, ttl)
+	}
+	if owner.UserID != "" {
+		pipe.SAdd(userSessionsPrefix+owner.UserID, session.ID)
+	}
+	if _, err := pipe.Exec(); err != nil {
+		return err
+	}
+
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+// ListUserSessions returns the active sessions of a user, most recently
+// used first
+func (s *RedisStore) ListUserSessions(userID string) ([]*SessionInfo, error) {
+	sessionIDs, err := s.redis.SMembers(userSessionsPrefix + userID).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var userSessions []*SessionInfo
+	for _, sessionID := range sessionIDs {
+		fields, err := s.redis.HGetAll(sessionPrefix + sessionID).Result()
+		if err != nil {
+			return nil, err
+		}
+		info := newSessionInfo(sessionID, fields)
+		// Ended sessions are removed from the index as they are found
+		if len(fields) == 0 || fields["user_id"] != userID || s.expired(info.CreatedAt) {
+			s.redis.SRem(userSessionsPrefix+userID, sessionID)
+			continue
+		}
+		userSessions = append(userSessions, info)
+	}
+
+	sort.Slice(userSessions, func(i, j int) bool {
+		return userSessions[i].LastSeenAt.After(userSessions[j].LastSeenAt)
+	})
+	return userSessions, nil
+}
+
+// RevokeSession ends a session of a user
+func (s *RedisStore) RevokeSession(userID, sessionID string) error {
+	isMember, err := s.redis.SIsMember(userSessionsPrefix+userID, sessionID).Result()
+	if err != nil {
+		return err
+	}
+	if !isMember {
+		return ErrSessionNotFound
+	}
+	return s.deleteSession(sessionID)
+}
+
+// RevokeUserSessions ends all sessions of a user
+func (s *RedisStore) RevokeUserSessions(userID string) error {
+	sessionIDs, err := s.redis.SMembers(userSessionsPrefix + userID).Result()
+	if err != nil {
+		return err
+	}
+	keys := []string{userSessionsPrefix + userID}
+	for _, sessionID := range sessionIDs {
+		keys = append(keys, sessionPrefix+sessionID)
+	}
+	return s.redis.Del(keys...).Err()
+}
+
+// load decodes the values of a session which has not ended, using the
+// session counts as activity for the idle timeout
+func (s *RedisStore) load(session *sessions.Session, sessionID string) (bool, error) {
+	key := sessionPrefix + sessionID
+	fields, err := s.redis.HGetAll(key).Result()
+	if err != nil {
+		return false, err
+	}
+	// Ended or revoked
+	if len(fields) == 0 {
+		return false, nil
+	}
+	info := newSessionInfo(sessionID, fields)
+	if s.expired(info.CreatedAt) {
+		return false, s.deleteSession(sessionID)
+	}
+
+	if err := gob.NewDecoder(bytes.NewBufferString(fields["data"])).Decode(&session.Values); err != nil {
+		return false, err
+	}
+
+	pipe := s.redis.TxPipeline()
+	pipe.HSet(key, "last_seen_at", time.Now().UTC().Format(time.RFC3339))
+	if ttl := s.ttl(info.CreatedAt); ttl > 0 {
+		pipe.Expire(key, ttl)
+	}
+	if _, err := pipe.Exec(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// deleteSession deletes a session and removes it from its user's index
+func (s *RedisStore) deleteSession(sessionID string) error {
+	key := sessionPrefix + sessionID
+	userID, err := s.redis.HGet(key, "user_id").Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	pipe := s.redis.TxPipeline()
+	pipe.Del(key)
+	if userID != "" {
+		pipe.SRem(userSessionsPrefix+userID, sessionID)
+	}
+	_, err = pipe.Exec()
+	return err
+}
+
+// ttl returns how long a session started at createdAt can stay unused, zero
+// when sessions never end
+func (s *RedisStore) ttl(createdAt time.Time) time.Duration {
+	ttl := s.idleTimeout
+	if s.absoluteTimeout > 0 {
+		remaining := s.absoluteTimeout - time.Since(createdAt)
+		if ttl == 0 || remaining < ttl {
+			ttl = remaining
+		}
+	}
+	return ttl
+}
+
+// expired returns true once a session started at createdAt is past the
+// absolute timeout
+func (s *RedisStore) expired(createdAt time.Time) bool {
+	return s.absoluteTimeout > 0 && time.Since(createdAt) >= s.absoluteTimeout
+}
+
+// sessionOwner returns who a session belongs to, the SSO session user if
+// there is one and the user session's otherwise
+func sessionOwner(session *sessions.Session) UserSession {
+	var owner UserSession
+	if userSession, ok := session.Values[UserSessionKey].(*UserSession); ok && userSession != nil {
+		owner = *userSession
+	}
+	if ssoSession, ok := session.Values[SSOSessionKey].(*SSOSession); ok && ssoSession != nil {
+		owner.UserID = ssoSession.UserID
+		owner.TenantID = ssoSession.TenantID
+	}
+	return owner
+}
+
+// newSessionInfo parses the fields of a session hash
+func newSessionInfo(sessionID string, fields map[string]string) *SessionInfo {
+	info := &SessionInfo{
+		ID:        sessionID,
+		ClientID:  fields["client_id"],
+		TenantID:  fields["tenant_id"],
+		IP:        fields["ip"],
+		UserAgent: fields["user_agent"],
+	}
+	info.CreatedAt, _ = time.Parse(time.RFC3339, fields["created_at"])
+	info.LastSeenAt, _ = time.Parse(time.RFC3339, fields["last_seen_at"])
+	return info
+}
+
+// newSessionID returns a random opaque session ID
+func newSessionID() (string, error) {
+	b := make([]byte, sessionIDLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,210 @@
+package session_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/session"
+	"github.com/gorilla/sessions"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *SessionTestSuite) TestRedisStoreSaveAndLoad() {
+	// Without a cookie a new session is started
+	redisSession, cookie := suite.saveRedisSession(nil, func(redisSession *sessions.Session) {
+		assert.True(suite.T(), redisSession.IsNew)
+		redisSession.Values[session.UserSessionKey] = &session.UserSession{
+			UserID:   "test_user_id",
+			ClientID: "test_client",
+			Account:  "test@user",
+		}
+	})
+	if !assert.NotNil(suite.T(), cookie) {
+		return
+	}
+	assert.NotEmpty(suite.T(), redisSession.ID)
+
+	// The cookie only holds the session ID, the values come from Redis
+	assert.NotContains(suite.T(), cookie.Value, "test@user")
+	loaded := suite.loadRedisSession(cookie)
+	assert.False(suite.T(), loaded.IsNew)
+	assert.Equal(suite.T(), redisSession.ID, loaded.ID)
+	if userSession, ok := loaded.Values[session.UserSessionKey].(*session.UserSession); assert.True(suite.T(), ok) {
+		assert.Equal(suite.T(), "test@user", userSession.Account)
+	}
+
+	// The session is listed for its user
+	userSessions, err := suite.store.ListUserSessions("test_user_id")
+	assert.NoError(suite.T(), err)
+	if assert.Len(suite.T(), userSessions, 1) {
+		assert.Equal(suite.T(), redisSession.ID, userSessions[0].ID)
+		assert.Equal(suite.T(), "test_client", userSessions[0].ClientID)
+		assert.Equal(suite.T(), "1.2.3.4", userSessions[0].IP)
+	}
+}
+
+func (suite *SessionTestSuite) TestRedisStoreTamperedCookie() {
+	_, cookie := suite.saveRedisSession(nil, func(redisSession *sessions.Session) {
+		redisSession.Values["foo"] = "bar"
+	})
+	if !assert.NotNil(suite.T(), cookie) {
+		return
+	}
+
+	// A cookie that fails to decode starts a new session
+	cookie.Value += "x"
+	loaded := suite.loadRedisSession(cookie)
+	assert.True(suite.T(), loaded.IsNew)
+	assert.Empty(suite.T(), loaded.Values)
+}
+
+func (suite *SessionTestSuite) TestRedisStoreLoginChangesSessionID() {
+	anonymous, cookie := suite.saveRedisSession(nil, func(redisSession *sessions.Session) {
+		redisSession.Values["foo"] = "bar"
+	})
+	if !assert.NotNil(suite.T(), cookie) {
+		return
+	}
+
+	// Logging in gets a new session ID, the anonymous session is gone
+	loggedIn, cookie := suite.saveRedisSession(cookie, func(redisSession *sessions.Session) {
+		redisSession.Values[session.SSOSessionKey] = &session.SSOSession{
+			ID:     "test_sso_session",
+			UserID: "test_user_id",
+		}
+	})
+	assert.NotEqual(suite.T(), anonymous.ID, loggedIn.ID)
+	assert.False(suite.T(), suite.redis.Exists("session:"+anonymous.ID))
+	assert.True(suite.T(), suite.redis.Exists("session:"+loggedIn.ID))
+
+	// Later saves by the same user keep the ID
+	again, _ := suite.saveRedisSession(cookie, func(redisSession *sessions.Session) {
+		redisSession.Values["foo"] = "baz"
+	})
+	assert.Equal(suite.T(), loggedIn.ID, again.ID)
+}
+
+func (suite *SessionTestSuite) TestRedisStoreTimeouts() {
+	_, cookie := suite.saveRedisSession(nil, func(redisSession *sessions.Session) {
+		redisSession.Values["foo"] = "bar"
+	})
+	if !assert.NotNil(suite.T(), cookie) {
+		return
+	}
+
+	// Using the session keeps it alive past the idle timeout
+	idleTimeout := time.Duration(suite.cnf.Session.IdleTimeout) * time.Second
+	suite.redis.FastForward(idleTimeout - time.Minute)
+	assert.False(suite.T(), suite.loadRedisSession(cookie).IsNew)
+	suite.redis.FastForward(idleTimeout - time.Minute)
+	assert.False(suite.T(), suite.loadRedisSession(cookie).IsNew)
+
+	// Unused sessions end after the idle timeout
+	suite.redis.FastForward(idleTimeout + time.Second)
+	assert.True(suite.T(), suite.loadRedisSession(cookie).IsNew)
+
+	// Sessions end after the absolute timeout however often they are used
+	redisSession, cookie := suite.saveRedisSession(nil, func(redisSession *sessions.Session) {
+		redisSession.Values["foo"] = "bar"
+	})
+	absoluteTimeout := time.Duration(suite.cnf.Session.AbsoluteTimeout) * time.Second
+	suite.redis.HSet(
+		"session:"+redisSession.ID,
+		"created_at",
+		time.Now().UTC().Add(-absoluteTimeout).Format(time.RFC3339),
+	)
+	assert.True(suite.T(), suite.loadRedisSession(cookie).IsNew)
+	assert.False(suite.T(), suite.redis.Exists("session:"+redisSession.ID))
+}
+
+func (suite *SessionTestSuite) TestRedisStoreRevokeSessions() {
+	var sessionIDs []string
+	var cookies []*http.Cookie
+	for i := 0; i < 2; i++ {
+		redisSession, cookie := suite.saveRedisSession(nil, func(redisSession *sessions.Session) {
+			redisSession.Values[session.UserSessionKey] = &session.UserSession{UserID: "test_user_id"}
+		})
+		if !assert.NotNil(suite.T(), cookie) {
+			return
+		}
+		sessionIDs = append(sessionIDs, redisSession.ID)
+		cookies = append(cookies, cookie)
+	}
+
+	// Users can only revoke their own sessions
+	err := suite.store.RevokeSession("bogus_user_id", sessionIDs[0])
+	assert.Equal(suite.T(), session.ErrSessionNotFound, err)
+
+	// A revoked session cannot be used anymore
+	assert.NoError(suite.T(), suite.store.RevokeSession("test_user_id", sessionIDs[0]))
+	assert.True(suite.T(), suite.loadRedisSession(cookies[0]).IsNew)
+	userSessions, err := suite.store.ListUserSessions("test_user_id")
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), userSessions, 1)
+
+	// Revoking all sessions of the user ends the rest
+	assert.NoError(suite.T(), suite.store.RevokeUserSessions("test_user_id"))
+	assert.True(suite.T(), suite.loadRedisSession(cookies[1]).IsNew)
+	userSessions, err = suite.store.ListUserSessions("test_user_id")
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), userSessions)
+}
+
+func (suite *SessionTestSuite) TestRedisStoreDeleteSession() {
+	redisSession, cookie := suite.saveRedisSession(nil, func(redisSession *sessions.Session) {
+		redisSession.Values[session.UserSessionKey] = &session.UserSession{UserID: "test_user_id"}
+	})
+	if !assert.NotNil(suite.T(), cookie) {
+		return
+	}
+
+	// A negative MaxAge deletes the session and its cookie
+	_, deleted := suite.saveRedisSession(cookie, func(redisSession *sessions.Session) {
+		redisSession.Options.MaxAge = -1
+	})
+	if assert.NotNil(suite.T(), deleted) {
+		assert.Empty(suite.T(), deleted.Value)
+	}
+	assert.False(suite.T(), suite.redis.Exists("session:"+redisSession.ID))
+	userSessions, err := suite.store.ListUserSessions("test_user_id")
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), userSessions)
+}
+
+// loadRedisSession loads the session of a cookie from the Redis store
+func (suite *SessionTestSuite) loadRedisSession(cookie *http.Cookie) *sessions.Session {
+	r := suite.newRedisSessionRequest(cookie)
+	redisSession, err := suite.store.Get(r, session.StorageSessionName)
+	assert.NoError(suite.T(), err)
+	return redisSession
+}
+
+// saveRedisSession loads the session of a cookie from the Redis store,
+// updates and saves it, and returns it with the cookie that was set
+func (suite *SessionTestSuite) saveRedisSession(cookie *http.Cookie, update func(*sessions.Session)) (*sessions.Session, *http.Cookie) {
+	r := suite.newRedisSessionRequest(cookie)
+	redisSession, err := suite.store.Get(r, session.StorageSessionName)
+	assert.NoError(suite.T(), err)
+	update(redisSession)
+
+	w := httptest.NewRecorder()
+	assert.NoError(suite.T(), suite.store.Save(r, w, redisSession))
+	for _, setCookie := range w.Result().Cookies() {
+		if setCookie.Name == session.StorageSessionName {
+			return redisSession, setCookie
+		}
+	}
+	return redisSession, nil
+}
+
+// newRedisSessionRequest returns a request carrying the session cookie
+func (suite *SessionTestSuite) newRedisSessionRequest(cookie *http.Cookie) *http.Request {
+	r, err := http.NewRequest("GET", "http://1.2.3.4/foo/bar", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.RemoteAddr = "1.2.3.4:1234"
+	if cookie != nil {
+		r.AddCookie(cookie)
+	}
+	return r
+}
@@ -1,11 +1,15 @@
 package session
 
 import (
+	"crypto/subtle"
 	"encoding/gob"
 	"errors"
 	"net/http"
+	"net/url"
+	"time"
 
 	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/go-redis/redis/v7"
 	"github.com/gorilla/sessions"
 )
 
@@ -20,6 +24,7 @@ type Service struct {
 
 // UserSession has user data stored in a session after logging in
 type UserSession struct {
+	UserID       string
 	ClientID     string
 	Account      string
 	TenantID     string
@@ -27,18 +32,52 @@ type UserSession struct {
 	RefreshToken string
 }
 
+// SSOSession is the login of a user shared by all clients of the user's
+// tenant, so authorization requests of other clients can skip logging in
+type SSOSession struct {
+	// ID is the sid of the session, set when the session is first saved
+	ID       string
+	UserID   string
+	TenantID string
+	// AuthTime is when the user last actively authenticated
+	AuthTime time.Time
+	// AMR lists the authentication methods used, like pwd, otp or sms
+	AMR []string
+}
+
 var (
 	// StorageSessionName ...
 	StorageSessionName = "go_oauth2_server_session"
 	// UserSessionKey ...
 	UserSessionKey = "go_oauth2_server_user"
+	// SSOSessionKey ...
+	SSOSessionKey = "go_oauth2_server_sso"
+	// CSRFTokenKey ...
+	CSRFTokenKey = "go_oauth2_server_csrf"
+	// AuthorizationRequestKey ...
+	AuthorizationRequestKey = "go_oauth2_server_authorization_request"
 	// ErrSessonNotStarted ...
 	ErrSessonNotStarted = errors.New("Session not started")
+	// ErrSessionNotFound ...
+	ErrSessionNotFound = errors.New("Session not found")
+	// ErrSessionManagementNotSupported ...
+	ErrSessionManagementNotSupported = errors.New("Session store cannot list or revoke sessions")
 )
 
+// ManagedStore is a session store able to list and revoke the sessions of
+// a user, which cookie-only stores cannot do
+type ManagedStore interface {
+	sessions.Store
+	ListUserSessions(userID string) ([]*SessionInfo, error)
+	RevokeSession(userID, sessionID string) error
+	RevokeUserSessions(userID string) error
+}
+
 func init() {
 	// Register a new datatype for storage in sessions
 	gob.Register(new(UserSession))
+	gob.Register(new(SSOSession))
+	gob.Register(url.Values{})
 }
 
 // NewService returns a new Service instance
@@ -55,6 +94,15 @@ func NewService(cnf *config.Config, sessionStore sessions.Store) *Service {
 	}
 }
 
+// NewStore returns the session store configured by Session.Store, Redis
+// unless the cookie store is asked for
+func NewStore(cnf *config.Config, redisClient *redis.Client) sessions.Store {
+	if cnf.Session.Store == "cookie" {
+		return sessions.NewCookieStore([]byte(cnf.Session.Secret))
+	}
+	return NewRedisStore(cnf, redisClient)
+}
+
 // SetSessionService sets the request and responseWriter on the session service
 func (s *Service) SetSessionService(r *http.Request, w http.ResponseWriter) {
 	s.r = r
@@ -112,6 +160,111 @@ func (s *Service) ClearUserSession() error {
 	return s.session.Save(s.r, s.w)
 }
 
+// GetSSOSession returns the SSO session, nil if the user has not logged in
+func (s *Service) GetSSOSession() (*SSOSession, error) {
+	// Make sure StartSession has been called
+	if s.session == nil {
+		return nil, ErrSessonNotStarted
+	}
+
+	ssoSession, _ := s.session.Values[SSOSessionKey].(*SSOSession)
+	return ssoSession, nil
+}
+
+// SetSSOSession saves the SSO session after the user authenticated
+func (s *Service) SetSSOSession(ssoSession *SSOSession) error {
+	// Make sure StartSession has been called
+	if s.session == nil {
+		return ErrSessonNotStarted
+	}
+
+	if ssoSession.ID == "" {
+		sessionID, err := newSessionID()
+		if err != nil {
+			return err
+		}
+		ssoSession.ID = sessionID
+	}
+
+	s.session.Values[SSOSessionKey] = ssoSession
+	return s.session.Save(s.r, s.w)
+}
+
+// ClearSSOSession deletes the SSO session, logging the user out of all
+// clients using it
+func (s *Service) ClearSSOSession() error {
+	// Make sure StartSession has been called
+	if s.session == nil {
+		return ErrSessonNotStarted
+	}
+
+	delete(s.session.Values, SSOSessionKey)
+	return s.session.Save(s.r, s.w)
+}
+
+// GetCSRFToken returns the token forms of the session must be posted with,
+// generating it the first time
+func (s *Service) GetCSRFToken() (string, error) {
+	// Make sure StartSession has been called
+	if s.session == nil {
+		return "", ErrSessonNotStarted
+	}
+
+	if csrfToken, ok := s.session.Values[CSRFTokenKey].(string); ok && csrfToken != "" {
+		return csrfToken, nil
+	}
+	csrfToken, err := newSessionID()
+	if err != nil {
+		return "", err
+	}
+	s.session.Values[CSRFTokenKey] = csrfToken
+	return csrfToken, s.session.Save(s.r, s.w)
+}
+
+// CheckCSRFToken returns true if a posted token is the session's
+func (s *Service) CheckCSRFToken(csrfToken string) bool {
+	if s.session == nil || csrfToken == "" {
+		return false
+	}
+	expected, _ := s.session.Values[CSRFTokenKey].(string)
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(csrfToken)) == 1
+}
+
+// GetAuthorizationRequest returns the parameters of the authorization
+// request waiting for the user to log in or consent, nil if there is none
+func (s *Service) GetAuthorizationRequest() (url.Values, error) {
+	// Make sure StartSession has been called
+	if s.session == nil {
+		return nil, ErrSessonNotStarted
+	}
+
+	values, _ := s.session.Values[AuthorizationRequestKey].(url.Values)
+	return values, nil
+}
+
+// SetAuthorizationRequest saves the parameters of an authorization request
+// while the user logs in or consents
+func (s *Service) SetAuthorizationRequest(values url.Values) error {
+	// Make sure StartSession has been called
+	if s.session == nil {
+		return ErrSessonNotStarted
+	}
+
+	s.session.Values[AuthorizationRequestKey] = values
+	return s.session.Save(s.r, s.w)
+}
+
+// ClearAuthorizationRequest deletes the waiting authorization request
+func (s *Service) ClearAuthorizationRequest() error {
+	// Make sure StartSession has been called
+	if s.session == nil {
+		return ErrSessonNotStarted
+	}
+
+	delete(s.session.Values, AuthorizationRequestKey)
+	return s.session.Save(s.r, s.w)
+}
+
 // SetFlashMessage sets a flash message,
 // useful for displaying an error after 302 redirection
 func (s *Service) SetFlashMessage(msg string) error {
@@ -143,5 +296,32 @@ func (s *Service) GetFlashMessage() (interface{}, error) {
 	return nil, nil
 }
 
+// ListUserSessions returns the active sessions of a user
+func (s *Service) ListUserSessions(userID string) ([]*SessionInfo, error) {
+	store, ok := s.sessionStore.(ManagedStore)
+	if !ok {
+		return nil, ErrSessionManagementNotSupported
+	}
+	return store.ListUserSessions(userID)
+}
+
+// RevokeSession ends a session of a user
+func (s *Service) RevokeSession(userID, sessionID string) error {
+	store, ok := s.sessionStore.(ManagedStore)
+	if !ok {
+		return ErrSessionManagementNotSupported
+	}
+	return store.RevokeSession(userID, sessionID)
+}
+
+// RevokeUserSessions ends all sessions of a user
+func (s *Service) RevokeUserSessions(userID string) error {
+	store, ok := s.sessionStore.(ManagedStore)
+	if !ok {
+		return ErrSessionManagementNotSupported
+	}
+	return store.RevokeUserSessions(userID)
+}
+
 // Close stops any running services
 func (s *Service) Close() {}
@@ -1,6 +1,9 @@
 package session
 
-import "net/http"
+import (
+	"net/http"
+	"net/url"
+)
 
 // ServiceInterface defines exported methods
 type ServiceInterface interface {
@@ -9,7 +12,18 @@ type ServiceInterface interface {
 	GetUserSession() (*UserSession, error)
 	SetUserSession(userSession *UserSession) error
 	ClearUserSession() error
+	GetSSOSession() (*SSOSession, error)
+	SetSSOSession(ssoSession *SSOSession) error
+	ClearSSOSession() error
+	GetCSRFToken() (string, error)
+	CheckCSRFToken(csrfToken string) bool
+	GetAuthorizationRequest() (url.Values, error)
+	SetAuthorizationRequest(values url.Values) error
+	ClearAuthorizationRequest() error
 	SetFlashMessage(msg string) error
 	GetFlashMessage() (interface{}, error)
+	ListUserSessions(userID string) ([]*SessionInfo, error)
+	RevokeSession(userID, sessionID string) error
+	RevokeUserSessions(userID string) error
 	Close()
 }
@@ -1,6 +1,8 @@
 package session_test
 
 import (
+	"net/url"
+
 	"github.com/RichardKnop/go-oauth2-server/session"
 	"github.com/stretchr/testify/assert"
 )
@@ -36,7 +38,7 @@ func (suite *SessionTestSuite) TestService() {
 	// Let's set the user session now
 	suite.service.SetUserSession(&session.UserSession{
 		ClientID:     "test_client",
-		Username:     "test@username",
+		Account:      "test@username",
 		AccessToken:  "test_access_token",
 		RefreshToken: "test_refresh_token",
 	})
@@ -46,7 +48,7 @@ func (suite *SessionTestSuite) TestService() {
 	assert.Nil(suite.T(), err)
 	if assert.NotNil(suite.T(), userSession) {
 		assert.Equal(suite.T(), "test_client", userSession.ClientID)
-		assert.Equal(suite.T(), "test@username", userSession.Username)
+		assert.Equal(suite.T(), "test@username", userSession.Account)
 		assert.Equal(suite.T(), "test_access_token", userSession.AccessToken)
 		assert.Equal(suite.T(), "test_refresh_token", userSession.RefreshToken)
 	}
@@ -62,3 +64,59 @@ func (suite *SessionTestSuite) TestService() {
 		assert.Equal(suite.T(), "User session type assertion error", err.Error())
 	}
 }
+
+func (suite *SessionTestSuite) TestCookieStoreSessionManagement() {
+	// Sessions kept in cookies cannot be listed or revoked server-side
+	userSessions, err := suite.service.ListUserSessions("test_user_id")
+	assert.Nil(suite.T(), userSessions)
+	assert.Equal(suite.T(), session.ErrSessionManagementNotSupported, err)
+
+	err = suite.service.RevokeSession("test_user_id", "test_session_id")
+	assert.Equal(suite.T(), session.ErrSessionManagementNotSupported, err)
+
+	err = suite.service.RevokeUserSessions("test_user_id")
+	assert.Equal(suite.T(), session.ErrSessionManagementNotSupported, err)
+}
+
+func (suite *SessionTestSuite) TestCSRFToken() {
+	err := suite.service.StartSession()
+	assert.Nil(suite.T(), err)
+
+	// The token is generated once per session
+	csrfToken, err := suite.service.GetCSRFToken()
+	assert.Nil(suite.T(), err)
+	assert.NotEmpty(suite.T(), csrfToken)
+	again, err := suite.service.GetCSRFToken()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), csrfToken, again)
+
+	assert.True(suite.T(), suite.service.CheckCSRFToken(csrfToken))
+	assert.False(suite.T(), suite.service.CheckCSRFToken(""))
+	assert.False(suite.T(), suite.service.CheckCSRFToken("bogus"))
+}
+
+func (suite *SessionTestSuite) TestAuthorizationRequest() {
+	err := suite.service.StartSession()
+	assert.Nil(suite.T(), err)
+
+	values, err := suite.service.GetAuthorizationRequest()
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), values)
+
+	err = suite.service.SetAuthorizationRequest(url.Values{
+		"client_id": {"test_client_1"},
+		"state":     {"somestate"},
+	})
+	assert.Nil(suite.T(), err)
+
+	values, err = suite.service.GetAuthorizationRequest()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "test_client_1", values.Get("client_id"))
+	assert.Equal(suite.T(), "somestate", values.Get("state"))
+
+	err = suite.service.ClearAuthorizationRequest()
+	assert.Nil(suite.T(), err)
+	values, err = suite.service.GetAuthorizationRequest()
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), values)
+}
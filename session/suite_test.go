@@ -7,6 +7,8 @@ import (
 
 	"github.com/RichardKnop/go-oauth2-server/config"
 	"github.com/RichardKnop/go-oauth2-server/session"
+	"github.com/alicebob/miniredis"
+	"github.com/go-redis/redis/v7"
 	"github.com/gorilla/sessions"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
@@ -17,6 +19,8 @@ type SessionTestSuite struct {
 	suite.Suite
 	cnf     *config.Config
 	service *session.Service
+	redis   *miniredis.Miniredis
+	store   *session.RedisStore
 }
 
 // The SetupSuite method will be run by testify once, at the very
@@ -28,34 +32,40 @@ func (suite *SessionTestSuite) SetupSuite() {
 	session.StorageSessionName = "test_session"
 	session.UserSessionKey = "test_user"
 
-	// Initialise the service
-	r, err := http.NewRequest("GET", "http://1.2.3.4/foo/bar", nil)
-	assert.NoError(suite.T(), err, "Request setup should not get an error")
-	w := httptest.NewRecorder()
-
-	suite.service = session.NewService(suite.cnf, sessions.NewCookieStore([]byte(suite.cnf.Session.Secret)))
-	suite.service.SetSessionService(r, w)
+	// The Redis store is tested against an in-memory Redis
+	var err error
+	suite.redis, err = miniredis.Run()
+	assert.NoError(suite.T(), err, "Redis setup should not get an error")
+	suite.store = session.NewRedisStore(suite.cnf, redis.NewClient(&redis.Options{
+		Addr: suite.redis.Addr(),
+	}))
 }
 
 // The TearDownSuite method will be run by testify once, at the very
 // end of the testing suite, after all tests have been run.
 func (suite *SessionTestSuite) TearDownSuite() {
-	//
+	suite.redis.Close()
 }
 
 // The SetupTest method will be run before every test in the suite.
 func (suite *SessionTestSuite) SetupTest() {
-	//
+	// Initialise the service, every test starts without a session
+	r, err := http.NewRequest("GET", "http://1.2.3.4/foo/bar", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	w := httptest.NewRecorder()
+
+	suite.service = session.NewService(suite.cnf, sessions.NewCookieStore([]byte(suite.cnf.Session.Secret)))
+	suite.service.SetSessionService(r, w)
 }
 
 // The TearDownTest method will be run after every test in the suite.
 func (suite *SessionTestSuite) TearDownTest() {
-	//
+	suite.redis.FlushAll()
 }
 
-// TesSessionTestSuite ...
+// TestSessionTestSuite ...
 // In order for 'go test' to run this suite, we need to create
 // a normal test function and pass our suite to suite.Run
-func TesSessionTestSuite(t *testing.T) {
+func TestSessionTestSuite(t *testing.T) {
 	suite.Run(t, new(SessionTestSuite))
 }
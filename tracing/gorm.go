@@ -0,0 +1,97 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// contextKey is the gorm setting holding the context of the queries
+	contextKey = "tracing:context"
+	// spanKey is the gorm setting holding the span of the running query
+	spanKey = "tracing:span"
+)
+
+// dbSystems maps gorm dialects to the OpenTelemetry database system names
+var dbSystems = map[string]string{
+	"postgres": "postgresql",
+	"mysql":    "mysql",
+	"sqlite3":  "sqlite",
+}
+
+// WithContext returns a database handle whose queries are traced as
+// children of the span in ctx, transactions begun from it inherit the context
+func WithContext(db *gorm.DB, ctx context.Context) *gorm.DB {
+	return db.Set(contextKey, ctx)
+}
+
+// InstrumentDatabase registers gorm callbacks creating a span for every
+// query run through a handle returned by WithContext
+func InstrumentDatabase(db *gorm.DB) {
+	callback := db.Callback()
+
+	callback.Create().Before("gorm:create").Register("tracing:before_create", startQuery("INSERT"))
+	callback.Create().After("gorm:create").Register("tracing:after_create", endQuery)
+
+	callback.Query().Before("gorm:query").Register("tracing:before_query", startQuery("SELECT"))
+	callback.Query().After("gorm:query").Register("tracing:after_query", endQuery)
+
+	callback.RowQuery().Before("gorm:row_query").Register("tracing:before_row_query", startQuery("SELECT"))
+	callback.RowQuery().After("gorm:row_query").Register("tracing:after_row_query", endQuery)
+
+	callback.Update().Before("gorm:update").Register("tracing:before_update", startQuery("UPDATE"))
+	callback.Update().After("gorm:update").Register("tracing:after_update", endQuery)
+
+	callback.Delete().Before("gorm:delete").Register("tracing:before_delete", startQuery("DELETE"))
+	callback.Delete().After("gorm:delete").Register("tracing:after_delete", endQuery)
+}
+
+func startQuery(operation string) func(scope *gorm.Scope) {
+	return func(scope *gorm.Scope) {
+		value, ok := scope.Get(contextKey)
+		if !ok {
+			return
+		}
+		ctx, _ := value.(context.Context)
+		if !hasParent(ctx) {
+			return
+		}
+
+		table := scope.TableName()
+		_, span := tracer().Start(
+			ctx,
+			operation+" "+table,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemKey.String(dbSystems[scope.Dialect().GetName()]),
+				semconv.DBOperationKey.String(operation),
+				semconv.DBSQLTableKey.String(table),
+			),
+		)
+		scope.Set(spanKey, span)
+	}
+}
+
+func endQuery(scope *gorm.Scope) {
+	value, ok := scope.Get(spanKey)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+
+	// The statement has placeholders in place of the values
+	span.SetAttributes(
+		semconv.DBStatementKey.String(scope.SQL),
+		attribute.Int64("db.rows_affected", scope.DB().RowsAffected),
+	)
+	if err := scope.DB().Error; err != nil && !gorm.IsRecordNotFoundError(err) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
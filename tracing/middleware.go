@@ -0,0 +1,38 @@
+package tracing
+
+import (
+	"net/http"
+
+	"github.com/urfave/negroni"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware is a negroni middleware starting a server span for every
+// request. It continues the trace of the W3C traceparent header, the span
+// is passed on in the request's context
+func Middleware(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx, span := tracer().Start(
+		ctx,
+		"HTTP "+r.Method,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.HTTPMethodKey.String(r.Method),
+			semconv.HTTPTargetKey.String(r.URL.Path),
+		),
+	)
+	defer span.End()
+
+	next(rw, r.WithContext(ctx))
+
+	// Handlers which write nothing respond with 200
+	status := http.StatusOK
+	if res, ok := rw.(negroni.ResponseWriter); ok && res.Status() != 0 {
+		status = res.Status()
+	}
+	span.SetAttributes(semconv.HTTPStatusCodeKey.Int(status))
+	span.SetStatus(semconv.SpanStatusFromHTTPStatusCodeAndSpanKind(status, trace.SpanKindServer))
+}
@@ -0,0 +1,87 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/go-redis/redis/v7"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentRedis adds a hook to the client creating a span for every
+// command or pipeline sent with a context carrying a span, see
+// redis.Client.WithContext. Only the command names are recorded, the
+// arguments hold tokens and codes
+func InstrumentRedis(client *redis.Client) {
+	client.AddHook(redisHook{})
+}
+
+type redisHook struct{}
+
+// BeforeProcess starts the span of a command
+func (redisHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	if !hasParent(ctx) {
+		return ctx, nil
+	}
+	ctx, _ = tracer().Start(
+		ctx,
+		"redis "+cmd.Name(),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemRedis,
+			semconv.DBOperationKey.String(cmd.Name()),
+		),
+	)
+	return ctx, nil
+}
+
+// AfterProcess ends the span of a command
+func (redisHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	endCommands(ctx, []redis.Cmder{cmd})
+	return nil
+}
+
+// BeforeProcessPipeline starts the span of a pipeline
+func (redisHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	if !hasParent(ctx) {
+		return ctx, nil
+	}
+	names := make([]string, len(cmds))
+	for i, cmd := range cmds {
+		names[i] = cmd.Name()
+	}
+	ctx, _ = tracer().Start(
+		ctx,
+		"redis pipeline",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemRedis,
+			attribute.StringSlice("db.redis.commands", names),
+		),
+	)
+	return ctx, nil
+}
+
+// AfterProcessPipeline ends the span of a pipeline
+func (redisHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	endCommands(ctx, cmds)
+	return nil
+}
+
+// endCommands ends the span started by the hook, a missing key is not an error
+func endCommands(ctx context.Context, cmds []redis.Cmder) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	for _, cmd := range cmds {
+		if err := cmd.Err(); err != nil && err != redis.Nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			break
+		}
+	}
+	span.End()
+}
@@ -0,0 +1,87 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+)
+
+// shutdownTimeout bounds how long Close waits for the last spans to export
+const shutdownTimeout = 5 * time.Second
+
+var logger = log.New("tracing")
+
+// Service exports the spans of the server
+type Service struct {
+	cnf      *config.Config
+	provider *sdktrace.TracerProvider
+}
+
+// NewService returns a new Service instance. It installs the global tracer
+// provider and the W3C trace context propagator, with no exporter
+// configured the spans are dropped
+func NewService(cnf *config.Config) *Service {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	s := &Service{cnf: cnf}
+	if cnf.Tracing.Exporter == "" {
+		return s
+	}
+
+	exporter, err := newExporter(cnf)
+	if err != nil {
+		logger.Errorf("Creating the trace exporter failed: %s", err)
+		return s
+	}
+	s.provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceNameKey.String(cnf.Tracing.ServiceName),
+		)),
+		sdktrace.WithSampler(sdktrace.ParentBased(
+			sdktrace.TraceIDRatioBased(cnf.Tracing.SampleRatio),
+		)),
+	)
+	otel.SetTracerProvider(s.provider)
+	return s
+}
+
+// Close exports the remaining spans
+func (s *Service) Close() {
+	if s.provider == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := s.provider.Shutdown(ctx); err != nil {
+		logger.Errorf("Shutting down the tracer provider failed: %s", err)
+	}
+}
+
+func newExporter(cnf *config.Config) (sdktrace.SpanExporter, error) {
+	switch cnf.Tracing.Exporter {
+	case "otlp":
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cnf.Tracing.Endpoint)}
+		if cnf.Tracing.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(context.Background(), opts...)
+	case "stdout":
+		return newStdoutExporter(os.Stdout), nil
+	}
+	return nil, fmt.Errorf("unknown trace exporter %q", cnf.Tracing.Exporter)
+}
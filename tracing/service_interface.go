@@ -0,0 +1,7 @@
+package tracing
+
+// ServiceInterface defines exported methods
+type ServiceInterface interface {
+	// Exported methods
+	Close()
+}
@@ -0,0 +1,72 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// stdoutSpan is a finished span as written by the stdout exporter
+type stdoutSpan struct {
+	Name         string                 `json:"name"`
+	Kind         string                 `json:"kind"`
+	TraceID      string                 `json:"trace_id"`
+	SpanID       string                 `json:"span_id"`
+	ParentSpanID string                 `json:"parent_span_id,omitempty"`
+	Start        time.Time              `json:"start"`
+	Duration     string                 `json:"duration"`
+	Attributes   map[string]interface{} `json:"attributes,omitempty"`
+	Status       string                 `json:"status"`
+	Error        string                 `json:"error,omitempty"`
+}
+
+// stdoutExporter writes finished spans as JSON lines, for local use
+type stdoutExporter struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+func newStdoutExporter(w io.Writer) *stdoutExporter {
+	return &stdoutExporter{encoder: json.NewEncoder(w)}
+}
+
+// ExportSpans writes the spans
+func (e *stdoutExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, span := range spans {
+		out := &stdoutSpan{
+			Name:     span.Name(),
+			Kind:     span.SpanKind().String(),
+			TraceID:  span.SpanContext().TraceID().String(),
+			SpanID:   span.SpanContext().SpanID().String(),
+			Start:    span.StartTime(),
+			Duration: span.EndTime().Sub(span.StartTime()).String(),
+			Status:   span.Status().Code.String(),
+			Error:    span.Status().Description,
+		}
+		if span.Parent().IsValid() {
+			out.ParentSpanID = span.Parent().SpanID().String()
+		}
+		if attrs := span.Attributes(); len(attrs) > 0 {
+			out.Attributes = make(map[string]interface{}, len(attrs))
+			for _, attr := range attrs {
+				out.Attributes[string(attr.Key)] = attr.Value.AsInterface()
+			}
+		}
+		if err := e.encoder.Encode(out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Shutdown has nothing to release
+func (e *stdoutExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,175 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/go-redis/redis/v7"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/negroni"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	// Drivers
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// record installs a tracer provider recording the ended spans
+func record() *tracetest.SpanRecorder {
+	NewService(new(config.Config))
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	return recorder
+}
+
+func attributeValue(span sdktrace.ReadOnlySpan, key attribute.Key) interface{} {
+	for _, attr := range span.Attributes() {
+		if attr.Key == key {
+			return attr.Value.AsInterface()
+		}
+	}
+	return nil
+}
+
+type widget struct {
+	ID   int
+	Name string
+}
+
+func TestMiddleware(t *testing.T) {
+	recorder := record()
+
+	app := negroni.New()
+	app.Use(negroni.HandlerFunc(Middleware))
+	app.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, span := Start(r.Context(), "oauth.grant password")
+		End(span, nil)
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
+	if !assert.NoError(t, err, "Request setup should not get an error") {
+		return
+	}
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	app.ServeHTTP(httptest.NewRecorder(), r)
+
+	spans := recorder.Ended()
+	if !assert.Len(t, spans, 2) {
+		return
+	}
+	grant, server := spans[0], spans[1]
+
+	// The server span continues the trace of the caller
+	assert.Equal(t, "HTTP POST", server.Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+	assert.Equal(t, int64(http.StatusUnauthorized), attributeValue(server, "http.status_code"))
+	assert.Equal(t, "/v1/oauth/tokens", attributeValue(server, "http.target"))
+
+	// Spans of the handlers are its children
+	assert.Equal(t, "oauth.grant password", grant.Name())
+	assert.Equal(t, server.SpanContext().SpanID(), grant.Parent().SpanID())
+}
+
+func TestInstrumentDatabase(t *testing.T) {
+	recorder := record()
+
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+	assert.NoError(t, db.CreateTable(new(widget)).Error)
+	InstrumentDatabase(db)
+
+	ctx, parent := Start(context.Background(), "oauth.authenticate")
+	traced := WithContext(db, ctx)
+	assert.NoError(t, traced.Create(&widget{ID: 1, Name: "foo"}).Error)
+	assert.True(t, traced.Where("name = ?", "bar").First(new(widget)).RecordNotFound())
+	assert.NoError(t, traced.Model(new(widget)).Where("id = ?", 1).UpdateColumn("name", "bar").Error)
+	End(parent, nil)
+
+	// Queries without the context of a request are not traced
+	assert.NoError(t, db.Where("id = ?", 1).First(new(widget)).Error)
+
+	spans := recorder.Ended()
+	if !assert.Len(t, spans, 4) {
+		return
+	}
+	insert, selectQuery, update := spans[0], spans[1], spans[2]
+
+	assert.Equal(t, "INSERT widgets", insert.Name())
+	assert.Equal(t, "SELECT widgets", selectQuery.Name())
+	assert.Equal(t, "UPDATE widgets", update.Name())
+	for _, span := range []sdktrace.ReadOnlySpan{insert, selectQuery, update} {
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+		assert.Equal(t, "sqlite", attributeValue(span, "db.system"))
+		assert.Equal(t, "widgets", attributeValue(span, "db.sql.table"))
+		// A record which is not found is not an error
+		assert.Equal(t, codes.Unset, span.Status().Code)
+	}
+	assert.Contains(t, attributeValue(selectQuery, "db.statement"), `WHERE (name = ?)`)
+	assert.Equal(t, int64(1), attributeValue(update, "db.rows_affected"))
+}
+
+func TestInstrumentRedis(t *testing.T) {
+	recorder := record()
+
+	// Nothing listens on the port so the commands fail
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:0"})
+	defer client.Close()
+	InstrumentRedis(client)
+
+	ctx, parent := Start(context.Background(), "oauth.grant password")
+	assert.Error(t, client.WithContext(ctx).Get("secret_token").Err())
+	End(parent, nil)
+
+	// Commands without the context of a request are not traced
+	assert.Error(t, client.Get("secret_token").Err())
+
+	spans := recorder.Ended()
+	if !assert.Len(t, spans, 2) {
+		return
+	}
+	command := spans[0]
+	assert.Equal(t, "redis get", command.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), command.Parent().SpanID())
+	assert.Equal(t, codes.Error, command.Status().Code)
+
+	// The arguments are not recorded
+	for _, attr := range command.Attributes() {
+		assert.NotContains(t, attr.Value.Emit(), "secret_token")
+	}
+}
+
+func TestStdoutExporter(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	_, span := provider.Tracer("test").Start(context.Background(), "oauth.grant password")
+	span.SetAttributes(attribute.String("oauth.grant_type", "password"))
+	span.End()
+
+	var buf bytes.Buffer
+	exporter := newStdoutExporter(&buf)
+	if !assert.NoError(t, exporter.ExportSpans(context.Background(), recorder.Ended())) {
+		return
+	}
+
+	out := new(stdoutSpan)
+	if assert.NoError(t, json.Unmarshal(buf.Bytes(), out)) {
+		assert.Equal(t, "oauth.grant password", out.Name)
+		assert.Equal(t, span.SpanContext().TraceID().String(), out.TraceID)
+		assert.Empty(t, out.ParentSpanID)
+		assert.Equal(t, "password", out.Attributes["oauth.grant_type"])
+	}
+}
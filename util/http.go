@@ -3,6 +3,7 @@ package util
 import (
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"strings"
 )
@@ -19,6 +20,111 @@ func ParseBearerToken(r *http.Request) ([]byte, error) {
 	return []byte(bearerToken), nil
 }
 
+// ParseDPoPToken parses DPoP token from Authorization header
+func ParseDPoPToken(r *http.Request) ([]byte, error) {
+	auth := r.Header.Get("Authorization")
+
+	if !strings.HasPrefix(auth, "DPoP ") {
+		return nil, errors.New("DPoP token not found")
+	}
+
+	dpopToken := strings.TrimPrefix(auth, "DPoP ")
+	return []byte(dpopToken), nil
+}
+
+// GetRequestURI returns the absolute request URL without query string, the
+// scheme is taken from X-Forwarded-Proto only when the peer is a trusted proxy
+func GetRequestURI(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if isTrustedProxy(net.ParseIP(remoteIP(r))) {
+		switch proto := strings.ToLower(r.Header.Get("X-Forwarded-Proto")); proto {
+		case "http", "https":
+			scheme = proto
+		}
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, r.Host, r.URL.EscapedPath())
+}
+
+// trustedProxies are the networks whose X-Forwarded-For header is trusted,
+// set with SetTrustedProxies before serving requests
+var trustedProxies []*net.IPNet
+
+// SetTrustedProxies sets the reverse proxies GetClientIP trusts, as IPs or
+// CIDR networks
+func SetTrustedProxies(proxies []string) error {
+	networks := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				bits = 8 * net.IPv4len
+			}
+			proxy = fmt.Sprintf("%s/%d", proxy, bits)
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy %q", proxy)
+		}
+		networks = append(networks, network)
+	}
+	trustedProxies = networks
+	return nil
+}
+
+// isTrustedProxy returns whether an IP belongs to a trusted proxy
+func isTrustedProxy(ip net.IP) bool {
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetClientIP returns the IP of the client. Only when the peer is a trusted
+// proxy is X-Forwarded-For read, from the right as proxies append to it, and
+// the first address which is not a trusted proxy is the client's, so
+// clients cannot choose their IP by sending the header themselves
+func GetClientIP(r *http.Request) string {
+	ip := remoteIP(r)
+	if !isTrustedProxy(net.ParseIP(ip)) {
+		return ip
+	}
+
+	hops := strings.Split(strings.Join(r.Header["X-Forwarded-For"], ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			break
+		}
+		ip = hop.String()
+		if !isTrustedProxy(hop) {
+			break
+		}
+	}
+	return ip
+}
+
+// remoteIP returns the IP of the peer of a request
+func remoteIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
 // GetCurrentURL returns the current request URL
 func GetCurrentURL(r *http.Request) string {
 	url := r.URL.Path
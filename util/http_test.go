@@ -39,3 +39,69 @@ func TestParseBearerToken(t *testing.T) {
 		assert.Equal(t, []byte("test_token"), token)
 	}
 }
+
+func TestParseDPoPToken(t *testing.T) {
+	r, err := http.NewRequest("GET", "http://1.2.3.4/something", nil)
+	assert.NoError(t, err, "Request setup should not get an error")
+
+	// Bearer tokens are not DPoP tokens
+	r.Header.Set("Authorization", "Bearer test_token")
+	_, err = util.ParseDPoPToken(r)
+	assert.NotNil(t, err)
+
+	r.Header.Set("Authorization", "DPoP test_token")
+	token, err := util.ParseDPoPToken(r)
+	if assert.Nil(t, err) {
+		assert.Equal(t, []byte("test_token"), token)
+	}
+}
+
+func TestGetRequestURI(t *testing.T) {
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/token?foo=bar", nil)
+	assert.NoError(t, err, "Request setup should not get an error")
+	assert.Equal(t, "http://1.2.3.4/v1/oauth/token", util.GetRequestURI(r))
+
+	// X-Forwarded-Proto is ignored unless the peer is a trusted proxy
+	r.RemoteAddr = "10.0.0.1:54321"
+	r.Header.Set("X-Forwarded-Proto", "https")
+	assert.Equal(t, "http://1.2.3.4/v1/oauth/token", util.GetRequestURI(r))
+
+	assert.NoError(t, util.SetTrustedProxies([]string{"10.0.0.0/24"}))
+	defer util.SetTrustedProxies(nil)
+	assert.Equal(t, "https://1.2.3.4/v1/oauth/token", util.GetRequestURI(r))
+
+	// Only http and https are taken
+	r.Header.Set("X-Forwarded-Proto", "javascript")
+	assert.Equal(t, "http://1.2.3.4/v1/oauth/token", util.GetRequestURI(r))
+}
+
+func TestGetClientIP(t *testing.T) {
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/token", nil)
+	assert.NoError(t, err, "Request setup should not get an error")
+	r.RemoteAddr = "10.0.0.1:54321"
+	assert.Equal(t, "10.0.0.1", util.GetClientIP(r))
+
+	// X-Forwarded-For is ignored unless the peer is a trusted proxy
+	r.Header.Set("X-Forwarded-For", "5.6.7.8")
+	assert.Equal(t, "10.0.0.1", util.GetClientIP(r))
+
+	assert.NoError(t, util.SetTrustedProxies([]string{"10.0.0.0/24", "192.168.1.1"}))
+	defer util.SetTrustedProxies(nil)
+	assert.Equal(t, "5.6.7.8", util.GetClientIP(r))
+
+	// The right-most address which is not a trusted proxy is the client's,
+	// the addresses left of it are sent by the client
+	r.Header.Set("X-Forwarded-For", "1.1.1.1, 5.6.7.8, 192.168.1.1, 10.0.0.2")
+	assert.Equal(t, "5.6.7.8", util.GetClientIP(r))
+
+	// Without a forwarded address the client is the proxy itself
+	r.Header.Del("X-Forwarded-For")
+	assert.Equal(t, "10.0.0.1", util.GetClientIP(r))
+
+	// A garbled address stops the search at the last trusted proxy
+	r.Header.Set("X-Forwarded-For", "5.6.7.8, bogus, 10.0.0.2")
+	assert.Equal(t, "10.0.0.2", util.GetClientIP(r))
+
+	assert.Error(t, util.SetTrustedProxies([]string{"bogus"}))
+	assert.Error(t, util.SetTrustedProxies([]string{"10.0.0.0/99"}))
+}
@@ -3,7 +3,6 @@ package migrations
 import (
 	"fmt"
 
-	"github.com/RichardKnop/go-oauth2-server/log"
 	"github.com/jinzhu/gorm"
 )
 
@@ -19,11 +18,11 @@ func Bootstrap(db *gorm.DB) error {
 	exists := nil == db.Where("name = ?", migrationName).First(migration).Error
 
 	if exists {
-		log.INFO.Printf("Skipping %s migration", migrationName)
+		logger.Infof("Skipping %s migration", migrationName)
 		return nil
 	}
 
-	log.INFO.Printf("Running %s migration", migrationName)
+	logger.Infof("Running %s migration", migrationName)
 
 	// Create migrations table
 	if err := db.CreateTable(new(Migration)).Error; err != nil {
@@ -7,6 +7,8 @@ import (
 	"github.com/jinzhu/gorm"
 )
 
+var logger = log.New("migrations")
+
 // MigrationStage ...
 type MigrationStage struct {
 	Name     string
@@ -36,12 +38,12 @@ func Migrate(db *gorm.DB, migrations []MigrationStage) error {
 // the specified database and logs any errors
 func MigrateAll(db *gorm.DB, migrationFunctions []func(*gorm.DB) error) {
 	if err := Bootstrap(db); err != nil {
-		log.ERROR.Print(err)
+		logger.Errorf("Bootstrapping migrations failed: %s", err)
 	}
 
 	for _, m := range migrationFunctions {
 		if err := m(db); err != nil {
-			log.ERROR.Print(err)
+			logger.Errorf("Migration failed: %s", err)
 		}
 	}
 }
@@ -52,9 +54,9 @@ func MigrationExists(db *gorm.DB, migrationName string) bool {
 	found := !db.Where("name = ?", migrationName).First(migration).RecordNotFound()
 
 	if found {
-		log.INFO.Printf("Skipping %s migration", migrationName)
+		logger.Infof("Skipping %s migration", migrationName)
 	} else {
-		log.INFO.Printf("Running %s migration", migrationName)
+		logger.Infof("Running %s migration", migrationName)
 	}
 
 	return found
@@ -66,7 +68,7 @@ func SaveMigration(db *gorm.DB, migrationName string) error {
 	migration.Name = migrationName
 
 	if err := db.Create(migration).Error; err != nil {
-		log.ERROR.Printf("Error saving record to migrations table: %s", err)
+		logger.Errorf("Error saving record to migrations table: %s", err)
 		return fmt.Errorf("Error saving record to migrations table: %s", err)
 	}
 
@@ -0,0 +1,106 @@
+package util
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ErrInternalAddress is returned for outbound requests to loopback, private,
+// link-local and other addresses which are not public
+var ErrInternalAddress = errors.New("Address is not public")
+
+// reservedNetworks are special purpose networks not covered by the net.IP
+// methods used in IsPublicIP
+var reservedNetworks = mustParseCIDRs(
+	"0.0.0.0/8",     // this network
+	"100.64.0.0/10", // carrier-grade NAT
+	"192.0.0.0/24",  // IETF protocol assignments
+	"198.18.0.0/15", // benchmarking
+	"240.0.0.0/4",   // reserved and broadcast
+	"64:ff9b::/96",  // NAT64, which can reach internal IPv4 addresses
+)
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
+
+// IsPublicIP returns false for loopback, private, link-local, multicast,
+// unspecified and other special purpose addresses
+func IsPublicIP(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, network := range reservedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckPublicHost returns ErrInternalAddress if the host, an IP or a name,
+// resolves to an address which is not public
+func CheckPublicHost(host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		if !IsPublicIP(ip) {
+			return ErrInternalAddress
+		}
+		return nil
+	}
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return err
+	}
+	for _, ip := range ips {
+		if !IsPublicIP(ip) {
+			return ErrInternalAddress
+		}
+	}
+	return nil
+}
+
+// NewPublicHTTPClient returns a client for requests to URLs registered by
+// tenants or clients. It only connects to public addresses, checked after
+// the host is resolved so a name cannot be rebound to an internal address,
+// ignores proxies from the environment and does not follow redirects.
+func NewPublicHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if !IsPublicIP(net.ParseIP(host)) {
+				return ErrInternalAddress
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
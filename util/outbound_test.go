@@ -0,0 +1,53 @@
+package util_test
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	testCases := map[string]bool{
+		"93.184.216.34":      true,
+		"2606:2800:220:1::1": true,
+		"127.0.0.1":          false,
+		"10.1.2.3":           false,
+		"172.16.0.1":         false,
+		"192.168.1.1":        false,
+		"169.254.169.254":    false,
+		"100.64.0.1":         false,
+		"0.0.0.0":            false,
+		"255.255.255.255":    false,
+		"::1":                false,
+		"fd00::1":            false,
+		"fe80::1":            false,
+		"::ffff:127.0.0.1":   false,
+		"64:ff9b::a00:1":     false,
+	}
+	for ip, expected := range testCases {
+		assert.Equal(t, expected, util.IsPublicIP(net.ParseIP(ip)), ip)
+	}
+	assert.False(t, util.IsPublicIP(nil))
+}
+
+func TestCheckPublicHost(t *testing.T) {
+	assert.NoError(t, util.CheckPublicHost("93.184.216.34"))
+	assert.Equal(t, util.ErrInternalAddress, util.CheckPublicHost("169.254.169.254"))
+	assert.Equal(t, util.ErrInternalAddress, util.CheckPublicHost("localhost"))
+}
+
+func TestPublicHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// The test server listens on loopback, which is refused when dialing
+	client := util.NewPublicHTTPClient(time.Second)
+	_, err := client.Get(server.URL)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), util.ErrInternalAddress.Error())
+}
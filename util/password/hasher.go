@@ -0,0 +1,251 @@
+package password
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// Argon2id names the argon2id scheme
+	Argon2id = "argon2id"
+	// Bcrypt names the bcrypt scheme
+	Bcrypt = "bcrypt"
+	// V2 names the legacy HMAC-SHA1 scheme
+	V2 = "v2"
+
+	argon2idPrefix = "$argon2id$"
+	v2Prefix       = "V2===="
+	argon2SaltLen  = 16
+	argon2KeyLen   = 32
+)
+
+var (
+	// ErrUnknownScheme ...
+	ErrUnknownScheme = errors.New("Unknown password hashing scheme")
+	// ErrInvalidHash ...
+	ErrInvalidHash = errors.New("Invalid password hash")
+)
+
+// PasswordHasher hashes and verifies passwords. Hashes start with a prefix
+// naming their scheme, so hashes of every scheme can be verified after the
+// default scheme changes.
+type PasswordHasher interface {
+	// Scheme returns the name of the scheme
+	Scheme() string
+	// Hash returns the hash of a password, the user ID is only used by the
+	// legacy V2 scheme
+	Hash(password, userID string) (string, error)
+	// Verify returns true if the password matches the hash
+	Verify(hash, password, userID string) bool
+	// Identifies returns true if the hash was created with this scheme
+	Identifies(hash string) bool
+	// NeedsRehash returns true if the hash was created with other parameters
+	NeedsRehash(hash string) bool
+}
+
+// Argon2idHasher hashes passwords with argon2id, encoded in the PHC string
+// format: $argon2id$v=19$m=65536,t=1,p=4$salt$key
+type Argon2idHasher struct {
+	Time    uint32
+	Memory  uint32
+	Threads uint8
+}
+
+// NewArgon2idHasher returns a new Argon2idHasher instance, memory is in KiB
+func NewArgon2idHasher(time, memory uint32, threads uint8) *Argon2idHasher {
+	return &Argon2idHasher{Time: time, Memory: memory, Threads: threads}
+}
+
+// Scheme returns the name of the scheme
+func (h *Argon2idHasher) Scheme() string {
+	return Argon2id
+}
+
+// Hash returns the hash of a password with a random salt
+func (h *Argon2idHasher) Hash(password, userID string) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.Time, h.Memory, h.Threads, argon2KeyLen)
+	return fmt.Sprintf(
+		"%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix,
+		argon2.Version,
+		h.Memory,
+		h.Time,
+		h.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify returns true if the password matches the hash
+func (h *Argon2idHasher) Verify(hash, password, userID string) bool {
+	params, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return false
+	}
+	otherKey := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, otherKey) == 1
+}
+
+// Identifies returns true for argon2id hashes
+func (h *Argon2idHasher) Identifies(hash string) bool {
+	return strings.HasPrefix(hash, argon2idPrefix)
+}
+
+// NeedsRehash returns true if the hash used other argon2id parameters
+func (h *Argon2idHasher) NeedsRehash(hash string) bool {
+	params, _, _, err := decodeArgon2id(hash)
+	return err != nil || *params != *h
+}
+
+func decodeArgon2id(hash string) (*Argon2idHasher, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=65536,t=1,p=4", salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != Argon2id {
+		return nil, nil, nil, ErrInvalidHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, nil, nil, ErrInvalidHash
+	}
+	params := new(Argon2idHasher)
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil {
+		return nil, nil, nil, ErrInvalidHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, ErrInvalidHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return nil, nil, nil, ErrInvalidHash
+	}
+	return params, salt, key, nil
+}
+
+// BcryptHasher hashes passwords with bcrypt
+type BcryptHasher struct {
+	Cost int
+}
+
+// NewBcryptHasher returns a new BcryptHasher instance
+func NewBcryptHasher(cost int) *BcryptHasher {
+	return &BcryptHasher{Cost: cost}
+}
+
+// Scheme returns the name of the scheme
+func (h *BcryptHasher) Scheme() string {
+	return Bcrypt
+}
+
+// Hash returns the bcrypt hash of a password
+func (h *BcryptHasher) Hash(password, userID string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Verify returns true if the password matches the hash
+func (h *BcryptHasher) Verify(hash, password, userID string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// Identifies returns true for bcrypt hashes
+func (h *BcryptHasher) Identifies(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") ||
+		strings.HasPrefix(hash, "$2b$") ||
+		strings.HasPrefix(hash, "$2y$")
+}
+
+// NeedsRehash returns true if the hash used another cost
+func (h *BcryptHasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != h.Cost
+}
+
+// V2Hasher is the legacy HMAC-SHA1 scheme of HashPassword2, kept to verify
+// existing hashes until their users log in again
+type V2Hasher struct {
+	Secret string
+	Salt   string
+}
+
+// NewV2Hasher returns a new V2Hasher instance
+func NewV2Hasher(secret, salt string) *V2Hasher {
+	return &V2Hasher{Secret: secret, Salt: salt}
+}
+
+// Scheme returns the name of the scheme
+func (h *V2Hasher) Scheme() string {
+	return V2
+}
+
+// Hash returns the V2 hash of a password
+func (h *V2Hasher) Hash(password, userID string) (string, error) {
+	return HashPassword2(password, userID, h.Secret, h.Salt), nil
+}
+
+// Verify returns true if the password matches the hash
+func (h *V2Hasher) Verify(hash, password, userID string) bool {
+	otherHash := HashPassword2(password, userID, h.Secret, h.Salt)
+	return hmac.Equal([]byte(hash), []byte(otherHash))
+}
+
+// Identifies returns true for V2 hashes
+func (h *V2Hasher) Identifies(hash string) bool {
+	return strings.HasPrefix(hash, v2Prefix)
+}
+
+// NeedsRehash is always false, V2 has no parameters
+func (h *V2Hasher) NeedsRehash(hash string) bool {
+	return false
+}
+
+// Verifier hashes passwords with a default hasher and verifies hashes of
+// any of its hashers
+type Verifier struct {
+	hasher  PasswordHasher
+	hashers []PasswordHasher
+}
+
+// NewVerifier returns a new Verifier hashing with the default hasher and
+// verifying with it or one of the others
+func NewVerifier(defaultHasher PasswordHasher, others ...PasswordHasher) *Verifier {
+	return &Verifier{
+		hasher:  defaultHasher,
+		hashers: append([]PasswordHasher{defaultHasher}, others...),
+	}
+}
+
+// Hash returns the hash of a password with the default hasher
+func (v *Verifier) Hash(password, userID string) (string, error) {
+	return v.hasher.Hash(password, userID)
+}
+
+// Verify returns true if the password matches the hash and whether the hash
+// should be replaced with a new hash of the default hasher
+func (v *Verifier) Verify(hash, password, userID string) (valid bool, rehash bool) {
+	for _, hasher := range v.hashers {
+		if !hasher.Identifies(hash) {
+			continue
+		}
+		if !hasher.Verify(hash, password, userID) {
+			return false, false
+		}
+		return true, hasher.Scheme() != v.hasher.Scheme() || hasher.NeedsRehash(hash)
+	}
+	return false, false
+}
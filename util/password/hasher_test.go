@@ -0,0 +1,96 @@
+package password_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/RichardKnop/go-oauth2-server/util/password"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswordHashers(t *testing.T) {
+	hashers := []password.PasswordHasher{
+		password.NewArgon2idHasher(1, 1024, 1),
+		password.NewBcryptHasher(bcrypt.MinCost),
+		password.NewV2Hasher("test_secret", "test_salt"),
+	}
+
+	for _, hasher := range hashers {
+		hash, err := hasher.Hash("test_password", "1")
+		if !assert.NoError(t, err, hasher.Scheme()) {
+			continue
+		}
+
+		assert.True(t, hasher.Identifies(hash), hasher.Scheme())
+		assert.True(t, hasher.Verify(hash, "test_password", "1"), hasher.Scheme())
+		assert.False(t, hasher.Verify(hash, "bogus", "1"), hasher.Scheme())
+		assert.False(t, hasher.NeedsRehash(hash), hasher.Scheme())
+
+		// Hashes of one scheme are not mistaken for another's
+		for _, other := range hashers {
+			if other != hasher {
+				assert.False(t, other.Identifies(hash), other.Scheme())
+			}
+		}
+	}
+}
+
+func TestArgon2idHasher(t *testing.T) {
+	hasher := password.NewArgon2idHasher(1, 1024, 1)
+
+	hash, err := hasher.Hash("test_password", "")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"))
+
+	// Salts are random
+	otherHash, err := hasher.Hash("test_password", "")
+	if assert.NoError(t, err) {
+		assert.NotEqual(t, hash, otherHash)
+	}
+
+	// Changed parameters need a rehash, old hashes still verify
+	stronger := password.NewArgon2idHasher(2, 1024, 1)
+	assert.True(t, stronger.NeedsRehash(hash))
+	assert.True(t, stronger.Verify(hash, "test_password", ""))
+
+	// Malformed hashes never verify
+	assert.False(t, hasher.Verify("$argon2id$v=19$m=1024,t=1,p=1$bogus", "test_password", ""))
+}
+
+func TestVerifier(t *testing.T) {
+	argon2idHasher := password.NewArgon2idHasher(1, 1024, 1)
+	bcryptHasher := password.NewBcryptHasher(bcrypt.MinCost)
+	v2Hasher := password.NewV2Hasher("test_secret", "test_salt")
+	verifier := password.NewVerifier(argon2idHasher, bcryptHasher, v2Hasher)
+
+	// New hashes use the default scheme
+	hash, err := verifier.Hash("test_password", "1")
+	if assert.NoError(t, err) {
+		assert.True(t, argon2idHasher.Identifies(hash))
+		valid, rehash := verifier.Verify(hash, "test_password", "1")
+		assert.True(t, valid)
+		assert.False(t, rehash)
+	}
+
+	// Legacy hashes verify and need a rehash
+	legacyHash := password.HashPassword2("test_password", "1", "test_secret", "test_salt")
+	valid, rehash := verifier.Verify(legacyHash, "test_password", "1")
+	assert.True(t, valid)
+	assert.True(t, rehash)
+
+	// Wrong passwords never need a rehash
+	valid, rehash = verifier.Verify(legacyHash, "bogus", "1")
+	assert.False(t, valid)
+	assert.False(t, rehash)
+
+	// The V2 hash depends on the user ID
+	valid, _ = verifier.Verify(legacyHash, "test_password", "2")
+	assert.False(t, valid)
+
+	// Unknown hashes never verify
+	valid, _ = verifier.Verify("bogus", "test_password", "1")
+	assert.False(t, valid)
+}
@@ -1,48 +1,48 @@
 package response
 
 import (
-	"fmt"
-	"log"
 	"net/http"
-	"os"
 	"time"
 
-	thelog "github.com/RichardKnop/go-oauth2-server/log"
+	"github.com/RichardKnop/go-oauth2-server/log"
+	"github.com/RichardKnop/go-oauth2-server/util"
 	"github.com/urfave/negroni"
 )
 
+var logger = log.New("http")
+
 // Logger is a middleware handler that logs the request as it goes in and the response as it goes out.
-type Logger struct {
-	// Logger inherits from log.Logger used to log messages with the Logger middleware
-	*log.Logger
-}
+type Logger struct{}
 
 // NewURLLogger returns a new Logger instance
 func NewURLLogger() *Logger {
-	return &Logger{log.New(os.Stdout, "[negroni] ", 0)}
+	return &Logger{}
 }
 
 func (l *Logger) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
 	start := time.Now()
-	ip := r.RemoteAddr
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		ip = xff
-	}
 
-	thelog.INFO.Printf("Started %s %s for %s", r.Method, r.URL.Path, ip)
+	requestLogger := logger.WithContext(r.Context()).With(
+		"method", r.Method,
+		"path", r.URL.Path,
+		"remote_ip", util.GetClientIP(r),
+	)
+	requestLogger.Debug("Request started")
 
 	next(rw, r)
 
-	res := rw.(negroni.ResponseWriter)
-
-	msg := fmt.Sprintf("Finished %s %s : %v %s in %v", r.Method, r.URL.Path, res.Status(), http.StatusText(res.Status()), time.Since(start))
+	status := http.StatusOK
+	if res, ok := rw.(negroni.ResponseWriter); ok && res.Status() != 0 {
+		status = res.Status()
+	}
+	keysAndValues := []interface{}{"status", status, "duration", time.Since(start)}
 
 	switch {
-	case res.Status() < 400:
-		thelog.INFO.Print(msg)
-	case res.Status() < 500:
-		thelog.WARNING.Print(msg)
+	case status < 400:
+		requestLogger.Info("Request finished", keysAndValues...)
+	case status < 500:
+		requestLogger.Warn("Request finished", keysAndValues...)
 	default:
-		thelog.ERROR.Print(msg)
+		requestLogger.Error("Request finished", keysAndValues...)
 	}
 }
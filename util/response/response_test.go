@@ -1,13 +1,17 @@
 package response_test
 
 import (
+	"bytes"
 	"encoding/json"
+	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
+	"github.com/RichardKnop/go-oauth2-server/log"
 	"github.com/RichardKnop/go-oauth2-server/util/response"
 	"github.com/stretchr/testify/assert"
+	"github.com/urfave/negroni"
 )
 
 func TestWriteJSON(t *testing.T) {
@@ -41,3 +45,25 @@ func TestError(t *testing.T) {
 	expected := "{\"error\":\"something went wrong\"}"
 	assert.Equal(t, expected, strings.TrimSpace(w.Body.String()))
 }
+
+func TestURLLogger(t *testing.T) {
+	buf := new(bytes.Buffer)
+	assert.NoError(t, log.Configure(log.Options{Format: "json", Output: buf}))
+	defer log.Configure(log.Options{})
+
+	r := httptest.NewRequest("POST", "/v1/oauth/tokens?code=abc", nil)
+	r = r.WithContext(log.WithRequestID(r.Context(), "abc-123"))
+	w := negroni.NewResponseWriter(httptest.NewRecorder())
+	response.NewURLLogger().ServeHTTP(w, r, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	})
+
+	entry := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "warn", entry["level"])
+	assert.Equal(t, "Request finished", entry["msg"])
+	assert.Equal(t, "abc-123", entry["request_id"])
+	assert.Equal(t, "POST", entry["method"])
+	assert.Equal(t, "/v1/oauth/tokens", entry["path"])
+	assert.Equal(t, float64(400), entry["status"])
+}
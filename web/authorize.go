@@ -0,0 +1,197 @@
+package web
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/session"
+	"github.com/gorilla/mux"
+)
+
+// authorizeHandler starts an authorization request, or continues the one
+// waiting in the session when called without parameters after the user
+// logged in or consented
+func (s *Service) authorizeHandler(w http.ResponseWriter, r *http.Request) {
+	if len(r.URL.Query()) == 0 {
+		client, req, err := s.getAuthorizationRequest()
+		if err != nil {
+			s.renderError(w, r, nil, err)
+			return
+		}
+		s.continueAuthorization(w, r, client, req)
+		return
+	}
+
+	// Errors are shown rather than redirected, the client and redirect URI
+	// cannot be trusted yet
+	client, req, err := s.oauthService.ResolveAuthorizationRequest(r.URL.Query())
+	if err != nil {
+		s.renderError(w, r, nil, err)
+		return
+	}
+	// The pages of a tenant's path only serve the tenant's clients
+	if pathTenantID, ok := mux.Vars(r)["tenant"]; ok && pathTenantID != client.TenantID {
+		s.renderError(w, r, nil, oauth.ErrTenantMismatch)
+		return
+	}
+
+	// Pushed requests can only be resolved once, so the request is kept in
+	// the session while the user logs in and consents
+	if err := s.sessionService.SetAuthorizationRequest(req.Values()); err != nil {
+		s.renderError(w, r, client, err)
+		return
+	}
+	s.continueAuthorization(w, r, client, req)
+}
+
+// continueAuthorization redirects back to the client with an authorization
+// code once the user has an SSO session and consented, or else to the page
+// of the next step
+func (s *Service) continueAuthorization(w http.ResponseWriter, r *http.Request, client *models.OauthClient, req *oauth.AuthorizationRequest) {
+	ssoSession, err := s.sessionService.GetSSOSession()
+	if err != nil {
+		s.renderError(w, r, client, err)
+		return
+	}
+
+	user, err := s.oauthService.AuthorizeSSOSession(client, req, ssoSession)
+	if err == nil {
+		authorizationCode, err := s.oauthService.GrantAuthorizationCodeForRequest(client, user, req, ssoSession)
+		if err != nil {
+			s.renderError(w, r, client, err)
+			return
+		}
+		s.sessionService.ClearAuthorizationRequest()
+		redirectToClient(w, r, req, url.Values{"code": {authorizationCode.Code}})
+		return
+	}
+
+	// prompt=none never shows a page
+	if req.HasPrompt(oauth.PromptNone) {
+		s.sessionService.ClearAuthorizationRequest()
+		redirectToClient(w, r, req, url.Values{"error": {oauth.AuthorizationErrorCode(err)}})
+		return
+	}
+
+	switch err {
+	case oauth.ErrLoginRequired:
+		if s.mfaPending(client, req, ssoSession) {
+			redirectToPage(w, r, mfaPath)
+			return
+		}
+		redirectToPage(w, r, loginPath)
+	case oauth.ErrConsentRequired:
+		redirectToPage(w, r, consentPath)
+	default:
+		s.renderError(w, r, client, err)
+	}
+}
+
+// getAuthorizationRequest returns the client and validated authorization
+// request waiting in the session
+func (s *Service) getAuthorizationRequest() (*models.OauthClient, *oauth.AuthorizationRequest, error) {
+	values, err := s.sessionService.GetAuthorizationRequest()
+	if err != nil {
+		return nil, nil, err
+	}
+	if values == nil {
+		return nil, nil, ErrNoAuthorizationRequest
+	}
+
+	client, err := s.oauthService.FindClientByClientID(values.Get("client_id"))
+	if err != nil {
+		return nil, nil, err
+	}
+	req := oauth.NewAuthorizationRequest(values)
+	if err := s.oauthService.ValidateAuthorizationRequest(client, req); err != nil {
+		return nil, nil, err
+	}
+	return client, req, nil
+}
+
+// getWaitingClient returns the client of the waiting authorization request,
+// nil if there is none
+func (s *Service) getWaitingClient() *models.OauthClient {
+	client, _, err := s.getAuthorizationRequest()
+	if err != nil {
+		return nil
+	}
+	return client
+}
+
+// completePrompt removes prompt values the user has answered from the
+// waiting authorization request, so continuing it does not ask again
+func (s *Service) completePrompt(answered ...string) error {
+	values, err := s.sessionService.GetAuthorizationRequest()
+	if err != nil || values == nil {
+		return err
+	}
+
+	var prompts []string
+	for _, prompt := range strings.Fields(values.Get("prompt")) {
+		keep := true
+		for _, value := range answered {
+			if prompt == value {
+				keep = false
+			}
+		}
+		if keep {
+			prompts = append(prompts, prompt)
+		}
+	}
+	if len(prompts) == 0 {
+		values.Del("prompt")
+	} else {
+		values.Set("prompt", strings.Join(prompts, " "))
+	}
+	return s.sessionService.SetAuthorizationRequest(values)
+}
+
+// continueOrShow continues the waiting authorization request, or shows the
+// login page telling the user is logged in when there is none
+func continueOrShow(w http.ResponseWriter, r *http.Request, waiting bool) {
+	if waiting {
+		redirectToPage(w, r, authorizePath)
+		return
+	}
+	redirectToPage(w, r, loginPath)
+}
+
+// mfaPending returns true if the SSO session user entered the password but
+// still has to complete the second factor the user must use
+func (s *Service) mfaPending(client *models.OauthClient, req *oauth.AuthorizationRequest, ssoSession *session.SSOSession) bool {
+	if ssoSession == nil || ssoSession.TenantID != client.TenantID {
+		return false
+	}
+	if req.HasPrompt(oauth.PromptLogin) || req.HasPrompt(oauth.PromptSelectAccount) {
+		return false
+	}
+	return s.getMFAUser(ssoSession) != nil
+}
+
+// redirectToClient redirects to the redirect URI of an authorization request
+// with the state and the response parameters
+func redirectToClient(w http.ResponseWriter, r *http.Request, req *oauth.AuthorizationRequest, params url.Values) {
+	redirectURI, err := url.Parse(req.RedirectURI)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	query := redirectURI.Query()
+	for name := range params {
+		query.Set(name, params.Get(name))
+	}
+	if req.State != "" {
+		query.Set("state", req.State)
+	}
+	redirectURI.RawQuery = query.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+// redirectToPage redirects to another page under the same prefix
+func redirectToPage(w http.ResponseWriter, r *http.Request, path string) {
+	http.Redirect(w, r, strings.TrimPrefix(path, "/"), http.StatusFound)
+}
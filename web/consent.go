@@ -0,0 +1,80 @@
+package web
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+)
+
+// consentFormHandler asks the user to grant the client of the waiting
+// authorization request its scope
+func (s *Service) consentFormHandler(w http.ResponseWriter, r *http.Request) {
+	client, req, err := s.getAuthorizationRequest()
+	if err != nil {
+		s.renderError(w, r, nil, err)
+		return
+	}
+	user := s.getConsentUser(client)
+	if user == nil {
+		redirectToPage(w, r, authorizePath)
+		return
+	}
+
+	s.render(w, r, client, http.StatusOK, consentTemplate, map[string]interface{}{
+		"Client": client,
+		"User":   user,
+		"Scopes": strings.Fields(req.Scope),
+	})
+}
+
+// consentHandler records the user's consent and continues the waiting
+// authorization request, or sends the client access_denied
+func (s *Service) consentHandler(w http.ResponseWriter, r *http.Request) {
+	client, req, err := s.getAuthorizationRequest()
+	if err != nil {
+		s.renderError(w, r, nil, err)
+		return
+	}
+	user := s.getConsentUser(client)
+	if user == nil {
+		redirectToPage(w, r, authorizePath)
+		return
+	}
+
+	if r.PostForm.Get("allow") == "" {
+		s.sessionService.ClearAuthorizationRequest()
+		logger.WithContext(r.Context()).Info("audit: consent denied", "user_id", user.ID, "client_id", client.Key, "scope", req.Scope, "tenant_id", user.TenantID)
+		redirectToClient(w, r, req, url.Values{"error": {"access_denied"}})
+		return
+	}
+
+	if err := s.oauthService.GrantConsent(client, user, req.Scope); err != nil {
+		s.renderError(w, r, client, err)
+		return
+	}
+	if err := s.completePrompt(oauth.PromptConsent); err != nil {
+		s.renderError(w, r, client, err)
+		return
+	}
+	redirectToPage(w, r, authorizePath)
+}
+
+// getConsentUser returns the user of the SSO session if the user is logged
+// in to the client's tenant, nil otherwise
+func (s *Service) getConsentUser(client *models.OauthClient) *models.OauthUser {
+	ssoSession, err := s.sessionService.GetSSOSession()
+	if err != nil || ssoSession == nil || ssoSession.TenantID != client.TenantID {
+		return nil
+	}
+	if s.getMFAUser(ssoSession) != nil {
+		return nil
+	}
+	user, err := s.oauthService.FindUserByID(ssoSession.UserID)
+	if err != nil || user.Disabled {
+		return nil
+	}
+	return user
+}
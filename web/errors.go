@@ -0,0 +1,50 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+)
+
+var (
+	// ErrNoAuthorizationRequest ...
+	ErrNoAuthorizationRequest = errors.New("No authorization request in progress")
+)
+
+// pageError is how an error is shown on the error page
+type pageError struct {
+	message string
+	status  int
+}
+
+var pageErrors = map[error]pageError{
+	ErrInvalidCSRFToken:                         {"error.invalid_csrf_token", http.StatusForbidden},
+	ErrNoAuthorizationRequest:                   {"error.no_authorization_request", http.StatusBadRequest},
+	oauth.ErrClientNotFound:                     {"error.client_not_found", http.StatusBadRequest},
+	oauth.ErrInvalidRedirectURI:                 {"error.invalid_redirect_uri", http.StatusBadRequest},
+	oauth.ErrInvalidResponseType:                {"error.invalid_request", http.StatusBadRequest},
+	oauth.ErrInvalidScope:                       {"error.invalid_request", http.StatusBadRequest},
+	oauth.ErrInvalidPrompt:                      {"error.invalid_request", http.StatusBadRequest},
+	oauth.ErrInvalidMaxAge:                      {"error.invalid_request", http.StatusBadRequest},
+	oauth.ErrRequestURINotFound:                 {"error.request_uri_not_found", http.StatusBadRequest},
+	oauth.ErrPushedAuthorizationRequestRequired: {"error.invalid_request", http.StatusBadRequest},
+	oauth.ErrTenantMismatch:                     {"error.tenant_mismatch", http.StatusBadRequest},
+	oauth.ErrTenantNotFound:                     {"error.tenant_not_found", http.StatusNotFound},
+	oauth.ErrTenantSuspended:                    {"error.tenant_suspended", http.StatusForbidden},
+	oauth.ErrGrantTypeNotAllowedForTenant:       {"error.unauthorized_client", http.StatusForbidden},
+}
+
+// renderError writes the error page, errors without a message of their
+// own are logged and shown as server errors
+func (s *Service) renderError(w http.ResponseWriter, r *http.Request, client *models.OauthClient, err error) {
+	e, ok := pageErrors[err]
+	if !ok {
+		logger.WithContext(r.Context()).Error("Web page failed", "path", r.URL.Path, "error", err)
+		e = pageError{"error.server_error", http.StatusInternalServerError}
+	}
+	s.render(w, r, client, e.status, errorTemplate, map[string]interface{}{
+		"Message": e.message,
+	})
+}
@@ -0,0 +1,86 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// catalogs holds the messages of the pages by language, loaded from
+// <language>.json files mapping message keys to translations
+type catalogs map[string]map[string]string
+
+// loadCatalogs reads the message catalogs of a directory
+func loadCatalogs(dir string) (catalogs, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("No message catalogs in %s", dir)
+	}
+
+	c := make(catalogs)
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		messages := make(map[string]string)
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return nil, fmt.Errorf("Parsing %s: %s", path, err)
+		}
+		language := strings.ToLower(strings.TrimSuffix(filepath.Base(path), ".json"))
+		c[language] = messages
+	}
+	return c, nil
+}
+
+// negotiate picks the language of the pages from an Accept-Language header,
+// falling back to the default language. Quality values are ignored, the
+// browser lists languages by preference anyway.
+func (c catalogs) negotiate(acceptLanguage, defaultLanguage string) string {
+	for _, tag := range strings.Split(acceptLanguage, ",") {
+		tag = strings.ToLower(strings.TrimSpace(strings.SplitN(tag, ";", 2)[0]))
+		if tag == "" {
+			continue
+		}
+		// zh-cn matches a zh-cn catalog first, then zh
+		if _, ok := c[tag]; ok {
+			return tag
+		}
+		if i := strings.Index(tag, "-"); i > 0 {
+			if _, ok := c[tag[:i]]; ok {
+				return tag[:i]
+			}
+		}
+	}
+	return defaultLanguage
+}
+
+// translate returns a message in a language, or in the fallback language
+// when it is not translated. Messages with arguments are fmt formats.
+func (c catalogs) translate(language, fallback, key string, args ...interface{}) string {
+	message, ok := c[language][key]
+	if !ok {
+		message, ok = c[fallback][key]
+	}
+	if !ok {
+		// Show the key rather than nothing so missing messages get noticed
+		message = key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(message, args...)
+	}
+	return message
+}
+
+// getCatalogs lazily loads the message catalogs
+func (s *Service) getCatalogs() (catalogs, error) {
+	s.catalogsOnce.Do(func() {
+		s.catalogs, s.catalogsErr = loadCatalogs(s.cnf.Web.LocalesDir)
+	})
+	return s.catalogs, s.catalogsErr
+}
@@ -0,0 +1,54 @@
+package web
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadCatalogs(t *testing.T) {
+	c, err := loadCatalogs("locales")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// Every language translates every message of the default language
+	for language, messages := range c {
+		for key := range c["en"] {
+			assert.Contains(t, messages, key, "%s is missing in %s", key, language)
+		}
+	}
+
+	_, err = loadCatalogs("bogus")
+	assert.Error(t, err)
+}
+
+func TestNegotiateLanguage(t *testing.T) {
+	c := catalogs{"en": {}, "zh": {}, "pt-br": {}}
+	testCases := []struct {
+		acceptLanguage string
+		expected       string
+	}{
+		{"", "en"},
+		{"zh-CN,zh;q=0.9,en;q=0.8", "zh"},
+		{"pt-BR,pt;q=0.9", "pt-br"},
+		{"de-DE,de;q=0.9,zh;q=0.5", "zh"},
+		{"de-DE", "en"},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, c.negotiate(testCase.acceptLanguage, "en"), testCase.acceptLanguage)
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	c := catalogs{
+		"en": {"login.title": "Log in", "login.continue_to": "to continue to %s"},
+		"zh": {"login.title": "登录"},
+	}
+
+	assert.Equal(t, "登录", c.translate("zh", "en", "login.title"))
+	// Untranslated messages fall back to the default language, then the key
+	assert.Equal(t, "to continue to Acme", c.translate("zh", "en", "login.continue_to", "Acme"))
+	assert.Equal(t, "login.bogus", c.translate("zh", "en", "login.bogus"))
+}
@@ -0,0 +1,123 @@
+package web
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/audit"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/session"
+	"github.com/RichardKnop/go-oauth2-server/util"
+)
+
+// loginFormHandler shows the login form, or who is logged in when no
+// authorization request is waiting
+func (s *Service) loginFormHandler(w http.ResponseWriter, r *http.Request) {
+	client := s.getWaitingClient()
+
+	data := map[string]interface{}{"Client": client}
+	if client == nil {
+		ssoSession, err := s.sessionService.GetSSOSession()
+		if err != nil {
+			s.renderError(w, r, nil, err)
+			return
+		}
+		if ssoSession != nil && ssoSession.TenantID == getTenantID(r, nil) && s.getMFAUser(ssoSession) == nil {
+			if user, err := s.oauthService.FindUserByID(ssoSession.UserID); err == nil {
+				data["User"] = user
+			}
+		}
+	}
+
+	s.render(w, r, client, http.StatusOK, loginTemplate, data)
+}
+
+// loginHandler starts an SSO session after checking the password, users who
+// must use a second factor continue with it
+func (s *Service) loginHandler(w http.ResponseWriter, r *http.Request) {
+	client := s.getWaitingClient()
+	tenantID := getTenantID(r, client)
+
+	user, err := s.oauthService.LoginUser(
+		tenantID,
+		r.PostForm.Get("username"),
+		r.PostForm.Get("password"),
+		util.GetClientIP(r),
+	)
+	if err != nil {
+		s.recordLogin(r, audit.LoginFailure, client, tenantID, "", map[string]string{
+			"username": r.PostForm.Get("username"),
+			"error":    err.Error(),
+		})
+		s.sessionService.SetFlashMessage(loginErrorMessage(err))
+		redirectToPage(w, r, loginPath)
+		return
+	}
+
+	// Logging in again as the same user keeps the session ID, so clients
+	// logged in through it are still logged out with it
+	ssoSession := &session.SSOSession{
+		UserID:   user.ID,
+		TenantID: user.TenantID,
+		AuthTime: time.Now().UTC(),
+		AMR:      []string{"pwd"},
+	}
+	if previous, err := s.sessionService.GetSSOSession(); err == nil && previous != nil && previous.UserID == user.ID {
+		ssoSession.ID = previous.ID
+	}
+	if err := s.sessionService.SetSSOSession(ssoSession); err != nil {
+		s.renderError(w, r, client, err)
+		return
+	}
+	if err := s.completePrompt(oauth.PromptLogin, oauth.PromptSelectAccount); err != nil {
+		s.renderError(w, r, client, err)
+		return
+	}
+	logger.WithContext(r.Context()).Info("audit: user logged in to the web pages", "user_id", user.ID, "tenant_id", user.TenantID)
+
+	mfaRequired, err := s.oauthService.MFARequired(user)
+	if err != nil {
+		s.renderError(w, r, client, err)
+		return
+	}
+	if mfaRequired {
+		redirectToPage(w, r, mfaPath)
+		return
+	}
+	s.recordLogin(r, audit.LoginSuccess, client, user.TenantID, user.ID, nil)
+	continueOrShow(w, r, client != nil)
+}
+
+// recordLogin records a login to the web pages succeeding or failing, logins
+// with a second factor succeed once the second factor was checked
+func (s *Service) recordLogin(r *http.Request, eventType string, client *models.OauthClient, tenantID, userID string, details map[string]string) {
+	if s.auditService == nil {
+		return
+	}
+	event := audit.NewEvent(eventType, r)
+	event.ActorID = userID
+	event.TenantID = tenantID
+	if client != nil {
+		event.ClientID = client.Key
+	}
+	event.Details["method"] = "web"
+	for key, value := range details {
+		event.Details[key] = value
+	}
+	s.auditService.Record(event)
+}
+
+// loginErrorMessage returns the message key of a failed login
+func loginErrorMessage(err error) string {
+	if _, ok := err.(*oauth.LockoutError); ok {
+		return "error.locked_out"
+	}
+	switch err {
+	case oauth.ErrPasswordExpired:
+		return "error.password_expired"
+	case oauth.ErrTenantNotFound, oauth.ErrTenantSuspended:
+		return "error.tenant_suspended"
+	}
+	return "error.invalid_credentials"
+}
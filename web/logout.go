@@ -0,0 +1,48 @@
+package web
+
+import (
+	"net/http"
+)
+
+// logoutFormHandler asks the user to confirm logging out, so other sites
+// cannot log the user out with a link
+func (s *Service) logoutFormHandler(w http.ResponseWriter, r *http.Request) {
+	ssoSession, err := s.sessionService.GetSSOSession()
+	if err != nil {
+		s.renderError(w, r, nil, err)
+		return
+	}
+
+	s.render(w, r, nil, http.StatusOK, logoutTemplate, map[string]interface{}{
+		"LoggedIn": ssoSession != nil,
+	})
+}
+
+// logoutHandler ends the SSO session, logging the user out of the clients
+// which got authorization codes through it
+func (s *Service) logoutHandler(w http.ResponseWriter, r *http.Request) {
+	ssoSession, err := s.sessionService.GetSSOSession()
+	if err != nil {
+		s.renderError(w, r, nil, err)
+		return
+	}
+
+	var frontchannelURIs []string
+	if ssoSession != nil {
+		frontchannelURIs, err = s.oauthService.EndSession(ssoSession.UserID, ssoSession.ID)
+		if err != nil {
+			s.renderError(w, r, nil, err)
+			return
+		}
+	}
+	if err := s.sessionService.ClearSSOSession(); err != nil {
+		s.renderError(w, r, nil, err)
+		return
+	}
+	s.sessionService.ClearAuthorizationRequest()
+
+	s.render(w, r, nil, http.StatusOK, logoutTemplate, map[string]interface{}{
+		"LoggedOut":        true,
+		"FrontchannelURIs": frontchannelURIs,
+	})
+}
@@ -0,0 +1,155 @@
+package web
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/audit"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/totp"
+	"github.com/RichardKnop/go-oauth2-server/session"
+)
+
+// mfaFormHandler asks for the second factor of a user who entered the
+// password, users without an authenticator enroll one first
+func (s *Service) mfaFormHandler(w http.ResponseWriter, r *http.Request) {
+	_, user := s.getMFASession()
+	if user == nil {
+		redirectToPage(w, r, loginPath)
+		return
+	}
+	client := s.getWaitingClient()
+
+	data := map[string]interface{}{"Client": client}
+	if !user.MFAEnabled {
+		enrollment, err := s.getEnrollment(user)
+		if err != nil {
+			s.renderError(w, r, client, err)
+			return
+		}
+		data["Enrollment"] = enrollment
+	}
+
+	s.render(w, r, client, http.StatusOK, mfaTemplate, data)
+}
+
+// mfaHandler adds the second factor to the SSO session, confirming the
+// enrollment of users who had no authenticator
+func (s *Service) mfaHandler(w http.ResponseWriter, r *http.Request) {
+	ssoSession, user := s.getMFASession()
+	if user == nil {
+		redirectToPage(w, r, loginPath)
+		return
+	}
+	client := s.getWaitingClient()
+
+	var recoveryCodes []string
+	if user.MFAEnabled {
+		err := s.oauthService.VerifyMFACode(user, r.PostForm.Get("otp"), r.PostForm.Get("recovery_code"))
+		if err != nil {
+			s.recordLogin(r, audit.LoginFailure, client, user.TenantID, user.ID, map[string]string{
+				"error": err.Error(),
+			})
+		}
+		if err == oauth.ErrMFAAttemptsExceeded {
+			// Guessing codes is stopped by asking for the password again
+			s.sessionService.ClearSSOSession()
+			s.sessionService.SetFlashMessage("error.mfa_attempts_exceeded")
+			redirectToPage(w, r, loginPath)
+			return
+		}
+		if err != nil {
+			s.sessionService.SetFlashMessage("error.invalid_code")
+			redirectToPage(w, r, mfaPath)
+			return
+		}
+	} else {
+		codes, err := s.oauthService.ConfirmTOTPEnrollment(user, r.PostForm.Get("otp"))
+		if err != nil {
+			s.recordLogin(r, audit.LoginFailure, client, user.TenantID, user.ID, map[string]string{
+				"error": err.Error(),
+			})
+			s.sessionService.SetFlashMessage("error.invalid_code")
+			redirectToPage(w, r, mfaPath)
+			return
+		}
+		recoveryCodes = codes
+	}
+
+	ssoSession.AuthTime = time.Now().UTC()
+	ssoSession.AMR = append(ssoSession.AMR, "otp")
+	if err := s.sessionService.SetSSOSession(ssoSession); err != nil {
+		s.renderError(w, r, client, err)
+		return
+	}
+	logger.WithContext(r.Context()).Info("audit: user completed MFA on the web pages", "user_id", user.ID, "tenant_id", user.TenantID)
+	s.recordLogin(r, audit.LoginSuccess, client, user.TenantID, user.ID, nil)
+
+	// Recovery codes of a new enrollment are only shown once
+	if len(recoveryCodes) > 0 {
+		s.render(w, r, client, http.StatusOK, mfaTemplate, map[string]interface{}{
+			"Client":        client,
+			"RecoveryCodes": recoveryCodes,
+		})
+		return
+	}
+	continueOrShow(w, r, client != nil)
+}
+
+// getMFASession returns the SSO session and its user when the user entered
+// the password but has not completed the second factor yet
+func (s *Service) getMFASession() (*session.SSOSession, *models.OauthUser) {
+	ssoSession, err := s.sessionService.GetSSOSession()
+	if err != nil || ssoSession == nil {
+		return nil, nil
+	}
+	user := s.getMFAUser(ssoSession)
+	if user == nil {
+		return nil, nil
+	}
+	return ssoSession, user
+}
+
+// getMFAUser returns the user of an SSO session missing the second factor
+// the user must use, nil otherwise
+func (s *Service) getMFAUser(ssoSession *session.SSOSession) *models.OauthUser {
+	if !hasAMR(ssoSession, "pwd") || hasAMR(ssoSession, "otp") {
+		return nil
+	}
+	user, err := s.oauthService.FindUserByID(ssoSession.UserID)
+	if err != nil || user.Disabled {
+		return nil
+	}
+	mfaRequired, err := s.oauthService.MFARequired(user)
+	if err != nil || !mfaRequired {
+		return nil
+	}
+	return user
+}
+
+// getEnrollment returns the authenticator enrollment of a user, reusing a
+// started one so reloading the page keeps the scanned secret valid
+func (s *Service) getEnrollment(user *models.OauthUser) (*oauth.TOTPEnrollment, error) {
+	if !user.TOTPSecret.Valid {
+		return s.oauthService.BeginTOTPEnrollment(user)
+	}
+	account := user.Account
+	if account == "" {
+		account = user.Phone
+	}
+	return &oauth.TOTPEnrollment{
+		Secret: user.TOTPSecret.String,
+		URI:    totp.URI(s.cnf.MFA.Issuer, account, user.TOTPSecret.String),
+	}, nil
+}
+
+// hasAMR returns true if the SSO session user authenticated with the method
+func hasAMR(ssoSession *session.SSOSession, method string) bool {
+	for _, amr := range ssoSession.AMR {
+		if amr == method {
+			return true
+		}
+	}
+	return false
+}
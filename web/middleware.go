@@ -0,0 +1,61 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+)
+
+var (
+	// ErrInvalidCSRFToken ...
+	ErrInvalidCSRFToken = errors.New("Invalid CSRF token")
+)
+
+// sessionMiddleware starts the session of the user agent for the pages and
+// keeps them out of frames and caches
+type sessionMiddleware struct {
+	service *Service
+}
+
+func newSessionMiddleware(service *Service) *sessionMiddleware {
+	return &sessionMiddleware{service: service}
+}
+
+// ServeHTTP as per the negroni.Handler interface
+func (m *sessionMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "frame-ancestors 'none'")
+	w.Header().Set("Cache-Control", "no-store")
+
+	m.service.sessionService.SetSessionService(r, w)
+	if err := m.service.sessionService.StartSession(); err != nil {
+		logger.WithContext(r.Context()).Errorf("Starting web session failed: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	next(w, r)
+}
+
+// csrfMiddleware refuses forms not posted with the session's CSRF token,
+// it must run after sessionMiddleware
+type csrfMiddleware struct {
+	service *Service
+}
+
+func newCSRFMiddleware(service *Service) *csrfMiddleware {
+	return &csrfMiddleware{service: service}
+}
+
+// ServeHTTP as per the negroni.Handler interface
+func (m *csrfMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if err := r.ParseForm(); err != nil {
+		m.service.renderError(w, r, nil, err)
+		return
+	}
+	if !m.service.sessionService.CheckCSRFToken(r.PostForm.Get(csrfTokenField)) {
+		m.service.renderError(w, r, nil, ErrInvalidCSRFToken)
+		return
+	}
+
+	next(w, r)
+}
@@ -0,0 +1,89 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/mocks"
+	"github.com/RichardKnop/go-oauth2-server/session"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/sessions"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestService() (*Service, *mux.Router) {
+	cnf := &config.Config{
+		Web: config.WebConfig{
+			TemplatesDir:    "templates",
+			LocalesDir:      "locales",
+			DefaultLanguage: "en",
+			PrimaryColor:    "#2a6ebb",
+		},
+	}
+	oauthServiceMock := new(mocks.ServiceInterface)
+	oauthServiceMock.On("GetTenantConfig", "").Return(&oauth.TenantConfig{
+		Branding: &oauth.Branding{PrimaryColor: "#ff0000", BackgroundColor: "red;}", DefaultLanguage: "en"},
+	}, nil)
+	sessionService := session.NewService(cnf, sessions.NewCookieStore([]byte("test_secret")))
+
+	service := NewService(cnf, oauthServiceMock, sessionService, nil)
+	router := mux.NewRouter()
+	service.RegisterRoutes(router, "/web")
+	return service, router
+}
+
+func TestLoginForm(t *testing.T) {
+	_, router := newTestService()
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/web/login", nil)
+	r.Header.Set("Accept-Language", "zh-CN,zh;q=0.9")
+	router.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "DENY", w.Header().Get("X-Frame-Options"))
+	assert.Contains(t, w.Body.String(), `<html lang="zh">`)
+	assert.Contains(t, w.Body.String(), "登录")
+	assert.Contains(t, w.Body.String(), `name="csrf_token"`)
+	// Only hex colors of the tenant's branding are used
+	assert.Contains(t, w.Body.String(), "#ff0000")
+	assert.NotContains(t, w.Body.String(), "red;}")
+}
+
+func TestCSRFMiddleware(t *testing.T) {
+	_, router := newTestService()
+
+	// Forms posted without the token of the session are refused
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/web/logout", strings.NewReader("csrf_token=bogus"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	router.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// The token of the form is accepted with the session cookie
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/web/login", nil))
+	if !assert.Equal(t, http.StatusOK, w.Code) {
+		return
+	}
+	body := w.Body.String()
+	start := strings.Index(body, `name="csrf_token" value="`) + len(`name="csrf_token" value="`)
+	csrfToken := body[start : start+strings.Index(body[start:], `"`)]
+	cookies := w.Result().Cookies()
+
+	w = httptest.NewRecorder()
+	form := url.Values{"csrf_token": {csrfToken}}
+	r = httptest.NewRequest("POST", "/web/logout", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for _, cookie := range cookies {
+		r.AddCookie(cookie)
+	}
+	router.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "You have been logged out")
+}
@@ -0,0 +1,162 @@
+package web
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/gorilla/mux"
+)
+
+// Templates of the pages, each page defines the title and content blocks
+// of the layout
+const (
+	layoutTemplate  = "layout.html"
+	loginTemplate   = "login.html"
+	mfaTemplate     = "mfa.html"
+	consentTemplate = "consent.html"
+	logoutTemplate  = "logout.html"
+	errorTemplate   = "error.html"
+
+	csrfTokenField = "csrf_token"
+)
+
+// Colors end up in the style sheet of the layout, only hex colors are used
+var colorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{3,8}$`)
+
+// page is what every template is rendered with
+type page struct {
+	Lang      string
+	Branding  *oauth.Branding
+	CSRFToken string
+	// Flash is the translated flash message of the previous request
+	Flash string
+	// Data holds the values of the page
+	Data map[string]interface{}
+
+	catalogs catalogs
+	fallback string
+}
+
+// T returns a message translated to the language of the page, templates
+// call it as {{.T "login.title"}}
+func (p *page) T(key string, args ...interface{}) string {
+	return p.catalogs.translate(p.Lang, p.fallback, key, args...)
+}
+
+// render writes a page in the branding of the client's tenant, the tenant
+// of the path when there is no client
+func (s *Service) render(w http.ResponseWriter, r *http.Request, client *models.OauthClient, status int, name string, data map[string]interface{}) {
+	c, err := s.getCatalogs()
+	if err != nil {
+		logger.WithContext(r.Context()).Errorf("Loading web page messages failed: %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	branding := s.getBranding(getTenantID(r, client))
+	p := &page{
+		Lang:     c.negotiate(r.Header.Get("Accept-Language"), branding.DefaultLanguage),
+		Branding: branding,
+		Data:     data,
+		catalogs: c,
+		fallback: s.cnf.Web.DefaultLanguage,
+	}
+	p.CSRFToken, err = s.sessionService.GetCSRFToken()
+	if err != nil {
+		logger.WithContext(r.Context()).Errorf("Generating CSRF token failed: %s", err)
+	}
+	// Flash messages are message keys
+	if flash, _ := s.sessionService.GetFlashMessage(); flash != nil {
+		if key, ok := flash.(string); ok {
+			p.Flash = p.T(key)
+		}
+	}
+
+	tmpl, err := s.getTemplate(branding.TemplatesDir, name)
+	if err != nil {
+		logger.WithContext(r.Context()).Errorf("Loading web page template %s failed: %s", name, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	// Render into a buffer first, so a failing template gives an error page
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, layoutTemplate, p); err != nil {
+		logger.WithContext(r.Context()).Errorf("Rendering web page template %s failed: %s", name, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+}
+
+// getTemplate returns a page parsed with the layout. Templates in the
+// tenant's directory replace the default ones of the same name, parsed
+// templates are cached outside of development.
+func (s *Service) getTemplate(tenantDir, name string) (*template.Template, error) {
+	cacheKey := tenantDir + "|" + name
+	s.templatesMu.Lock()
+	defer s.templatesMu.Unlock()
+	if tmpl, ok := s.templates[cacheKey]; ok && !s.cnf.IsDevelopment {
+		return tmpl, nil
+	}
+
+	tmpl, err := template.ParseFiles(
+		s.templatePath(tenantDir, layoutTemplate),
+		s.templatePath(tenantDir, name),
+	)
+	if err != nil {
+		return nil, err
+	}
+	s.templates[cacheKey] = tmpl
+	return tmpl, nil
+}
+
+// templatePath returns the tenant's template if there is one and the
+// default template otherwise
+func (s *Service) templatePath(tenantDir, name string) string {
+	if tenantDir != "" {
+		path := filepath.Join(tenantDir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return filepath.Join(s.cnf.Web.TemplatesDir, name)
+}
+
+// getBranding returns the branding of a tenant, the default branding when
+// the tenant cannot be found
+func (s *Service) getBranding(tenantID string) *oauth.Branding {
+	branding := &oauth.Branding{
+		LogoURL:         s.cnf.Web.LogoURL,
+		PrimaryColor:    s.cnf.Web.PrimaryColor,
+		BackgroundColor: s.cnf.Web.BackgroundColor,
+		DefaultLanguage: s.cnf.Web.DefaultLanguage,
+	}
+	if tenant, err := s.oauthService.GetTenantConfig(tenantID); err == nil && tenant.Branding != nil {
+		branding = tenant.Branding
+	}
+	if !colorPattern.MatchString(branding.PrimaryColor) {
+		branding.PrimaryColor = ""
+	}
+	if !colorPattern.MatchString(branding.BackgroundColor) {
+		branding.BackgroundColor = ""
+	}
+	return branding
+}
+
+// getTenantID returns the tenant the pages are shown for, the client's
+// tenant or else the tenant of the path
+func getTenantID(r *http.Request, client *models.OauthClient) string {
+	if client != nil {
+		return client.TenantID
+	}
+	return mux.Vars(r)["tenant"]
+}
@@ -0,0 +1,94 @@
+package web
+
+import (
+	"github.com/RichardKnop/go-oauth2-server/util/routes"
+	"github.com/gorilla/mux"
+	"github.com/urfave/negroni"
+)
+
+// Pages link to each other with relative paths, so they work under every
+// prefix the routes are registered with
+const (
+	authorizePath = "/authorize"
+	loginPath     = "/login"
+	mfaPath       = "/mfa"
+	consentPath   = "/consent"
+	logoutPath    = "/logout"
+)
+
+// RegisterRoutes registers route handlers for the web service
+func (s *Service) RegisterRoutes(router *mux.Router, prefix string) {
+	subRouter := router.PathPrefix(prefix).Subrouter()
+	routes.AddRoutes(s.GetRoutes(), subRouter)
+}
+
+// GetRoutes returns []routes.Route slice for the web service
+func (s *Service) GetRoutes() []routes.Route {
+	page := []negroni.Handler{newSessionMiddleware(s)}
+	form := []negroni.Handler{newSessionMiddleware(s), newCSRFMiddleware(s)}
+	return []routes.Route{
+		{
+			Name:        "web_authorize",
+			Method:      "GET",
+			Pattern:     authorizePath,
+			HandlerFunc: s.authorizeHandler,
+			Middlewares: page,
+		},
+		{
+			Name:        "web_login_form",
+			Method:      "GET",
+			Pattern:     loginPath,
+			HandlerFunc: s.loginFormHandler,
+			Middlewares: page,
+		},
+		{
+			Name:        "web_login",
+			Method:      "POST",
+			Pattern:     loginPath,
+			HandlerFunc: s.loginHandler,
+			Middlewares: form,
+		},
+		{
+			Name:        "web_mfa_form",
+			Method:      "GET",
+			Pattern:     mfaPath,
+			HandlerFunc: s.mfaFormHandler,
+			Middlewares: page,
+		},
+		{
+			Name:        "web_mfa",
+			Method:      "POST",
+			Pattern:     mfaPath,
+			HandlerFunc: s.mfaHandler,
+			Middlewares: form,
+		},
+		{
+			Name:        "web_consent_form",
+			Method:      "GET",
+			Pattern:     consentPath,
+			HandlerFunc: s.consentFormHandler,
+			Middlewares: page,
+		},
+		{
+			Name:        "web_consent",
+			Method:      "POST",
+			Pattern:     consentPath,
+			HandlerFunc: s.consentHandler,
+			Middlewares: form,
+		},
+		{
+			Name:        "web_logout_form",
+			Method:      "GET",
+			Pattern:     logoutPath,
+			HandlerFunc: s.logoutFormHandler,
+			Middlewares: page,
+		},
+		{
+			Name:        "web_logout",
+			Method:      "POST",
+			Pattern:     logoutPath,
+			HandlerFunc: s.logoutHandler,
+			Middlewares: form,
+		},
+	}
+}
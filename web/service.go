@@ -0,0 +1,58 @@
+package web
+
+import (
+	"html/template"
+	"sync"
+
+	"github.com/RichardKnop/go-oauth2-server/audit"
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/log"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/session"
+)
+
+var logger = log.New("web")
+
+// Service struct keeps objects to avoid passing them around
+type Service struct {
+	cnf            *config.Config
+	oauthService   oauth.ServiceInterface
+	sessionService session.ServiceInterface
+	auditService   audit.ServiceInterface
+
+	templates   map[string]*template.Template
+	templatesMu sync.Mutex
+
+	catalogs     catalogs
+	catalogsErr  error
+	catalogsOnce sync.Once
+}
+
+// NewService returns a new Service instance
+func NewService(cnf *config.Config, oauthService oauth.ServiceInterface, sessionService session.ServiceInterface, auditService audit.ServiceInterface) *Service {
+	return &Service{
+		cnf:            cnf,
+		oauthService:   oauthService,
+		sessionService: sessionService,
+		auditService:   auditService,
+		templates:      make(map[string]*template.Template),
+	}
+}
+
+// GetConfig returns config.Config instance
+func (s *Service) GetConfig() *config.Config {
+	return s.cnf
+}
+
+// GetOauthService returns oauth.Service instance
+func (s *Service) GetOauthService() oauth.ServiceInterface {
+	return s.oauthService
+}
+
+// GetSessionService returns session.Service instance
+func (s *Service) GetSessionService() session.ServiceInterface {
+	return s.sessionService
+}
+
+// Close stops any running services
+func (s *Service) Close() {}
@@ -0,0 +1,20 @@
+package web
+
+import (
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/session"
+	"github.com/RichardKnop/go-oauth2-server/util/routes"
+	"github.com/gorilla/mux"
+)
+
+// ServiceInterface defines exported methods
+type ServiceInterface interface {
+	// Exported methods
+	GetConfig() *config.Config
+	GetOauthService() oauth.ServiceInterface
+	GetSessionService() session.ServiceInterface
+	GetRoutes() []routes.Route
+	RegisterRoutes(router *mux.Router, prefix string)
+	Close()
+}
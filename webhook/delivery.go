@@ -0,0 +1,186 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+)
+
+const maxErrorLength = 500
+
+var (
+	// ErrDeliveryNotFound ...
+	ErrDeliveryNotFound = errors.New("Webhook delivery not found")
+	// ErrDeliveryNotDead ...
+	ErrDeliveryNotDead = errors.New("Only dead webhook deliveries can be replayed")
+)
+
+// Sign returns the hex encoded HMAC-SHA256 of the timestamp and payload,
+// which receivers recompute with the subscription secret to check the
+// X-Webhook-Signature header
+func Sign(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ListDeadLetters returns a page of the tenant's deliveries which ran out of
+// attempts, most recent first, and how many there are in total
+func (s *Service) ListDeadLetters(tenantID string, offset, limit int) ([]*models.WebhookDelivery, int, error) {
+	query := s.db.Model(new(models.WebhookDelivery)).
+		Where("tenant_id = ? AND status = ?", tenantID, models.WebhookDeliveryDead)
+
+	var count int
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+	var deliveries []*models.WebhookDelivery
+	err := query.Order("updated_at desc").Offset(offset).Limit(limit).Find(&deliveries).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return deliveries, count, nil
+}
+
+// FindDeliveryByID looks up a delivery by ID
+func (s *Service) FindDeliveryByID(id string) (*models.WebhookDelivery, error) {
+	delivery := new(models.WebhookDelivery)
+	if s.db.Where("id = ?", id).First(delivery).RecordNotFound() {
+		return nil, ErrDeliveryNotFound
+	}
+	return delivery, nil
+}
+
+// ReplayDelivery puts a dead delivery back in the outbox with a fresh set
+// of attempts
+func (s *Service) ReplayDelivery(delivery *models.WebhookDelivery) error {
+	if delivery.Status != models.WebhookDeliveryDead {
+		return ErrDeliveryNotDead
+	}
+	now := time.Now().UTC()
+	err := s.db.Model(delivery).UpdateColumns(map[string]interface{}{
+		"status":          models.WebhookDeliveryPending,
+		"attempts":        0,
+		"next_attempt_at": now,
+		"updated_at":      now,
+	}).Error
+	if err != nil {
+		return err
+	}
+	delivery.Status = models.WebhookDeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = now
+	return nil
+}
+
+// dispatch sends a batch of the deliveries which are due
+func (s *Service) dispatch() {
+	now := time.Now().UTC()
+	var deliveries []*models.WebhookDelivery
+	err := s.db.Preload("Subscription").
+		Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, now).
+		Order("next_attempt_at").Limit(s.cnf.Webhook.BatchSize).Find(&deliveries).Error
+	if err != nil {
+		logger.Errorf("Loading webhook deliveries failed: %s", err)
+		return
+	}
+
+	// A claimed delivery is retried if the server stops before the attempt
+	// is recorded
+	lease := 2 * s.httpClient.Timeout
+	for _, delivery := range deliveries {
+		// Other servers polling the outbox skip deliveries claimed already
+		claimed := s.db.Model(new(models.WebhookDelivery)).
+			Where("id = ? AND status = ? AND next_attempt_at = ?",
+				delivery.ID, models.WebhookDeliveryPending, delivery.NextAttemptAt).
+			UpdateColumn("next_attempt_at", now.Add(lease)).RowsAffected == 1
+		if claimed {
+			s.attempt(delivery)
+		}
+	}
+}
+
+// attempt sends a delivery and records the outcome, failed deliveries are
+// retried with an exponential delay until they run out of attempts
+func (s *Service) attempt(delivery *models.WebhookDelivery) {
+	err := s.send(delivery)
+	now := time.Now().UTC()
+	updates := map[string]interface{}{
+		"attempts":   delivery.Attempts + 1,
+		"updated_at": now,
+	}
+	if err == nil {
+		updates["status"] = models.WebhookDeliveryDelivered
+		updates["delivered_at"] = now
+		updates["last_error"] = ""
+	} else {
+		message := err.Error()
+		if len(message) > maxErrorLength {
+			message = message[:maxErrorLength]
+		}
+		updates["last_error"] = message
+		if delivery.Attempts+1 >= s.cnf.Webhook.MaxAttempts {
+			updates["status"] = models.WebhookDeliveryDead
+			logger.Warnf("Webhook delivery %s gave up after %d attempts: %s",
+				delivery.ID, delivery.Attempts+1, err)
+		} else {
+			updates["next_attempt_at"] = now.Add(s.retryDelay(delivery.Attempts + 1))
+		}
+	}
+	if err := s.db.Model(delivery).UpdateColumns(updates).Error; err != nil {
+		logger.Errorf("Recording webhook delivery %s failed: %s", delivery.ID, err)
+	}
+}
+
+// send posts the payload of a delivery to its subscription's URL, signed
+// with the subscription secret
+func (s *Service) send(delivery *models.WebhookDelivery) error {
+	if delivery.Subscription == nil {
+		return ErrSubscriptionNotFound
+	}
+	payload := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequest("POST", delivery.Subscription.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-ID", delivery.ID)
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Webhook-Signature", "sha256="+Sign(delivery.Subscription.Secret, timestamp, payload))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// retryDelay returns how long to wait after the given number of failed
+// attempts, doubling from the retry interval up to the max retry interval
+func (s *Service) retryDelay(attempts int) time.Duration {
+	delay := time.Duration(s.cnf.Webhook.RetryInterval) * time.Second
+	maxDelay := time.Duration(s.cnf.Webhook.MaxRetryInterval) * time.Second
+	for i := 1; i < attempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
@@ -0,0 +1,101 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSign(t *testing.T) {
+	payload := []byte(`{"type":"token_issued"}`)
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("1500000000." + string(payload)))
+	assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), Sign("secret", 1500000000, payload))
+
+	// The timestamp is signed so a captured request can't be replayed later
+	assert.NotEqual(t, Sign("secret", 1500000000, payload), Sign("secret", 1500000001, payload))
+	assert.NotEqual(t, Sign("secret", 1500000000, payload), Sign("other", 1500000000, payload))
+}
+
+func TestRetryDelay(t *testing.T) {
+	service := NewService(&config.Config{
+		Webhook: config.WebhookConfig{RetryInterval: 30, MaxRetryInterval: 300},
+	}, nil)
+
+	assert.Equal(t, 30*time.Second, service.retryDelay(1))
+	assert.Equal(t, 60*time.Second, service.retryDelay(2))
+	assert.Equal(t, 120*time.Second, service.retryDelay(3))
+	assert.Equal(t, 240*time.Second, service.retryDelay(4))
+	assert.Equal(t, 300*time.Second, service.retryDelay(5))
+	assert.Equal(t, 300*time.Second, service.retryDelay(20))
+}
+
+func TestSend(t *testing.T) {
+	var (
+		headers http.Header
+		body    []byte
+		status  = http.StatusNoContent
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+		body, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	service := NewService(&config.Config{Webhook: config.WebhookConfig{Timeout: 5}}, nil)
+	delivery := &models.WebhookDelivery{
+		ID:        "delivery_id",
+		EventType: "login_failure",
+		Payload:   `{"type":"login_failure"}`,
+		Subscription: &models.WebhookSubscription{
+			URL:    server.URL,
+			Secret: "secret",
+		},
+	}
+
+	// The test server listens on loopback, which deliveries never reach
+	assert.Error(t, service.send(delivery))
+	assert.Nil(t, headers)
+	service.httpClient = server.Client()
+
+	if !assert.NoError(t, service.send(delivery)) {
+		return
+	}
+	assert.Equal(t, delivery.Payload, string(body))
+	assert.Equal(t, "application/json", headers.Get("Content-Type"))
+	assert.Equal(t, "delivery_id", headers.Get("X-Webhook-ID"))
+	assert.Equal(t, "login_failure", headers.Get("X-Webhook-Event"))
+
+	// Receivers check the signature against the timestamp header
+	timestamp, err := strconv.ParseInt(headers.Get("X-Webhook-Timestamp"), 10, 64)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "sha256="+Sign("secret", timestamp, body), headers.Get("X-Webhook-Signature"))
+	}
+
+	// Responses other than 2xx fail the attempt
+	status = http.StatusInternalServerError
+	assert.EqualError(t, service.send(delivery), "status 500")
+
+	// So do deliveries of deleted subscriptions
+	delivery.Subscription = nil
+	assert.Equal(t, ErrSubscriptionNotFound, service.send(delivery))
+}
+
+func TestSubscribes(t *testing.T) {
+	subscription := &models.WebhookSubscription{EventTypes: "token_issued token_revoked"}
+	assert.True(t, subscribes(subscription, "token_issued"))
+	assert.True(t, subscribes(subscription, "token_revoked"))
+	assert.False(t, subscribes(subscription, "login_failure"))
+}
@@ -0,0 +1,83 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/audit"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/uuid"
+	"github.com/jinzhu/gorm"
+)
+
+// transactionalEventTypes are enqueued by the oauth service in the
+// transaction of the change they are about, the audit sink skips them so
+// they are not delivered twice
+var transactionalEventTypes = []string{
+	audit.TokenIssued,
+	audit.TokenRefreshed,
+	audit.TokenRevoked,
+	audit.KeyRotated,
+}
+
+// Enqueue writes a delivery of the event to the outbox for every
+// subscription of the event's tenant to its type. Pass the transaction of
+// the change the event is about so the event is only sent if the change
+// is committed
+func (s *Service) Enqueue(db *gorm.DB, event *audit.Event) error {
+	var subscriptions []*models.WebhookSubscription
+	if err := db.Where("tenant_id = ?", event.TenantID).Find(&subscriptions).Error; err != nil {
+		return err
+	}
+
+	event.Stamp()
+	var payload []byte
+	for _, subscription := range subscriptions {
+		if !subscribes(subscription, event.Type) {
+			continue
+		}
+		if payload == nil {
+			var err error
+			if payload, err = json.Marshal(event); err != nil {
+				return err
+			}
+		}
+		now := time.Now().UTC()
+		delivery := &models.WebhookDelivery{
+			ID:             uuid.New(),
+			SubscriptionID: subscription.ID,
+			TenantID:       event.TenantID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Payload:        string(payload),
+			Status:         models.WebhookDeliveryPending,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+		}
+		if err := db.Create(delivery).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AuditSink returns an audit sink enqueueing the recorded events which are
+// not enqueued in a transaction
+func (s *Service) AuditSink() audit.Sink {
+	return &auditSink{service: s}
+}
+
+// auditSink enqueues events as they are recorded
+type auditSink struct {
+	service *Service
+}
+
+// Write enqueues an event
+func (s *auditSink) Write(event *audit.Event) error {
+	for _, eventType := range transactionalEventTypes {
+		if event.Type == eventType {
+			return nil
+		}
+	}
+	return s.service.Enqueue(s.service.db, event)
+}
@@ -0,0 +1,71 @@
+package webhook
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/log"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/jinzhu/gorm"
+)
+
+var logger = log.New("webhook")
+
+// Service keeps the subscriptions and delivers the events of the outbox
+type Service struct {
+	cnf        *config.Config
+	db         *gorm.DB
+	httpClient *http.Client
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewService returns a new Service instance
+func NewService(cnf *config.Config, db *gorm.DB) *Service {
+	return &Service{
+		cnf: cnf,
+		db:  db,
+		// Subscription URLs are chosen by superusers of any tenant, so
+		// deliveries never reach internal addresses or follow redirects
+		httpClient: util.NewPublicHTTPClient(time.Duration(cnf.Webhook.Timeout) * time.Second),
+	}
+}
+
+// Start polls the outbox in the background until Close is called
+func (s *Service) Start() {
+	if s.stop != nil {
+		return
+	}
+	s.stop = make(chan struct{})
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		interval := time.Duration(s.cnf.Webhook.PollInterval) * time.Second
+		if interval <= 0 {
+			interval = 5 * time.Second
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.dispatch()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops polling the outbox, waiting for the running deliveries
+func (s *Service) Close() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	s.wg.Wait()
+	s.stop = nil
+}
@@ -0,0 +1,23 @@
+package webhook
+
+import (
+	"github.com/RichardKnop/go-oauth2-server/audit"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/jinzhu/gorm"
+)
+
+// ServiceInterface defines exported methods
+type ServiceInterface interface {
+	// Exported methods
+	CreateSubscription(tenantID, url string, eventTypes []string) (*models.WebhookSubscription, error)
+	ListSubscriptions(tenantID string) ([]*models.WebhookSubscription, error)
+	FindSubscriptionByID(id string) (*models.WebhookSubscription, error)
+	DeleteSubscription(subscription *models.WebhookSubscription) error
+	Enqueue(db *gorm.DB, event *audit.Event) error
+	AuditSink() audit.Sink
+	ListDeadLetters(tenantID string, offset, limit int) ([]*models.WebhookDelivery, int, error)
+	FindDeliveryByID(id string) (*models.WebhookDelivery, error)
+	ReplayDelivery(delivery *models.WebhookDelivery) error
+	Start()
+	Close()
+}
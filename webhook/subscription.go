@@ -0,0 +1,117 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/audit"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/RichardKnop/uuid"
+)
+
+const secretLength = 32
+
+var (
+	// ErrSubscriptionNotFound ...
+	ErrSubscriptionNotFound = errors.New("Webhook subscription not found")
+	// ErrInvalidURL ...
+	ErrInvalidURL = errors.New("Webhook URL must be an absolute http or https URL")
+	// ErrInternalURL ...
+	ErrInternalURL = errors.New("Webhook URL must not resolve to an internal address")
+	// ErrInvalidEventType ...
+	ErrInvalidEventType = errors.New("Invalid webhook event type")
+)
+
+// CreateSubscription subscribes a URL to events of the tenant, the returned
+// subscription holds the generated signing secret
+func (s *Service) CreateSubscription(tenantID, rawURL string, eventTypes []string) (*models.WebhookSubscription, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, ErrInvalidURL
+	}
+	// Deliveries are refused when dialing internal addresses too, this
+	// catches mistakes early
+	if err := util.CheckPublicHost(parsed.Hostname()); err != nil {
+		return nil, ErrInternalURL
+	}
+	if len(eventTypes) == 0 {
+		return nil, ErrInvalidEventType
+	}
+	for _, eventType := range eventTypes {
+		if !util.StringInSlice(eventType, audit.EventTypes) {
+			return nil, ErrInvalidEventType
+		}
+	}
+
+	b := make([]byte, secretLength)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	subscription := &models.WebhookSubscription{
+		MyGormModel: models.MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		TenantID:   tenantID,
+		URL:        rawURL,
+		Secret:     hex.EncodeToString(b),
+		EventTypes: strings.Join(eventTypes, " "),
+	}
+	if err := s.db.Create(subscription).Error; err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// ListSubscriptions returns the subscriptions of a tenant, oldest first
+func (s *Service) ListSubscriptions(tenantID string) ([]*models.WebhookSubscription, error) {
+	var subscriptions []*models.WebhookSubscription
+	err := s.db.Where("tenant_id = ?", tenantID).Order("created_at").Find(&subscriptions).Error
+	if err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// FindSubscriptionByID looks up a subscription by ID
+func (s *Service) FindSubscriptionByID(id string) (*models.WebhookSubscription, error) {
+	subscription := new(models.WebhookSubscription)
+	if s.db.Where("id = ?", id).First(subscription).RecordNotFound() {
+		return nil, ErrSubscriptionNotFound
+	}
+	return subscription, nil
+}
+
+// DeleteSubscription deletes a subscription along with its deliveries,
+// pending ones are never sent
+func (s *Service) DeleteSubscription(subscription *models.WebhookSubscription) error {
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	err := tx.Where("subscription_id = ?", subscription.ID).Delete(new(models.WebhookDelivery)).Error
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+	if err := tx.Unscoped().Delete(subscription).Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+	return nil
+}
+
+// subscribes returns true if the subscription wants events of the type
+func subscribes(subscription *models.WebhookSubscription, eventType string) bool {
+	return util.StringInSlice(eventType, strings.Fields(subscription.EventTypes))
+}